@@ -0,0 +1,40 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound     = errors.New("agent not found")
+	ErrDuplicate    = errors.New("agent with this email already exists")
+	ErrInvalidAgent = errors.New("agent requires a name and an email")
+)
+
+// Agent is a person answering customers in the conversation inbox
+type Agent struct {
+	Id    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	// Active agents can be assigned conversations
+	Active bool `json:"active"`
+	// AutoAssign puts the agent in the pool that new inbound conversations
+	// are distributed to
+	AutoAssign     bool       `json:"auto_assign"`
+	LastAssignedAt *time.Time `json:"last_assigned_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type Repository interface {
+	Create(context.Context, Agent) error
+	Update(context.Context, Agent) error
+	FindById(context.Context, uuid.UUID) (*Agent, error)
+	List(context.Context) ([]Agent, error)
+	// NextInPool returns the active auto-assign agent that was assigned a
+	// conversation the longest time ago and marks it assigned now, or nil
+	// when the pool is empty
+	NextInPool(context.Context) (*Agent, error)
+}
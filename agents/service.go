@@ -0,0 +1,59 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(ctx context.Context, agent Agent) (*Agent, error) {
+	agent.Name = strings.TrimSpace(agent.Name)
+	agent.Email = strings.ToLower(strings.TrimSpace(agent.Email))
+	if agent.Name == "" || !strings.Contains(agent.Email, "@") {
+		return nil, ErrInvalidAgent
+	}
+
+	agent.Id = uuid.New()
+	agent.CreatedAt = time.Now()
+	if err := s.repo.Create(ctx, agent); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+func (s *Service) Update(ctx context.Context, agent Agent) (*Agent, error) {
+	agent.Name = strings.TrimSpace(agent.Name)
+	agent.Email = strings.ToLower(strings.TrimSpace(agent.Email))
+	if agent.Name == "" || !strings.Contains(agent.Email, "@") {
+		return nil, ErrInvalidAgent
+	}
+
+	if err := s.repo.Update(ctx, agent); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id uuid.UUID) (*Agent, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]Agent, error) {
+	return s.repo.List(ctx)
+}
+
+// Next picks the agent for a new conversation, round-robin over the active
+// agents in the auto-assign pool. It returns nil when the pool is empty.
+func (s *Service) Next(ctx context.Context) (*Agent, error) {
+	return s.repo.NextInPool(ctx)
+}
@@ -0,0 +1,82 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound     = errors.New("API key not found")
+	ErrInvalidKey   = errors.New("invalid API key")
+	ErrInvalidScope = errors.New("invalid scope")
+	ErrInvalidName  = errors.New("API key requires a name")
+)
+
+// Scope is a permission granted to an API key
+type Scope string
+
+const (
+	ScopeSend           Scope = "send"
+	ScopeSchedule       Scope = "schedule"
+	ScopeTemplatesRead  Scope = "templates:read"
+	ScopeTemplatesWrite Scope = "templates:write"
+	ScopeHistoryRead    Scope = "history:read"
+	// ScopeAdmin grants every other scope
+	ScopeAdmin Scope = "admin"
+)
+
+var scopes = []Scope{ScopeSend, ScopeSchedule, ScopeTemplatesRead, ScopeTemplatesWrite, ScopeHistoryRead, ScopeAdmin}
+
+func (s Scope) Valid() bool {
+	return slices.Contains(scopes, s)
+}
+
+// Key is an API key. Only the hash of its token is stored; the token itself
+// is shown once, when the key is created.
+type Key struct {
+	Id uuid.UUID `json:"id"`
+	// TenantId is the tenant every request made with the key is scoped to
+	TenantId uuid.UUID `json:"tenant_id"`
+	Name     string    `json:"name"`
+	// Prefix is the start of the token, to tell keys apart
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether the key grants scope
+func (k *Key) Allows(scope Scope) bool {
+	return slices.Contains(k.Scopes, ScopeAdmin) || slices.Contains(k.Scopes, scope)
+}
+
+type Repository interface {
+	Create(context.Context, Key) error
+	// FindByHash returns the key with the token hash, revoked or not
+	FindByHash(ctx context.Context, hash string) (*Key, error)
+	List(context.Context) ([]Key, error)
+	// Revoke marks the key revoked at the given time
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	// Touch records that the key was used at the given time
+	Touch(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the key that authenticated the
+// request
+func NewContext(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key that authenticated the request, if any
+func FromContext(ctx context.Context) *Key {
+	key, _ := ctx.Value(contextKey{}).(*Key)
+	return key
+}
@@ -0,0 +1,92 @@
+package apikeys_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"mbx/apikeys"
+	"mbx/apikeys/mocks"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestService_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := apikeys.NewService(repo)
+
+	_, _, err := service.Create(context.Background(), "ops", []apikeys.Scope{"everything"})
+	if !errors.Is(err, apikeys.ErrInvalidScope) {
+		t.Errorf("Expected ErrInvalidScope, got %v", err)
+	}
+	_, _, err = service.Create(context.Background(), " ", []apikeys.Scope{apikeys.ScopeAdmin})
+	if !errors.Is(err, apikeys.ErrInvalidName) {
+		t.Errorf("Expected ErrInvalidName, got %v", err)
+	}
+
+	var stored apikeys.Key
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, k apikeys.Key) error {
+		stored = k
+		return nil
+	})
+	key, token, err := service.Create(context.Background(), "ops", []apikeys.Scope{apikeys.ScopeSend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, key.Prefix) || len(token) != 68 {
+		t.Errorf("Expected a token starting with %q, got %q", key.Prefix, token)
+	}
+	if stored.Hash == "" || strings.Contains(stored.Hash, token) {
+		t.Errorf("Expected only the hash of the token to be stored, got %q", stored.Hash)
+	}
+}
+
+func TestService_Authenticate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := apikeys.NewService(repo)
+
+	var stored apikeys.Key
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, k apikeys.Key) error {
+		stored = k
+		return nil
+	})
+	_, token, err := service.Create(context.Background(), "ops", []apikeys.Scope{apikeys.ScopeHistoryRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Authenticate(context.Background(), "not-a-key"); !errors.Is(err, apikeys.ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for a malformed token, got %v", err)
+	}
+
+	// used a moment ago, so the last use is not written again
+	recently := time.Now().Add(-time.Second)
+	stored.LastUsedAt = &recently
+	repo.EXPECT().FindByHash(gomock.Any(), stored.Hash).Return(&stored, nil)
+	key, err := service.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Allows(apikeys.ScopeHistoryRead) || key.Allows(apikeys.ScopeSend) {
+		t.Errorf("Expected only the history:read scope, got %v", key.Scopes)
+	}
+
+	stale := time.Now().Add(-time.Hour)
+	stored.LastUsedAt = &stale
+	repo.EXPECT().FindByHash(gomock.Any(), stored.Hash).Return(&stored, nil)
+	repo.EXPECT().Touch(gomock.Any(), stored.Id, gomock.Any()).Return(nil)
+	if _, err := service.Authenticate(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked := stored
+	revoked.RevokedAt = &stale
+	repo.EXPECT().FindByHash(gomock.Any(), stored.Hash).Return(&revoked, nil)
+	if _, err := service.Authenticate(context.Background(), token); !errors.Is(err, apikeys.ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for a revoked key, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package autoreply
+
+import (
+	"mbx/contacts"
+	"mbx/inbound"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// folds maps accented letters used in Portuguese and Spanish to their base
+// letter, so "horário" matches "horario" and "2ª via" matches "2a via"
+var folds = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'ª': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'º': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+// normalizeText lowercases text, folds accents and turns everything but
+// letters and digits into single spaces, padding the result with spaces so
+// whole words can be found with strings.Contains
+func normalizeText(text string) string {
+	var b strings.Builder
+	b.WriteByte(' ')
+	space := true
+	for _, r := range strings.ToLower(text) {
+		if folded, ok := folds[r]; ok {
+			r = folded
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	if !space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func (m Match) matches(msg *inbound.Message) bool {
+	switch m.Type {
+	case MatchKeyword:
+		text := normalizeText(msg.Body)
+		for _, keyword := range m.Keywords {
+			if k := normalizeText(keyword); k != " " && strings.Contains(text, k) {
+				return true
+			}
+		}
+	case MatchRegex:
+		re, err := regexp.Compile(m.Pattern)
+		return err == nil && re.MatchString(msg.Body)
+	case MatchPayload:
+		return msg.Payload() != "" && msg.Payload() == m.Payload
+	}
+	return false
+}
+
+func (c Conditions) hold(contact *contacts.Contact, now time.Time) bool {
+	for _, tag := range c.Tags {
+		if !contact.HasTag(tag) {
+			return false
+		}
+	}
+	if len(c.Weekdays) > 0 && !slices.Contains(c.Weekdays, now.Weekday()) {
+		return false
+	}
+	if c.From == "" || c.To == "" {
+		return true
+	}
+
+	from, _ := parseClock(c.From)
+	to, _ := parseClock(c.To)
+	minute := now.Hour()*60 + now.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to
+}
+
+// parseClock returns the minutes since midnight of a "15:04" time
+func parseClock(value string) (int, bool) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
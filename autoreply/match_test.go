@@ -0,0 +1,96 @@
+package autoreply
+
+import (
+	"mbx/contacts"
+	"mbx/history"
+	"mbx/inbound"
+	"testing"
+	"time"
+)
+
+func TestMatch_Keyword(t *testing.T) {
+	match := Match{Type: MatchKeyword, Keywords: []string{"horario", "2a via", "boleto"}}
+
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{"Qual o horário de vocês?", true},
+		{"Preciso da 2ª via", true},
+		{"BOLETO!", true},
+		{"boletos", false},
+		{"Oi, tudo bem?", false},
+	}
+	for _, tt := range tests {
+		if got := match.matches(&inbound.Message{Body: tt.body}); got != tt.want {
+			t.Errorf("matches(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestMatch_RegexAndPayload(t *testing.T) {
+	regex := Match{Type: MatchRegex, Pattern: `(?i)pedido\s+#?\d+`}
+	if !regex.matches(&inbound.Message{Body: "Status do pedido #1234"}) {
+		t.Error("Expected regex to match order number")
+	}
+
+	payload := Match{Type: MatchPayload, Payload: "TRACK_ORDER"}
+	tapped := &inbound.Message{
+		Body:        "Track",
+		Interaction: &history.Interaction{Type: history.InteractionButton, Payload: "TRACK_ORDER"},
+	}
+	if !payload.matches(tapped) {
+		t.Error("Expected payload to match")
+	}
+	if payload.matches(&inbound.Message{Body: "TRACK_ORDER"}) {
+		t.Error("Expected typed text not to match a button payload")
+	}
+}
+
+func TestConditions_Hold(t *testing.T) {
+	contact := &contacts.Contact{Tags: []string{"vip"}}
+	afterHours := Conditions{Weekdays: []time.Weekday{time.Friday}, From: "18:00", To: "08:00"}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"evening", time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC), true},
+		{"early morning", time.Date(2026, 10, 16, 7, 59, 0, 0, time.UTC), true},
+		{"business hours", time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), false},
+		{"other weekday", time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got := afterHours.hold(contact, tt.at); got != tt.want {
+			t.Errorf("%s: hold() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if (Conditions{Tags: []string{"vip", "b2b"}}).hold(contact, time.Now()) {
+		t.Error("Expected conditions requiring a missing tag not to hold")
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	valid := Rule{
+		Name:    "Opening hours",
+		Match:   Match{Type: MatchKeyword, Keywords: []string{"horario"}},
+		Actions: []Action{{Type: ActionReplyText, Text: "We are open 8am to 6pm"}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid rule, got %v", err)
+	}
+
+	invalid := valid
+	invalid.Conditions = Conditions{From: "8am", To: "18:00"}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected invalid time to be rejected")
+	}
+
+	invalid = valid
+	invalid.Match = Match{Type: MatchRegex, Pattern: "("}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected invalid regex to be rejected")
+	}
+}
@@ -0,0 +1,169 @@
+package autoreply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound    = errors.New("auto-reply rule not found")
+	ErrInvalidRule = errors.New("invalid auto-reply rule")
+)
+
+type MatchType string
+
+const (
+	// MatchKeyword matches messages containing any of the keywords as whole
+	// words, ignoring case and accents
+	MatchKeyword MatchType = "keyword"
+	// MatchRegex matches messages against a regular expression
+	MatchRegex MatchType = "regex"
+	// MatchPayload matches the payload of the quick reply button the customer
+	// tapped
+	MatchPayload MatchType = "payload"
+)
+
+type Match struct {
+	Type     MatchType `json:"type"`
+	Keywords []string  `json:"keywords,omitempty"`
+	Pattern  string    `json:"pattern,omitempty"`
+	Payload  string    `json:"payload,omitempty"`
+}
+
+// Conditions restrict when a matching rule applies. Times are evaluated in
+// the timezone of the contact, or the default timezone of the service.
+type Conditions struct {
+	// Tags the contact must all have
+	Tags []string `json:"tags,omitempty"`
+	// Weekdays the rule applies on, 0 being Sunday. Empty means every day.
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+	// From and To bound the time of day as "15:04". A range that ends before
+	// it starts spans midnight, e.g. 18:00 to 08:00.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+type ActionType string
+
+const (
+	// ActionReplyText replies with a freeform message
+	ActionReplyText ActionType = "reply_text"
+	// ActionReplyTemplate replies with a template group in the locale of the
+	// contact
+	ActionReplyTemplate ActionType = "reply_template"
+	// ActionTag adds a tag to the contact
+	ActionTag ActionType = "tag"
+	// ActionWebhook forwards the message to an HTTP endpoint
+	ActionWebhook ActionType = "webhook"
+)
+
+// Action is what a rule does when it matches. Text and template variables may
+// use the {{name}}, {{phone}} and {{profile_name}} placeholders.
+type Action struct {
+	Type      ActionType        `json:"type"`
+	Text      string            `json:"text,omitempty"`
+	Template  string            `json:"template,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Tag       string            `json:"tag,omitempty"`
+	URL       string            `json:"url,omitempty"`
+}
+
+// Rule answers inbound messages automatically. Enabled rules are evaluated by
+// ascending priority and the first one that matches runs its actions.
+type Rule struct {
+	Id         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Enabled    bool       `json:"enabled"`
+	Priority   int        `json:"priority"`
+	Match      Match      `json:"match"`
+	Conditions Conditions `json:"conditions"`
+	Actions    []Action   `json:"actions"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Validate reports the first problem with the rule, wrapping ErrInvalidRule
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidRule)
+	}
+
+	switch r.Match.Type {
+	case MatchKeyword:
+		if len(r.Match.Keywords) == 0 {
+			return fmt.Errorf("%w: keyword match requires keywords", ErrInvalidRule)
+		}
+	case MatchRegex:
+		if _, err := regexp.Compile(r.Match.Pattern); err != nil || r.Match.Pattern == "" {
+			return fmt.Errorf("%w: invalid regex pattern", ErrInvalidRule)
+		}
+	case MatchPayload:
+		if r.Match.Payload == "" {
+			return fmt.Errorf("%w: payload match requires a payload", ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: match type must be one of: keyword, regex, payload", ErrInvalidRule)
+	}
+
+	if (r.Conditions.From == "") != (r.Conditions.To == "") {
+		return fmt.Errorf("%w: time conditions require both from and to", ErrInvalidRule)
+	}
+	for _, value := range []string{r.Conditions.From, r.Conditions.To} {
+		if _, ok := parseClock(value); value != "" && !ok {
+			return fmt.Errorf("%w: times must be formatted as HH:MM", ErrInvalidRule)
+		}
+	}
+	for _, day := range r.Conditions.Weekdays {
+		if day < time.Sunday || day > time.Saturday {
+			return fmt.Errorf("%w: weekdays must be between 0 and 6", ErrInvalidRule)
+		}
+	}
+
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("%w: at least one action is required", ErrInvalidRule)
+	}
+	for _, a := range r.Actions {
+		if err := a.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a Action) validate() error {
+	switch a.Type {
+	case ActionReplyText:
+		if a.Text == "" {
+			return fmt.Errorf("%w: reply_text requires text", ErrInvalidRule)
+		}
+	case ActionReplyTemplate:
+		if a.Template == "" {
+			return fmt.Errorf("%w: reply_template requires a template group", ErrInvalidRule)
+		}
+	case ActionTag:
+		if a.Tag == "" {
+			return fmt.Errorf("%w: tag requires a tag", ErrInvalidRule)
+		}
+	case ActionWebhook:
+		if a.URL == "" {
+			return fmt.Errorf("%w: webhook requires a URL", ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: action type must be one of: reply_text, reply_template, tag, webhook", ErrInvalidRule)
+	}
+	return nil
+}
+
+type Repository interface {
+	Create(context.Context, Rule) error
+	Update(context.Context, Rule) error
+	Delete(context.Context, uuid.UUID) error
+	FindById(context.Context, uuid.UUID) (*Rule, error)
+	// List returns every rule ordered by priority
+	List(context.Context) ([]Rule, error)
+}
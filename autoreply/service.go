@@ -0,0 +1,261 @@
+package autoreply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mbx/contacts"
+	"mbx/egress"
+	"mbx/history"
+	"mbx/inbound"
+	"mbx/models"
+	"mbx/sender"
+	"mbx/templates"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContactUpdater saves the tags added by rules
+type ContactUpdater interface {
+	Update(context.Context, contacts.Contact) (*contacts.Contact, error)
+}
+
+type Config struct {
+	// DefaultTimezone evaluates time conditions for contacts without one
+	DefaultTimezone *time.Location
+	// WebhookTimeout bounds the requests of webhook actions
+	WebhookTimeout time.Duration
+	// AllowPrivateNetworks lets webhook actions reach private and local
+	// addresses, for development
+	AllowPrivateNetworks bool
+}
+
+type Service struct {
+	repo      Repository
+	config    Config
+	w         sender.Whatsapp
+	wt        sender.WhatsappTemplate
+	templates templates.GroupResolver
+	contacts  ContactUpdater
+	client    *http.Client
+}
+
+var _ inbound.Listener = (*Service)(nil)
+
+func NewService(repo Repository, config Config, w sender.Whatsapp, wt sender.WhatsappTemplate, templates templates.GroupResolver, contacts ContactUpdater) *Service {
+	if config.DefaultTimezone == nil {
+		config.DefaultTimezone = time.UTC
+	}
+	if config.WebhookTimeout == 0 {
+		config.WebhookTimeout = 10 * time.Second
+	}
+	return &Service{
+		repo:      repo,
+		config:    config,
+		w:         w,
+		wt:        wt,
+		templates: templates,
+		contacts:  contacts,
+		client:    egress.NewClient(config.WebhookTimeout, config.AllowPrivateNetworks),
+	}
+}
+
+func (s *Service) Create(ctx context.Context, rule Rule) (*Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule.Id = uuid.New()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *Service) Update(ctx context.Context, rule Rule) (*Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	rule.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *Service) FindById(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]Rule, error) {
+	return s.repo.List(ctx)
+}
+
+// HandleInbound runs the actions of the first enabled rule matching the
+// message and marks it handled. A failing action does not stop the others.
+func (s *Service) HandleInbound(ctx context.Context, msg *inbound.Message) error {
+	rules, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	rule := s.firstMatch(rules, msg)
+	if rule == nil {
+		return nil
+	}
+	msg.Handled = true
+	slog.Info("Auto-reply rule matched", "rule", rule.Name, "sid", msg.Sid)
+
+	var errs []error
+	for _, action := range rule.Actions {
+		if err := s.run(ctx, rule, action, msg); err != nil {
+			errs = append(errs, fmt.Errorf("rule %q action %s: %w", rule.Name, action.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) firstMatch(rules []Rule, msg *inbound.Message) *Rule {
+	now := time.Now().In(s.location(msg.Contact))
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Enabled && rule.Match.matches(msg) && rule.Conditions.hold(msg.Contact, now) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (s *Service) location(contact *contacts.Contact) *time.Location {
+	if contact.Timezone != "" {
+		if loc, err := time.LoadLocation(contact.Timezone); err == nil {
+			return loc
+		}
+	}
+	return s.config.DefaultTimezone
+}
+
+func (s *Service) run(ctx context.Context, rule *Rule, action Action, msg *inbound.Message) error {
+	contact := msg.Contact
+
+	switch action.Type {
+	case ActionReplyText:
+		_, err := s.w.Send(ctx, models.WhatsappBody{
+			To:   fmt.Sprintf("whatsapp:%s", contact.Phone),
+			Body: expand(action.Text, msg),
+		})
+		return err
+
+	case ActionReplyTemplate:
+		resolved, err := s.templates.Resolve(ctx, action.Template, contact.Locale)
+		if err != nil {
+			return err
+		}
+		variables := make(map[string]string, len(action.Variables))
+		for key, value := range action.Variables {
+			variables[key] = expand(value, msg)
+		}
+		content, err := json.Marshal(variables)
+		if err != nil {
+			return err
+		}
+		_, err = s.wt.SendTemplate(ctx, templates.WhatsappTemplate{
+			To:         contact.Phone,
+			TemplateId: resolved.ContentSid,
+			Content:    string(content),
+			Language:   resolved.Language,
+		})
+		return err
+
+	case ActionTag:
+		if contact.HasTag(action.Tag) {
+			return nil
+		}
+		contact.Tags = append(contact.Tags, action.Tag)
+		_, err := s.contacts.Update(ctx, *contact)
+		return err
+
+	case ActionWebhook:
+		return s.forward(ctx, rule, action.URL, msg)
+	}
+	return nil
+}
+
+type webhookMessage struct {
+	Sid         string               `json:"sid"`
+	From        string               `json:"from"`
+	To          string               `json:"to"`
+	Body        string               `json:"body"`
+	ProfileName string               `json:"profile_name,omitempty"`
+	Interaction *history.Interaction `json:"interaction,omitempty"`
+	ReceivedAt  time.Time            `json:"received_at"`
+}
+
+// forward posts the message, its contact and the rule that matched it as JSON
+func (s *Service) forward(ctx context.Context, rule *Rule, url string, msg *inbound.Message) error {
+	payload, err := json.Marshal(struct {
+		RuleId   uuid.UUID         `json:"rule_id"`
+		RuleName string            `json:"rule_name"`
+		Contact  *contacts.Contact `json:"contact"`
+		Message  webhookMessage    `json:"message"`
+	}{
+		RuleId:   rule.Id,
+		RuleName: rule.Name,
+		Contact:  msg.Contact,
+		Message: webhookMessage{
+			Sid:         msg.Sid,
+			From:        msg.From,
+			To:          msg.To,
+			Body:        msg.Body,
+			ProfileName: msg.ProfileName,
+			Interaction: msg.Interaction,
+			ReceivedAt:  msg.ReceivedAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// expand fills the contact placeholders of a reply
+func expand(text string, msg *inbound.Message) string {
+	name := msg.Contact.Name
+	if name == "" {
+		name = msg.ProfileName
+	}
+	return strings.NewReplacer(
+		"{{name}}", name,
+		"{{phone}}", msg.Contact.Phone,
+		"{{profile_name}}", msg.ProfileName,
+	).Replace(text)
+}
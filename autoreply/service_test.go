@@ -0,0 +1,71 @@
+package autoreply_test
+
+import (
+	"context"
+	"testing"
+
+	"mbx/autoreply"
+	"mbx/autoreply/mocks"
+	"mbx/contacts"
+	"mbx/inbound"
+	"mbx/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type stubSender struct {
+	sent []models.WhatsappBody
+}
+
+func (s *stubSender) Send(_ context.Context, msg models.WhatsappBody) (*api.ApiV2010Message, error) {
+	s.sent = append(s.sent, msg)
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CancelMessage(context.Context, string) error { return nil }
+
+func TestService_HandleInbound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().List(gomock.Any()).Return([]autoreply.Rule{
+		{
+			Id:      uuid.New(),
+			Name:    "Disabled",
+			Enabled: false,
+			Match:   autoreply.Match{Type: autoreply.MatchKeyword, Keywords: []string{"boleto"}},
+			Actions: []autoreply.Action{{Type: autoreply.ActionReplyText, Text: "disabled"}},
+		},
+		{
+			Id:      uuid.New(),
+			Name:    "Invoice",
+			Enabled: true,
+			Match:   autoreply.Match{Type: autoreply.MatchKeyword, Keywords: []string{"boleto"}},
+			Actions: []autoreply.Action{{Type: autoreply.ActionReplyText, Text: "Oi {{name}}, seu boleto está no app"}},
+		},
+	}, nil).Times(2)
+
+	w := &stubSender{}
+	service := autoreply.NewService(repo, autoreply.Config{}, w, nil, nil, nil)
+	contact := &contacts.Contact{Id: uuid.New(), Phone: "+5511999998888", Name: "Maria"}
+
+	msg := &inbound.Message{Body: "Quero meu boleto", Contact: contact}
+	if err := service.HandleInbound(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to handle inbound message: %v", err)
+	}
+	if !msg.Handled {
+		t.Error("Expected matched message to be marked handled")
+	}
+	if len(w.sent) != 1 || w.sent[0].Body != "Oi Maria, seu boleto está no app" || w.sent[0].To != "whatsapp:+5511999998888" {
+		t.Errorf("Unexpected replies %+v", w.sent)
+	}
+
+	other := &inbound.Message{Body: "Bom dia", Contact: contact}
+	if err := service.HandleInbound(context.Background(), other); err != nil {
+		t.Fatalf("Failed to handle inbound message: %v", err)
+	}
+	if other.Handled || len(w.sent) != 1 {
+		t.Error("Expected unmatched message to be left alone")
+	}
+}
@@ -0,0 +1,125 @@
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound          = errors.New("campaign not found")
+	ErrInvalidCampaign   = errors.New("invalid campaign")
+	ErrInvalidTransition = errors.New("campaign cannot change to this status")
+)
+
+type Status string
+
+const (
+	// StatusDraft campaigns are still getting recipients and do not send
+	// until started
+	StatusDraft Status = "draft"
+	// StatusScheduled campaigns start sending at their start time
+	StatusScheduled Status = "scheduled"
+	StatusRunning   Status = "running"
+	StatusPaused    Status = "paused"
+	StatusCompleted Status = "completed"
+	StatusCanceled  Status = "canceled"
+)
+
+type RecipientStatus string
+
+const (
+	RecipientQueued RecipientStatus = "queued"
+	// RecipientSending is set while a runner sends to the recipient
+	RecipientSending   RecipientStatus = "sending"
+	RecipientSent      RecipientStatus = "sent"
+	RecipientDelivered RecipientStatus = "delivered"
+	RecipientRead      RecipientStatus = "read"
+	RecipientFailed    RecipientStatus = "failed"
+	RecipientCanceled  RecipientStatus = "canceled"
+)
+
+// Campaign sends one template to a list of recipients, paced to a throughput
+// limit
+type Campaign struct {
+	Id       uuid.UUID `json:"id"`
+	TenantId uuid.UUID `json:"-"`
+	Name     string    `json:"name"`
+	// TemplateId is the content SID, or TemplateName a template group
+	// resolved per recipient locale
+	TemplateId   string `json:"template,omitempty"`
+	TemplateName string `json:"template_name,omitempty"`
+	// Locale is used for recipients without one
+	Locale string `json:"locale,omitempty"`
+	// Variables are shared by every recipient, which can override them
+	Variables     map[string]string `json:"variables"`
+	StartAt       time.Time         `json:"start_at"`
+	RatePerMinute int               `json:"rate_per_minute"`
+	Status        Status            `json:"status"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// Recipient is one send of a campaign. Delivered, read and failed deliveries
+// are reported from the status of the sent message.
+type Recipient struct {
+	Id          uuid.UUID         `json:"id"`
+	CampaignId  uuid.UUID         `json:"campaign_id"`
+	ContactId   *uuid.UUID        `json:"contact_id,omitempty"`
+	Phone       string            `json:"phone"`
+	Locale      string            `json:"locale,omitempty"`
+	Variables   map[string]string `json:"variables"`
+	Status      RecipientStatus   `json:"status"`
+	ProviderSid string            `json:"provider_sid,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	SentAt      *time.Time        `json:"sent_at,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// Progress counts the recipients of a campaign by status. Recipients being
+// sent are counted as queued.
+type Progress struct {
+	Total     int `json:"total"`
+	Queued    int `json:"queued"`
+	Sent      int `json:"sent"`
+	Delivered int `json:"delivered"`
+	Read      int `json:"read"`
+	Failed    int `json:"failed"`
+	Canceled  int `json:"canceled"`
+}
+
+type Repository interface {
+	// Create stores the campaign with its recipients
+	Create(context.Context, Campaign, []Recipient) error
+	// AddRecipients stores more recipients, reporting for each one whether it
+	// was added or its phone was already in the campaign
+	AddRecipients(context.Context, []Recipient) ([]bool, error)
+	FindById(context.Context, uuid.UUID) (*Campaign, error)
+	List(context.Context) ([]Campaign, error)
+	// Transition moves the campaign to status when it is in one of from,
+	// reporting whether it did
+	Transition(ctx context.Context, id uuid.UUID, status Status, from ...Status) (bool, error)
+	// ListActive returns the running campaigns and the scheduled ones whose
+	// start time is not after now
+	ListActive(ctx context.Context, now time.Time) ([]Campaign, error)
+	// ClaimRecipients marks up to limit queued recipients as sending and
+	// returns them. Recipients claimed before staleBefore and still sending
+	// were left by a runner that stopped, and are claimed again.
+	ClaimRecipients(ctx context.Context, campaignId uuid.UUID, limit int, staleBefore time.Time) ([]Recipient, error)
+	UpdateRecipient(context.Context, Recipient) error
+	// Pace takes the sends the campaign may make by now, one every interval
+	// and at most burst at once. The pace is kept with the campaign, so the
+	// runners of every replica share it.
+	Pace(ctx context.Context, campaignId uuid.UUID, now time.Time, interval time.Duration, burst int) (int, error)
+	// Refund gives back n sends taken with Pace that were not made
+	Refund(ctx context.Context, campaignId uuid.UUID, interval time.Duration, n int) error
+	// CancelQueued cancels the recipients that were not sent yet, queued or
+	// being sent
+	CancelQueued(ctx context.Context, campaignId uuid.UUID) error
+	// ListRecipients returns the recipients in status, or all of them when
+	// status is empty
+	ListRecipients(ctx context.Context, campaignId uuid.UUID, status RecipientStatus, limit int, offset int) ([]Recipient, error)
+	Progress(ctx context.Context, campaignId uuid.UUID) (*Progress, error)
+}
@@ -0,0 +1,234 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: campaigns/campaign.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	campaigns "mbx/campaigns"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddRecipients mocks base method.
+func (m *MockRepository) AddRecipients(arg0 context.Context, arg1 []campaigns.Recipient) ([]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRecipients", arg0, arg1)
+	ret0, _ := ret[0].([]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddRecipients indicates an expected call of AddRecipients.
+func (mr *MockRepositoryMockRecorder) AddRecipients(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRecipients", reflect.TypeOf((*MockRepository)(nil).AddRecipients), arg0, arg1)
+}
+
+// CancelQueued mocks base method.
+func (m *MockRepository) CancelQueued(ctx context.Context, campaignId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelQueued", ctx, campaignId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelQueued indicates an expected call of CancelQueued.
+func (mr *MockRepositoryMockRecorder) CancelQueued(ctx, campaignId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelQueued", reflect.TypeOf((*MockRepository)(nil).CancelQueued), ctx, campaignId)
+}
+
+// ClaimRecipients mocks base method.
+func (m *MockRepository) ClaimRecipients(ctx context.Context, campaignId uuid.UUID, limit int, staleBefore time.Time) ([]campaigns.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimRecipients", ctx, campaignId, limit, staleBefore)
+	ret0, _ := ret[0].([]campaigns.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimRecipients indicates an expected call of ClaimRecipients.
+func (mr *MockRepositoryMockRecorder) ClaimRecipients(ctx, campaignId, limit, staleBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimRecipients", reflect.TypeOf((*MockRepository)(nil).ClaimRecipients), ctx, campaignId, limit, staleBefore)
+}
+
+// Create mocks base method.
+func (m *MockRepository) Create(arg0 context.Context, arg1 campaigns.Campaign, arg2 []campaigns.Recipient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRepositoryMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), arg0, arg1, arg2)
+}
+
+// FindById mocks base method.
+func (m *MockRepository) FindById(arg0 context.Context, arg1 uuid.UUID) (*campaigns.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*campaigns.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockRepositoryMockRecorder) FindById(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockRepository)(nil).FindById), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockRepository) List(arg0 context.Context) ([]campaigns.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]campaigns.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockRepositoryMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRepository)(nil).List), arg0)
+}
+
+// ListActive mocks base method.
+func (m *MockRepository) ListActive(ctx context.Context, now time.Time) ([]campaigns.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActive", ctx, now)
+	ret0, _ := ret[0].([]campaigns.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActive indicates an expected call of ListActive.
+func (mr *MockRepositoryMockRecorder) ListActive(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActive", reflect.TypeOf((*MockRepository)(nil).ListActive), ctx, now)
+}
+
+// ListRecipients mocks base method.
+func (m *MockRepository) ListRecipients(ctx context.Context, campaignId uuid.UUID, status campaigns.RecipientStatus, limit, offset int) ([]campaigns.Recipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRecipients", ctx, campaignId, status, limit, offset)
+	ret0, _ := ret[0].([]campaigns.Recipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRecipients indicates an expected call of ListRecipients.
+func (mr *MockRepositoryMockRecorder) ListRecipients(ctx, campaignId, status, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRecipients", reflect.TypeOf((*MockRepository)(nil).ListRecipients), ctx, campaignId, status, limit, offset)
+}
+
+// Pace mocks base method.
+func (m *MockRepository) Pace(ctx context.Context, campaignId uuid.UUID, now time.Time, interval time.Duration, burst int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pace", ctx, campaignId, now, interval, burst)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Pace indicates an expected call of Pace.
+func (mr *MockRepositoryMockRecorder) Pace(ctx, campaignId, now, interval, burst interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pace", reflect.TypeOf((*MockRepository)(nil).Pace), ctx, campaignId, now, interval, burst)
+}
+
+// Progress mocks base method.
+func (m *MockRepository) Progress(ctx context.Context, campaignId uuid.UUID) (*campaigns.Progress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Progress", ctx, campaignId)
+	ret0, _ := ret[0].(*campaigns.Progress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Progress indicates an expected call of Progress.
+func (mr *MockRepositoryMockRecorder) Progress(ctx, campaignId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Progress", reflect.TypeOf((*MockRepository)(nil).Progress), ctx, campaignId)
+}
+
+// Refund mocks base method.
+func (m *MockRepository) Refund(ctx context.Context, campaignId uuid.UUID, interval time.Duration, n int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refund", ctx, campaignId, interval, n)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Refund indicates an expected call of Refund.
+func (mr *MockRepositoryMockRecorder) Refund(ctx, campaignId, interval, n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockRepository)(nil).Refund), ctx, campaignId, interval, n)
+}
+
+// Transition mocks base method.
+func (m *MockRepository) Transition(ctx context.Context, id uuid.UUID, status campaigns.Status, from ...campaigns.Status) (bool, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, id, status}
+	for _, a := range from {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Transition", varargs...)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transition indicates an expected call of Transition.
+func (mr *MockRepositoryMockRecorder) Transition(ctx, id, status interface{}, from ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, id, status}, from...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transition", reflect.TypeOf((*MockRepository)(nil).Transition), varargs...)
+}
+
+// UpdateRecipient mocks base method.
+func (m *MockRepository) UpdateRecipient(arg0 context.Context, arg1 campaigns.Recipient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRecipient", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRecipient indicates an expected call of UpdateRecipient.
+func (mr *MockRepositoryMockRecorder) UpdateRecipient(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRecipient", reflect.TypeOf((*MockRepository)(nil).UpdateRecipient), arg0, arg1)
+}
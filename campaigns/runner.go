@@ -0,0 +1,251 @@
+package campaigns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mbx/sender"
+	"mbx/templates"
+	"mbx/tenants"
+	"mbx/throttle"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RunnerConfig struct {
+	PoolingRate time.Duration
+	// DefaultLocale is used for recipients of template groups without a locale
+	DefaultLocale string
+	// ClaimTimeout is how long a recipient stays claimed by a runner before
+	// another one sends to it, defaulting to ten minutes
+	ClaimTimeout time.Duration
+}
+
+const defaultClaimTimeout = 10 * time.Minute
+
+// Runner fans the active campaigns out into template sends, keeping each
+// campaign under its rate across every replica.
+type Runner struct {
+	config   RunnerConfig
+	wt       sender.WhatsappTemplate
+	repo     Repository
+	resolver templates.GroupResolver
+
+	// held is when the daily tier limit of each tenant's sender resets, until
+	// which none of its campaigns send from this runner. The limit itself is
+	// shared; this only saves asking for it.
+	held map[uuid.UUID]time.Time
+}
+
+func NewRunner(config RunnerConfig, wt sender.WhatsappTemplate, repo Repository, resolver templates.GroupResolver) *Runner {
+	if config.ClaimTimeout <= 0 {
+		config.ClaimTimeout = defaultClaimTimeout
+	}
+	return &Runner{
+		config:   config,
+		wt:       wt,
+		repo:     repo,
+		resolver: resolver,
+		held:     make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PoolingRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Tick(ctx, time.Now())
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Tick sends what each active campaign is allowed to send by now
+func (r *Runner) Tick(ctx context.Context, now time.Time) {
+	active, err := r.repo.ListActive(ctx, now)
+	if err != nil {
+		slog.Error("failed to list active campaigns", slog.Any("error", err))
+		return
+	}
+
+	for _, campaign := range active {
+		if now.Before(r.held[campaign.TenantId]) {
+			continue
+		}
+
+		if err := r.advance(tenants.NewContext(ctx, campaign.TenantId), campaign, now); err != nil {
+			slog.Error("failed to run campaign", slog.Any("error", err), slog.String("id", campaign.Id.String()))
+		}
+	}
+}
+
+func (r *Runner) advance(ctx context.Context, campaign Campaign, now time.Time) error {
+	if _, err := tenants.Require(ctx); err != nil {
+		return err
+	}
+	if campaign.Status == StatusScheduled {
+		ok, err := r.repo.Transition(ctx, campaign.Id, StatusRunning, StatusScheduled)
+		if err != nil || !ok {
+			return err
+		}
+		slog.Info("campaign started", slog.String("id", campaign.Id.String()), slog.String("name", campaign.Name))
+	}
+
+	interval, burst := pace(campaign.RatePerMinute)
+	allowed, err := r.repo.Pace(ctx, campaign.Id, now, interval, burst)
+	if err != nil || allowed == 0 {
+		return err
+	}
+
+	recipients, err := r.repo.ClaimRecipients(ctx, campaign.Id, allowed, now.Add(-r.config.ClaimTimeout))
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		// recipients still being sent count as queued, and complete the
+		// campaign once sent or claimed again when their runner stopped
+		progress, err := r.repo.Progress(ctx, campaign.Id)
+		if err != nil {
+			return err
+		}
+		if progress.Queued > 0 {
+			return r.refund(ctx, campaign, allowed)
+		}
+		if _, err := r.repo.Transition(ctx, campaign.Id, StatusCompleted, StatusRunning); err != nil {
+			return err
+		}
+		slog.Info("campaign completed", slog.String("id", campaign.Id.String()), slog.String("name", campaign.Name))
+		return nil
+	}
+	// unused sends go back, so a short list does not waste the rate
+	if err := r.refund(ctx, campaign, allowed-len(recipients)); err != nil {
+		return err
+	}
+
+	for i, recipient := range recipients {
+		err := r.send(ctx, campaign, recipient)
+		if errors.Is(err, throttle.ErrLimited) {
+			slog.Info("campaign held back by the sender limits", slog.Any("reason", err), slog.String("id", campaign.Id.String()))
+			if errors.Is(err, throttle.ErrTierLimit) {
+				r.held[campaign.TenantId] = throttle.NextDay(now)
+			}
+			if err := r.refund(ctx, campaign, len(recipients)-i); err != nil {
+				return err
+			}
+			return r.requeue(ctx, recipients[i:])
+		}
+	}
+	return nil
+}
+
+// refund gives back n sends of the campaign's pace that were not made
+func (r *Runner) refund(ctx context.Context, campaign Campaign, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	interval, _ := pace(campaign.RatePerMinute)
+	return r.repo.Refund(ctx, campaign.Id, interval, n)
+}
+
+// requeue puts claimed recipients back in the queue, to be sent once the
+// sender has room for them
+func (r *Runner) requeue(ctx context.Context, recipients []Recipient) error {
+	for _, recipient := range recipients {
+		recipient.Status = RecipientQueued
+		recipient.UpdatedAt = time.Now()
+		if err := r.repo.UpdateRecipient(ctx, recipient); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send delivers the template to a recipient and records the outcome on it.
+// Sends held back by the sender limits are returned without being recorded.
+func (r *Runner) send(ctx context.Context, campaign Campaign, recipient Recipient) error {
+	sid, err := r.deliver(ctx, campaign, recipient)
+	if errors.Is(err, throttle.ErrLimited) {
+		return err
+	}
+
+	now := time.Now()
+	recipient.UpdatedAt = now
+	if err != nil {
+		slog.Info("campaign message not sent", slog.Any("reason", err), slog.String("campaign", campaign.Id.String()), slog.String("recipient", recipient.Id.String()))
+		recipient.Status = RecipientFailed
+		recipient.Error = err.Error()
+	} else {
+		recipient.Status = RecipientSent
+		recipient.ProviderSid = sid
+		recipient.SentAt = &now
+	}
+
+	if err := r.repo.UpdateRecipient(ctx, recipient); err != nil {
+		slog.Error("failed to update campaign recipient", slog.Any("error", err), slog.String("recipient", recipient.Id.String()))
+	}
+	return nil
+}
+
+func (r *Runner) deliver(ctx context.Context, campaign Campaign, recipient Recipient) (string, error) {
+	language := recipient.Locale
+	if language == "" {
+		language = campaign.Locale
+	}
+	if language == "" {
+		language = r.config.DefaultLocale
+	}
+
+	templateId := campaign.TemplateId
+	if campaign.TemplateName != "" {
+		resolved, err := r.resolver.Resolve(ctx, campaign.TemplateName, language)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve template group %q for locale %q: %w", campaign.TemplateName, language, err)
+		}
+		templateId, language = resolved.ContentSid, resolved.Language
+	}
+
+	variables := make(map[string]string, len(campaign.Variables)+len(recipient.Variables))
+	for k, v := range campaign.Variables {
+		variables[k] = v
+	}
+	for k, v := range recipient.Variables {
+		variables[k] = v
+	}
+	content := ""
+	if len(variables) > 0 {
+		encoded, err := json.Marshal(variables)
+		if err != nil {
+			return "", err
+		}
+		content = string(encoded)
+	}
+
+	resp, err := r.wt.SendTemplate(ctx, templates.WhatsappTemplate{
+		To:         recipient.Phone,
+		TemplateId: templateId,
+		Content:    content,
+		Language:   language,
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp != nil && resp.Sid != nil {
+		return *resp.Sid, nil
+	}
+	return "", nil
+}
+
+// pace returns how often a campaign sending perMinute messages may send, and
+// how many sends it may make at once: up to one second of them, so a tick
+// never sends more than the rate allows
+func pace(perMinute int) (time.Duration, int) {
+	return time.Minute / time.Duration(perMinute), max(perMinute/60, 1)
+}
@@ -0,0 +1,205 @@
+package campaigns_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"mbx/campaigns"
+	"mbx/campaigns/mocks"
+	"mbx/sender"
+	"mbx/templates"
+	tmocks "mbx/templates/mocks"
+	"mbx/throttle"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type stubTemplateSender struct {
+	sent []templates.WhatsappTemplate
+	err  error
+}
+
+func (s *stubTemplateSender) SendTemplate(_ context.Context, t templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.sent = append(s.sent, t)
+	sid := "SM" + t.To
+	return &api.ApiV2010Message{Sid: &sid}, nil
+}
+
+func (s *stubTemplateSender) CreateTemplate(context.Context, templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return nil, nil
+}
+
+func recipient(campaignId uuid.UUID, phone string, variables map[string]string) campaigns.Recipient {
+	return campaigns.Recipient{
+		Id:         uuid.New(),
+		CampaignId: campaignId,
+		Phone:      phone,
+		Variables:  variables,
+		Status:     campaigns.RecipientSending,
+	}
+}
+
+func TestRunner_SendsWithinRate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	resolver := tmocks.NewMockGroupResolver(ctrl)
+
+	campaign := campaigns.Campaign{
+		Id:            uuid.New(),
+		Name:          "Black Friday",
+		TemplateName:  "promo",
+		Variables:     map[string]string{"1": "Maria", "2": "50%"},
+		RatePerMinute: 120,
+		Status:        campaigns.StatusScheduled,
+	}
+	now := time.Now()
+
+	// first tick: the campaign starts and its pace allows one send; at 120 a
+	// minute, there is one every half second and two at most at once
+	interval := 500 * time.Millisecond
+	repo.EXPECT().ListActive(gomock.Any(), now).Return([]campaigns.Campaign{campaign}, nil)
+	repo.EXPECT().Transition(gomock.Any(), campaign.Id, campaigns.StatusRunning, campaigns.StatusScheduled).Return(true, nil)
+	repo.EXPECT().Pace(gomock.Any(), campaign.Id, now, interval, 2).Return(1, nil)
+	first := recipient(campaign.Id, "+5511999990001", map[string]string{"1": "João"})
+	first.Locale = "es"
+	repo.EXPECT().ClaimRecipients(gomock.Any(), campaign.Id, 1, gomock.Any()).Return([]campaigns.Recipient{first}, nil)
+	resolver.EXPECT().Resolve(gomock.Any(), "promo", "es").Return(&templates.ResolvedTemplate{Name: "promo", Language: "es", ContentSid: "HXes"}, nil)
+	repo.EXPECT().UpdateRecipient(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r campaigns.Recipient) error {
+		if r.Status != campaigns.RecipientSent || r.ProviderSid != "SM+5511999990001" || r.SentAt == nil {
+			t.Errorf("Expected sent recipient with SID, got %+v", r)
+		}
+		return nil
+	})
+
+	// a second later, two more sends are allowed at 120 a minute
+	campaign.Status = campaigns.StatusRunning
+	later := now.Add(time.Second)
+	repo.EXPECT().ListActive(gomock.Any(), later).Return([]campaigns.Campaign{campaign}, nil)
+	repo.EXPECT().Pace(gomock.Any(), campaign.Id, later, interval, 2).Return(2, nil)
+	repo.EXPECT().ClaimRecipients(gomock.Any(), campaign.Id, 2, gomock.Any()).Return([]campaigns.Recipient{
+		recipient(campaign.Id, "+5511999990002", nil),
+	}, nil)
+	// the unused send goes back
+	repo.EXPECT().Refund(gomock.Any(), campaign.Id, interval, 1).Return(nil)
+	resolver.EXPECT().Resolve(gomock.Any(), "promo", "pt_BR").Return(&templates.ResolvedTemplate{Name: "promo", Language: "pt_BR", ContentSid: "HXpt"}, nil)
+	repo.EXPECT().UpdateRecipient(gomock.Any(), gomock.Any()).Return(nil)
+
+	// the list is over
+	last := later.Add(100 * time.Millisecond)
+	repo.EXPECT().ListActive(gomock.Any(), last).Return([]campaigns.Campaign{campaign}, nil)
+	repo.EXPECT().Pace(gomock.Any(), campaign.Id, last, interval, 2).Return(1, nil)
+	repo.EXPECT().ClaimRecipients(gomock.Any(), campaign.Id, 1, gomock.Any()).Return(nil, nil)
+	repo.EXPECT().Progress(gomock.Any(), campaign.Id).Return(&campaigns.Progress{Total: 2, Sent: 2}, nil)
+	repo.EXPECT().Transition(gomock.Any(), campaign.Id, campaigns.StatusCompleted, campaigns.StatusRunning).Return(true, nil)
+
+	s := &stubTemplateSender{}
+	runner := campaigns.NewRunner(campaigns.RunnerConfig{DefaultLocale: "pt_BR"}, s, repo, resolver)
+	runner.Tick(context.Background(), now)
+	runner.Tick(context.Background(), later)
+	runner.Tick(context.Background(), last)
+
+	if len(s.sent) != 2 {
+		t.Fatalf("Expected 2 sends, got %d", len(s.sent))
+	}
+	if s.sent[0].TemplateId != "HXes" || s.sent[0].Language != "es" {
+		t.Errorf("Expected the es variant, got %+v", s.sent[0])
+	}
+	var content map[string]string
+	if err := json.Unmarshal([]byte(s.sent[0].Content), &content); err != nil {
+		t.Fatalf("Invalid content variables: %v", err)
+	}
+	if content["1"] != "João" || content["2"] != "50%" {
+		t.Errorf("Expected recipient variables over campaign ones, got %v", content)
+	}
+}
+
+func TestRunner_RecordsFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+
+	campaign := campaigns.Campaign{
+		Id:            uuid.New(),
+		TemplateId:    "HX123",
+		RatePerMinute: 60,
+		Status:        campaigns.StatusRunning,
+	}
+	repo.EXPECT().ListActive(gomock.Any(), gomock.Any()).Return([]campaigns.Campaign{campaign}, nil)
+	repo.EXPECT().Pace(gomock.Any(), campaign.Id, gomock.Any(), time.Second, 1).Return(1, nil)
+	repo.EXPECT().ClaimRecipients(gomock.Any(), campaign.Id, 1, gomock.Any()).Return([]campaigns.Recipient{
+		recipient(campaign.Id, "+5511999990003", nil),
+	}, nil)
+	repo.EXPECT().UpdateRecipient(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r campaigns.Recipient) error {
+		if r.Status != campaigns.RecipientFailed || r.Error == "" {
+			t.Errorf("Expected failed recipient with error, got %+v", r)
+		}
+		return nil
+	})
+
+	s := &stubTemplateSender{err: sender.ErrRejected}
+	runner := campaigns.NewRunner(campaigns.RunnerConfig{}, s, repo, nil)
+	runner.Tick(context.Background(), time.Now())
+}
+
+func TestRunner_RequeuesHeldBackRecipients(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+
+	campaign := campaigns.Campaign{
+		Id:            uuid.New(),
+		TemplateId:    "HX123",
+		RatePerMinute: 120,
+		Status:        campaigns.StatusRunning,
+	}
+	repo.EXPECT().ListActive(gomock.Any(), gomock.Any()).Return([]campaigns.Campaign{campaign}, nil).Times(2)
+	repo.EXPECT().Pace(gomock.Any(), campaign.Id, gomock.Any(), 500*time.Millisecond, 2).Return(1, nil)
+	// the send that was held back goes back to the pace
+	repo.EXPECT().Refund(gomock.Any(), campaign.Id, 500*time.Millisecond, 1).Return(nil)
+	repo.EXPECT().ClaimRecipients(gomock.Any(), campaign.Id, 1, gomock.Any()).Return([]campaigns.Recipient{
+		recipient(campaign.Id, "+5511999990004", nil),
+	}, nil)
+	repo.EXPECT().UpdateRecipient(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r campaigns.Recipient) error {
+		if r.Status != campaigns.RecipientQueued || r.Error != "" {
+			t.Errorf("Expected the recipient back in the queue, got %+v", r)
+		}
+		return nil
+	})
+
+	s := &stubTemplateSender{err: throttle.ErrTierLimit}
+	runner := campaigns.NewRunner(campaigns.RunnerConfig{}, s, repo, nil)
+	now := time.Now()
+	runner.Tick(context.Background(), now)
+
+	// nothing is sent until the tier resets
+	runner.Tick(context.Background(), now.Add(time.Second))
+}
+
+func TestRunner_WaitsForRecipientsBeingSent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+
+	campaign := campaigns.Campaign{
+		Id:            uuid.New(),
+		TemplateId:    "HX123",
+		RatePerMinute: 60,
+		Status:        campaigns.StatusRunning,
+	}
+	now := time.Now()
+
+	// a recipient claimed by a runner that stopped keeps the campaign running
+	// until it is claimed again, once the claim is stale
+	repo.EXPECT().ListActive(gomock.Any(), now).Return([]campaigns.Campaign{campaign}, nil)
+	repo.EXPECT().Pace(gomock.Any(), campaign.Id, now, time.Second, 1).Return(1, nil)
+	repo.EXPECT().ClaimRecipients(gomock.Any(), campaign.Id, 1, now.Add(-10*time.Minute)).Return(nil, nil)
+	repo.EXPECT().Progress(gomock.Any(), campaign.Id).Return(&campaigns.Progress{Total: 1, Queued: 1}, nil)
+	repo.EXPECT().Refund(gomock.Any(), campaign.Id, time.Second, 1).Return(nil)
+
+	runner := campaigns.NewRunner(campaigns.RunnerConfig{}, &stubTemplateSender{}, repo, nil)
+	runner.Tick(context.Background(), now)
+}
@@ -0,0 +1,210 @@
+package campaigns
+
+import (
+	"context"
+	"fmt"
+	"mbx/contacts"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo     Repository
+	contacts contacts.Resolver
+}
+
+func NewService(repo Repository, contacts contacts.Resolver) *Service {
+	return &Service{repo: repo, contacts: contacts}
+}
+
+// Create validates the campaign and links every recipient to its contact. A
+// recipient is given either a phone or a contact ID. Campaigns without a start
+// time start right away, and without a rate send 60 messages a minute. Draft
+// campaigns can be created without recipients and are started with Start.
+func (s *Service) Create(ctx context.Context, campaign Campaign, recipients []Recipient) (*Campaign, error) {
+	switch {
+	case campaign.Name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidCampaign)
+	case (campaign.TemplateId == "") == (campaign.TemplateName == ""):
+		return nil, fmt.Errorf("%w: either template or template_name is required", ErrInvalidCampaign)
+	case campaign.RatePerMinute < 0:
+		return nil, fmt.Errorf("%w: rate_per_minute must be positive", ErrInvalidCampaign)
+	case len(recipients) == 0 && campaign.Status != StatusDraft:
+		return nil, fmt.Errorf("%w: at least one recipient is required", ErrInvalidCampaign)
+	}
+
+	now := time.Now()
+	campaign.Id = uuid.New()
+	if campaign.Status != StatusDraft {
+		campaign.Status = StatusScheduled
+	}
+	campaign.CreatedAt = now
+	campaign.UpdatedAt = now
+	if campaign.StartAt.IsZero() {
+		campaign.StartAt = now
+	}
+	if campaign.RatePerMinute == 0 {
+		campaign.RatePerMinute = 60
+	}
+	if campaign.Variables == nil {
+		campaign.Variables = map[string]string{}
+	}
+
+	seen := make(map[string]bool, len(recipients))
+	for i := range recipients {
+		if err := s.link(ctx, &recipients[i]); err != nil {
+			return nil, fmt.Errorf("%w: recipient %d: %w", ErrInvalidCampaign, i+1, err)
+		}
+		r := &recipients[i]
+		if seen[r.Phone] {
+			return nil, fmt.Errorf("%w: recipient %d: %s is listed more than once", ErrInvalidCampaign, i+1, r.Phone)
+		}
+		seen[r.Phone] = true
+		r.prepare(campaign.Id, now)
+	}
+
+	if err := s.repo.Create(ctx, campaign, recipients); err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// AddRecipients adds recipients to a draft campaign, reporting for each one
+// whether it was added or was already in the campaign. Recipients that come
+// with both their contact ID and phone are taken as already linked.
+func (s *Service) AddRecipients(ctx context.Context, id uuid.UUID, recipients []Recipient) ([]bool, error) {
+	campaign, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrNotFound
+	}
+	if campaign.Status != StatusDraft {
+		return nil, fmt.Errorf("%w: recipients can only be added to draft campaigns", ErrInvalidTransition)
+	}
+
+	now := time.Now()
+	for i := range recipients {
+		r := &recipients[i]
+		if r.ContactId == nil || r.Phone == "" {
+			if err := s.link(ctx, r); err != nil {
+				return nil, fmt.Errorf("%w: recipient %d: %w", ErrInvalidCampaign, i+1, err)
+			}
+		}
+		r.prepare(id, now)
+	}
+	return s.repo.AddRecipients(ctx, recipients)
+}
+
+func (r *Recipient) prepare(campaignId uuid.UUID, now time.Time) {
+	r.Id = uuid.New()
+	r.CampaignId = campaignId
+	r.Status = RecipientQueued
+	r.UpdatedAt = now
+	if r.Variables == nil {
+		r.Variables = map[string]string{}
+	}
+}
+
+// link resolves the contact of a recipient, taking the locale of the contact
+// when the recipient has none
+func (s *Service) link(ctx context.Context, r *Recipient) error {
+	var contact *contacts.Contact
+	var err error
+	switch {
+	case r.ContactId != nil:
+		contact, err = s.contacts.FindById(ctx, *r.ContactId)
+		if err == nil && contact == nil {
+			err = contacts.ErrNotFound
+		}
+	case r.Phone != "":
+		contact, err = s.contacts.Resolve(ctx, r.Phone)
+	default:
+		err = fmt.Errorf("phone or contact_id is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	r.ContactId = &contact.Id
+	r.Phone = contact.Phone
+	if r.Locale == "" {
+		r.Locale = contact.Locale
+	}
+	return nil
+}
+
+func (s *Service) FindById(ctx context.Context, id uuid.UUID) (*Campaign, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]Campaign, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *Service) Progress(ctx context.Context, id uuid.UUID) (*Progress, error) {
+	return s.repo.Progress(ctx, id)
+}
+
+func (s *Service) Recipients(ctx context.Context, id uuid.UUID, status RecipientStatus, limit int, offset int) ([]Recipient, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	return s.repo.ListRecipients(ctx, id, status, limit, offset)
+}
+
+// Start schedules a draft campaign, which then starts at its start time
+func (s *Service) Start(ctx context.Context, id uuid.UUID) (*Campaign, error) {
+	progress, err := s.repo.Progress(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if progress.Total == 0 {
+		return nil, fmt.Errorf("%w: campaign has no recipients", ErrInvalidCampaign)
+	}
+	return s.transition(ctx, id, StatusScheduled, StatusDraft)
+}
+
+// Pause stops sending until the campaign is resumed
+func (s *Service) Pause(ctx context.Context, id uuid.UUID) (*Campaign, error) {
+	return s.transition(ctx, id, StatusPaused, StatusScheduled, StatusRunning)
+}
+
+// Resume continues a paused campaign. It is scheduled again, so it waits for
+// its start time if that has not come yet.
+func (s *Service) Resume(ctx context.Context, id uuid.UUID) (*Campaign, error) {
+	return s.transition(ctx, id, StatusScheduled, StatusPaused)
+}
+
+// Cancel stops the campaign for good, canceling the recipients not sent yet
+func (s *Service) Cancel(ctx context.Context, id uuid.UUID) (*Campaign, error) {
+	campaign, err := s.transition(ctx, id, StatusCanceled, StatusDraft, StatusScheduled, StatusRunning, StatusPaused)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CancelQueued(ctx, id); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+func (s *Service) transition(ctx context.Context, id uuid.UUID, status Status, from ...Status) (*Campaign, error) {
+	ok, err := s.repo.Transition(ctx, id, status, from...)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrNotFound
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: campaign is %s", ErrInvalidTransition, campaign.Status)
+	}
+	return campaign, nil
+}
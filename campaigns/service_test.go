@@ -0,0 +1,88 @@
+package campaigns_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mbx/campaigns"
+	"mbx/campaigns/mocks"
+	"mbx/contacts"
+	cmocks "mbx/contacts/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+func TestService_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	resolver := cmocks.NewMockResolver(ctrl)
+	resolver.EXPECT().Resolve(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, phone string) (*contacts.Contact, error) {
+			return &contacts.Contact{Id: uuid.New(), Phone: "+55" + phone, Locale: "es"}, nil
+		}).AnyTimes()
+
+	service := campaigns.NewService(repo, resolver)
+
+	_, err := service.Create(context.Background(), campaigns.Campaign{Name: "No template"}, []campaigns.Recipient{{Phone: "11999990001"}})
+	if !errors.Is(err, campaigns.ErrInvalidCampaign) {
+		t.Errorf("Expected ErrInvalidCampaign without a template, got %v", err)
+	}
+
+	_, err = service.Create(context.Background(), campaigns.Campaign{Name: "Duplicates", TemplateId: "HX123"}, []campaigns.Recipient{
+		{Phone: "11999990001"},
+		{Phone: "11999990001"},
+	})
+	if !errors.Is(err, campaigns.ErrInvalidCampaign) {
+		t.Errorf("Expected ErrInvalidCampaign for a duplicate recipient, got %v", err)
+	}
+
+	repo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, c campaigns.Campaign, recipients []campaigns.Recipient) error {
+			if c.Status != campaigns.StatusScheduled || c.RatePerMinute != 60 || c.StartAt.IsZero() {
+				t.Errorf("Expected defaults to be applied, got %+v", c)
+			}
+			if len(recipients) != 2 {
+				t.Fatalf("Expected 2 recipients, got %d", len(recipients))
+			}
+			if recipients[0].Phone != "+5511999990001" || recipients[0].ContactId == nil || recipients[0].Locale != "es" {
+				t.Errorf("Expected recipient linked to its contact, got %+v", recipients[0])
+			}
+			if recipients[1].Locale != "pt_BR" {
+				t.Errorf("Expected the recipient locale to be kept, got %q", recipients[1].Locale)
+			}
+			if recipients[0].CampaignId != c.Id || recipients[0].Status != campaigns.RecipientQueued {
+				t.Errorf("Expected queued recipient of the campaign, got %+v", recipients[0])
+			}
+			return nil
+		})
+	_, err = service.Create(context.Background(), campaigns.Campaign{Name: "Black Friday", TemplateId: "HX123"}, []campaigns.Recipient{
+		{Phone: "11999990001"},
+		{Phone: "11999990002", Locale: "pt_BR"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestService_Cancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := campaigns.NewService(repo, nil)
+	id := uuid.New()
+
+	repo.EXPECT().Transition(gomock.Any(), id, campaigns.StatusCanceled, campaigns.StatusDraft, campaigns.StatusScheduled, campaigns.StatusRunning, campaigns.StatusPaused).Return(true, nil)
+	repo.EXPECT().FindById(gomock.Any(), id).Return(&campaigns.Campaign{Id: id, Status: campaigns.StatusCanceled}, nil)
+	repo.EXPECT().CancelQueued(gomock.Any(), id).Return(nil)
+	if _, err := service.Cancel(context.Background(), id); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// a completed campaign cannot be paused
+	repo.EXPECT().Transition(gomock.Any(), id, campaigns.StatusPaused, campaigns.StatusScheduled, campaigns.StatusRunning).Return(false, nil)
+	repo.EXPECT().FindById(gomock.Any(), id).Return(&campaigns.Campaign{Id: id, Status: campaigns.StatusCompleted}, nil)
+	if _, err := service.Pause(context.Background(), id); !errors.Is(err, campaigns.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// defaultPageSize is the page size of list calls that don't set one
+const defaultPageSize = 100
+
+// SendMessage sends a freeform WhatsApp message
+func (c *Client) SendMessage(ctx context.Context, req SendMessageRequest) (*Message, error) {
+	var msg Message
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/send-message", body: req, idempotent: true}, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// SendTemplate sends a WhatsApp template
+func (c *Client) SendTemplate(ctx context.Context, req SendTemplateRequest) (*Message, error) {
+	var msg Message
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/send-template", body: req, idempotent: true}, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// CancelMessage cancels a message the provider has scheduled, by its SID
+func (c *Client) CancelMessage(ctx context.Context, sid string) error {
+	body := map[string]string{"message_id": sid}
+	return c.do(ctx, request{method: http.MethodPost, path: "/messages/cancel", body: body}, nil)
+}
+
+// ListMessages iterates over the sent messages page by page, newest first,
+// starting at q.Cursor. It stops after the first error.
+func (c *Client) ListMessages(ctx context.Context, q MessageQuery) iter.Seq2[[]SentMessage, error] {
+	return func(yield func([]SentMessage, error) bool) {
+		for {
+			page, err := c.GetMessagePage(ctx, q)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(page.Messages) > 0 && !yield(page.Messages, nil) {
+				return
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			q.Cursor = page.NextCursor
+		}
+	}
+}
+
+// GetMessagePage returns the page of sent messages at q.Cursor, and the
+// cursor of the page that follows it
+func (c *Client) GetMessagePage(ctx context.Context, q MessageQuery) (*MessagePage, error) {
+	query := url.Values{"after": {q.After.Format("2006-01-02")}}
+	if q.Status != "" {
+		query.Set("status", q.Status)
+	}
+	if q.PageSize > 0 {
+		query.Set("limit", strconv.Itoa(q.PageSize))
+	}
+	if q.Cursor != "" {
+		query.Set("cursor", q.Cursor)
+	}
+
+	page := MessagePage{}
+	header := http.Header{}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/messages", query: query, header: header}, &page.Messages); err != nil {
+		return nil, err
+	}
+	page.NextCursor = header.Get("Next-Cursor")
+	return &page, nil
+}
+
+// ScheduleMessage schedules a message for later
+func (c *Client) ScheduleMessage(ctx context.Context, req ScheduleMessageRequest) (*ScheduledMessage, error) {
+	var msg ScheduledMessage
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/scheduled-messages", body: req, idempotent: true}, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (c *Client) GetScheduledMessage(ctx context.Context, id uuid.UUID) (*ScheduledMessage, error) {
+	var msg ScheduledMessage
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/scheduled-messages/" + id.String()}, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// ListTemplates returns the approved templates of the tenant
+func (c *Client) ListTemplates(ctx context.Context) ([]Template, error) {
+	var templates []Template
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/templates"}, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ListContacts iterates over the contacts page by page. It stops after the
+// first error.
+func (c *Client) ListContacts(ctx context.Context, q ContactQuery) iter.Seq2[[]Contact, error] {
+	query := url.Values{}
+	if q.Tag != "" {
+		query.Set("tag", q.Tag)
+	}
+	if q.Search != "" {
+		query.Set("q", q.Search)
+	}
+	return list[Contact](ctx, c, "/contacts", query, q.PageSize)
+}
+
+func (c *Client) GetContact(ctx context.Context, id uuid.UUID) (*Contact, error) {
+	var contact Contact
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/contacts/" + id.String()}, &contact); err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// list pages through a list endpoint with limit and offset, until a page
+// comes back short
+func list[T any](ctx context.Context, c *Client, path string, query url.Values, size int) iter.Seq2[[]T, error] {
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	return func(yield func([]T, error) bool) {
+		for offset := 0; ; offset += size {
+			pageQuery := maps.Clone(query)
+			pageQuery.Set("limit", strconv.Itoa(size))
+			pageQuery.Set("offset", strconv.Itoa(offset))
+
+			var page []T
+			if err := c.do(ctx, request{method: http.MethodGet, path: path, query: pageQuery}, &page); err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(page) == 0 {
+				return
+			}
+			if !yield(page, nil) || len(page) < size {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package client calls the mbx API from Go services. It retries requests
+// that failed on the way, and sends each message with an Idempotency-Key so
+// a retry never sends it twice.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// version is the path prefix of the API version the client speaks
+const version = "/v1"
+
+type Config struct {
+	// HTTPClient makes the requests, http.DefaultClient by default
+	HTTPClient *http.Client
+	// MaxRetries is how many times a failed request is retried, 3 by
+	// default. A negative value turns retries off.
+	MaxRetries int
+	// Backoff is the wait before the first retry, doubling for every next
+	// one. 200ms by default.
+	Backoff time.Duration
+	// MaxBackoff bounds the wait between retries, 10s by default. Requests
+	// the API asks to retry later than that fail instead.
+	MaxBackoff time.Duration
+}
+
+// Client calls the API with one API key
+type Client struct {
+	baseURL string
+	apiKey  string
+	config  Config
+}
+
+// New returns a client of the API at baseURL, such as https://mbx.example.com
+func New(baseURL string, apiKey string, config Config) *Client {
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = 200 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 10 * time.Second
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/") + version,
+		apiKey:  apiKey,
+		config:  config,
+	}
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey makes the send or schedule call made with ctx use key
+// instead of a new one, so it can be retried safely across restarts of the
+// caller too
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// request is a call to the API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	// idempotent requests carry an Idempotency-Key, which makes them safe
+	// to retry
+	idempotent bool
+	// header receives the headers of a successful response when set
+	header http.Header
+}
+
+// do makes req, retrying it while it fails in a way a retry can fix, and
+// decodes the response into out
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("mbx: encoding request: %w", err)
+		}
+	}
+
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var key string
+	if req.idempotent {
+		key, _ = ctx.Value(idempotencyKey{}).(string)
+		if key == "" {
+			key = uuid.NewString()
+		}
+	}
+	// only requests that can't have an effect twice are retried
+	retryable := req.method == http.MethodGet || req.method == http.MethodPut || req.method == http.MethodDelete || key != ""
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, target, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("mbx: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+		httpReq.Header.Set("Accept", "application/json")
+		httpReq.Header.Set("User-Agent", "mbx-go-client")
+		if body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		if key != "" {
+			httpReq.Header.Set("Idempotency-Key", key)
+		}
+
+		resp, err := c.config.HTTPClient.Do(httpReq)
+		var wait time.Duration
+		if err != nil {
+			if ctx.Err() != nil || !retryable {
+				return fmt.Errorf("mbx: %w", err)
+			}
+		} else {
+			if err = decode(resp, out); err == nil && req.header != nil {
+				maps.Copy(req.header, resp.Header)
+			}
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || !retryable || !apiErr.Temporary() {
+				return err
+			}
+			wait = apiErr.RetryAfter
+		}
+
+		if attempt >= c.config.MaxRetries {
+			return err
+		}
+		if wait <= 0 {
+			wait = c.backoff(attempt)
+		} else if wait > c.config.MaxBackoff {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff is the wait before retry attempt+1, with jitter so clients that
+// failed together don't retry together
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := min(c.config.Backoff<<attempt, c.config.MaxBackoff)
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// decode reads resp into out, or into an *Error when it failed
+func decode(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+			if apiErr.Message == "" {
+				apiErr.Message = http.StatusText(resp.StatusCode)
+			}
+		}
+		if apiErr.RequestId == "" {
+			apiErr.RequestId = resp.Header.Get("X-Request-Id")
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return apiErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("mbx: decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mbx"
+	"mbx/apikeys"
+	akmocks "mbx/apikeys/mocks"
+	"mbx/client"
+	"mbx/contacts"
+	cmocks "mbx/contacts/mocks"
+	"mbx/handler"
+	"mbx/idempotency"
+	"mbx/models"
+	"mbx/provider/twilio"
+	"mbx/schedules"
+	smocks "mbx/schedules/mocks"
+	"mbx/senders"
+	"mbx/templates"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// fakeProvider counts the messages it sends and has five in its history
+type fakeProvider struct {
+	sent atomic.Int32
+}
+
+func (p *fakeProvider) Send(_ context.Context, body models.WhatsappBody) (*api.ApiV2010Message, error) {
+	p.sent.Add(1)
+	sid, status := "SM123", "queued"
+	return &api.ApiV2010Message{Sid: &sid, To: &body.To, Body: &body.Body, Status: &status}, nil
+}
+
+func (p *fakeProvider) SendTemplate(_ context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	p.sent.Add(1)
+	sid, status := "SM124", "accepted"
+	return &api.ApiV2010Message{Sid: &sid, To: &template.To, Status: &status}, nil
+}
+
+func (p *fakeProvider) CancelMessage(context.Context, string) error { return nil }
+
+func (p *fakeProvider) CreateTemplate(_ context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return &templates.SavedTemplate{ContentId: "HX123", FriendlyName: dto.FriendlyName}, nil
+}
+
+func (p *fakeProvider) GetTemplates(context.Context) ([]templates.SavedTemplate, error) {
+	return []templates.SavedTemplate{{ContentId: "HX123", FriendlyName: "welcome", Language: "en", Body: "Hi {{1}}"}}, nil
+}
+
+// GetMessages pages through five messages, with the index of the next one as
+// the cursor
+func (p *fakeProvider) GetMessages(_ context.Context, _ time.Time, size int, cursor string) (*models.MessagePage, error) {
+	var messages []models.SentMessage
+	for _, id := range []string{"SM1", "SM2", "SM3", "SM4", "SM5"} {
+		messages = append(messages, models.SentMessage{ID: id, To: "whatsapp:+31612345678", Body: "Hi", Status: "delivered"})
+	}
+
+	start := 0
+	if cursor != "" {
+		var err error
+		if start, err = strconv.Atoi(cursor); err != nil {
+			return nil, twilio.ErrInvalidCursor
+		}
+	}
+	end := min(start+size, len(messages))
+	page := &models.MessagePage{Messages: messages[start:end]}
+	if end < len(messages) {
+		page.NextCursor = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+func (p *fakeProvider) GetScheduledMessages(context.Context, time.Time) ([]models.SentMessage, error) {
+	return nil, nil
+}
+
+func (p *fakeProvider) ListMessagingServices(context.Context) ([]models.MessagingService, error) {
+	return nil, nil
+}
+
+func (p *fakeProvider) FromNumber(context.Context) (string, error) { return "", nil }
+
+// memoryKeys keeps idempotency keys in memory
+type memoryKeys struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func (m *memoryKeys) Claim(_ context.Context, record idempotency.Record, _ time.Time) (*idempotency.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.records[record.Key]; ok {
+		return &existing, nil
+	}
+	m.records[record.Key] = record
+	return nil, nil
+}
+
+func (m *memoryKeys) Complete(_ context.Context, key string, claim uuid.UUID, status int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[key]
+	if !ok || record.Claim != claim {
+		return idempotency.ErrClaimLost
+	}
+	record.Status, record.ContentType, record.Body = status, contentType, body
+	m.records[key] = record
+	return nil
+}
+
+func (m *memoryKeys) Release(_ context.Context, key string, claim uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.records[key].Claim == claim {
+		delete(m.records, key)
+	}
+	return nil
+}
+
+func (m *memoryKeys) DeleteExpired(context.Context, time.Time) (int64, error) { return 0, nil }
+
+// newTestServer serves the API with a fake provider, returning the provider
+// and an API key with every scope
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) (*httptest.Server, *fakeProvider, string) {
+	ctrl := gomock.NewController(t)
+
+	keyRepo := akmocks.NewMockRepository(ctrl)
+	keyService := apikeys.NewService(keyRepo)
+	var key apikeys.Key
+	keyRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, created apikeys.Key) error {
+		key = created
+		return nil
+	})
+	keyRepo.EXPECT().FindByHash(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, hash string) (*apikeys.Key, error) {
+		if key.Hash == hash {
+			return &key, nil
+		}
+		return nil, nil
+	}).AnyTimes()
+	keyRepo.EXPECT().Touch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	_, token, err := keyService.Create(t.Context(), "service", []apikeys.Scope{
+		apikeys.ScopeSend, apikeys.ScopeSchedule, apikeys.ScopeTemplatesRead, apikeys.ScopeHistoryRead,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	contact := contacts.Contact{Id: uuid.New(), Phone: "+31612345678", CreatedAt: now, UpdatedAt: now}
+	contactRepo := cmocks.NewMockRepository(ctrl)
+	contactRepo.EXPECT().FindByPhone(gomock.Any(), gomock.Any()).Return(&contact, nil).AnyTimes()
+	contactService := contacts.NewService(contactRepo, contacts.Config{})
+
+	scheduleRepo := smocks.NewMockRepository(ctrl)
+	var mu sync.Mutex
+	scheduled := map[uuid.UUID]models.ScheduledMessage{}
+	scheduleRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, message models.ScheduledMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		scheduled[message.Id] = message
+		return nil
+	}).AnyTimes()
+	scheduleRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id uuid.UUID) (*models.ScheduledMessage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if message, ok := scheduled[id]; ok {
+			return &message, nil
+		}
+		return nil, nil
+	}).AnyTimes()
+	scheduleService := schedules.NewService(scheduleRepo)
+
+	keys := &memoryKeys{records: map[string]idempotency.Record{}}
+	provider := &fakeProvider{}
+	var router http.Handler = mbx.SetupRouter(
+		handler.NewMessageHandler(provider, provider, provider, contactService),
+		handler.NewTemplateHandler(provider, provider, nil, contactService),
+		nil,
+		handler.NewScheduledMessageHandler(scheduleService, contactService, senders.NewService(nil, provider, senders.Config{})),
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		handler.NewAuthenticator(keyService),
+		handler.NewIdempotency(idempotency.NewService(keys, idempotency.Config{})),
+	)
+	if wrap != nil {
+		router = wrap(router)
+	}
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, provider, token
+}
+
+// lossyTransport loses the response to the first request after the server
+// handled it
+type lossyTransport struct {
+	lost atomic.Bool
+	keys []string
+}
+
+func (l *lossyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.keys = append(l.keys, req.Header.Get("Idempotency-Key"))
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || l.lost.Swap(true) {
+		return resp, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestClient_SendMessage(t *testing.T) {
+	server, provider, token := newTestServer(t, nil)
+	c := client.New(server.URL, token, client.Config{})
+
+	msg, err := c.SendMessage(t.Context(), client.SendMessageRequest{To: "+31612345678", Body: "Hi"})
+	if err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if msg.Sid != "SM123" || msg.To != "whatsapp:+31612345678" || msg.Status != "queued" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+
+	msg, err = c.SendTemplate(t.Context(), client.SendTemplateRequest{To: "+31612345678", Template: "HX123", Content: map[string]string{"1": "Ada"}})
+	if err != nil {
+		t.Fatalf("Failed to send template: %v", err)
+	}
+	if msg.Sid != "SM124" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	if sent := provider.sent.Load(); sent != 2 {
+		t.Errorf("Expected 2 messages to be sent, got %d", sent)
+	}
+}
+
+func TestClient_RetriesLostResponse(t *testing.T) {
+	server, provider, token := newTestServer(t, nil)
+	transport := &lossyTransport{}
+	c := client.New(server.URL, token, client.Config{HTTPClient: &http.Client{Transport: transport}, Backoff: time.Millisecond})
+
+	msg, err := c.SendMessage(t.Context(), client.SendMessageRequest{To: "+31612345678", Body: "Hi"})
+	if err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if msg.Sid != "SM123" {
+		t.Errorf("Expected the stored response to be replayed, got %+v", msg)
+	}
+	if sent := provider.sent.Load(); sent != 1 {
+		t.Errorf("Expected the message to be sent once, got %d", sent)
+	}
+	if len(transport.keys) != 2 || transport.keys[0] == "" || transport.keys[0] != transport.keys[1] {
+		t.Errorf("Expected the retry to reuse the idempotency key, got %q", transport.keys)
+	}
+}
+
+func TestClient_RetriesUnavailable(t *testing.T) {
+	var requests atomic.Int32
+	server, _, token := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= 2 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := client.New(server.URL, token, client.Config{Backoff: time.Millisecond})
+
+	templates, err := c.ListTemplates(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to list templates: %v", err)
+	}
+	if len(templates) != 1 || templates[0].ContentId != "HX123" {
+		t.Errorf("Unexpected templates %+v", templates)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected 3 requests, got %d", n)
+	}
+
+	requests.Store(0)
+	c = client.New(server.URL, token, client.Config{MaxRetries: -1})
+	_, err = c.ListTemplates(t.Context())
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "upstream unavailable" {
+		t.Errorf("Expected the 503 without retries, got %v", err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	var requests atomic.Int32
+	server, provider, token := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	c := client.New(server.URL, token, client.Config{Backoff: time.Millisecond})
+	_, err := c.SendMessage(t.Context(), client.SendMessageRequest{To: "+31612345678"})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an API error, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != client.CodeInvalidRequest || apiErr.RequestId == "" {
+		t.Errorf("Unexpected error %+v", apiErr)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected a bad request not to be retried, got %d requests", n)
+	}
+	if provider.sent.Load() != 0 {
+		t.Error("Expected nothing to be sent")
+	}
+
+	c = client.New(server.URL, "mbx_wrong", client.Config{})
+	if _, err := c.ListTemplates(t.Context()); !client.IsCode(err, client.CodeUnauthorized) {
+		t.Errorf("Expected unauthorized, got %v", err)
+	}
+}
+
+func TestClient_ListMessages(t *testing.T) {
+	server, _, token := newTestServer(t, nil)
+	c := client.New(server.URL, token, client.Config{})
+
+	var sizes []int
+	var ids []string
+	for page, err := range c.ListMessages(t.Context(), client.MessageQuery{After: time.Now().AddDate(0, 0, -7), PageSize: 2}) {
+		if err != nil {
+			t.Fatalf("Failed to list messages: %v", err)
+		}
+		sizes = append(sizes, len(page))
+		for _, msg := range page {
+			ids = append(ids, msg.Id)
+		}
+	}
+
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("Expected pages of 2, 2 and 1 messages, got %v", sizes)
+	}
+	if len(ids) != 5 || ids[0] != "SM1" || ids[4] != "SM5" {
+		t.Errorf("Unexpected messages %v", ids)
+	}
+
+	// a page can be fetched on its own and continued from later
+	page, err := c.GetMessagePage(t.Context(), client.MessageQuery{After: time.Now(), PageSize: 3})
+	if err != nil || len(page.Messages) != 3 || page.NextCursor == "" {
+		t.Fatalf("Expected a page of 3 messages with a cursor, got %+v, %v", page, err)
+	}
+	page, err = c.GetMessagePage(t.Context(), client.MessageQuery{After: time.Now(), PageSize: 3, Cursor: page.NextCursor})
+	if err != nil || len(page.Messages) != 2 || page.Messages[0].Id != "SM4" || page.NextCursor != "" {
+		t.Errorf("Expected the last 2 messages without a cursor, got %+v, %v", page, err)
+	}
+	if _, err := c.GetMessagePage(t.Context(), client.MessageQuery{After: time.Now(), Cursor: "lost"}); !client.IsCode(err, client.CodeInvalidRequest) {
+		t.Errorf("Expected an invalid cursor to be refused, got %v", err)
+	}
+
+	for _, err := range c.ListMessages(t.Context(), client.MessageQuery{Status: "lost"}) {
+		if !client.IsCode(err, client.CodeInvalidRequest) {
+			t.Errorf("Expected an invalid status to be refused, got %v", err)
+		}
+	}
+}
+
+func TestClient_ScheduleMessage(t *testing.T) {
+	server, _, token := newTestServer(t, nil)
+	c := client.New(server.URL, token, client.Config{})
+
+	sendAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	ctx := client.WithIdempotencyKey(t.Context(), "reminder-42")
+	scheduled, err := c.ScheduleMessage(ctx, client.ScheduleMessageRequest{
+		To: "+31612345678", Type: client.ScheduledFreeform, Content: "See you tomorrow", SendAt: sendAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to schedule: %v", err)
+	}
+
+	again, err := c.ScheduleMessage(ctx, client.ScheduleMessageRequest{
+		To: "+31612345678", Type: client.ScheduledFreeform, Content: "See you tomorrow", SendAt: sendAt,
+	})
+	if err != nil || again.Id != scheduled.Id {
+		t.Errorf("Expected the same key to return the same message, got %v, %v", again, err)
+	}
+
+	found, err := c.GetScheduledMessage(t.Context(), scheduled.Id)
+	if err != nil {
+		t.Fatalf("Failed to get the scheduled message: %v", err)
+	}
+	if found.Content != "See you tomorrow" || !found.SendAt.Equal(sendAt) || found.Status != "pending" || found.ContactId == nil {
+		t.Errorf("Unexpected scheduled message %+v", found)
+	}
+
+	if _, err := c.GetScheduledMessage(t.Context(), uuid.New()); !client.IsCode(err, client.CodeNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrorCode is the stable code of an API error, see the codes below
+type ErrorCode string
+
+const (
+	CodeInvalidRequest ErrorCode = "invalid_request"
+	CodeUnauthorized   ErrorCode = "unauthorized"
+	CodeForbidden      ErrorCode = "forbidden"
+	CodeNotFound       ErrorCode = "not_found"
+	CodeConflict       ErrorCode = "conflict"
+	CodeUnprocessable  ErrorCode = "unprocessable"
+	CodeRateLimited    ErrorCode = "rate_limited"
+	CodeInternal       ErrorCode = "internal_error"
+	CodeProviderError  ErrorCode = "provider_error"
+
+	CodeInvalidPhoneNumber  ErrorCode = "invalid_phone_number"
+	CodeOutsideWindow       ErrorCode = "outside_window"
+	CodeRecipientOptedOut   ErrorCode = "recipient_opted_out"
+	CodeConsentRequired     ErrorCode = "consent_required"
+	CodeMessageRejected     ErrorCode = "message_rejected"
+	CodeTierLimit           ErrorCode = "tier_limit_reached"
+	CodeThrottled           ErrorCode = "throttled"
+	CodeInvalidSender       ErrorCode = "invalid_sender"
+	CodeIdempotencyMismatch ErrorCode = "idempotency_key_reused"
+	CodeIdempotencyPending  ErrorCode = "idempotency_key_in_progress"
+)
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int       `json:"-"`
+	Code       ErrorCode `json:"code"`
+	Message    string    `json:"message"`
+	// Details points at the fields of the request that are wrong
+	Details []FieldError `json:"details,omitempty"`
+	// ProviderCode is the Twilio error code when the provider refused the
+	// request, see https://www.twilio.com/docs/api/errors
+	ProviderCode int `json:"provider_code,omitempty"`
+	// RequestId identifies the request in the logs of the API
+	RequestId string `json:"request_id,omitempty"`
+	// RetryAfter is how long the API asked to wait before trying again
+	RetryAfter time.Duration `json:"-"`
+}
+
+// FieldError is what is wrong with one field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("mbx: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("mbx: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Temporary reports whether the same request may succeed when it is tried
+// again
+func (e *Error) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		return e.Code == CodeIdempotencyPending
+	}
+	return false
+}
+
+// IsCode reports whether err is an API error with the given code
+func IsCode(err error, code ErrorCode) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
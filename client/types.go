@@ -0,0 +1,165 @@
+package client
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sender picks the number a message is sent from. Left empty, the tenant's
+// default sender is used.
+type Sender struct {
+	// From is a number of the tenant's pool
+	From string `json:"from,omitempty"`
+	// MessagingServiceSid sends through a Messaging Service instead of a
+	// number
+	MessagingServiceSid string `json:"messaging_service_sid,omitempty"`
+	// Strategy is how a number is picked from the pool: sticky, round_robin
+	// or least_loaded
+	Strategy string `json:"sender_strategy,omitempty"`
+}
+
+type SendMessageRequest struct {
+	// To is the recipient's number, unless ContactId is set
+	To        string     `json:"to,omitempty"`
+	ContactId *uuid.UUID `json:"contact_id,omitempty"`
+	Body      string     `json:"body"`
+	Sender
+}
+
+type SendTemplateRequest struct {
+	// To is the recipient's number, unless ContactId is set
+	To        string     `json:"to,omitempty"`
+	ContactId *uuid.UUID `json:"contact_id,omitempty"`
+	// Template is the Content SID of the template
+	Template string `json:"template,omitempty"`
+	// TemplateName is a template group to pick a variant from instead
+	TemplateName string `json:"template_name,omitempty"`
+	// Locale of the variant, the contact's by default
+	Locale string `json:"locale,omitempty"`
+	// Content fills in the template variables
+	Content  map[string]string `json:"content,omitempty"`
+	Language string            `json:"language,omitempty"`
+	Sender
+}
+
+// Message is a message the provider accepted
+type Message struct {
+	Sid                 string `json:"sid"`
+	AccountSid          string `json:"account_sid"`
+	MessagingServiceSid string `json:"messaging_service_sid"`
+	From                string `json:"from"`
+	To                  string `json:"to"`
+	Body                string `json:"body"`
+	Status              string `json:"status"`
+	Direction           string `json:"direction"`
+	ErrorCode           int    `json:"error_code"`
+	ErrorMessage        string `json:"error_message"`
+	DateCreated         string `json:"date_created"`
+}
+
+// MessageQuery selects the messages ListMessages returns
+type MessageQuery struct {
+	// After is the day from which on messages are listed
+	After time.Time
+	// Status only lists messages in this status
+	Status string
+	// PageSize is the number of messages per page, 100 by default
+	PageSize int
+	// Cursor is the NextCursor of a previous page to continue from, the
+	// first page when empty
+	Cursor string
+}
+
+// MessagePage is a page of sent messages. NextCursor continues after it, and
+// is empty on the last page.
+type MessagePage struct {
+	Messages   []SentMessage
+	NextCursor string
+}
+
+// SentMessage is a message of the history
+type SentMessage struct {
+	Id           string `json:"id"`
+	To           string `json:"to"`
+	Body         string `json:"body"`
+	DateSent     string `json:"date_sent"`
+	ErrorCode    int    `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+	Status       string `json:"status"`
+	Price        string `json:"price"`
+	PriceUnit    string `json:"price_unit"`
+}
+
+const (
+	ScheduledTemplate = "template"
+	ScheduledFreeform = "freeform"
+)
+
+type ScheduleMessageRequest struct {
+	// To is the recipient's number, unless ContactId is set
+	To        string     `json:"to,omitempty"`
+	ContactId *uuid.UUID `json:"contact_id,omitempty"`
+	// Type is ScheduledTemplate or ScheduledFreeform
+	Type string `json:"type"`
+	// Content is the body, or the JSON template variables of templates
+	Content string    `json:"content"`
+	SendAt  time.Time `json:"send_at"`
+	// ProviderTemplateId is the Content SID of the template
+	ProviderTemplateId string `json:"provider_template_id,omitempty"`
+	// TemplateName is a template group, resolved when the message is sent
+	TemplateName string `json:"template_name,omitempty"`
+	Locale       string `json:"locale,omitempty"`
+	Sender
+}
+
+// ScheduledMessage is a message waiting to be sent. Its fields are named as
+// the API names them.
+type ScheduledMessage struct {
+	Id           uuid.UUID
+	ContactId    *uuid.UUID
+	To           string
+	SendAt       time.Time
+	Content      string
+	ProviderId   string
+	TemplateName string
+	Locale       string
+	Type         string
+	// Status is pending, sent, failed, suppressed or rejected
+	Status    string
+	From      Sender
+	CreatedAt time.Time
+}
+
+// Template is an approved WhatsApp template
+type Template struct {
+	ContentId    string         `json:"content_id"`
+	FriendlyName string         `json:"friendly_name"`
+	Language     string         `json:"language"`
+	Body         string         `json:"body"`
+	Variables    map[string]any `json:"variables"`
+	Types        any            `json:"types"`
+	DateCreated  string         `json:"date_created"`
+	DateUpdated  string         `json:"date_updated"`
+}
+
+// ContactQuery selects the contacts ListContacts returns
+type ContactQuery struct {
+	Tag string
+	// Search matches the name and phone number
+	Search string
+	// PageSize is the number of contacts per page, 100 by default
+	PageSize int
+}
+
+type Contact struct {
+	Id         uuid.UUID         `json:"id"`
+	Phone      string            `json:"phone"`
+	Name       string            `json:"name"`
+	Locale     string            `json:"locale"`
+	Timezone   string            `json:"timezone"`
+	Tags       []string          `json:"tags"`
+	Attributes map[string]string `json:"attributes"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
@@ -6,13 +6,42 @@ import (
 	"log"
 	"log/slog"
 	"mbx"
+	"mbx/agents"
+	"mbx/apikeys"
+	"mbx/autoreply"
+	"mbx/campaigns"
+	"mbx/consent"
+	"mbx/contacts"
+	"mbx/conversations"
+	"mbx/events"
+	"mbx/flows"
 	"mbx/handler"
+	"mbx/history"
+	"mbx/idempotency"
+	"mbx/imports"
+	"mbx/inbound"
+	"mbx/metrics"
+	"mbx/optout"
+	"mbx/persistence/postgres"
+	"mbx/provider/twilio"
+	"mbx/schedules"
 	"mbx/sender"
+	"mbx/senders"
+	"mbx/templates"
+	"mbx/tenants"
+	"mbx/throttle"
+	"mbx/tracing"
+	"mbx/webhooks"
+	"mbx/window"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
@@ -27,27 +56,260 @@ func main() {
 		slog.Error("TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN environment variables are required")
 		return
 	}
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		slog.Error("DATABASE_URL environment variable is required")
+		return
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	// OTEL_TRACES_EXPORTER is otlp, stdout or none; the OTLP exporter reads
+	// OTEL_EXPORTER_OTLP_ENDPOINT and the other standard variables
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    os.Getenv("OTEL_TRACES_EXPORTER"),
+		ServiceName: "mbx",
+	})
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		return
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		slog.Error("Invalid DATABASE_URL", "error", err)
+		return
+	}
+	poolConfig.ConnConfig.Tracer = postgres.QueryTracer{}
+	db, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return
+	}
+	defer db.Close()
+
+	publicURL := os.Getenv("PUBLIC_URL")
+	if publicURL == "" {
+		slog.Warn("PUBLIC_URL is not set, Twilio webhook signatures will not be validated")
+	}
 
 	cfg := &sender.Config{
 		TwilioAccountSID: accountSid,
 		TwilioAuthToken:  authToken,
 		TwilioFromNumber: fmt.Sprintf("whatsapp:%s", fromNumber),
+		// status callbacks go to the legacy callbacks service until this
+		// instance is reachable by Twilio
+		StatusCallbackURL: "https://mbx-sender-callbacks.fly.dev/api/v1/callbacks/twilio",
+	}
+	if publicURL != "" {
+		cfg.StatusCallbackURL = strings.TrimSuffix(publicURL, "/") + mbx.APIVersion + "/callbacks/twilio/status"
+	}
+
+	// the environment holds the main account, used by the default tenant and
+	// by tenants without a subaccount of their own
+	tenantService := tenants.NewService(postgres.NewTenantRepository(db), *cfg)
+	twilioClients := twilio.NewTenantClients(tenantService, 5*time.Minute)
+
+	defaultCountry := os.Getenv("DEFAULT_COUNTRY")
+	if defaultCountry == "" {
+		defaultCountry = "BR"
+	}
+	contactService := contacts.NewService(postgres.NewContactRepository(db), contacts.Config{
+		DefaultCountry: defaultCountry,
+	})
+
+	groupService := templates.NewGroupService(postgres.NewTemplateGroupRepository(db), templates.GroupConfig{
+		DefaultLanguages: []string{"en"},
+		PreferredRegions: map[string]string{"pt": "pt_BR", "es": "es_MX"},
+	})
+
+	// every message and scheduled message change goes to the event log
+	eventService := events.NewService(postgres.NewEventRepository(db))
+	historyRepo := events.NewHistoryRecorder(postgres.NewHistoryRepository(db), eventService)
+	// innermost, so only messages that actually go out use up the limits of
+	// the sender number
+	throttleConfig := throttle.Config{}
+	if value := os.Getenv("WHATSAPP_RATE"); value != "" {
+		if throttleConfig.Rate, err = strconv.ParseFloat(value, 64); err != nil {
+			slog.Error("Invalid WHATSAPP_RATE", "error", err)
+			return
+		}
+	}
+	if value := os.Getenv("WHATSAPP_TIER"); value != "" {
+		tier, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Invalid WHATSAPP_TIER", "error", err)
+			return
+		}
+		throttleConfig.Tier = throttle.Tier(tier)
+	}
+	throttledSender := throttle.NewSender(twilioClients, twilioClients, postgres.NewThrottleRepository(db), throttleConfig, twilioClients)
+	// picks the number of every message before it is paced, and is inside
+	// the recorder so the history knows which number each message went out from
+	senderService := senders.NewService(postgres.NewSenderRepository(db), twilioClients, senders.Config{})
+	selectingSender := senders.NewSelectingSender(throttledSender, throttledSender, senderService)
+	recordingSender := history.NewRecordingSender(selectingSender, selectingSender, contactService, historyRepo)
+
+	optoutService := optout.NewService(postgres.NewSuppressionRepository(db), optout.Config{
+		DefaultLanguage: "pt",
+	}, recordingSender)
+	var guardedSender sender.WhatsappSender = optout.NewGuardedSender(recordingSender, recordingSender, optoutService)
+
+	consentService := consent.NewService(postgres.NewConsentRepository(db))
+	if os.Getenv("CONSENT_ENFORCEMENT") == "true" {
+		slog.Info("Marketing templates require recorded consent")
+		guardedSender = consent.NewGuardedSender(guardedSender, guardedSender, consentService, contactService, twilioClients)
 	}
 
-	twilioClient := sender.NewTwilioClient(cfg)
+	// outermost, so freeform messages converted to the fallback template
+	// still go through the consent and opt-out guards
+	windowService := window.NewService(historyRepo)
+	guardedSender = window.NewGuardedSender(guardedSender, guardedSender, windowService, contactService, groupService, window.Config{
+		FallbackTemplate: os.Getenv("WINDOW_FALLBACK_TEMPLATE"),
+	})
 
-	twilioSender := sender.NewTwilioSender(twilioClient, cfg)
-	twilioFetcher := sender.NewTwilioFetcher(twilioClient, cfg)
+	scheduleRepo := events.NewScheduleRecorder(postgres.NewMessageRepository(db), eventService)
+	scheduleService := schedules.NewService(scheduleRepo)
 
-	messageHandler := handler.NewMessageHandler(twilioSender, twilioSender, twilioFetcher)
-	templateHandler := handler.NewTemplateHandler(twilioSender, twilioFetcher)
+	agentService := agents.NewService(postgres.NewAgentRepository(db))
+	conversationService := conversations.NewService(postgres.NewConversationRepository(db), historyRepo, scheduleService, agentService, guardedSender)
+
+	// webhooks, auto-reply webhooks and flow http nodes only reach public
+	// addresses, unless local endpoints are allowed for development
+	allowPrivateNetworks := os.Getenv("ALLOW_PRIVATE_NETWORKS") == "true"
+	if allowPrivateNetworks {
+		slog.Warn("Outgoing webhook and flow requests may reach private networks")
+	}
+
+	defaultTimezone, err := time.LoadLocation(os.Getenv("DEFAULT_TIMEZONE"))
+	if err != nil {
+		slog.Error("Invalid DEFAULT_TIMEZONE", "error", err)
+		return
+	}
+	autoReplyService := autoreply.NewService(postgres.NewAutoReplyRepository(db), autoreply.Config{
+		DefaultTimezone:      defaultTimezone,
+		AllowPrivateNetworks: allowPrivateNetworks,
+	}, guardedSender, guardedSender, groupService, contactService)
 
-	router := mbx.SetupRouter(messageHandler, templateHandler)
+	flowRepo := postgres.NewFlowRepository(db)
+	flowService := flows.NewService(flowRepo)
+	flowConfig := flows.Config{MaxAttempts: 3, AllowPrivateNetworks: allowPrivateNetworks}
+	flowEngine := flows.NewEngine(flowRepo, postgres.NewFlowSessionRepository(db), flowConfig,
+		guardedSender, guardedSender, groupService, conversationService)
+
+	// the workers serve every tenant, and scope each piece of work to its own
+	background := tenants.Background(ctx)
+
+	// event streams also wake up for the events of the other replicas
+	go eventService.Follow(ctx, postgres.NewEventNotifier(db))
+
+	webhookRepo := postgres.NewWebhookRepository(db)
+	webhookService := webhooks.NewService(webhookRepo)
+	dispatcher := webhooks.NewDispatcher(webhooks.DispatcherConfig{
+		PoolingRate:          5 * time.Second,
+		AllowPrivateNetworks: allowPrivateNetworks,
+	}, webhookRepo)
+	go dispatcher.Run(background)
+
+	// webhooks and conversations first, so every message is published and
+	// opt-out keywords still reopen and assign threads, and opt-out before
+	// the bots so STOP is never answered. Flow sessions take precedence over
+	// one-shot auto-replies.
+	inboundService := inbound.NewService(contactService, historyRepo, webhookService, conversationService, optoutService, flowEngine, autoReplyService)
+
+	worker := schedules.NewWorker(schedules.Config{
+		PoolingRate:   time.Minute,
+		DefaultLocale: "pt_BR",
+	}, guardedSender, guardedSender, scheduleRepo, groupService, postgres.NewScheduleNotifier(db))
+	go worker.Run(background)
+
+	if err := metrics.RegisterQueue("scheduled_messages", scheduleService); err != nil {
+		slog.Error("Failed to register queue metrics", "error", err)
+		return
+	}
+	if err := metrics.RegisterQueue("webhook_deliveries", webhookService); err != nil {
+		slog.Error("Failed to register queue metrics", "error", err)
+		return
+	}
+
+	campaignRepo := postgres.NewCampaignRepository(db)
+	campaignService := campaigns.NewService(campaignRepo, contactService)
+	campaignRunner := campaigns.NewRunner(campaigns.RunnerConfig{
+		PoolingRate:   time.Second,
+		DefaultLocale: "pt_BR",
+	}, guardedSender, campaignRepo, groupService)
+	go campaignRunner.Run(background)
+
+	importService := imports.NewService(postgres.NewImportRepository(db), contactService, campaignService)
+
+	messageHandler := handler.NewMessageHandler(guardedSender, guardedSender, twilioClients, contactService)
+	templateHandler := handler.NewTemplateHandler(guardedSender, twilioClients, groupService, contactService)
+	templateGroupHandler := handler.NewTemplateGroupHandler(groupService)
+	scheduleHandler := handler.NewScheduledMessageHandler(scheduleService, contactService, senderService)
+	contactHandler := handler.NewContactHandler(contactService, historyRepo, scheduleService)
+	inboundHandler := handler.NewInboundHandler(inboundService, tenantService, publicURL)
+	suppressionHandler := handler.NewSuppressionHandler(optoutService, contactService)
+	consentHandler := handler.NewConsentHandler(consentService, contactService)
+	windowHandler := handler.NewWindowHandler(windowService, contactService)
+	conversationHandler := handler.NewConversationHandler(conversationService, contactService, agentService)
+	agentHandler := handler.NewAgentHandler(agentService)
+	autoReplyHandler := handler.NewAutoReplyHandler(autoReplyService)
+	flowHandler := handler.NewFlowHandler(flowService, flowConfig, groupService)
+	statusHandler := handler.NewStatusHandler(historyRepo, webhookService, tenantService, publicURL)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	eventStreamHandler := handler.NewEventStreamHandler(eventService, 15*time.Second)
+	campaignHandler := handler.NewCampaignHandler(campaignService)
+	importHandler := handler.NewImportHandler(importService)
+	apiKeyService := apikeys.NewService(postgres.NewAPIKeyRepository(db))
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	authenticator := handler.NewAuthenticator(apiKeyService)
+	tenantHandler := handler.NewTenantHandler(tenantService, apiKeyService)
+	senderHandler := handler.NewSenderHandler(senderService)
+
+	idempotencyConfig := idempotency.Config{}
+	if value := os.Getenv("IDEMPOTENCY_TTL"); value != "" {
+		if idempotencyConfig.TTL, err = time.ParseDuration(value); err != nil {
+			slog.Error("Invalid IDEMPOTENCY_TTL", "error", err)
+			return
+		}
+	}
+	idempotencyService := idempotency.NewService(postgres.NewIdempotencyRepository(db), idempotencyConfig)
+	go idempotencyService.Run(background)
+	idempotent := handler.NewIdempotency(idempotencyService)
+
+	router := mbx.SetupRouter(
+		messageHandler,
+		templateHandler,
+		templateGroupHandler,
+		scheduleHandler,
+		contactHandler,
+		inboundHandler,
+		suppressionHandler,
+		consentHandler,
+		windowHandler,
+		conversationHandler,
+		agentHandler,
+		autoReplyHandler,
+		flowHandler,
+		statusHandler,
+		webhookHandler,
+		eventStreamHandler,
+		campaignHandler,
+		importHandler,
+		apiKeyHandler,
+		tenantHandler,
+		senderHandler,
+		authenticator,
+		idempotent,
+	)
 
 	server := &http.Server{
 		Addr:    ":8765",
 		Handler: router,
 	}
+	// open event streams only end with their subscription
+	server.RegisterOnShutdown(eventService.Close)
 
 	go func() {
 		log.Println("Starting server on :8765")
@@ -61,13 +323,17 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stop()
 
 	// Give outstanding requests 30 seconds to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
 
 	log.Println("Server exited")
 }
@@ -0,0 +1,116 @@
+// Command apikey manages the API keys of the server, including the first
+// admin key, which cannot be created through the API. Keys belong to the
+// default tenant unless -tenant names another one.
+//
+//	apikey create [-tenant ID] -name NAME -scopes send,history:read
+//	apikey list [-tenant ID]
+//	apikey revoke [-tenant ID] ID
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"mbx/apikeys"
+	"mbx/persistence/postgres"
+	"mbx/tenants"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "apikey:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: apikey create|list|revoke")
+	}
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		return fmt.Errorf("DATABASE_URL environment variable is required")
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	service := apikeys.NewService(postgres.NewAPIKeyRepository(db))
+
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	tenant := flags.String("tenant", tenants.DefaultId.String(), "ID of the tenant the keys belong to")
+	name := flags.String("name", "", "name of the key")
+	scopeList := flags.String("scopes", "", "comma separated scopes: send, schedule, templates:read, templates:write, history:read, admin")
+	flags.Parse(args[1:])
+	tenantId, err := uuid.Parse(*tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant ID: %w", err)
+	}
+	ctx = tenants.NewContext(ctx, tenantId)
+
+	switch args[0] {
+	case "create":
+		var scopes []apikeys.Scope
+		for _, scope := range strings.Split(*scopeList, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, apikeys.Scope(scope))
+			}
+		}
+		key, token, err := service.Create(ctx, *name, scopes)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created key %s (%s)\n", key.Id, key.Name)
+		fmt.Println("Token, shown only once:")
+		fmt.Println(token)
+		return nil
+
+	case "list":
+		keys, err := service.List(ctx)
+		if err != nil {
+			return err
+		}
+		out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, "ID\tNAME\tPREFIX\tSCOPES\tLAST USED\tREVOKED")
+		for _, key := range keys {
+			scopes := make([]string, 0, len(key.Scopes))
+			for _, s := range key.Scopes {
+				scopes = append(scopes, string(s))
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%s\n", key.Id, key.Name, key.Prefix, strings.Join(scopes, ","), formatTime(key.LastUsedAt), formatTime(key.RevokedAt))
+		}
+		return out.Flush()
+
+	case "revoke":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: apikey revoke [-tenant ID] ID")
+		}
+		id, err := uuid.Parse(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid key ID: %w", err)
+		}
+		if err := service.Revoke(ctx, id); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked key %s\n", id)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,91 @@
+package consent
+
+import (
+	"context"
+	"fmt"
+	"mbx/sender"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNoConsent = fmt.Errorf("%w: recipient has not consented to marketing messages", sender.ErrRejected)
+
+type Channel string
+
+const (
+	ChannelWhatsapp Channel = "whatsapp"
+	ChannelSMS      Channel = "sms"
+)
+
+type Purpose string
+
+const (
+	PurposeMarketing Purpose = "marketing"
+	PurposeUtility   Purpose = "utility"
+)
+
+type Action string
+
+const (
+	ActionGranted Action = "granted"
+	ActionRevoked Action = "revoked"
+)
+
+// Source is how the consent was collected
+type Source string
+
+const (
+	SourceWebForm        Source = "web_form"
+	SourceInboundMessage Source = "inbound_message"
+	SourceImport         Source = "import"
+	SourceAPI            Source = "api"
+)
+
+// Record is an entry of the consent audit trail. Records are never updated;
+// the current consent of a contact is its latest record per channel and purpose.
+type Record struct {
+	Id        uuid.UUID `json:"id"`
+	ContactId uuid.UUID `json:"contact_id"`
+	Channel   Channel   `json:"channel"`
+	Purpose   Purpose   `json:"purpose"`
+	Action    Action    `json:"action"`
+	Source    Source    `json:"source"`
+	// Evidence describes the proof kept for audits, e.g. the form URL or the
+	// SID of the inbound message
+	Evidence string `json:"evidence,omitempty"`
+	// RecordedAt is when consent was given or revoked, which can be earlier
+	// than CreatedAt for imported records
+	RecordedAt time.Time `json:"recorded_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (r Record) Valid() bool {
+	switch r.Channel {
+	case ChannelWhatsapp, ChannelSMS:
+	default:
+		return false
+	}
+	switch r.Purpose {
+	case PurposeMarketing, PurposeUtility:
+	default:
+		return false
+	}
+	switch r.Action {
+	case ActionGranted, ActionRevoked:
+	default:
+		return false
+	}
+	switch r.Source {
+	case SourceWebForm, SourceInboundMessage, SourceImport, SourceAPI:
+	default:
+		return false
+	}
+	return true
+}
+
+type Repository interface {
+	Record(context.Context, Record) error
+	ListByContact(context.Context, uuid.UUID) ([]Record, error)
+	Latest(ctx context.Context, contactId uuid.UUID, channel Channel, purpose Purpose) (*Record, error)
+}
@@ -0,0 +1,112 @@
+package consent
+
+import (
+	"context"
+	"fmt"
+	"mbx/contacts"
+	"mbx/models"
+	"mbx/sender"
+	"mbx/templates"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// CategoryLookup returns the WhatsApp category of a content template, such as
+// MARKETING, UTILITY or AUTHENTICATION
+type CategoryLookup interface {
+	GetTemplateCategory(ctx context.Context, contentSid string) (string, error)
+}
+
+// categoryTTL bounds how long a template category is cached, since a template
+// can be recategorized by Meta after approval
+const categoryTTL = time.Hour
+
+type cachedCategory struct {
+	category  string
+	fetchedAt time.Time
+}
+
+// GuardedSender refuses marketing templates for contacts without a valid
+// WhatsApp marketing consent. Freeform messages are passed through, as they
+// can only be sent inside a conversation opened by the customer.
+type GuardedSender struct {
+	w          sender.Whatsapp
+	wt         sender.WhatsappTemplate
+	service    *Service
+	contacts   contacts.Resolver
+	categories CategoryLookup
+
+	mu    sync.Mutex
+	cache map[string]cachedCategory
+}
+
+var _ sender.Whatsapp = (*GuardedSender)(nil)
+var _ sender.WhatsappTemplate = (*GuardedSender)(nil)
+
+func NewGuardedSender(w sender.Whatsapp, wt sender.WhatsappTemplate, service *Service, contacts contacts.Resolver, categories CategoryLookup) *GuardedSender {
+	return &GuardedSender{
+		w:          w,
+		wt:         wt,
+		service:    service,
+		contacts:   contacts,
+		categories: categories,
+		cache:      make(map[string]cachedCategory),
+	}
+}
+
+func (s *GuardedSender) category(ctx context.Context, contentSid string) (string, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[contentSid]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < categoryTTL {
+		return cached.category, nil
+	}
+
+	category, err := s.categories.GetTemplateCategory(ctx, contentSid)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.cache[contentSid] = cachedCategory{category: category, fetchedAt: time.Now()}
+	s.mu.Unlock()
+	return category, nil
+}
+
+func (s *GuardedSender) SendTemplate(ctx context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	category, err := s.category(ctx, template.TemplateId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up template category: %w", err)
+	}
+
+	if strings.EqualFold(category, string(PurposeMarketing)) {
+		contact, err := s.contacts.Resolve(ctx, template.To)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := s.service.Has(ctx, contact.Id, ChannelWhatsapp, PurposeMarketing)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrNoConsent
+		}
+	}
+
+	return s.wt.SendTemplate(ctx, template)
+}
+
+func (s *GuardedSender) Send(ctx context.Context, message models.WhatsappBody) (*api.ApiV2010Message, error) {
+	return s.w.Send(ctx, message)
+}
+
+func (s *GuardedSender) CreateTemplate(ctx context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return s.wt.CreateTemplate(ctx, dto)
+}
+
+func (s *GuardedSender) CancelMessage(ctx context.Context, twilioId string) error {
+	return s.w.CancelMessage(ctx, twilioId)
+}
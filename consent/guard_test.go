@@ -0,0 +1,107 @@
+package consent_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mbx/consent"
+	"mbx/consent/mocks"
+	"mbx/contacts"
+	cmocks "mbx/contacts/mocks"
+	"mbx/models"
+	"mbx/templates"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type stubSender struct {
+	templates []templates.WhatsappTemplate
+}
+
+func (s *stubSender) Send(context.Context, models.WhatsappBody) (*api.ApiV2010Message, error) {
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CancelMessage(context.Context, string) error { return nil }
+
+func (s *stubSender) SendTemplate(_ context.Context, t templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	s.templates = append(s.templates, t)
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CreateTemplate(context.Context, templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return nil, nil
+}
+
+type categories map[string]string
+
+func (c categories) GetTemplateCategory(_ context.Context, sid string) (string, error) {
+	return c[sid], nil
+}
+
+func newGuard(t *testing.T, latest *consent.Record) (*consent.GuardedSender, *stubSender) {
+	ctrl := gomock.NewController(t)
+
+	contact := &contacts.Contact{Id: uuid.New(), Phone: "+5511999998888"}
+	resolver := cmocks.NewMockResolver(ctrl)
+	resolver.EXPECT().Resolve(gomock.Any(), contact.Phone).Return(contact, nil).AnyTimes()
+
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().
+		Latest(gomock.Any(), contact.Id, consent.ChannelWhatsapp, consent.PurposeMarketing).
+		Return(latest, nil).
+		AnyTimes()
+
+	next := &stubSender{}
+	lookup := categories{"HX-promo": "MARKETING", "HX-invoice": "UTILITY"}
+	return consent.NewGuardedSender(next, next, consent.NewService(repo), resolver, lookup), next
+}
+
+func TestGuardedSender_MarketingWithoutConsent(t *testing.T) {
+	guard, next := newGuard(t, nil)
+
+	_, err := guard.SendTemplate(context.Background(), templates.WhatsappTemplate{To: "+5511999998888", TemplateId: "HX-promo"})
+	if !errors.Is(err, consent.ErrNoConsent) {
+		t.Errorf("Expected ErrNoConsent, got %v", err)
+	}
+	if len(next.templates) != 0 {
+		t.Errorf("Expected nothing to be sent, got %+v", next.templates)
+	}
+}
+
+func TestGuardedSender_MarketingRevoked(t *testing.T) {
+	guard, _ := newGuard(t, &consent.Record{Action: consent.ActionRevoked, RecordedAt: time.Now()})
+
+	_, err := guard.SendTemplate(context.Background(), templates.WhatsappTemplate{To: "+5511999998888", TemplateId: "HX-promo"})
+	if !errors.Is(err, consent.ErrNoConsent) {
+		t.Errorf("Expected ErrNoConsent, got %v", err)
+	}
+}
+
+func TestGuardedSender_MarketingWithConsent(t *testing.T) {
+	guard, next := newGuard(t, &consent.Record{Action: consent.ActionGranted, RecordedAt: time.Now()})
+
+	_, err := guard.SendTemplate(context.Background(), templates.WhatsappTemplate{To: "+5511999998888", TemplateId: "HX-promo"})
+	if err != nil {
+		t.Fatalf("Expected template to be sent, got %v", err)
+	}
+	if len(next.templates) != 1 {
+		t.Errorf("Expected one template to be sent, got %d", len(next.templates))
+	}
+}
+
+func TestGuardedSender_UtilityWithoutConsent(t *testing.T) {
+	guard, next := newGuard(t, nil)
+
+	_, err := guard.SendTemplate(context.Background(), templates.WhatsappTemplate{To: "+5511999998888", TemplateId: "HX-invoice"})
+	if err != nil {
+		t.Fatalf("Expected utility template to be sent, got %v", err)
+	}
+	if len(next.templates) != 1 {
+		t.Errorf("Expected one template to be sent, got %d", len(next.templates))
+	}
+}
@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: consent/consent.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	consent "mbx/consent"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Latest mocks base method.
+func (m *MockRepository) Latest(ctx context.Context, contactId uuid.UUID, channel consent.Channel, purpose consent.Purpose) (*consent.Record, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Latest", ctx, contactId, channel, purpose)
+	ret0, _ := ret[0].(*consent.Record)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Latest indicates an expected call of Latest.
+func (mr *MockRepositoryMockRecorder) Latest(ctx, contactId, channel, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Latest", reflect.TypeOf((*MockRepository)(nil).Latest), ctx, contactId, channel, purpose)
+}
+
+// ListByContact mocks base method.
+func (m *MockRepository) ListByContact(arg0 context.Context, arg1 uuid.UUID) ([]consent.Record, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByContact", arg0, arg1)
+	ret0, _ := ret[0].([]consent.Record)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByContact indicates an expected call of ListByContact.
+func (mr *MockRepositoryMockRecorder) ListByContact(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByContact", reflect.TypeOf((*MockRepository)(nil).ListByContact), arg0, arg1)
+}
+
+// Record mocks base method.
+func (m *MockRepository) Record(arg0 context.Context, arg1 consent.Record) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockRepositoryMockRecorder) Record(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockRepository)(nil).Record), arg0, arg1)
+}
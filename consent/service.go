@@ -0,0 +1,74 @@
+package consent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidRecord = errors.New("invalid consent record")
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Record(ctx context.Context, record Record) (*Record, error) {
+	if !record.Valid() {
+		return nil, ErrInvalidRecord
+	}
+
+	record.Id = uuid.New()
+	record.CreatedAt = time.Now()
+	if record.RecordedAt.IsZero() {
+		record.RecordedAt = record.CreatedAt
+	}
+
+	if err := s.repo.Record(ctx, record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// History returns every consent record of the contact, newest first
+func (s *Service) History(ctx context.Context, contactId uuid.UUID) ([]Record, error) {
+	return s.repo.ListByContact(ctx, contactId)
+}
+
+// Current returns the latest record of the contact per channel and purpose
+func (s *Service) Current(ctx context.Context, contactId uuid.UUID) ([]Record, error) {
+	records, err := s.repo.ListByContact(ctx, contactId)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		channel Channel
+		purpose Purpose
+	}
+	seen := make(map[key]bool)
+	current := []Record{}
+	for _, r := range records {
+		k := key{r.Channel, r.Purpose}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		current = append(current, r)
+	}
+	return current, nil
+}
+
+// Has reports whether the contact currently consents to the purpose
+func (s *Service) Has(ctx context.Context, contactId uuid.UUID, channel Channel, purpose Purpose) (bool, error) {
+	latest, err := s.repo.Latest(ctx, contactId, channel, purpose)
+	if err != nil {
+		return false, err
+	}
+	return latest != nil && latest.Action == ActionGranted, nil
+}
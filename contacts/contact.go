@@ -0,0 +1,47 @@
+package contacts
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound     = errors.New("contact not found")
+	ErrDuplicate    = errors.New("contact with this phone number already exists")
+	ErrInvalidPhone = errors.New("invalid phone number")
+
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
+
+// Contact is a recipient identified by its E.164 phone number
+type Contact struct {
+	Id         uuid.UUID         `json:"id"`
+	Phone      string            `json:"phone"`
+	Name       string            `json:"name,omitempty"`
+	Locale     string            `json:"locale,omitempty"`
+	Timezone   string            `json:"timezone,omitempty"`
+	Tags       []string          `json:"tags"`
+	Attributes map[string]string `json:"attributes"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// HasTag reports whether the contact is tagged with tag
+func (c *Contact) HasTag(tag string) bool {
+	for _, t := range c.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ListFilter narrows down GET /contacts
+type ListFilter struct {
+	Tag    string
+	Query  string // matched against name and phone
+	Limit  int
+	Offset int
+}
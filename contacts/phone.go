@@ -0,0 +1,73 @@
+package contacts
+
+import (
+	"strings"
+)
+
+// callingCodes maps ISO 3166 country codes to their E.164 calling code, used
+// to complete numbers given in national format.
+var callingCodes = map[string]string{
+	"AR": "54",
+	"BO": "591",
+	"BR": "55",
+	"CA": "1",
+	"CL": "56",
+	"CO": "57",
+	"DE": "49",
+	"ES": "34",
+	"FR": "33",
+	"GB": "44",
+	"IT": "39",
+	"MX": "52",
+	"PE": "51",
+	"PT": "351",
+	"PY": "595",
+	"US": "1",
+	"UY": "598",
+}
+
+// NormalizePhone parses a phone number written in any common format
+// ("+55 (11) 99999-8888", "0055...", "whatsapp:+55...", "11 99999-8888")
+// into E.164. Numbers without an international prefix are taken to belong
+// to defaultCountry.
+func NormalizePhone(raw string, defaultCountry string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if len(s) >= len("whatsapp:") && strings.EqualFold(s[:len("whatsapp:")], "whatsapp:") {
+		s = strings.TrimSpace(s[len("whatsapp:"):])
+	}
+
+	international := strings.HasPrefix(s, "+")
+	s = strings.TrimPrefix(s, "+")
+
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	if !international && strings.HasPrefix(number, "00") {
+		international = true
+		number = strings.TrimPrefix(number, "00")
+	}
+
+	if !international {
+		code, ok := callingCodes[strings.ToUpper(defaultCountry)]
+		if !ok {
+			return "", ErrInvalidPhone
+		}
+		// drop the national trunk prefix, e.g. 011 9999-8888 in Brazil
+		number = code + strings.TrimLeft(number, "0")
+	}
+
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+
+	return "+" + number, nil
+}
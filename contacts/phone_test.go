@@ -0,0 +1,51 @@
+package contacts
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	cases := []struct {
+		raw     string
+		country string
+		want    string
+	}{
+		{"+55 (11) 99999-8888", "BR", "+5511999998888"},
+		{"whatsapp:+5511999998888", "BR", "+5511999998888"},
+		{"11 99999-8888", "BR", "+5511999998888"},
+		{"011 99999-8888", "BR", "+5511999998888"},
+		{"0055 11 99999 8888", "US", "+5511999998888"},
+		{"912 345 678", "pt", "+351912345678"},
+		{"(415) 555-0100", "US", "+14155550100"},
+	}
+	for _, c := range cases {
+		got, err := NormalizePhone(c.raw, c.country)
+		if err != nil {
+			t.Errorf("NormalizePhone(%q, %q) returned error: %v", c.raw, c.country, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("NormalizePhone(%q, %q) = %q, want %q", c.raw, c.country, got, c.want)
+		}
+	}
+}
+
+func TestNormalizePhone_Invalid(t *testing.T) {
+	cases := []struct {
+		raw     string
+		country string
+	}{
+		{"", "BR"},
+		{"abc", "BR"},
+		{"+55 11 9999x8888", "BR"},
+		{"12345", "BR"},
+		{"+1234567890123456", "BR"},
+		{"11 99999-8888", ""},
+	}
+	for _, c := range cases {
+		if _, err := NormalizePhone(c.raw, c.country); !errors.Is(err, ErrInvalidPhone) {
+			t.Errorf("NormalizePhone(%q, %q) = %v, want ErrInvalidPhone", c.raw, c.country, err)
+		}
+	}
+}
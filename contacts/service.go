@@ -0,0 +1,148 @@
+package contacts
+
+import (
+	"context"
+	"errors"
+	"mbx/templates"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	Create(context.Context, Contact) error
+	Update(context.Context, Contact) error
+	Delete(context.Context, uuid.UUID) error
+	FindById(context.Context, uuid.UUID) (*Contact, error)
+	FindByPhone(ctx context.Context, phone string) (*Contact, error)
+	List(context.Context, ListFilter) ([]Contact, error)
+}
+
+// Resolver links free-form recipients to contacts. Send paths use it to
+// normalize the number they were given and find, or create, its contact.
+type Resolver interface {
+	Resolve(ctx context.Context, phone string) (*Contact, error)
+	FindById(ctx context.Context, id uuid.UUID) (*Contact, error)
+}
+
+type Config struct {
+	// DefaultCountry is the ISO 3166 country assumed for national numbers
+	DefaultCountry string
+}
+
+type Service struct {
+	repo   Repository
+	config Config
+}
+
+var _ Resolver = (*Service)(nil)
+
+func NewService(repo Repository, config Config) *Service {
+	return &Service{repo: repo, config: config}
+}
+
+// Normalize returns the E.164 form of phone using the default country
+func (s *Service) Normalize(phone string) (string, error) {
+	return NormalizePhone(phone, s.config.DefaultCountry)
+}
+
+func (s *Service) Create(ctx context.Context, contact Contact) (*Contact, error) {
+	phone, err := s.Normalize(contact.Phone)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(&contact); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindByPhone(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, ErrDuplicate
+	}
+
+	now := time.Now()
+	contact.Id = uuid.New()
+	contact.Phone = phone
+	contact.CreatedAt = now
+	contact.UpdatedAt = now
+	if contact.Tags == nil {
+		contact.Tags = []string{}
+	}
+	if contact.Attributes == nil {
+		contact.Attributes = map[string]string{}
+	}
+
+	if err := s.repo.Create(ctx, contact); err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+func (s *Service) Update(ctx context.Context, contact Contact) (*Contact, error) {
+	phone, err := s.Normalize(contact.Phone)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(&contact); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindByPhone(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Id != contact.Id {
+		return existing, ErrDuplicate
+	}
+
+	contact.Phone = phone
+	contact.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, contact); err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *Service) FindById(ctx context.Context, id uuid.UUID) (*Contact, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context, filter ListFilter) ([]Contact, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	return s.repo.List(ctx, filter)
+}
+
+// Resolve normalizes phone and returns its contact, creating a bare one the
+// first time the number is seen.
+func (s *Service) Resolve(ctx context.Context, phone string) (*Contact, error) {
+	contact, err := s.Create(ctx, Contact{Phone: phone})
+	if !errors.Is(err, ErrDuplicate) {
+		return contact, err
+	}
+	if contact != nil {
+		return contact, nil
+	}
+
+	// lost a race with a concurrent insert of the same number
+	normalized, _ := s.Normalize(phone)
+	return s.repo.FindByPhone(ctx, normalized)
+}
+
+func validate(contact *Contact) error {
+	contact.Locale = templates.NormalizeLocale(contact.Locale)
+	if contact.Timezone != "" {
+		if _, err := time.LoadLocation(contact.Timezone); err != nil {
+			return ErrInvalidTimezone
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package conversations
+
+import (
+	"context"
+	"errors"
+	"mbx/contacts"
+	"mbx/history"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound      = errors.New("conversation not found")
+	ErrInvalidStatus = errors.New("invalid conversation status")
+	ErrEmptyNote     = errors.New("note cannot be empty")
+)
+
+type Status string
+
+const (
+	// StatusOpen conversations wait for an answer from an agent
+	StatusOpen Status = "open"
+	// StatusPending conversations wait for the customer or someone else
+	StatusPending Status = "pending"
+	// StatusClosed conversations are resolved. They reopen when the customer
+	// writes again.
+	StatusClosed Status = "closed"
+)
+
+func (s Status) Valid() bool {
+	switch s {
+	case StatusOpen, StatusPending, StatusClosed:
+		return true
+	}
+	return false
+}
+
+// Conversation is the thread of messages exchanged with a contact, together
+// with the inbox state agents keep on it.
+type Conversation struct {
+	Contact       contacts.Contact  `json:"contact"`
+	LastMessageAt time.Time         `json:"last_message_at"`
+	LastMessage   string            `json:"last_message"`
+	LastDirection history.Direction `json:"last_direction"`
+	UnreadCount   int               `json:"unread_count"`
+	LastReadAt    *time.Time        `json:"last_read_at,omitempty"`
+	State
+}
+
+// State is what agents change on a conversation
+type State struct {
+	Status     Status     `json:"status"`
+	AssigneeId *uuid.UUID `json:"assignee_id,omitempty"`
+	Tags       []string   `json:"tags"`
+	Archived   bool       `json:"archived"`
+}
+
+type ListFilter struct {
+	Archived   bool
+	UnreadOnly bool
+	Status     Status
+	AssigneeId *uuid.UUID
+	Tag        string
+	Limit      int
+	Offset     int
+}
+
+type EntryKind string
+
+const (
+	// EntryMessage is a message that was sent or received
+	EntryMessage EntryKind = "message"
+	// EntryScheduled is a scheduled send that has not been delivered
+	EntryScheduled EntryKind = "scheduled"
+	// EntryNote is an internal note, never sent to the customer
+	EntryNote EntryKind = "note"
+)
+
+// Entry is one item of a conversation thread
+type Entry struct {
+	Id         uuid.UUID         `json:"id"`
+	Kind       EntryKind         `json:"kind"`
+	Direction  history.Direction `json:"direction,omitempty"`
+	AgentId    *uuid.UUID        `json:"agent_id,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	TemplateId string            `json:"template_id,omitempty"`
+	Status     string            `json:"status,omitempty"`
+	// Interaction is the button or list item an inbound message picked
+	Interaction *history.Interaction `json:"interaction,omitempty"`
+	At          time.Time            `json:"at"`
+}
+
+// Note is an internal comment agents leave on a conversation
+type Note struct {
+	Id        uuid.UUID  `json:"id"`
+	ContactId uuid.UUID  `json:"contact_id"`
+	AgentId   *uuid.UUID `json:"agent_id,omitempty"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type EventType string
+
+const (
+	EventAssigned      EventType = "assigned"
+	EventStatusChanged EventType = "status_changed"
+	EventTagsChanged   EventType = "tags_changed"
+	EventArchived      EventType = "archived"
+)
+
+// Event records a change of the state of a conversation. AgentId is who made
+// the change, nil for changes made by the system.
+type Event struct {
+	Id        uuid.UUID  `json:"id"`
+	ContactId uuid.UUID  `json:"contact_id"`
+	AgentId   *uuid.UUID `json:"agent_id,omitempty"`
+	Type      EventType  `json:"type"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type Repository interface {
+	List(context.Context, ListFilter) ([]Conversation, error)
+	// Find returns the conversation with the contact, or nil if no message
+	// was exchanged with it yet
+	Find(ctx context.Context, contactId uuid.UUID) (*Conversation, error)
+	// State returns the state of the conversation, with defaults when it was
+	// never changed
+	State(ctx context.Context, contactId uuid.UUID) (*State, error)
+	MarkRead(ctx context.Context, contactId uuid.UUID, at time.Time) error
+	MarkUnread(ctx context.Context, contactId uuid.UUID) error
+	SetArchived(ctx context.Context, contactId uuid.UUID, archived bool) error
+	SetStatus(ctx context.Context, contactId uuid.UUID, status Status) error
+	SetAssignee(ctx context.Context, contactId uuid.UUID, agentId *uuid.UUID) error
+	SetTags(ctx context.Context, contactId uuid.UUID, tags []string) error
+
+	AddNote(context.Context, Note) error
+	ListNotes(ctx context.Context, contactId uuid.UUID) ([]Note, error)
+	RecordEvent(context.Context, Event) error
+	ListEvents(ctx context.Context, contactId uuid.UUID) ([]Event, error)
+}
@@ -0,0 +1,225 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: conversations/conversation.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	conversations "mbx/conversations"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddNote mocks base method.
+func (m *MockRepository) AddNote(arg0 context.Context, arg1 conversations.Note) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNote", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddNote indicates an expected call of AddNote.
+func (mr *MockRepositoryMockRecorder) AddNote(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNote", reflect.TypeOf((*MockRepository)(nil).AddNote), arg0, arg1)
+}
+
+// Find mocks base method.
+func (m *MockRepository) Find(ctx context.Context, contactId uuid.UUID) (*conversations.Conversation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", ctx, contactId)
+	ret0, _ := ret[0].(*conversations.Conversation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockRepositoryMockRecorder) Find(ctx, contactId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockRepository)(nil).Find), ctx, contactId)
+}
+
+// List mocks base method.
+func (m *MockRepository) List(arg0 context.Context, arg1 conversations.ListFilter) ([]conversations.Conversation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]conversations.Conversation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockRepositoryMockRecorder) List(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRepository)(nil).List), arg0, arg1)
+}
+
+// ListEvents mocks base method.
+func (m *MockRepository) ListEvents(ctx context.Context, contactId uuid.UUID) ([]conversations.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEvents", ctx, contactId)
+	ret0, _ := ret[0].([]conversations.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEvents indicates an expected call of ListEvents.
+func (mr *MockRepositoryMockRecorder) ListEvents(ctx, contactId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEvents", reflect.TypeOf((*MockRepository)(nil).ListEvents), ctx, contactId)
+}
+
+// ListNotes mocks base method.
+func (m *MockRepository) ListNotes(ctx context.Context, contactId uuid.UUID) ([]conversations.Note, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNotes", ctx, contactId)
+	ret0, _ := ret[0].([]conversations.Note)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNotes indicates an expected call of ListNotes.
+func (mr *MockRepositoryMockRecorder) ListNotes(ctx, contactId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNotes", reflect.TypeOf((*MockRepository)(nil).ListNotes), ctx, contactId)
+}
+
+// MarkRead mocks base method.
+func (m *MockRepository) MarkRead(ctx context.Context, contactId uuid.UUID, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRead", ctx, contactId, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkRead indicates an expected call of MarkRead.
+func (mr *MockRepositoryMockRecorder) MarkRead(ctx, contactId, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRead", reflect.TypeOf((*MockRepository)(nil).MarkRead), ctx, contactId, at)
+}
+
+// MarkUnread mocks base method.
+func (m *MockRepository) MarkUnread(ctx context.Context, contactId uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkUnread", ctx, contactId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkUnread indicates an expected call of MarkUnread.
+func (mr *MockRepositoryMockRecorder) MarkUnread(ctx, contactId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkUnread", reflect.TypeOf((*MockRepository)(nil).MarkUnread), ctx, contactId)
+}
+
+// RecordEvent mocks base method.
+func (m *MockRepository) RecordEvent(arg0 context.Context, arg1 conversations.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordEvent", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordEvent indicates an expected call of RecordEvent.
+func (mr *MockRepositoryMockRecorder) RecordEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordEvent", reflect.TypeOf((*MockRepository)(nil).RecordEvent), arg0, arg1)
+}
+
+// SetArchived mocks base method.
+func (m *MockRepository) SetArchived(ctx context.Context, contactId uuid.UUID, archived bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetArchived", ctx, contactId, archived)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetArchived indicates an expected call of SetArchived.
+func (mr *MockRepositoryMockRecorder) SetArchived(ctx, contactId, archived interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetArchived", reflect.TypeOf((*MockRepository)(nil).SetArchived), ctx, contactId, archived)
+}
+
+// SetAssignee mocks base method.
+func (m *MockRepository) SetAssignee(ctx context.Context, contactId uuid.UUID, agentId *uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAssignee", ctx, contactId, agentId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAssignee indicates an expected call of SetAssignee.
+func (mr *MockRepositoryMockRecorder) SetAssignee(ctx, contactId, agentId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAssignee", reflect.TypeOf((*MockRepository)(nil).SetAssignee), ctx, contactId, agentId)
+}
+
+// SetStatus mocks base method.
+func (m *MockRepository) SetStatus(ctx context.Context, contactId uuid.UUID, status conversations.Status) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStatus", ctx, contactId, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetStatus indicates an expected call of SetStatus.
+func (mr *MockRepositoryMockRecorder) SetStatus(ctx, contactId, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStatus", reflect.TypeOf((*MockRepository)(nil).SetStatus), ctx, contactId, status)
+}
+
+// SetTags mocks base method.
+func (m *MockRepository) SetTags(ctx context.Context, contactId uuid.UUID, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTags", ctx, contactId, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTags indicates an expected call of SetTags.
+func (mr *MockRepositoryMockRecorder) SetTags(ctx, contactId, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTags", reflect.TypeOf((*MockRepository)(nil).SetTags), ctx, contactId, tags)
+}
+
+// State mocks base method.
+func (m *MockRepository) State(ctx context.Context, contactId uuid.UUID) (*conversations.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State", ctx, contactId)
+	ret0, _ := ret[0].(*conversations.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// State indicates an expected call of State.
+func (mr *MockRepositoryMockRecorder) State(ctx, contactId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockRepository)(nil).State), ctx, contactId)
+}
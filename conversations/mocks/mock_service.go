@@ -0,0 +1,106 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: conversations/service.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	agents "mbx/agents"
+	models "mbx/models"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockScheduleLister is a mock of ScheduleLister interface.
+type MockScheduleLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockScheduleListerMockRecorder
+}
+
+// MockScheduleListerMockRecorder is the mock recorder for MockScheduleLister.
+type MockScheduleListerMockRecorder struct {
+	mock *MockScheduleLister
+}
+
+// NewMockScheduleLister creates a new mock instance.
+func NewMockScheduleLister(ctrl *gomock.Controller) *MockScheduleLister {
+	mock := &MockScheduleLister{ctrl: ctrl}
+	mock.recorder = &MockScheduleListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScheduleLister) EXPECT() *MockScheduleListerMockRecorder {
+	return m.recorder
+}
+
+// ListByContact mocks base method.
+func (m *MockScheduleLister) ListByContact(arg0 context.Context, arg1 uuid.UUID) ([]models.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByContact", arg0, arg1)
+	ret0, _ := ret[0].([]models.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByContact indicates an expected call of ListByContact.
+func (mr *MockScheduleListerMockRecorder) ListByContact(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByContact", reflect.TypeOf((*MockScheduleLister)(nil).ListByContact), arg0, arg1)
+}
+
+// MockAgentDirectory is a mock of AgentDirectory interface.
+type MockAgentDirectory struct {
+	ctrl     *gomock.Controller
+	recorder *MockAgentDirectoryMockRecorder
+}
+
+// MockAgentDirectoryMockRecorder is the mock recorder for MockAgentDirectory.
+type MockAgentDirectoryMockRecorder struct {
+	mock *MockAgentDirectory
+}
+
+// NewMockAgentDirectory creates a new mock instance.
+func NewMockAgentDirectory(ctrl *gomock.Controller) *MockAgentDirectory {
+	mock := &MockAgentDirectory{ctrl: ctrl}
+	mock.recorder = &MockAgentDirectoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAgentDirectory) EXPECT() *MockAgentDirectoryMockRecorder {
+	return m.recorder
+}
+
+// FindById mocks base method.
+func (m *MockAgentDirectory) FindById(arg0 context.Context, arg1 uuid.UUID) (*agents.Agent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*agents.Agent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockAgentDirectoryMockRecorder) FindById(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockAgentDirectory)(nil).FindById), arg0, arg1)
+}
+
+// Next mocks base method.
+func (m *MockAgentDirectory) Next(arg0 context.Context) (*agents.Agent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Next", arg0)
+	ret0, _ := ret[0].(*agents.Agent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockAgentDirectoryMockRecorder) Next(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockAgentDirectory)(nil).Next), arg0)
+}
@@ -0,0 +1,329 @@
+package conversations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mbx/agents"
+	"mbx/contacts"
+	"mbx/history"
+	"mbx/inbound"
+	"mbx/models"
+	"mbx/sender"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// ScheduleLister lists the scheduled messages of a contact
+type ScheduleLister interface {
+	ListByContact(context.Context, uuid.UUID) ([]models.ScheduledMessage, error)
+}
+
+// AgentDirectory finds the agents conversations are assigned to
+type AgentDirectory interface {
+	FindById(context.Context, uuid.UUID) (*agents.Agent, error)
+	// Next returns the agent for a new conversation, nil if there is none
+	Next(context.Context) (*agents.Agent, error)
+}
+
+type Service struct {
+	repo      Repository
+	history   history.Repository
+	schedules ScheduleLister
+	agents    AgentDirectory
+	w         sender.Whatsapp
+}
+
+var _ inbound.Listener = (*Service)(nil)
+
+func NewService(repo Repository, history history.Repository, schedules ScheduleLister, agents AgentDirectory, w sender.Whatsapp) *Service {
+	return &Service{
+		repo:      repo,
+		history:   history,
+		schedules: schedules,
+		agents:    agents,
+		w:         w,
+	}
+}
+
+func (s *Service) List(ctx context.Context, filter ListFilter) ([]Conversation, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	return s.repo.List(ctx, filter)
+}
+
+func (s *Service) Find(ctx context.Context, contactId uuid.UUID) (*Conversation, error) {
+	return s.repo.Find(ctx, contactId)
+}
+
+// Thread merges the message history, the undelivered scheduled sends and the
+// internal notes of the contact into one list, oldest first. Delivered
+// scheduled messages are left out since they are already part of the history.
+func (s *Service) Thread(ctx context.Context, contactId uuid.UUID, limit int) ([]Entry, error) {
+	messages, err := s.history.ListByContact(ctx, contactId, limit)
+	if err != nil {
+		return nil, err
+	}
+	scheduled, err := s.schedules.ListByContact(ctx, contactId)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.repo.ListNotes(ctx, contactId)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(messages)+len(scheduled)+len(notes))
+	for _, m := range messages {
+		entries = append(entries, Entry{
+			Id:          m.Id,
+			Kind:        EntryMessage,
+			Direction:   m.Direction,
+			Body:        m.Body,
+			TemplateId:  m.TemplateId,
+			Status:      m.Status,
+			Interaction: m.Interaction,
+			At:          m.CreatedAt,
+		})
+	}
+	for _, m := range scheduled {
+		if m.Status == models.StatusSent {
+			continue
+		}
+		entries = append(entries, Entry{
+			Id:         m.Id,
+			Kind:       EntryScheduled,
+			Direction:  history.DirectionOutbound,
+			Body:       m.Content,
+			TemplateId: m.ProviderId,
+			Status:     string(m.Status),
+			At:         m.SendAt,
+		})
+	}
+	for _, n := range notes {
+		entries = append(entries, Entry{
+			Id:      n.Id,
+			Kind:    EntryNote,
+			AgentId: n.AgentId,
+			Body:    n.Body,
+			At:      n.CreatedAt,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.Before(entries[j].At)
+	})
+	return entries, nil
+}
+
+// Reply sends a freeform message to the contact and marks the conversation
+// read, since the agent answering it has seen it.
+func (s *Service) Reply(ctx context.Context, contact *contacts.Contact, body string) (*api.ApiV2010Message, error) {
+	msg, err := s.w.Send(ctx, models.WhatsappBody{
+		To:   fmt.Sprintf("whatsapp:%s", contact.Phone),
+		Body: body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the reply went out, so a failure here must not be reported as a failed send
+	if err := s.repo.MarkRead(ctx, contact.Id, time.Now()); err != nil {
+		slog.Error("Failed to mark conversation read", "error", err, "contact_id", contact.Id)
+	}
+	return msg, nil
+}
+
+func (s *Service) MarkRead(ctx context.Context, contactId uuid.UUID) error {
+	return s.repo.MarkRead(ctx, contactId, time.Now())
+}
+
+func (s *Service) MarkUnread(ctx context.Context, contactId uuid.UUID) error {
+	return s.repo.MarkUnread(ctx, contactId)
+}
+
+// SetArchived archives or unarchives the conversation. actor is the agent
+// making the change, nil for the system.
+func (s *Service) SetArchived(ctx context.Context, contactId uuid.UUID, archived bool, actor *uuid.UUID) error {
+	state, err := s.repo.State(ctx, contactId)
+	if err != nil {
+		return err
+	}
+	if state.Archived == archived {
+		return nil
+	}
+	if err := s.repo.SetArchived(ctx, contactId, archived); err != nil {
+		return err
+	}
+	return s.record(ctx, contactId, actor, EventArchived, strconv.FormatBool(state.Archived), strconv.FormatBool(archived))
+}
+
+func (s *Service) SetStatus(ctx context.Context, contactId uuid.UUID, status Status, actor *uuid.UUID) error {
+	if !status.Valid() {
+		return ErrInvalidStatus
+	}
+	state, err := s.repo.State(ctx, contactId)
+	if err != nil {
+		return err
+	}
+	if state.Status == status {
+		return nil
+	}
+	if err := s.repo.SetStatus(ctx, contactId, status); err != nil {
+		return err
+	}
+	return s.record(ctx, contactId, actor, EventStatusChanged, string(state.Status), string(status))
+}
+
+// Assign gives the conversation to an agent, or unassigns it when agentId is nil
+func (s *Service) Assign(ctx context.Context, contactId uuid.UUID, agentId *uuid.UUID, actor *uuid.UUID) error {
+	if agentId != nil {
+		agent, err := s.agents.FindById(ctx, *agentId)
+		if err != nil {
+			return err
+		}
+		if agent == nil {
+			return agents.ErrNotFound
+		}
+	}
+
+	state, err := s.repo.State(ctx, contactId)
+	if err != nil {
+		return err
+	}
+	if formatId(state.AssigneeId) == formatId(agentId) {
+		return nil
+	}
+	if err := s.repo.SetAssignee(ctx, contactId, agentId); err != nil {
+		return err
+	}
+	return s.record(ctx, contactId, actor, EventAssigned, formatId(state.AssigneeId), formatId(agentId))
+}
+
+// SetTags replaces the tags of the conversation
+func (s *Service) SetTags(ctx context.Context, contactId uuid.UUID, tags []string, actor *uuid.UUID) error {
+	tags = normalizeTags(tags)
+
+	state, err := s.repo.State(ctx, contactId)
+	if err != nil {
+		return err
+	}
+	if slices.Equal(normalizeTags(state.Tags), tags) {
+		return nil
+	}
+	if err := s.repo.SetTags(ctx, contactId, tags); err != nil {
+		return err
+	}
+	return s.record(ctx, contactId, actor, EventTagsChanged, strings.Join(state.Tags, ","), strings.Join(tags, ","))
+}
+
+func (s *Service) AddNote(ctx context.Context, contactId uuid.UUID, body string, author *uuid.UUID) (*Note, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyNote
+	}
+
+	note := Note{
+		Id:        uuid.New(),
+		ContactId: contactId,
+		AgentId:   author,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.AddNote(ctx, note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (s *Service) Notes(ctx context.Context, contactId uuid.UUID) ([]Note, error) {
+	return s.repo.ListNotes(ctx, contactId)
+}
+
+// Events returns the audit trail of the conversation, oldest first
+func (s *Service) Events(ctx context.Context, contactId uuid.UUID) ([]Event, error) {
+	return s.repo.ListEvents(ctx, contactId)
+}
+
+// Handoff reopens the conversation for the agents when a bot stops handling
+// it, leaving what the bot collected as a note
+func (s *Service) Handoff(ctx context.Context, contact *contacts.Contact, summary string) error {
+	if err := s.SetStatus(ctx, contact.Id, StatusOpen, nil); err != nil {
+		return err
+	}
+	_, err := s.AddNote(ctx, contact.Id, summary, nil)
+	return err
+}
+
+// HandleInbound moves archived or closed conversations back to the inbox when
+// the customer writes again, and gives unassigned ones to the next agent of
+// the auto-assign pool.
+func (s *Service) HandleInbound(ctx context.Context, msg *inbound.Message) error {
+	contactId := msg.Contact.Id
+
+	state, err := s.repo.State(ctx, contactId)
+	if err != nil {
+		return err
+	}
+	if state.Archived {
+		if err := s.SetArchived(ctx, contactId, false, nil); err != nil {
+			return err
+		}
+	}
+	if state.Status == StatusClosed {
+		if err := s.SetStatus(ctx, contactId, StatusOpen, nil); err != nil {
+			return err
+		}
+	}
+	if state.AssigneeId != nil {
+		return nil
+	}
+
+	agent, err := s.agents.Next(ctx)
+	if err != nil || agent == nil {
+		return err
+	}
+	if err := s.repo.SetAssignee(ctx, contactId, &agent.Id); err != nil {
+		return err
+	}
+	return s.record(ctx, contactId, nil, EventAssigned, "", agent.Id.String())
+}
+
+func (s *Service) record(ctx context.Context, contactId uuid.UUID, actor *uuid.UUID, eventType EventType, from, to string) error {
+	return s.repo.RecordEvent(ctx, Event{
+		Id:        uuid.New(),
+		ContactId: contactId,
+		AgentId:   actor,
+		Type:      eventType,
+		From:      from,
+		To:        to,
+		CreatedAt: time.Now(),
+	})
+}
+
+func formatId(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// normalizeTags trims, deduplicates and sorts tags so that changes compare
+// and read the same regardless of the order they were sent in
+func normalizeTags(tags []string) []string {
+	out := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
@@ -0,0 +1,105 @@
+package conversations_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/agents"
+	"mbx/contacts"
+	"mbx/conversations"
+	cvmocks "mbx/conversations/mocks"
+	"mbx/history"
+	hmocks "mbx/history/mocks"
+	"mbx/inbound"
+	"mbx/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+type scheduleLister []models.ScheduledMessage
+
+func (l scheduleLister) ListByContact(context.Context, uuid.UUID) ([]models.ScheduledMessage, error) {
+	return l, nil
+}
+
+func TestService_Thread(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	contactId := uuid.New()
+	now := time.Now()
+
+	historyRepo := hmocks.NewMockRepository(ctrl)
+	historyRepo.EXPECT().ListByContact(gomock.Any(), contactId, 100).Return([]history.Message{
+		{Id: uuid.New(), Direction: history.DirectionInbound, Body: "Thanks!", CreatedAt: now.Add(-time.Minute)},
+		{Id: uuid.New(), Direction: history.DirectionOutbound, Body: "Your order shipped", CreatedAt: now.Add(-time.Hour)},
+	}, nil)
+
+	schedules := scheduleLister{
+		{Id: uuid.New(), Content: "Already delivered", SendAt: now.Add(-time.Hour), Status: models.StatusSent},
+		{Id: uuid.New(), Content: "Rate your purchase", SendAt: now.Add(time.Hour), Status: models.StatusPending},
+	}
+
+	repo := cvmocks.NewMockRepository(ctrl)
+	repo.EXPECT().ListNotes(gomock.Any(), contactId).Return([]conversations.Note{
+		{Id: uuid.New(), ContactId: contactId, Body: "Customer asked for a refund", CreatedAt: now},
+	}, nil)
+
+	service := conversations.NewService(repo, historyRepo, schedules, nil, nil)
+	thread, err := service.Thread(context.Background(), contactId, 100)
+	if err != nil {
+		t.Fatalf("Failed to build thread: %v", err)
+	}
+
+	want := []string{"Your order shipped", "Thanks!", "Customer asked for a refund", "Rate your purchase"}
+	if len(thread) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), thread)
+	}
+	for i, body := range want {
+		if thread[i].Body != body {
+			t.Errorf("Expected entry %d to be %q, got %q", i, body, thread[i].Body)
+		}
+	}
+	if thread[2].Kind != conversations.EntryNote {
+		t.Errorf("Expected note entry, got %+v", thread[2])
+	}
+	if thread[3].Kind != conversations.EntryScheduled || thread[3].Direction != history.DirectionOutbound {
+		t.Errorf("Expected pending send to be an outbound scheduled entry, got %+v", thread[3])
+	}
+}
+
+func TestService_HandleInboundReopensAndAssigns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	contact := &contacts.Contact{Id: uuid.New(), Phone: "+5511999998888"}
+	agent := &agents.Agent{Id: uuid.New(), Name: "Ana", Active: true, AutoAssign: true}
+
+	repo := cvmocks.NewMockRepository(ctrl)
+	repo.EXPECT().State(gomock.Any(), contact.Id).Return(&conversations.State{Status: conversations.StatusClosed, Archived: true}, nil).AnyTimes()
+	repo.EXPECT().SetArchived(gomock.Any(), contact.Id, false)
+	repo.EXPECT().SetStatus(gomock.Any(), contact.Id, conversations.StatusOpen)
+	repo.EXPECT().SetAssignee(gomock.Any(), contact.Id, &agent.Id)
+
+	var events []conversations.Event
+	repo.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, e conversations.Event) error {
+		events = append(events, e)
+		return nil
+	}).Times(3)
+
+	directory := cvmocks.NewMockAgentDirectory(ctrl)
+	directory.EXPECT().Next(gomock.Any()).Return(agent, nil)
+
+	service := conversations.NewService(repo, nil, nil, directory, nil)
+	if err := service.HandleInbound(context.Background(), &inbound.Message{Contact: contact}); err != nil {
+		t.Fatalf("Failed to handle inbound message: %v", err)
+	}
+
+	types := []conversations.EventType{conversations.EventArchived, conversations.EventStatusChanged, conversations.EventAssigned}
+	for i, eventType := range types {
+		if events[i].Type != eventType || events[i].AgentId != nil {
+			t.Errorf("Expected system %s event, got %+v", eventType, events[i])
+		}
+	}
+	if events[2].To != agent.Id.String() {
+		t.Errorf("Expected conversation to be assigned to %s, got %+v", agent.Id, events[2])
+	}
+}
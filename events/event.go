@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	// MessageCreated is an outbound message accepted by the provider
+	MessageCreated Type = "message.created"
+	// StatusChanged is a new delivery status reported for a message
+	StatusChanged Type = "message.status_changed"
+	// InboundReceived is a message sent by a customer
+	InboundReceived Type = "inbound.received"
+	// ScheduleChanged is a scheduled message created or moved to a new status
+	ScheduleChanged Type = "schedule.changed"
+)
+
+// Types lists every event type
+var Types = []Type{MessageCreated, StatusChanged, InboundReceived, ScheduleChanged}
+
+// Event is an entry of the event log. Seq identifies the event, so clients
+// can resume after the last one they saw.
+type Event struct {
+	Seq       int64           `json:"seq"`
+	Type      Type            `json:"type"`
+	ContactId *uuid.UUID      `json:"contact_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Filter selects events by type and contact. Empty fields match everything.
+type Filter struct {
+	Types     []Type
+	ContactId *uuid.UUID
+}
+
+type Repository interface {
+	// Append stores the event and sets its Seq
+	Append(context.Context, *Event) error
+	// ListAfter returns the events following the one with seq, in the order
+	// they were committed. Events are only listed once every event before
+	// them is, so a stream resuming after seq never skips one that committed
+	// late.
+	ListAfter(ctx context.Context, seq int64, filter Filter, limit int) ([]Event, error)
+	// LastSeq returns the Seq of the latest event ListAfter would return, or
+	// 0 when there is none
+	LastSeq(context.Context) (int64, error)
+	// Resume returns the seq a stream that last received seq goes on after:
+	// seq itself when it is an event of the tenant, or LastSeq otherwise, so
+	// an unknown cursor does not replay the whole log
+	Resume(ctx context.Context, seq int64) (int64, error)
+}
+
+// Notifier reports events appended to the log by any replica
+type Notifier interface {
+	// Listen returns a channel that receives a value after events were
+	// appended. It is closed when ctx is done.
+	Listen(ctx context.Context) <-chan struct{}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"mbx/history"
+	"mbx/models"
+	"mbx/schedules"
+
+	"github.com/google/uuid"
+)
+
+// HistoryRecorder wraps the message history and publishes an event for every
+// message recorded and status updated, wherever the change comes from.
+type HistoryRecorder struct {
+	history.Repository
+	events *Service
+}
+
+var _ history.Repository = (*HistoryRecorder)(nil)
+
+func NewHistoryRecorder(repo history.Repository, events *Service) *HistoryRecorder {
+	return &HistoryRecorder{Repository: repo, events: events}
+}
+
+func (r *HistoryRecorder) Record(ctx context.Context, message history.Message) error {
+	if err := r.Repository.Record(ctx, message); err != nil {
+		return err
+	}
+
+	eventType := MessageCreated
+	if message.Direction == history.DirectionInbound {
+		eventType = InboundReceived
+	}
+	r.events.Publish(ctx, eventType, message.ContactId, message)
+	return nil
+}
+
+func (r *HistoryRecorder) UpdateStatus(ctx context.Context, sid string, status string) error {
+	if err := r.Repository.UpdateStatus(ctx, sid, status); err != nil {
+		return err
+	}
+
+	message, err := r.Repository.FindByProviderSid(ctx, sid)
+	if err != nil {
+		slog.Error("Failed to fetch message for status event", "error", err, "sid", sid)
+		return nil
+	}
+	if message != nil {
+		r.events.Publish(ctx, StatusChanged, message.ContactId, message)
+	}
+	return nil
+}
+
+// ScheduleRecorder wraps the scheduled messages and publishes an event when
+// one is created or changes status.
+type ScheduleRecorder struct {
+	schedules.Repository
+	events *Service
+}
+
+var _ schedules.Repository = (*ScheduleRecorder)(nil)
+
+func NewScheduleRecorder(repo schedules.Repository, events *Service) *ScheduleRecorder {
+	return &ScheduleRecorder{Repository: repo, events: events}
+}
+
+func (r *ScheduleRecorder) Create(ctx context.Context, message models.ScheduledMessage) error {
+	if err := r.Repository.Create(ctx, message); err != nil {
+		return err
+	}
+	r.events.Publish(ctx, ScheduleChanged, message.ContactId, message)
+	return nil
+}
+
+func (r *ScheduleRecorder) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) error {
+	if err := r.Repository.UpdateStatus(ctx, id, status); err != nil {
+		return err
+	}
+
+	message, err := r.Repository.FindById(ctx, id)
+	if err != nil {
+		slog.Error("Failed to fetch scheduled message for event", "error", err, "id", id)
+		return nil
+	}
+	if message != nil {
+		r.events.Publish(ctx, ScheduleChanged, message.ContactId, message)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Service records events and wakes up the streams following them
+type Service struct {
+	repo Repository
+
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+	closed      bool
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{
+		repo:        repo,
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Publish appends an event to the log. Events describe changes that already
+// happened, so failures are only logged.
+func (s *Service) Publish(ctx context.Context, eventType Type, contactId *uuid.UUID, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("Failed to encode event", "error", err, "type", eventType)
+		return
+	}
+
+	event := Event{
+		Type:      eventType,
+		ContactId: contactId,
+		Data:      payload,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Append(ctx, &event); err != nil {
+		slog.Error("Failed to record event", "error", err, "type", eventType)
+		return
+	}
+	s.notify()
+}
+
+// After returns the events following seq that match the filter
+func (s *Service) After(ctx context.Context, seq int64, filter Filter, limit int) ([]Event, error) {
+	return s.repo.ListAfter(ctx, seq, filter, limit)
+}
+
+func (s *Service) LastSeq(ctx context.Context) (int64, error) {
+	return s.repo.LastSeq(ctx)
+}
+
+// Resume returns the seq to go on after for a stream that last received seq.
+// 0 starts from the beginning of the log.
+func (s *Service) Resume(ctx context.Context, seq int64) (int64, error) {
+	if seq == 0 {
+		return 0, nil
+	}
+	return s.repo.Resume(ctx, seq)
+}
+
+// Follow wakes up the subscribers whenever the notifier reports events, so
+// streams see the events published by other replicas. It returns when ctx is
+// done.
+func (s *Service) Follow(ctx context.Context, notifier Notifier) {
+	for range notifier.Listen(ctx) {
+		s.notify()
+	}
+}
+
+// Subscribe returns a channel that receives a value when new events were
+// published by this process or reported by Follow, and is closed when the
+// service shuts down.
+func (s *Service) Subscribe() (<-chan struct{}, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if s.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	s.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription, so open streams let the server shut down
+func (s *Service) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for ch := range s.subscribers {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (s *Service) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscribers {
+		// a pending wake-up already covers this event
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package flows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mbx/contacts"
+	"mbx/egress"
+	"mbx/inbound"
+	"mbx/models"
+	"mbx/sender"
+	"mbx/templates"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSteps stops flows whose nodes loop without waiting for an answer
+const maxSteps = 50
+
+// Handoff passes a conversation from a flow to the agents
+type Handoff interface {
+	Handoff(ctx context.Context, contact *contacts.Contact, summary string) error
+}
+
+type Config struct {
+	// MaxAttempts is how many invalid answers a node accepts before the flow
+	// moves to its error node
+	MaxAttempts int
+	// HTTPTimeout bounds the requests of http nodes
+	HTTPTimeout time.Duration
+	// AllowPrivateNetworks lets http nodes reach private and local
+	// addresses, for development
+	AllowPrivateNetworks bool
+}
+
+// Engine runs flows. It starts them from inbound messages matching their
+// trigger and feeds the following messages of the contact to its session.
+type Engine struct {
+	flows     Repository
+	sessions  SessionRepository
+	config    Config
+	w         sender.Whatsapp
+	wt        sender.WhatsappTemplate
+	templates templates.GroupResolver
+	handoff   Handoff
+	client    *http.Client
+}
+
+var _ inbound.Listener = (*Engine)(nil)
+
+func NewEngine(flows Repository, sessions SessionRepository, config Config, w sender.Whatsapp, wt sender.WhatsappTemplate, templates templates.GroupResolver, handoff Handoff) *Engine {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.HTTPTimeout == 0 {
+		config.HTTPTimeout = 10 * time.Second
+	}
+	return &Engine{
+		flows:     flows,
+		sessions:  sessions,
+		config:    config,
+		w:         w,
+		wt:        wt,
+		templates: templates,
+		handoff:   handoff,
+		client:    egress.NewClient(config.HTTPTimeout, config.AllowPrivateNetworks),
+	}
+}
+
+// HandleInbound continues the session of the contact, or starts the flow the
+// message triggers. Expired sessions are discarded.
+func (e *Engine) HandleInbound(ctx context.Context, msg *inbound.Message) error {
+	contact := msg.Contact
+
+	session, err := e.sessions.Find(ctx, contact.Id)
+	if err != nil {
+		return err
+	}
+	if session != nil {
+		flow, err := e.flows.FindById(ctx, session.FlowId)
+		if err != nil {
+			return err
+		}
+		if flow != nil && time.Now().Before(session.ExpiresAt) {
+			msg.Handled = true
+			return e.answer(ctx, contact, flow, session, msg)
+		}
+		if err := e.sessions.Delete(ctx, contact.Id); err != nil {
+			return err
+		}
+	}
+
+	flow, err := e.triggered(ctx, msg)
+	if err != nil || flow == nil {
+		return err
+	}
+	msg.Handled = true
+	slog.Info("Starting flow", "flow", flow.Name, "contact_id", contact.Id)
+	return e.Start(ctx, contact, flow)
+}
+
+// Start runs the flow from its start node for the contact, replacing any
+// session it had
+func (e *Engine) Start(ctx context.Context, contact *contacts.Contact, flow *Flow) error {
+	now := time.Now()
+	session := &Session{
+		ContactId: contact.Id,
+		FlowId:    flow.Id,
+		Node:      flow.Start,
+		Variables: map[string]string{},
+		CreatedAt: now,
+	}
+	return e.run(ctx, contact, flow, session)
+}
+
+// triggered returns the enabled flow whose trigger payload is the button the
+// customer tapped, or whose keyword is the whole message, ignoring case
+func (e *Engine) triggered(ctx context.Context, msg *inbound.Message) (*Flow, error) {
+	flows, err := e.flows.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body := strings.ToLower(strings.Trim(msg.Body, " \t\r\n.!?"))
+	for i := range flows {
+		flow := &flows[i]
+		if !flow.Enabled {
+			continue
+		}
+		if msg.Payload() != "" && flow.Trigger.Payload == msg.Payload() {
+			return flow, nil
+		}
+		for _, keyword := range flow.Trigger.Keywords {
+			if body != "" && strings.ToLower(keyword) == body {
+				return flow, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// run executes nodes from the current one until the flow waits for an answer
+// or ends
+func (e *Engine) run(ctx context.Context, contact *contacts.Contact, flow *Flow, session *Session) error {
+	for range maxSteps {
+		if session.Node == "" {
+			return e.sessions.Delete(ctx, contact.Id)
+		}
+		node, ok := flow.Nodes[session.Node]
+		if !ok {
+			e.discard(ctx, contact)
+			return fmt.Errorf("flow %q has no node %q", flow.Name, session.Node)
+		}
+
+		switch node.Type {
+		case NodeMessage:
+			if err := e.send(ctx, contact, session, node); err != nil {
+				return err
+			}
+			session.Node = node.Next
+
+		case NodeQuestion, NodeMenu:
+			if err := e.send(ctx, contact, session, node); err != nil {
+				return err
+			}
+			session.Attempts = 0
+			return e.save(ctx, flow, session)
+
+		case NodeBranch:
+			session.Node = node.Next
+			for _, b := range node.Branches {
+				if strings.EqualFold(session.Variables[b.Variable], b.Equals) {
+					session.Node = b.Next
+					break
+				}
+			}
+
+		case NodeHTTP:
+			if err := e.call(ctx, contact, session, node.Request); err != nil {
+				slog.Error("Flow HTTP step failed", "error", err, "flow", flow.Name, "node", session.Node)
+				session.Node = node.OnError
+			} else {
+				session.Node = node.Next
+			}
+
+		case NodeHandoff:
+			if err := e.finish(ctx, contact, session, node); err != nil {
+				return err
+			}
+			if e.handoff == nil {
+				return nil
+			}
+			return e.handoff.Handoff(ctx, contact, summary(flow, session))
+
+		case NodeEnd:
+			return e.finish(ctx, contact, session, node)
+
+		default:
+			e.discard(ctx, contact)
+			return fmt.Errorf("flow %q node %q has unknown type %q", flow.Name, session.Node, node.Type)
+		}
+	}
+
+	e.discard(ctx, contact)
+	return fmt.Errorf("flow %q exceeded %d steps without waiting for an answer", flow.Name, maxSteps)
+}
+
+// answer feeds a message to the question or menu the session waits on
+func (e *Engine) answer(ctx context.Context, contact *contacts.Contact, flow *Flow, session *Session, msg *inbound.Message) error {
+	node, ok := flow.Nodes[session.Node]
+	if !ok {
+		return e.run(ctx, contact, flow, session)
+	}
+
+	var value, next string
+	switch node.Type {
+	case NodeQuestion:
+		value, ok = validate(node, msg.Body)
+		next = node.Next
+	case NodeMenu:
+		var option *Option
+		if option = pick(node.Options, msg); option != nil {
+			value, next = option.Id, option.Next
+		}
+		ok = option != nil
+	default:
+		return e.run(ctx, contact, flow, session)
+	}
+
+	if !ok {
+		session.Attempts++
+		if session.Attempts >= e.config.MaxAttempts {
+			session.Node = node.OnError
+			session.Attempts = 0
+			return e.run(ctx, contact, flow, session)
+		}
+		retry := node
+		if node.ErrorText != "" {
+			retry = Node{Text: node.ErrorText}
+		}
+		if err := e.send(ctx, contact, session, retry); err != nil {
+			return err
+		}
+		return e.save(ctx, flow, session)
+	}
+
+	if node.Variable != "" {
+		session.Variables[node.Variable] = value
+	}
+	session.Node = next
+	session.Attempts = 0
+	return e.run(ctx, contact, flow, session)
+}
+
+// pick returns the menu option chosen by button payload, number or title
+func pick(options []Option, msg *inbound.Message) *Option {
+	answer := strings.TrimSpace(msg.Body)
+	for i := range options {
+		o := &options[i]
+		if msg.Payload() != "" && msg.Payload() == o.Id {
+			return o
+		}
+		if answer == strconv.Itoa(i+1) || strings.EqualFold(answer, o.Title) {
+			return o
+		}
+	}
+	return nil
+}
+
+func (e *Engine) save(ctx context.Context, flow *Flow, session *Session) error {
+	session.UpdatedAt = time.Now()
+	session.ExpiresAt = session.UpdatedAt.Add(flow.timeout())
+	return e.sessions.Save(ctx, *session)
+}
+
+// discard drops the session of a broken flow so the contact is not stuck in it
+func (e *Engine) discard(ctx context.Context, contact *contacts.Contact) {
+	if err := e.sessions.Delete(ctx, contact.Id); err != nil {
+		slog.Error("Failed to discard flow session", "error", err, "contact_id", contact.Id)
+	}
+}
+
+func (e *Engine) finish(ctx context.Context, contact *contacts.Contact, session *Session, node Node) error {
+	if err := e.sessions.Delete(ctx, contact.Id); err != nil {
+		return err
+	}
+	if node.Text == "" && node.Template == "" {
+		return nil
+	}
+	return e.send(ctx, contact, session, node)
+}
+
+// send sends the text or template of a node, listing the options of menus
+func (e *Engine) send(ctx context.Context, contact *contacts.Contact, session *Session, node Node) error {
+	if node.Template != "" {
+		resolved, err := e.templates.Resolve(ctx, node.Template, contact.Locale)
+		if err != nil {
+			return err
+		}
+		variables := make(map[string]string, len(node.Variables))
+		for key, value := range node.Variables {
+			variables[key] = expand(value, contact, session.Variables)
+		}
+		content, err := json.Marshal(variables)
+		if err != nil {
+			return err
+		}
+		_, err = e.wt.SendTemplate(ctx, templates.WhatsappTemplate{
+			To:         contact.Phone,
+			TemplateId: resolved.ContentSid,
+			Content:    string(content),
+			Language:   resolved.Language,
+		})
+		return err
+	}
+
+	text := expand(node.Text, contact, session.Variables)
+	if node.Type == NodeMenu {
+		var b strings.Builder
+		b.WriteString(text)
+		for i, o := range node.Options {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, o.Title)
+		}
+		text = b.String()
+	}
+
+	_, err := e.w.Send(ctx, models.WhatsappBody{
+		To:   fmt.Sprintf("whatsapp:%s", contact.Phone),
+		Body: text,
+	})
+	return err
+}
+
+// call performs the request of an http node and saves the selected fields
+// of its JSON response
+func (e *Engine) call(ctx context.Context, contact *contacts.Contact, session *Session, request *Request) error {
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+		if request.Body != "" {
+			method = http.MethodPost
+		}
+	}
+
+	var body io.Reader
+	if request.Body != "" {
+		body = strings.NewReader(expand(request.Body, contact, session.Variables))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, expand(request.URL, contact, session.Variables), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range request.Headers {
+		req.Header.Set(key, expand(value, contact, session.Variables))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("backend responded with status %d", resp.StatusCode)
+	}
+	if len(request.Save) == 0 {
+		return nil
+	}
+
+	var payload any
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("backend response is not JSON: %w", err)
+	}
+	for variable, path := range request.Save {
+		session.Variables[variable] = lookup(payload, path)
+	}
+	return nil
+}
+
+// lookup returns the value at a dotted path of a decoded JSON document as a
+// string, or "" if there is none
+func lookup(payload any, path string) string {
+	for _, key := range strings.Split(path, ".") {
+		switch v := payload.(type) {
+		case map[string]any:
+			payload = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return ""
+			}
+			payload = v[i]
+		default:
+			return ""
+		}
+	}
+
+	switch v := payload.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+var placeholder = regexp.MustCompile(`\{\{\s*([\w.]+)\s*\}\}`)
+
+// expand fills the {{variable}} placeholders of text
+func expand(text string, contact *contacts.Contact, variables map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		switch name {
+		case "contact.name":
+			return contact.Name
+		case "contact.phone":
+			return contact.Phone
+		}
+		return variables[name]
+	})
+}
+
+// summary describes what the customer answered, for the agent taking over
+func summary(flow *Flow, session *Session) string {
+	keys := make([]string, 0, len(session.Variables))
+	for key := range session.Variables {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Handed off by flow %q", flow.Name)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\n%s: %s", key, session.Variables[key])
+	}
+	return b.String()
+}
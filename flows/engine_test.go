@@ -0,0 +1,162 @@
+package flows_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mbx/flows"
+)
+
+func supportFlow(backendURL string) flows.Flow {
+	return flows.Flow{
+		Name:    "Support",
+		Trigger: flows.Trigger{Keywords: []string{"menu"}},
+		Start:   "welcome",
+		Nodes: map[string]flows.Node{
+			"welcome": {Type: flows.NodeMessage, Text: "Hi {{contact.name}}!", Next: "cpf"},
+			"cpf": {
+				Type:       flows.NodeQuestion,
+				Text:       "What is your CPF?",
+				Variable:   "cpf",
+				Validation: flows.ValidationCPF,
+				ErrorText:  "That CPF is not valid, try again",
+				Next:       "lookup",
+				OnError:    "agent",
+			},
+			"lookup": {
+				Type: flows.NodeHTTP,
+				Request: &flows.Request{
+					URL:  backendURL + "/customers/{{cpf}}",
+					Save: map[string]string{"plan": "customer.plan"},
+				},
+				Next:    "options",
+				OnError: "agent",
+			},
+			"options": {
+				Type:     flows.NodeMenu,
+				Text:     "How can we help with your {{plan}} plan?",
+				Variable: "topic",
+				Options: []flows.Option{
+					{Id: "invoice", Title: "Invoice", Next: "route"},
+					{Id: "other", Title: "Something else", Next: "route"},
+				},
+			},
+			"route": {
+				Type:     flows.NodeBranch,
+				Branches: []flows.Branch{{Variable: "topic", Equals: "invoice", Next: "invoice"}},
+				Next:     "agent",
+			},
+			"invoice": {Type: flows.NodeEnd, Text: "Your invoice was sent by email"},
+			"agent":   {Type: flows.NodeHandoff, Text: "An agent will answer you shortly"},
+		},
+	}
+}
+
+func newBackend(t *testing.T) *httptest.Server {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/customers/52998224725" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"customer": map[string]any{"plan": "gold"}})
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func say(t *testing.T, sim *flows.Simulator, text string) []string {
+	t.Helper()
+	replies, err := sim.Say(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Say(%q) failed: %v", text, err)
+	}
+	return replies
+}
+
+func TestEngine_MenuBranch(t *testing.T) {
+	flow := supportFlow(newBackend(t).URL)
+	if err := flow.Validate(); err != nil {
+		t.Fatalf("Expected valid flow, got %v", err)
+	}
+	sim := flows.NewSimulator(flow, flows.Config{AllowPrivateNetworks: true}, nil)
+
+	if replies := say(t, sim, "hello"); len(replies) != 0 {
+		t.Fatalf("Expected messages that do not trigger the flow to be ignored, got %q", replies)
+	}
+
+	replies := say(t, sim, "Menu")
+	if len(replies) != 2 || replies[0] != "Hi Simulated contact!" || replies[1] != "What is your CPF?" {
+		t.Fatalf("Unexpected start replies %q", replies)
+	}
+
+	replies = say(t, sim, "111.111.111-11")
+	if len(replies) != 1 || replies[0] != "That CPF is not valid, try again" {
+		t.Fatalf("Expected invalid CPF to be rejected, got %q", replies)
+	}
+
+	replies = say(t, sim, "529.982.247-25")
+	want := "How can we help with your gold plan?\n1. Invoice\n2. Something else"
+	if len(replies) != 1 || replies[0] != want {
+		t.Fatalf("Expected menu %q, got %q", want, replies)
+	}
+	if cpf := sim.Session().Variables["cpf"]; cpf != "52998224725" {
+		t.Errorf("Expected normalized CPF to be saved, got %q", cpf)
+	}
+
+	replies, err := sim.Tap(context.Background(), "invoice")
+	if err != nil {
+		t.Fatalf("Tap failed: %v", err)
+	}
+	if len(replies) != 1 || replies[0] != "Your invoice was sent by email" {
+		t.Fatalf("Unexpected end replies %q", replies)
+	}
+	if sim.Session() != nil {
+		t.Error("Expected session to end with the flow")
+	}
+}
+
+func TestEngine_HandoffAfterInvalidAnswers(t *testing.T) {
+	sim := flows.NewSimulator(supportFlow(newBackend(t).URL), flows.Config{MaxAttempts: 2, AllowPrivateNetworks: true}, nil)
+	if _, err := sim.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	say(t, sim, "not a cpf")
+	replies := say(t, sim, "still not a cpf")
+	if len(replies) != 1 || replies[0] != "An agent will answer you shortly" {
+		t.Fatalf("Expected handoff after too many invalid answers, got %q", replies)
+	}
+	if !strings.HasPrefix(sim.HandedOff(), `Handed off by flow "Support"`) {
+		t.Errorf("Unexpected handoff summary %q", sim.HandedOff())
+	}
+}
+
+func TestEngine_BackendFailure(t *testing.T) {
+	backend := newBackend(t)
+	sim := flows.NewSimulator(supportFlow(backend.URL), flows.Config{AllowPrivateNetworks: true}, nil)
+	sim.Start(context.Background())
+	backend.Close()
+
+	replies := say(t, sim, "52998224725")
+	if len(replies) != 1 || replies[0] != "An agent will answer you shortly" {
+		t.Fatalf("Expected handoff when the backend is down, got %q", replies)
+	}
+	if !strings.Contains(sim.HandedOff(), "cpf: 52998224725") {
+		t.Errorf("Expected answers in the handoff summary, got %q", sim.HandedOff())
+	}
+}
+
+func TestFlow_ValidateReferences(t *testing.T) {
+	flow := supportFlow("http://localhost")
+	node := flow.Nodes["welcome"]
+	node.Next = "missing"
+	flow.Nodes["welcome"] = node
+
+	if err := flow.Validate(); err == nil {
+		t.Error("Expected reference to a missing node to be rejected")
+	}
+}
@@ -0,0 +1,219 @@
+package flows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound    = errors.New("flow not found")
+	ErrInvalidFlow = errors.New("invalid flow")
+)
+
+type NodeType string
+
+const (
+	// NodeMessage sends a text or a template and moves on
+	NodeMessage NodeType = "message"
+	// NodeQuestion sends a prompt and waits for an answer, which is validated
+	// and saved in a variable
+	NodeQuestion NodeType = "question"
+	// NodeMenu sends a prompt with numbered options and waits for one to be
+	// picked, by number, title or button payload
+	NodeMenu NodeType = "menu"
+	// NodeBranch moves to the first branch whose variable has the value
+	NodeBranch NodeType = "branch"
+	// NodeHTTP calls a backend and saves fields of its JSON response
+	NodeHTTP NodeType = "http"
+	// NodeHandoff ends the flow and hands the conversation to an agent
+	NodeHandoff NodeType = "handoff"
+	// NodeEnd ends the flow
+	NodeEnd NodeType = "end"
+)
+
+// Trigger starts a flow when a contact without a session sends one of the
+// keywords, or taps a button with the payload
+type Trigger struct {
+	Keywords []string `json:"keywords,omitempty"`
+	Payload  string   `json:"payload,omitempty"`
+}
+
+type Option struct {
+	// Id is saved in the variable of the menu and matches button payloads
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	Next  string `json:"next"`
+}
+
+type Branch struct {
+	Variable string `json:"variable"`
+	Equals   string `json:"equals"`
+	Next     string `json:"next"`
+}
+
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// Save maps variables to dotted paths of the JSON response, e.g.
+	// {"status": "order.status"}
+	Save map[string]string `json:"save,omitempty"`
+}
+
+// Node is a step of a flow. Texts, template variables, URLs and bodies may
+// use {{variable}} placeholders, including {{contact.name}} and
+// {{contact.phone}}.
+type Node struct {
+	Type NodeType `json:"type"`
+	Text string   `json:"text,omitempty"`
+
+	// Template and Variables send a template group instead of Text
+	Template  string            `json:"template,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// Variable receives the answer of a question or menu
+	Variable string `json:"variable,omitempty"`
+	// Validation is one of: cpf, email, number, regex
+	Validation string `json:"validation,omitempty"`
+	Pattern    string `json:"pattern,omitempty"`
+	// ErrorText is sent when an answer is invalid
+	ErrorText string `json:"error_text,omitempty"`
+
+	Options  []Option `json:"options,omitempty"`
+	Branches []Branch `json:"branches,omitempty"`
+	Request  *Request `json:"request,omitempty"`
+
+	Next string `json:"next,omitempty"`
+	// OnError is where to go when an HTTP call fails or an answer is
+	// invalid too many times. The flow ends when it is empty.
+	OnError string `json:"on_error,omitempty"`
+}
+
+// Flow is a multi-step conversation run by the engine
+type Flow struct {
+	Id      uuid.UUID       `json:"id"`
+	Name    string          `json:"name"`
+	Enabled bool            `json:"enabled"`
+	Trigger Trigger         `json:"trigger"`
+	Start   string          `json:"start"`
+	Nodes   map[string]Node `json:"nodes"`
+	// TimeoutMinutes is how long a session waits for an answer before it is
+	// discarded
+	TimeoutMinutes int       `json:"timeout_minutes"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (f Flow) timeout() time.Duration {
+	if f.TimeoutMinutes <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(f.TimeoutMinutes) * time.Minute
+}
+
+// Validate reports the first problem with the flow, wrapping ErrInvalidFlow
+func (f Flow) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidFlow)
+	}
+	if _, ok := f.Nodes[f.Start]; !ok {
+		return fmt.Errorf("%w: start node %q does not exist", ErrInvalidFlow, f.Start)
+	}
+
+	for name, node := range f.Nodes {
+		if err := f.validateNode(node); err != nil {
+			return fmt.Errorf("%w: node %q: %s", ErrInvalidFlow, name, err)
+		}
+	}
+	return nil
+}
+
+func (f Flow) validateNode(node Node) error {
+	exists := func(name string) bool {
+		_, ok := f.Nodes[name]
+		return name == "" || ok
+	}
+	if !exists(node.Next) {
+		return fmt.Errorf("next node %q does not exist", node.Next)
+	}
+	if !exists(node.OnError) {
+		return fmt.Errorf("error node %q does not exist", node.OnError)
+	}
+
+	switch node.Type {
+	case NodeMessage:
+		if node.Text == "" && node.Template == "" {
+			return errors.New("message requires text or a template")
+		}
+	case NodeQuestion:
+		if node.Text == "" || node.Variable == "" {
+			return errors.New("question requires text and a variable")
+		}
+		switch node.Validation {
+		case "", ValidationCPF, ValidationEmail, ValidationNumber:
+		case ValidationRegex:
+			if _, err := regexp.Compile(node.Pattern); err != nil {
+				return errors.New("invalid validation pattern")
+			}
+		default:
+			return fmt.Errorf("unknown validation %q", node.Validation)
+		}
+	case NodeMenu:
+		if node.Text == "" || len(node.Options) == 0 {
+			return errors.New("menu requires text and options")
+		}
+		for _, o := range node.Options {
+			if o.Id == "" || o.Title == "" || o.Next == "" || !exists(o.Next) {
+				return fmt.Errorf("option %q requires an id, a title and an existing next node", o.Id)
+			}
+		}
+	case NodeBranch:
+		for _, b := range node.Branches {
+			if b.Variable == "" || b.Next == "" || !exists(b.Next) {
+				return errors.New("branches require a variable and an existing next node")
+			}
+		}
+	case NodeHTTP:
+		if node.Request == nil || node.Request.URL == "" {
+			return errors.New("http requires a request URL")
+		}
+	case NodeHandoff, NodeEnd:
+	default:
+		return fmt.Errorf("unknown node type %q", node.Type)
+	}
+	return nil
+}
+
+// Session is where a contact is in a flow
+type Session struct {
+	ContactId uuid.UUID         `json:"contact_id"`
+	FlowId    uuid.UUID         `json:"flow_id"`
+	Node      string            `json:"node"`
+	Variables map[string]string `json:"variables"`
+	// Attempts counts invalid answers to the current node
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Repository interface {
+	Create(context.Context, Flow) error
+	Update(context.Context, Flow) error
+	Delete(context.Context, uuid.UUID) error
+	FindById(context.Context, uuid.UUID) (*Flow, error)
+	List(context.Context) ([]Flow, error)
+}
+
+type SessionRepository interface {
+	// Find returns the session of the contact, or nil if it has none
+	Find(ctx context.Context, contactId uuid.UUID) (*Session, error)
+	Save(context.Context, Session) error
+	Delete(ctx context.Context, contactId uuid.UUID) error
+}
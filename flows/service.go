@@ -0,0 +1,55 @@
+package flows
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(ctx context.Context, flow Flow) (*Flow, error) {
+	if err := flow.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	flow.Id = uuid.New()
+	flow.CreatedAt = now
+	flow.UpdatedAt = now
+	if err := s.repo.Create(ctx, flow); err != nil {
+		return nil, err
+	}
+	return &flow, nil
+}
+
+func (s *Service) Update(ctx context.Context, flow Flow) (*Flow, error) {
+	if err := flow.Validate(); err != nil {
+		return nil, err
+	}
+
+	flow.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, flow); err != nil {
+		return nil, err
+	}
+	return &flow, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *Service) FindById(ctx context.Context, id uuid.UUID) (*Flow, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]Flow, error) {
+	return s.repo.List(ctx)
+}
@@ -0,0 +1,198 @@
+package flows
+
+import (
+	"context"
+	"fmt"
+	"mbx/contacts"
+	"mbx/history"
+	"mbx/inbound"
+	"mbx/models"
+	"mbx/templates"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// Simulator runs a flow for a fake contact with sessions kept in memory,
+// collecting the messages the engine sends instead of calling the provider.
+// HTTP nodes still call their backends.
+type Simulator struct {
+	engine  *Engine
+	flow    *Flow
+	contact *contacts.Contact
+	out     *transcript
+	store   *memorySessions
+	handoff *recordedHandoff
+}
+
+// NewSimulator prepares a simulation of the flow. A nil resolver uses
+// template group names as content SIDs.
+func NewSimulator(flow Flow, config Config, resolver templates.GroupResolver) *Simulator {
+	if flow.Id == uuid.Nil {
+		flow.Id = uuid.New()
+	}
+	flow.Enabled = true
+	if resolver == nil {
+		resolver = nameResolver{}
+	}
+
+	s := &Simulator{
+		flow:    &flow,
+		contact: &contacts.Contact{Id: uuid.New(), Phone: "+10000000000", Name: "Simulated contact"},
+		out:     &transcript{},
+		store:   &memorySessions{sessions: map[uuid.UUID]Session{}},
+		handoff: &recordedHandoff{},
+	}
+	s.engine = NewEngine(singleFlow{flow: s.flow}, s.store, config, s.out, s.out, resolver, s.handoff)
+	return s
+}
+
+// Start runs the flow from its start node regardless of its trigger
+func (s *Simulator) Start(ctx context.Context) ([]string, error) {
+	return s.out.collect(func() error {
+		return s.engine.Start(ctx, s.contact, s.flow)
+	})
+}
+
+// Say sends a typed message from the contact
+func (s *Simulator) Say(ctx context.Context, text string) ([]string, error) {
+	return s.receive(ctx, inbound.Message{Body: text})
+}
+
+// Tap sends a quick reply button press from the contact
+func (s *Simulator) Tap(ctx context.Context, payload string) ([]string, error) {
+	return s.receive(ctx, inbound.Message{
+		Interaction: &history.Interaction{Type: history.InteractionButton, Payload: payload},
+	})
+}
+
+func (s *Simulator) receive(ctx context.Context, msg inbound.Message) ([]string, error) {
+	msg.Sid = "SM" + uuid.NewString()
+	msg.From = s.contact.Phone
+	msg.Contact = s.contact
+	msg.ReceivedAt = time.Now()
+	return s.out.collect(func() error {
+		return s.engine.HandleInbound(ctx, &msg)
+	})
+}
+
+// Session returns the current session of the contact, nil once the flow ended
+func (s *Simulator) Session() *Session {
+	session, _ := s.store.Find(context.Background(), s.contact.Id)
+	return session
+}
+
+// HandedOff returns the summary passed to the agents, or "" if the flow did
+// not hand off
+func (s *Simulator) HandedOff() string {
+	return s.handoff.summary
+}
+
+// transcript records outgoing messages as text
+type transcript struct {
+	mu      sync.Mutex
+	replies []string
+}
+
+func (t *transcript) collect(run func() error) ([]string, error) {
+	t.mu.Lock()
+	t.replies = nil
+	t.mu.Unlock()
+
+	err := run()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.replies, err
+}
+
+func (t *transcript) add(reply string) *api.ApiV2010Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.replies = append(t.replies, reply)
+	sid := "SM" + uuid.NewString()
+	return &api.ApiV2010Message{Sid: &sid}
+}
+
+func (t *transcript) Send(_ context.Context, msg models.WhatsappBody) (*api.ApiV2010Message, error) {
+	return t.add(msg.Body), nil
+}
+
+func (t *transcript) CancelMessage(context.Context, string) error { return nil }
+
+func (t *transcript) SendTemplate(_ context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	return t.add(fmt.Sprintf("[template %s %s]", template.TemplateId, template.Content)), nil
+}
+
+func (t *transcript) CreateTemplate(context.Context, templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return nil, fmt.Errorf("templates cannot be created in a simulation")
+}
+
+type nameResolver struct{}
+
+func (nameResolver) Resolve(_ context.Context, name, locale string) (*templates.ResolvedTemplate, error) {
+	return &templates.ResolvedTemplate{Name: name, Language: locale, ContentSid: name}, nil
+}
+
+type recordedHandoff struct {
+	summary string
+}
+
+func (h *recordedHandoff) Handoff(_ context.Context, _ *contacts.Contact, summary string) error {
+	h.summary = summary
+	return nil
+}
+
+// singleFlow serves the simulated flow to the engine
+type singleFlow struct {
+	Repository
+	flow *Flow
+}
+
+func (r singleFlow) FindById(_ context.Context, id uuid.UUID) (*Flow, error) {
+	if id != r.flow.Id {
+		return nil, nil
+	}
+	flow := *r.flow
+	return &flow, nil
+}
+
+func (r singleFlow) List(context.Context) ([]Flow, error) {
+	return []Flow{*r.flow}, nil
+}
+
+type memorySessions struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]Session
+}
+
+func (m *memorySessions) Find(_ context.Context, contactId uuid.UUID) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[contactId]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (m *memorySessions) Save(_ context.Context, session Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	variables := make(map[string]string, len(session.Variables))
+	for key, value := range session.Variables {
+		variables[key] = value
+	}
+	session.Variables = variables
+	m.sessions[session.ContactId] = session
+	return nil
+}
+
+func (m *memorySessions) Delete(_ context.Context, contactId uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, contactId)
+	return nil
+}
@@ -0,0 +1,74 @@
+package flows
+
+import (
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ValidationCPF    = "cpf"
+	ValidationEmail  = "email"
+	ValidationNumber = "number"
+	ValidationRegex  = "regex"
+)
+
+// validate checks an answer to a question and returns the value to save,
+// e.g. a CPF without punctuation
+func validate(node Node, answer string) (string, bool) {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return "", false
+	}
+
+	switch node.Validation {
+	case ValidationCPF:
+		digits := onlyDigits(answer)
+		return digits, validCPF(digits)
+	case ValidationEmail:
+		address, err := mail.ParseAddress(answer)
+		if err != nil {
+			return "", false
+		}
+		return strings.ToLower(address.Address), true
+	case ValidationNumber:
+		normalized := strings.Replace(answer, ",", ".", 1)
+		_, err := strconv.ParseFloat(normalized, 64)
+		return normalized, err == nil
+	case ValidationRegex:
+		re, err := regexp.Compile(node.Pattern)
+		return answer, err == nil && re.MatchString(answer)
+	}
+	return answer, true
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// validCPF checks the two check digits of a Brazilian CPF
+func validCPF(cpf string) bool {
+	if len(cpf) != 11 || strings.Count(cpf, cpf[:1]) == 11 {
+		return false
+	}
+
+	digit := func(n int) byte {
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += int(cpf[i]-'0') * (n + 1 - i)
+		}
+		rest := sum * 10 % 11
+		if rest == 10 {
+			rest = 0
+		}
+		return byte(rest) + '0'
+	}
+	return cpf[9] == digit(9) && cpf[10] == digit(10)
+}
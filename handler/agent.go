@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/agents"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type AgentHandler struct {
+	agents *agents.Service
+}
+
+func NewAgentHandler(agentService *agents.Service) *AgentHandler {
+	return &AgentHandler{
+		agents: agentService,
+	}
+}
+
+// AgentRequest represents the payload for creating or updating an agent. On
+// update, only the fields that are present are changed.
+type AgentRequest struct {
+	Name       *string `json:"name,omitempty"`
+	Email      *string `json:"email,omitempty"`
+	Active     *bool   `json:"active,omitempty"`
+	AutoAssign *bool   `json:"auto_assign,omitempty"`
+}
+
+func (req AgentRequest) apply(agent *agents.Agent) {
+	if req.Name != nil {
+		agent.Name = *req.Name
+	}
+	if req.Email != nil {
+		agent.Email = *req.Email
+	}
+	if req.Active != nil {
+		agent.Active = *req.Active
+	}
+	if req.AutoAssign != nil {
+		agent.AutoAssign = *req.AutoAssign
+	}
+}
+
+// actorFromRequest returns the agent named by the X-Agent-Id header, or nil
+// when the header is absent. It writes the error response and returns false
+// when the header does not name a known agent.
+func actorFromRequest(w http.ResponseWriter, r *http.Request, agentService *agents.Service) (*uuid.UUID, bool) {
+	header := r.Header.Get("X-Agent-Id")
+	if header == "" {
+		return nil, true
+	}
+
+	id, err := uuid.Parse(header)
+	if err != nil {
+		writeError(w, "Invalid X-Agent-Id header", http.StatusBadRequest)
+		return nil, false
+	}
+	agent, err := agentService.FindById(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch agent", "error", err, "id", id)
+		writeError(w, "Failed to fetch agent", http.StatusInternalServerError)
+		return nil, false
+	}
+	if agent == nil {
+		writeError(w, "Unknown agent in X-Agent-Id header", http.StatusBadRequest)
+		return nil, false
+	}
+	return &agent.Id, true
+}
+
+// writeAgentError writes the response for an error from the agents service
+func writeAgentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, agents.ErrInvalidAgent):
+		writeError(w, "Agent requires a name and a valid email", http.StatusBadRequest)
+	case errors.Is(err, agents.ErrDuplicate):
+		writeError(w, "Agent with this email already exists", http.StatusConflict)
+	case errors.Is(err, agents.ErrNotFound):
+		writeError(w, "Agent not found", http.StatusNotFound)
+	default:
+		slog.Error("Agent operation failed", "error", err)
+		writeError(w, "Failed to save agent", http.StatusInternalServerError)
+	}
+}
+
+// ListAgents handles GET /agents
+func (h *AgentHandler) ListAgents(w http.ResponseWriter, r *http.Request) {
+	list, err := h.agents.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list agents", "error", err)
+		writeError(w, "Failed to list agents", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []agents.Agent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateAgent handles POST /agents
+func (h *AgentHandler) CreateAgent(w http.ResponseWriter, r *http.Request) {
+	var req AgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	agent := agents.Agent{Active: true}
+	req.apply(&agent)
+
+	created, err := h.agents.Create(r.Context(), agent)
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UpdateAgent handles PATCH /agents/{id}
+func (h *AgentHandler) UpdateAgent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid agent ID format", http.StatusBadRequest)
+		return
+	}
+
+	agent, err := h.agents.FindById(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch agent", "error", err, "id", id)
+		writeError(w, "Failed to fetch agent", http.StatusInternalServerError)
+		return
+	}
+	if agent == nil {
+		writeError(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+
+	var req AgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.apply(agent)
+
+	updated, err := h.agents.Update(r.Context(), *agent)
+	if err != nil {
+		writeAgentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/apikeys"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	keys *apikeys.Service
+}
+
+func NewAPIKeyHandler(keyService *apikeys.Service) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys: keyService,
+	}
+}
+
+// CreateAPIKeyRequest represents the payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name   string          `json:"name"`
+	Scopes []apikeys.Scope `json:"scopes"`
+}
+
+// CreateAPIKeyResponse is a new key with its token. The token is only
+// returned here.
+type CreateAPIKeyResponse struct {
+	apikeys.Key
+	Token string `json:"token"`
+}
+
+// ListAPIKeys handles GET /api-keys
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list API keys", "error", err)
+		writeError(w, "Failed to list API keys", http.StatusInternalServerError)
+		return
+	}
+	if keys == nil {
+		keys = []apikeys.Key{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKey handles POST /api-keys
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	key, token, err := h.keys.Create(r.Context(), req.Name, req.Scopes)
+	switch {
+	case errors.Is(err, apikeys.ErrInvalidName), errors.Is(err, apikeys.ErrInvalidScope):
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		slog.Error("Failed to create API key", "error", err)
+		writeError(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPIKeyResponse{Key: *key, Token: token})
+}
+
+// RevokeAPIKey handles DELETE /api-keys/{id}. Revoked keys stay listed.
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid API key ID format", http.StatusBadRequest)
+		return
+	}
+
+	err = h.keys.Revoke(r.Context(), id)
+	switch {
+	case errors.Is(err, apikeys.ErrNotFound):
+		writeError(w, "API key not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("Failed to revoke API key", "error", err, "id", id)
+		writeError(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
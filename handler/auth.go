@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"mbx/apikeys"
+	"mbx/tenants"
+	"net/http"
+	"strings"
+)
+
+// Authenticator checks the API key of requests against the scope of the
+// route they are for
+type Authenticator struct {
+	keys *apikeys.Service
+}
+
+func NewAuthenticator(keyService *apikeys.Service) *Authenticator {
+	return &Authenticator{
+		keys: keyService,
+	}
+}
+
+// Require only lets requests through to next when they carry an active API
+// key that grants scope. The request context carries the key on, scoped to
+// the tenant of the key.
+func (a *Authenticator) Require(scope apikeys.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mbx"`)
+			writeError(w, "Missing API key", http.StatusUnauthorized)
+			return
+		}
+
+		key, err := a.keys.Authenticate(r.Context(), token)
+		if errors.Is(err, apikeys.ErrInvalidKey) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mbx", error="invalid_token"`)
+			writeError(w, "Invalid or revoked API key", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to authenticate API key", "error", err)
+			writeError(w, "Failed to authenticate API key", http.StatusInternalServerError)
+			return
+		}
+		if !key.Allows(scope) {
+			writeError(w, "API key lacks the '"+string(scope)+"' scope", http.StatusForbidden)
+			return
+		}
+
+		ctx := tenants.NewContext(apikeys.NewContext(r.Context(), key), key.TenantId)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// bearerToken returns the token of the Authorization header. Browsers cannot
+// set headers on event streams, so those may pass it as access_token instead.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mbx/apikeys"
+	"mbx/apikeys/mocks"
+	"mbx/tenants"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+func TestAuthenticator_Require(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	keyService := apikeys.NewService(repo)
+
+	var keys []apikeys.Key
+	sender, senderToken, err := keyService.Create(t.Context(), "sender", []apikeys.Scope{apikeys.ScopeSend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	brand := uuid.New()
+	admin, adminToken, err := keyService.Create(tenants.NewContext(t.Context(), brand), "admin", []apikeys.Scope{apikeys.ScopeAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys = append(keys, *sender, *admin)
+	repo.EXPECT().FindByHash(gomock.Any(), gomock.Any()).DoAndReturn(func(_ any, hash string) (*apikeys.Key, error) {
+		for _, k := range keys {
+			if k.Hash == hash {
+				return &k, nil
+			}
+		}
+		return nil, nil
+	}).AnyTimes()
+	repo.EXPECT().Touch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	var authenticated *apikeys.Key
+	var tenant uuid.UUID
+	next := func(w http.ResponseWriter, r *http.Request) {
+		authenticated = apikeys.FromContext(r.Context())
+		tenant = tenants.FromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}
+	handler := NewAuthenticator(keyService).Require(apikeys.ScopeSend, next)
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		key    uuid.UUID
+		tenant uuid.UUID
+	}{
+		{"missing", "", http.StatusUnauthorized, uuid.Nil, uuid.Nil},
+		{"unknown", "Bearer mbx_unknown", http.StatusUnauthorized, uuid.Nil, uuid.Nil},
+		{"not bearer", "Basic " + senderToken, http.StatusUnauthorized, uuid.Nil, uuid.Nil},
+		{"scoped", "Bearer " + senderToken, http.StatusNoContent, sender.Id, tenants.DefaultId},
+		{"admin", "bearer " + adminToken, http.StatusNoContent, admin.Id, brand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticated = nil
+			req := httptest.NewRequest(http.MethodPost, "/send-message", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.key != uuid.Nil && (authenticated == nil || authenticated.Id != tt.key) {
+				t.Errorf("Expected key %s in the request context, got %+v", tt.key, authenticated)
+			}
+			if tt.tenant != uuid.Nil && tenant != tt.tenant {
+				t.Errorf("Expected the request scoped to tenant %s, got %s", tt.tenant, tenant)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api-keys", nil)
+	req.Header.Set("Authorization", "Bearer "+senderToken)
+	rec := httptest.NewRecorder()
+	NewAuthenticator(keyService).Require(apikeys.ScopeAdmin, next)(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a key without the scope, got %d", rec.Code)
+	}
+}
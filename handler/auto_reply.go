@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/autoreply"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type AutoReplyHandler struct {
+	rules *autoreply.Service
+}
+
+func NewAutoReplyHandler(rules *autoreply.Service) *AutoReplyHandler {
+	return &AutoReplyHandler{
+		rules: rules,
+	}
+}
+
+// AutoReplyRequest represents the payload for creating or replacing a rule.
+// Rules are enabled unless enabled is false.
+type AutoReplyRequest struct {
+	Name       string               `json:"name"`
+	Enabled    *bool                `json:"enabled,omitempty"`
+	Priority   int                  `json:"priority"`
+	Match      autoreply.Match      `json:"match"`
+	Conditions autoreply.Conditions `json:"conditions"`
+	Actions    []autoreply.Action   `json:"actions"`
+}
+
+func (req AutoReplyRequest) apply(rule *autoreply.Rule) {
+	rule.Name = req.Name
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.Priority = req.Priority
+	rule.Match = req.Match
+	rule.Conditions = req.Conditions
+	rule.Actions = req.Actions
+}
+
+// writeAutoReplyError writes the response for an error from the auto-reply
+// service
+func writeAutoReplyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, autoreply.ErrInvalidRule):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, autoreply.ErrNotFound):
+		writeError(w, "Auto-reply rule not found", http.StatusNotFound)
+	default:
+		slog.Error("Auto-reply rule operation failed", "error", err)
+		writeError(w, "Failed to save auto-reply rule", http.StatusInternalServerError)
+	}
+}
+
+// ListRules handles GET /auto-replies
+func (h *AutoReplyHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.rules.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list auto-reply rules", "error", err)
+		writeError(w, "Failed to list auto-reply rules", http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []autoreply.Rule{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// CreateRule handles POST /auto-replies
+func (h *AutoReplyHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req AutoReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	var rule autoreply.Rule
+	req.apply(&rule)
+
+	created, err := h.rules.Create(r.Context(), rule)
+	if err != nil {
+		writeAutoReplyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetRule handles GET /auto-replies/{id}
+func (h *AutoReplyHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	rule := h.ruleFromPath(w, r)
+	if rule == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateRule handles PUT /auto-replies/{id}
+func (h *AutoReplyHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	rule := h.ruleFromPath(w, r)
+	if rule == nil {
+		return
+	}
+
+	var req AutoReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.apply(rule)
+
+	updated, err := h.rules.Update(r.Context(), *rule)
+	if err != nil {
+		writeAutoReplyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteRule handles DELETE /auto-replies/{id}
+func (h *AutoReplyHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid rule ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.rules.Delete(r.Context(), id); err != nil {
+		writeAutoReplyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AutoReplyHandler) ruleFromPath(w http.ResponseWriter, r *http.Request) *autoreply.Rule {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid rule ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	rule, err := h.rules.FindById(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch auto-reply rule", "error", err, "id", id)
+		writeError(w, "Failed to fetch auto-reply rule", http.StatusInternalServerError)
+		return nil
+	}
+	if rule == nil {
+		writeError(w, "Auto-reply rule not found", http.StatusNotFound)
+		return nil
+	}
+	return rule
+}
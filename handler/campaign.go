@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/campaigns"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CampaignHandler struct {
+	campaigns *campaigns.Service
+}
+
+func NewCampaignHandler(campaignService *campaigns.Service) *CampaignHandler {
+	return &CampaignHandler{
+		campaigns: campaignService,
+	}
+}
+
+// CampaignRecipientRequest is a recipient of a new campaign, addressed by
+// phone or contact ID
+type CampaignRecipientRequest struct {
+	Phone     string            `json:"phone,omitempty"`
+	ContactId *uuid.UUID        `json:"contact_id,omitempty"`
+	Locale    string            `json:"locale,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// CreateCampaignRequest represents the payload for creating a campaign
+type CreateCampaignRequest struct {
+	Name          string                     `json:"name"`
+	TemplateId    string                     `json:"template,omitempty"`
+	TemplateName  string                     `json:"template_name,omitempty"`
+	Locale        string                     `json:"locale,omitempty"`
+	Variables     map[string]string          `json:"variables,omitempty"`
+	StartAt       time.Time                  `json:"start_at,omitempty"`
+	RatePerMinute int                        `json:"rate_per_minute,omitempty"`
+	Recipients    []CampaignRecipientRequest `json:"recipients"`
+	// Draft campaigns wait for more recipients, such as from an import, and
+	// for POST /campaigns/{id}/start
+	Draft bool `json:"draft,omitempty"`
+}
+
+// CampaignResponse is a campaign with the progress of its recipients
+type CampaignResponse struct {
+	campaigns.Campaign
+	Progress *campaigns.Progress `json:"progress,omitempty"`
+}
+
+// writeCampaignError writes the response for an error from the campaigns
+// service
+func writeCampaignError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, campaigns.ErrInvalidCampaign):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, campaigns.ErrInvalidTransition):
+		writeError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, campaigns.ErrNotFound):
+		writeError(w, "Campaign not found", http.StatusNotFound)
+	default:
+		slog.Error("Campaign operation failed", "error", err)
+		writeError(w, "Failed to save campaign", http.StatusInternalServerError)
+	}
+}
+
+// ListCampaigns handles GET /campaigns
+func (h *CampaignHandler) ListCampaigns(w http.ResponseWriter, r *http.Request) {
+	list, err := h.campaigns.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list campaigns", "error", err)
+		writeError(w, "Failed to list campaigns", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []campaigns.Campaign{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateCampaign handles POST /campaigns
+func (h *CampaignHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var req CreateCampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	campaign := campaigns.Campaign{
+		Name:          req.Name,
+		TemplateId:    req.TemplateId,
+		TemplateName:  req.TemplateName,
+		Locale:        req.Locale,
+		Variables:     req.Variables,
+		StartAt:       req.StartAt,
+		RatePerMinute: req.RatePerMinute,
+	}
+	if req.Draft {
+		campaign.Status = campaigns.StatusDraft
+	}
+	recipients := make([]campaigns.Recipient, 0, len(req.Recipients))
+	for _, rr := range req.Recipients {
+		recipients = append(recipients, campaigns.Recipient{
+			Phone:     rr.Phone,
+			ContactId: rr.ContactId,
+			Locale:    rr.Locale,
+			Variables: rr.Variables,
+		})
+	}
+
+	created, err := h.campaigns.Create(r.Context(), campaign, recipients)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CampaignResponse{
+		Campaign: *created,
+		Progress: &campaigns.Progress{Total: len(recipients), Queued: len(recipients)},
+	})
+}
+
+// GetCampaign handles GET /campaigns/{id}
+func (h *CampaignHandler) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	campaign := h.campaignFromPath(w, r)
+	if campaign == nil {
+		return
+	}
+
+	progress, err := h.campaigns.Progress(r.Context(), campaign.Id)
+	if err != nil {
+		slog.Error("Failed to compute campaign progress", "error", err, "id", campaign.Id)
+		writeError(w, "Failed to fetch campaign", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CampaignResponse{Campaign: *campaign, Progress: progress})
+}
+
+// GetRecipients handles GET /campaigns/{id}/recipients?status=failed&limit=100&offset=0
+func (h *CampaignHandler) GetRecipients(w http.ResponseWriter, r *http.Request) {
+	campaign := h.campaignFromPath(w, r)
+	if campaign == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	limit, ok := limitFromQuery(w, r, 100)
+	if !ok {
+		return
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		var err error
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			writeError(w, "Invalid 'offset' value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	recipients, err := h.campaigns.Recipients(r.Context(), campaign.Id, campaigns.RecipientStatus(query.Get("status")), limit, offset)
+	if err != nil {
+		slog.Error("Failed to list campaign recipients", "error", err, "id", campaign.Id)
+		writeError(w, "Failed to list campaign recipients", http.StatusInternalServerError)
+		return
+	}
+	if recipients == nil {
+		recipients = []campaigns.Recipient{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recipients)
+}
+
+// StartCampaign handles POST /campaigns/{id}/start
+func (h *CampaignHandler) StartCampaign(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.campaigns.Start)
+}
+
+// PauseCampaign handles POST /campaigns/{id}/pause
+func (h *CampaignHandler) PauseCampaign(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.campaigns.Pause)
+}
+
+// ResumeCampaign handles POST /campaigns/{id}/resume
+func (h *CampaignHandler) ResumeCampaign(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.campaigns.Resume)
+}
+
+// CancelCampaign handles POST /campaigns/{id}/cancel
+func (h *CampaignHandler) CancelCampaign(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.campaigns.Cancel)
+}
+
+func (h *CampaignHandler) transition(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, id uuid.UUID) (*campaigns.Campaign, error)) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid campaign ID format", http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := change(r.Context(), id)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaign)
+}
+
+func (h *CampaignHandler) campaignFromPath(w http.ResponseWriter, r *http.Request) *campaigns.Campaign {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid campaign ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	campaign, err := h.campaigns.FindById(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch campaign", "error", err, "id", id)
+		writeError(w, "Failed to fetch campaign", http.StatusInternalServerError)
+		return nil
+	}
+	if campaign == nil {
+		writeError(w, "Campaign not found", http.StatusNotFound)
+		return nil
+	}
+	return campaign
+}
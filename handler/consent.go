@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/consent"
+	"mbx/contacts"
+	"net/http"
+	"time"
+)
+
+type ConsentHandler struct {
+	consent  *consent.Service
+	contacts *contacts.Service
+}
+
+func NewConsentHandler(consentService *consent.Service, contactService *contacts.Service) *ConsentHandler {
+	return &ConsentHandler{
+		consent:  consentService,
+		contacts: contactService,
+	}
+}
+
+// RecordConsentRequest represents the payload for recording a consent change
+type RecordConsentRequest struct {
+	Channel    consent.Channel `json:"channel"`
+	Purpose    consent.Purpose `json:"purpose"`
+	Action     consent.Action  `json:"action"` // "granted" or "revoked"
+	Source     consent.Source  `json:"source"`
+	Evidence   string          `json:"evidence,omitempty"`
+	RecordedAt *time.Time      `json:"recorded_at,omitempty"`
+}
+
+// ConsentResponse is the current consent of a contact along with its audit trail
+type ConsentResponse struct {
+	Current []consent.Record `json:"current"`
+	History []consent.Record `json:"history"`
+}
+
+// RecordConsent handles POST /contacts/{id}/consents
+func (h *ConsentHandler) RecordConsent(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	var req RecordConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	record := consent.Record{
+		ContactId: contact.Id,
+		Channel:   req.Channel,
+		Purpose:   req.Purpose,
+		Action:    req.Action,
+		Source:    req.Source,
+		Evidence:  req.Evidence,
+	}
+	if req.RecordedAt != nil {
+		if req.RecordedAt.After(time.Now()) {
+			writeError(w, "Recorded time cannot be in the future", http.StatusBadRequest)
+			return
+		}
+		record.RecordedAt = *req.RecordedAt
+	}
+
+	created, err := h.consent.Record(r.Context(), record)
+	if errors.Is(err, consent.ErrInvalidRecord) {
+		writeError(w, "Invalid channel, purpose, action or source", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to record consent", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to record consent", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetConsents handles GET /contacts/{id}/consents
+func (h *ConsentHandler) GetConsents(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	history, err := h.consent.History(r.Context(), contact.Id)
+	if err != nil {
+		slog.Error("Failed to retrieve consents", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to retrieve consents", http.StatusInternalServerError)
+		return
+	}
+	current, err := h.consent.Current(r.Context(), contact.Id)
+	if err != nil {
+		slog.Error("Failed to retrieve consents", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to retrieve consents", http.StatusInternalServerError)
+		return
+	}
+	if history == nil {
+		history = []consent.Record{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConsentResponse{Current: current, History: history})
+}
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/contacts"
+	"mbx/history"
+	"mbx/schedules"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+type ContactHandler struct {
+	contacts        *contacts.Service
+	history         history.Repository
+	scheduleService *schedules.Service
+}
+
+func NewContactHandler(contactService *contacts.Service, historyRepo history.Repository, scheduleService *schedules.Service) *ContactHandler {
+	return &ContactHandler{
+		contacts:        contactService,
+		history:         historyRepo,
+		scheduleService: scheduleService,
+	}
+}
+
+// ContactRequest represents the payload for creating or updating a contact.
+// On update, only the fields that are present are changed.
+type ContactRequest struct {
+	Phone      *string            `json:"phone,omitempty"`
+	Name       *string            `json:"name,omitempty"`
+	Locale     *string            `json:"locale,omitempty"`
+	Timezone   *string            `json:"timezone,omitempty"`
+	Tags       *[]string          `json:"tags,omitempty"`
+	Attributes *map[string]string `json:"attributes,omitempty"`
+}
+
+func (req ContactRequest) apply(contact *contacts.Contact) {
+	if req.Phone != nil {
+		contact.Phone = *req.Phone
+	}
+	if req.Name != nil {
+		contact.Name = *req.Name
+	}
+	if req.Locale != nil {
+		contact.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		contact.Timezone = *req.Timezone
+	}
+	if req.Tags != nil {
+		contact.Tags = *req.Tags
+	}
+	if req.Attributes != nil {
+		contact.Attributes = *req.Attributes
+	}
+}
+
+// resolveRecipient returns the contact a message is addressed to, either by
+// its ID or by a phone number that is normalized and linked to a contact.
+func resolveRecipient(ctx context.Context, resolver contacts.Resolver, to string, contactId *uuid.UUID) (*contacts.Contact, error) {
+	if contactId == nil {
+		return resolver.Resolve(ctx, to)
+	}
+
+	contact, err := resolver.FindById(ctx, *contactId)
+	if err != nil {
+		return nil, err
+	}
+	if contact == nil {
+		return nil, contacts.ErrNotFound
+	}
+	return contact, nil
+}
+
+// writeRecipientError writes the response for an error from resolveRecipient
+func writeRecipientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, contacts.ErrInvalidPhone):
+		writeAPIError(w, http.StatusBadRequest, APIError{Code: CodeInvalidPhoneNumber, Message: "Invalid recipient number"})
+	case errors.Is(err, contacts.ErrNotFound):
+		writeError(w, "Contact not found", http.StatusNotFound)
+	default:
+		slog.Error("Failed to resolve recipient", "error", err)
+		writeError(w, "Failed to resolve recipient", http.StatusInternalServerError)
+	}
+}
+
+// writeContactError writes the response for an error from the contacts service
+func writeContactError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, contacts.ErrInvalidPhone):
+		writeAPIError(w, http.StatusBadRequest, APIError{Code: CodeInvalidPhoneNumber, Message: "Invalid phone number"})
+	case errors.Is(err, contacts.ErrInvalidTimezone):
+		writeError(w, "Invalid timezone", http.StatusBadRequest)
+	case errors.Is(err, contacts.ErrDuplicate):
+		writeError(w, "Contact with this phone number already exists", http.StatusConflict)
+	case errors.Is(err, contacts.ErrNotFound):
+		writeError(w, "Contact not found", http.StatusNotFound)
+	default:
+		slog.Error("Contact operation failed", "error", err)
+		writeError(w, "Failed to save contact", http.StatusInternalServerError)
+	}
+}
+
+// contactFromPath loads the contact of the {id} path value, writing the error
+// response and returning nil when it cannot be found.
+func contactFromPath(w http.ResponseWriter, r *http.Request, resolver contacts.Resolver) *contacts.Contact {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid contact ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	contact, err := resolver.FindById(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch contact", "error", err, "id", id)
+		writeError(w, "Failed to fetch contact", http.StatusInternalServerError)
+		return nil
+	}
+	if contact == nil {
+		writeError(w, "Contact not found", http.StatusNotFound)
+		return nil
+	}
+	return contact
+}
+
+// CreateContact handles POST /contacts
+func (h *ContactHandler) CreateContact(w http.ResponseWriter, r *http.Request) {
+	var req ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Phone == nil || *req.Phone == "" {
+		writeError(w, "Phone number cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	var contact contacts.Contact
+	req.apply(&contact)
+
+	created, err := h.contacts.Create(r.Context(), contact)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ListContacts handles GET /contacts?tag=vip&q=maria&limit=50&offset=0
+func (h *ContactHandler) ListContacts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := contacts.ListFilter{
+		Tag:   query.Get("tag"),
+		Query: query.Get("q"),
+	}
+
+	var err error
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			writeError(w, "Invalid 'limit' value", http.StatusBadRequest)
+			return
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		if filter.Offset, err = strconv.Atoi(offset); err != nil || filter.Offset < 0 {
+			writeError(w, "Invalid 'offset' value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	list, err := h.contacts.List(r.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to list contacts", "error", err)
+		writeError(w, "Failed to list contacts", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []contacts.Contact{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetContact handles GET /contacts/{id}
+func (h *ContactHandler) GetContact(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contact)
+}
+
+// UpdateContact handles PATCH /contacts/{id}
+func (h *ContactHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	var req ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.apply(contact)
+
+	updated, err := h.contacts.Update(r.Context(), *contact)
+	if err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteContact handles DELETE /contacts/{id}
+func (h *ContactHandler) DeleteContact(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid contact ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.contacts.Delete(r.Context(), id); err != nil {
+		writeContactError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetContactMessages handles GET /contacts/{id}/messages
+func (h *ContactHandler) GetContactMessages(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	messages, err := h.history.ListByContact(r.Context(), contact.Id, 200)
+	if err != nil {
+		slog.Error("Failed to retrieve contact messages", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to retrieve contact messages", http.StatusInternalServerError)
+		return
+	}
+	if messages == nil {
+		messages = []history.Message{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// GetContactScheduledMessages handles GET /contacts/{id}/scheduled-messages
+func (h *ContactHandler) GetContactScheduledMessages(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	messages, err := h.scheduleService.ListByContact(r.Context(), contact.Id)
+	if err != nil {
+		slog.Error("Failed to retrieve contact scheduled messages", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to retrieve contact scheduled messages", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
@@ -0,0 +1,337 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/agents"
+	"mbx/contacts"
+	"mbx/conversations"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+type ConversationHandler struct {
+	conversations *conversations.Service
+	contacts      *contacts.Service
+	agents        *agents.Service
+}
+
+func NewConversationHandler(conversationService *conversations.Service, contactService *contacts.Service, agentService *agents.Service) *ConversationHandler {
+	return &ConversationHandler{
+		conversations: conversationService,
+		contacts:      contactService,
+		agents:        agentService,
+	}
+}
+
+// UpdateConversationRequest changes the inbox state of a conversation. Only
+// the fields that are present are changed.
+type UpdateConversationRequest struct {
+	Read     *bool                 `json:"read,omitempty"`
+	Archived *bool                 `json:"archived,omitempty"`
+	Status   *conversations.Status `json:"status,omitempty"`
+	Tags     *[]string             `json:"tags,omitempty"`
+}
+
+// writeConversationError writes the response for an error from the
+// conversations service
+func writeConversationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, conversations.ErrInvalidStatus):
+		writeError(w, "Status must be one of: open, pending, closed", http.StatusBadRequest)
+	case errors.Is(err, conversations.ErrEmptyNote):
+		writeError(w, "Note cannot be empty", http.StatusBadRequest)
+	case errors.Is(err, agents.ErrNotFound):
+		writeError(w, "Agent not found", http.StatusNotFound)
+	case errors.Is(err, conversations.ErrNotFound):
+		writeError(w, "Conversation not found", http.StatusNotFound)
+	default:
+		slog.Error("Conversation operation failed", "error", err)
+		writeError(w, "Failed to update conversation", http.StatusInternalServerError)
+	}
+}
+
+// ListConversations handles GET /conversations?archived=false&unread=true&status=open&assignee_id=...&tag=vip&limit=50&offset=0
+func (h *ConversationHandler) ListConversations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := conversations.ListFilter{
+		Status: conversations.Status(query.Get("status")),
+		Tag:    query.Get("tag"),
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		writeError(w, "Status must be one of: open, pending, closed", http.StatusBadRequest)
+		return
+	}
+	if assignee := query.Get("assignee_id"); assignee != "" {
+		id, err := uuid.Parse(assignee)
+		if err != nil {
+			writeError(w, "Invalid 'assignee_id' value", http.StatusBadRequest)
+			return
+		}
+		filter.AssigneeId = &id
+	}
+
+	var err error
+	if archived := query.Get("archived"); archived != "" {
+		if filter.Archived, err = strconv.ParseBool(archived); err != nil {
+			writeError(w, "Invalid 'archived' value", http.StatusBadRequest)
+			return
+		}
+	}
+	if unread := query.Get("unread"); unread != "" {
+		if filter.UnreadOnly, err = strconv.ParseBool(unread); err != nil {
+			writeError(w, "Invalid 'unread' value", http.StatusBadRequest)
+			return
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			writeError(w, "Invalid 'limit' value", http.StatusBadRequest)
+			return
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		if filter.Offset, err = strconv.Atoi(offset); err != nil || filter.Offset < 0 {
+			writeError(w, "Invalid 'offset' value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	list, err := h.conversations.List(r.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to list conversations", "error", err)
+		writeError(w, "Failed to list conversations", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []conversations.Conversation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetConversationMessages handles GET /conversations/{id}/messages?limit=200
+func (h *ConversationHandler) GetConversationMessages(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	limit := 200
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > 1000 {
+			writeError(w, "Invalid 'limit' value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	thread, err := h.conversations.Thread(r.Context(), contact.Id, limit)
+	if err != nil {
+		slog.Error("Failed to retrieve conversation", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to retrieve conversation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(thread)
+}
+
+// Reply handles POST /conversations/{id}/messages
+func (h *ConversationHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	req := struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Body == "" {
+		writeError(w, "Message body cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	msgResponse, err := h.conversations.Reply(r.Context(), contact, req.Body)
+	if err != nil {
+		writeSendError(w, err, "Failed to send message")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(msgResponse)
+}
+
+// UpdateConversation handles PATCH /conversations/{id}
+func (h *ConversationHandler) UpdateConversation(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+	actor, ok := actorFromRequest(w, r, h.agents)
+	if !ok {
+		return
+	}
+
+	var req UpdateConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if req.Read != nil {
+		var err error
+		if *req.Read {
+			err = h.conversations.MarkRead(ctx, contact.Id)
+		} else {
+			err = h.conversations.MarkUnread(ctx, contact.Id)
+		}
+		if err != nil {
+			writeConversationError(w, err)
+			return
+		}
+	}
+	if req.Archived != nil {
+		if err := h.conversations.SetArchived(ctx, contact.Id, *req.Archived, actor); err != nil {
+			writeConversationError(w, err)
+			return
+		}
+	}
+	if req.Status != nil {
+		if err := h.conversations.SetStatus(ctx, contact.Id, *req.Status, actor); err != nil {
+			writeConversationError(w, err)
+			return
+		}
+	}
+	if req.Tags != nil {
+		if err := h.conversations.SetTags(ctx, contact.Id, *req.Tags, actor); err != nil {
+			writeConversationError(w, err)
+			return
+		}
+	}
+
+	conversation, err := h.conversations.Find(ctx, contact.Id)
+	if err != nil {
+		slog.Error("Failed to fetch conversation", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to fetch conversation", http.StatusInternalServerError)
+		return
+	}
+	if conversation == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversation)
+}
+
+// AssignConversation handles PUT /conversations/{id}/assignee. A null
+// agent_id unassigns the conversation.
+func (h *ConversationHandler) AssignConversation(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+	actor, ok := actorFromRequest(w, r, h.agents)
+	if !ok {
+		return
+	}
+
+	req := struct {
+		AgentId *uuid.UUID `json:"agent_id"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.conversations.Assign(r.Context(), contact.Id, req.AgentId, actor); err != nil {
+		writeConversationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetNotes handles GET /conversations/{id}/notes
+func (h *ConversationHandler) GetNotes(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	notes, err := h.conversations.Notes(r.Context(), contact.Id)
+	if err != nil {
+		slog.Error("Failed to retrieve conversation notes", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to retrieve conversation notes", http.StatusInternalServerError)
+		return
+	}
+	if notes == nil {
+		notes = []conversations.Note{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+// AddNote handles POST /conversations/{id}/notes. Notes are internal and are
+// never sent to the customer.
+func (h *ConversationHandler) AddNote(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+	actor, ok := actorFromRequest(w, r, h.agents)
+	if !ok {
+		return
+	}
+
+	req := struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	note, err := h.conversations.AddNote(r.Context(), contact.Id, req.Body, actor)
+	if err != nil {
+		writeConversationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+// GetEvents handles GET /conversations/{id}/events
+func (h *ConversationHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	events, err := h.conversations.Events(r.Context(), contact.Id)
+	if err != nil {
+		slog.Error("Failed to retrieve conversation events", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to retrieve conversation events", http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []conversations.Event{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
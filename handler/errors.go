@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mbx/provider/twilio"
+	"net/http"
+)
+
+// ErrorCode is a stable, machine-readable error code clients can branch on
+type ErrorCode string
+
+const (
+	CodeInvalidRequest ErrorCode = "invalid_request"
+	CodeUnauthorized   ErrorCode = "unauthorized"
+	CodeForbidden      ErrorCode = "forbidden"
+	CodeNotFound       ErrorCode = "not_found"
+	CodeConflict       ErrorCode = "conflict"
+	CodeUnprocessable  ErrorCode = "unprocessable"
+	CodeRateLimited    ErrorCode = "rate_limited"
+	CodeInternal       ErrorCode = "internal_error"
+	CodeProviderError  ErrorCode = "provider_error"
+
+	CodeInvalidPhoneNumber  ErrorCode = "invalid_phone_number"
+	CodeOutsideWindow       ErrorCode = "outside_window"
+	CodeRecipientOptedOut   ErrorCode = "recipient_opted_out"
+	CodeConsentRequired     ErrorCode = "consent_required"
+	CodeMessageRejected     ErrorCode = "message_rejected"
+	CodeTierLimit           ErrorCode = "tier_limit_reached"
+	CodeThrottled           ErrorCode = "throttled"
+	CodeInvalidSender       ErrorCode = "invalid_sender"
+	CodeIdempotencyMismatch ErrorCode = "idempotency_key_reused"
+	CodeIdempotencyPending  ErrorCode = "idempotency_key_in_progress"
+)
+
+// statusCodes is the code of errors that have nothing more specific to say
+// than their status
+var statusCodes = map[int]ErrorCode{
+	http.StatusBadRequest:            CodeInvalidRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodeInvalidRequest,
+	http.StatusUnprocessableEntity:   CodeUnprocessable,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusBadGateway:            CodeProviderError,
+}
+
+// APIError is the body of every error response
+type APIError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// Details points at the fields of the request that are wrong
+	Details []FieldError `json:"details,omitempty"`
+	// ProviderCode is the Twilio error code when the provider refused the
+	// request, see https://www.twilio.com/docs/api/errors
+	ProviderCode int `json:"provider_code,omitempty"`
+	// RequestId is the X-Request-Id of the request, to look it up in the logs
+	RequestId string `json:"request_id,omitempty"`
+}
+
+// FieldError is what is wrong with one field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// writeError replaces http.Error, coding the error after its status
+func writeError(w http.ResponseWriter, message string, status int) {
+	code, ok := statusCodes[status]
+	if !ok {
+		code = CodeInternal
+	}
+	writeAPIError(w, status, APIError{Code: code, Message: message})
+}
+
+// writeFieldError rejects the request because of one of its fields
+func writeFieldError(w http.ResponseWriter, field string, message string) {
+	writeAPIError(w, http.StatusBadRequest, APIError{
+		Code:    CodeInvalidRequest,
+		Message: message,
+		Details: []FieldError{{Field: field, Message: message}},
+	})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, apiErr APIError) {
+	apiErr.RequestId = w.Header().Get(RequestIdHeader)
+
+	h := w.Header()
+	// a Content-Length set for a response that failed midway would be wrong
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiErr)
+}
+
+// providerErrors maps the Twilio errors callers can do something about to
+// our codes and statuses
+var providerErrors = map[int]struct {
+	status  int
+	code    ErrorCode
+	message string
+}{
+	twilio.CodeInvalidNumber:  {http.StatusBadRequest, CodeInvalidPhoneNumber, "The recipient is not a valid phone number"},
+	twilio.CodeOutsideWindow:  {http.StatusUnprocessableEntity, CodeOutsideWindow, "Outside the 24-hour customer service window, send a template instead"},
+	twilio.CodeRateLimit:      {http.StatusTooManyRequests, CodeRateLimited, "The provider rate limited the sender, try again later"},
+	twilio.CodeNotWhatsapp:    {http.StatusUnprocessableEntity, CodeInvalidPhoneNumber, "The recipient is not on WhatsApp"},
+	twilio.CodeInvalidContent: {http.StatusBadRequest, CodeInvalidRequest, "The provider rejected the template or its variables"},
+}
+
+// writeProviderError writes the response for a failed provider call. Known
+// Twilio errors get their own code, the others are reported as a bad gateway
+// without passing the provider's text on.
+func writeProviderError(w http.ResponseWriter, err error, message string) {
+	providerCode := twilio.ErrorCode(err)
+	if mapped, ok := providerErrors[providerCode]; ok {
+		if mapped.status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		writeAPIError(w, mapped.status, APIError{Code: mapped.code, Message: mapped.message, ProviderCode: providerCode})
+		return
+	}
+
+	slog.Error(message, "error", err, "provider_code", providerCode)
+	if providerCode != 0 {
+		writeAPIError(w, http.StatusBadGateway, APIError{Code: CodeProviderError, Message: message, ProviderCode: providerCode})
+		return
+	}
+	writeError(w, message, http.StatusInternalServerError)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mbx/provider/twilio"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) APIError {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Expected a JSON error, got %q", ct)
+	}
+	var apiErr APIError
+	if err := json.NewDecoder(rec.Body).Decode(&apiErr); err != nil {
+		t.Fatalf("Failed to decode error: %v", err)
+	}
+	return apiErr
+}
+
+func TestWriteProviderError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		status       int
+		code         ErrorCode
+		providerCode int
+	}{
+		{"invalid number", &client.TwilioRestError{Code: twilio.CodeInvalidNumber}, http.StatusBadRequest, CodeInvalidPhoneNumber, twilio.CodeInvalidNumber},
+		{"outside window", fmt.Errorf("send: %w", &client.TwilioRestError{Code: twilio.CodeOutsideWindow}), http.StatusUnprocessableEntity, CodeOutsideWindow, twilio.CodeOutsideWindow},
+		{"rate limit", &client.TwilioRestError{Code: twilio.CodeRateLimit}, http.StatusTooManyRequests, CodeRateLimited, twilio.CodeRateLimit},
+		{"unmapped", &client.TwilioRestError{Code: 20003, Message: "Authenticate"}, http.StatusBadGateway, CodeProviderError, 20003},
+		{"not from the provider", errors.New("connection refused"), http.StatusInternalServerError, CodeInternal, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeProviderError(rec, tt.err, "Failed to send message")
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			apiErr := decodeError(t, rec)
+			if apiErr.Code != tt.code || apiErr.ProviderCode != tt.providerCode {
+				t.Errorf("Expected %s with provider code %d, got %+v", tt.code, tt.providerCode, apiErr)
+			}
+			if strings.Contains(apiErr.Message, "Authenticate") || strings.Contains(apiErr.Message, "refused") {
+				t.Errorf("Expected the provider's text to stay out of the response, got %q", apiErr.Message)
+			}
+		})
+	}
+}
+
+func TestRequestId(t *testing.T) {
+	h := RequestId(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, "Tenant not found", http.StatusNotFound)
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/tenants/1", nil)
+	req.Header.Set(RequestIdHeader, "abc-123")
+	h.ServeHTTP(rec, req)
+
+	if id := rec.Header().Get(RequestIdHeader); id != "abc-123" {
+		t.Errorf("Expected the incoming request ID to be kept, got %q", id)
+	}
+	apiErr := decodeError(t, rec)
+	if apiErr.Code != CodeNotFound || apiErr.RequestId != "abc-123" {
+		t.Errorf("Expected not_found with the request ID, got %+v", apiErr)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/tenants/1", nil)
+	req.Header.Set(RequestIdHeader, strings.Repeat("x", 200))
+	h.ServeHTTP(rec, req)
+
+	if id := rec.Header().Get(RequestIdHeader); id == "" || len(id) > 128 {
+		t.Errorf("Expected a generated request ID, got %q", id)
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mbx/events"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// streamBatch is how many events are read from the log at a time
+const streamBatch = 100
+
+type EventStreamHandler struct {
+	events    *events.Service
+	heartbeat time.Duration
+}
+
+// NewEventStreamHandler creates the SSE handler. Streams send a ping comment
+// every heartbeat, which also picks up events that were held back until an
+// older transaction finished.
+func NewEventStreamHandler(eventService *events.Service, heartbeat time.Duration) *EventStreamHandler {
+	return &EventStreamHandler{
+		events:    eventService,
+		heartbeat: heartbeat,
+	}
+}
+
+// Stream handles GET /events/stream?type=message.created,inbound.received&contact_id=...
+//
+// Clients resume after the last event they got with the Last-Event-ID header,
+// or the last_event_id query parameter. Without either, the stream starts
+// with the events published after the connection.
+func (h *EventStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var filter events.Filter
+	query := r.URL.Query()
+	for _, value := range query["type"] {
+		for _, t := range strings.Split(value, ",") {
+			eventType := events.Type(strings.TrimSpace(t))
+			if !slices.Contains(events.Types, eventType) {
+				writeError(w, fmt.Sprintf("Unknown event type %q", eventType), http.StatusBadRequest)
+				return
+			}
+			filter.Types = append(filter.Types, eventType)
+		}
+	}
+	if value := query.Get("contact_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			writeError(w, "Invalid 'contact_id' value", http.StatusBadRequest)
+			return
+		}
+		filter.ContactId = &id
+	}
+
+	// subscribe before reading the log, so nothing published in between is
+	// missed
+	wake, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = query.Get("last_event_id")
+	}
+	var seq int64
+	if lastEventId != "" {
+		var err error
+		if seq, err = strconv.ParseInt(lastEventId, 10, 64); err != nil || seq < 0 {
+			writeError(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		// an id that is not one of the tenant's events starts from now
+		if seq, err = h.events.Resume(r.Context(), seq); err != nil {
+			slog.Error("Failed to read event log", "error", err)
+			writeError(w, "Failed to open event stream", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		var err error
+		if seq, err = h.events.LastSeq(r.Context()); err != nil {
+			slog.Error("Failed to read event log", "error", err)
+			writeError(w, "Failed to open event stream", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		if seq, err = h.send(w, r, seq, filter); err != nil {
+			slog.Warn("Event stream closed", "error", err)
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case _, open := <-wake:
+			if !open {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes the events following seq and returns the seq of the last one
+func (h *EventStreamHandler) send(w http.ResponseWriter, r *http.Request, seq int64, filter events.Filter) (int64, error) {
+	for {
+		list, err := h.events.After(r.Context(), seq, filter, streamBatch)
+		if err != nil {
+			return seq, err
+		}
+
+		for _, e := range list {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return seq, err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data); err != nil {
+				return seq, err
+			}
+			seq = e.Seq
+		}
+
+		if len(list) < streamBatch {
+			return seq, nil
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mbx/events"
+
+	"github.com/google/uuid"
+)
+
+// memoryEvents is an in-memory event log
+type memoryEvents struct {
+	mu   sync.Mutex
+	list []events.Event
+}
+
+func (m *memoryEvents) Append(_ context.Context, e *events.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.Seq = int64(len(m.list) + 1)
+	m.list = append(m.list, *e)
+	return nil
+}
+
+func (m *memoryEvents) ListAfter(_ context.Context, seq int64, filter events.Filter, limit int) ([]events.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []events.Event
+	for _, e := range m.list {
+		if e.Seq <= seq || len(out) == limit {
+			continue
+		}
+		if len(filter.Types) > 0 && !slices.Contains(filter.Types, e.Type) {
+			continue
+		}
+		if filter.ContactId != nil && (e.ContactId == nil || *e.ContactId != *filter.ContactId) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (m *memoryEvents) LastSeq(context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.list)), nil
+}
+
+func (m *memoryEvents) Resume(ctx context.Context, seq int64) (int64, error) {
+	m.mu.Lock()
+	known := seq <= int64(len(m.list))
+	m.mu.Unlock()
+	if known {
+		return seq, nil
+	}
+	return m.LastSeq(ctx)
+}
+
+// readEvents reads SSE frames until n events were received, returning the id
+// and event lines of each
+func readEvents(t *testing.T, scanner *bufio.Scanner, n int) []string {
+	t.Helper()
+	var got []string
+	var frame []string
+	for len(got) < n && scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(frame) > 0 {
+				got = append(got, strings.Join(frame, " "))
+			}
+			frame = nil
+			continue
+		}
+		if strings.HasPrefix(line, "id:") || strings.HasPrefix(line, "event:") {
+			frame = append(frame, line)
+		}
+	}
+	if len(got) < n {
+		t.Fatalf("Expected %d events, got %v (%v)", n, got, scanner.Err())
+	}
+	return got
+}
+
+func TestEventStream_LiveAndResume(t *testing.T) {
+	service := events.NewService(&memoryEvents{})
+	server := httptest.NewServer(http.HandlerFunc(NewEventStreamHandler(service, time.Hour).Stream))
+	defer server.Close()
+	defer service.Close()
+
+	ctx := context.Background()
+	contact := uuid.New()
+	other := uuid.New()
+	service.Publish(ctx, events.MessageCreated, &contact, map[string]string{"body": "before"})
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?type=message.created,inbound.received&contact_id="+contact.String(), nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	service.Publish(ctx, events.InboundReceived, &other, map[string]string{"body": "other contact"})
+	service.Publish(ctx, events.ScheduleChanged, &contact, map[string]string{"status": "sent"})
+	service.Publish(ctx, events.InboundReceived, &contact, map[string]string{"body": "reply"})
+
+	got := readEvents(t, bufio.NewScanner(resp.Body), 2)
+	want := []string{"id: 1 event: message.created", "id: 4 event: inbound.received"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestEventStream_UnknownLastEventIdStartsFromNow(t *testing.T) {
+	service := events.NewService(&memoryEvents{})
+	server := httptest.NewServer(http.HandlerFunc(NewEventStreamHandler(service, time.Hour).Stream))
+	defer server.Close()
+	defer service.Close()
+
+	ctx := context.Background()
+	service.Publish(ctx, events.MessageCreated, nil, map[string]string{"body": "before"})
+	service.Publish(ctx, events.StatusChanged, nil, map[string]string{"status": "sent"})
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Last-Event-ID", "99")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	service.Publish(ctx, events.InboundReceived, nil, map[string]string{"body": "after"})
+
+	got := readEvents(t, bufio.NewScanner(resp.Body), 1)
+	want := []string{"id: 3 event: inbound.received"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestEventStream_InvalidType(t *testing.T) {
+	service := events.NewService(&memoryEvents{})
+	handler := NewEventStreamHandler(service, time.Hour)
+
+	req := httptest.NewRequest(http.MethodGet, "/events/stream?type=message.bounced", nil)
+	w := httptest.NewRecorder()
+	handler.Stream(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+type stubNotifier chan struct{}
+
+func (n stubNotifier) Listen(context.Context) <-chan struct{} { return n }
+
+func TestEventStream_WakesForOtherReplicas(t *testing.T) {
+	log := &memoryEvents{}
+	service := events.NewService(log)
+	server := httptest.NewServer(http.HandlerFunc(NewEventStreamHandler(service, time.Hour).Stream))
+	defer server.Close()
+	defer service.Close()
+
+	notifier := make(stubNotifier)
+	go service.Follow(context.Background(), notifier)
+	defer close(notifier)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// another replica appends to the log, and only the notification tells
+	log.Append(context.Background(), &events.Event{Type: events.StatusChanged, Data: []byte(`{}`)})
+	notifier <- struct{}{}
+
+	got := readEvents(t, bufio.NewScanner(resp.Body), 1)
+	if want := []string{"id: 1 event: message.status_changed"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
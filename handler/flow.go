@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/flows"
+	"mbx/templates"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type FlowHandler struct {
+	flows     *flows.Service
+	config    flows.Config
+	templates templates.GroupResolver
+}
+
+func NewFlowHandler(flowService *flows.Service, config flows.Config, resolver templates.GroupResolver) *FlowHandler {
+	return &FlowHandler{
+		flows:     flowService,
+		config:    config,
+		templates: resolver,
+	}
+}
+
+// FlowRequest represents the payload for creating or replacing a flow. Flows
+// are enabled unless enabled is false.
+type FlowRequest struct {
+	Name           string                `json:"name"`
+	Enabled        *bool                 `json:"enabled,omitempty"`
+	Trigger        flows.Trigger         `json:"trigger"`
+	Start          string                `json:"start"`
+	Nodes          map[string]flows.Node `json:"nodes"`
+	TimeoutMinutes int                   `json:"timeout_minutes"`
+}
+
+func (req FlowRequest) apply(flow *flows.Flow) {
+	flow.Name = req.Name
+	flow.Enabled = req.Enabled == nil || *req.Enabled
+	flow.Trigger = req.Trigger
+	flow.Start = req.Start
+	flow.Nodes = req.Nodes
+	flow.TimeoutMinutes = req.TimeoutMinutes
+}
+
+// SimulateFlowRequest lists the messages of a simulated contact. Each input
+// is either typed text or the payload of a tapped button.
+type SimulateFlowRequest struct {
+	Inputs []SimulatedInput `json:"inputs"`
+}
+
+type SimulatedInput struct {
+	Text    string `json:"text,omitempty"`
+	Payload string `json:"payload,omitempty"`
+}
+
+type SimulatedTurn struct {
+	Input   *SimulatedInput `json:"input,omitempty"`
+	Replies []string        `json:"replies"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// writeFlowError writes the response for an error from the flows service
+func writeFlowError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, flows.ErrInvalidFlow):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, flows.ErrNotFound):
+		writeError(w, "Flow not found", http.StatusNotFound)
+	default:
+		slog.Error("Flow operation failed", "error", err)
+		writeError(w, "Failed to save flow", http.StatusInternalServerError)
+	}
+}
+
+// ListFlows handles GET /flows
+func (h *FlowHandler) ListFlows(w http.ResponseWriter, r *http.Request) {
+	list, err := h.flows.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list flows", "error", err)
+		writeError(w, "Failed to list flows", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []flows.Flow{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateFlow handles POST /flows
+func (h *FlowHandler) CreateFlow(w http.ResponseWriter, r *http.Request) {
+	var req FlowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	var flow flows.Flow
+	req.apply(&flow)
+
+	created, err := h.flows.Create(r.Context(), flow)
+	if err != nil {
+		writeFlowError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetFlow handles GET /flows/{id}
+func (h *FlowHandler) GetFlow(w http.ResponseWriter, r *http.Request) {
+	flow := h.flowFromPath(w, r)
+	if flow == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flow)
+}
+
+// UpdateFlow handles PUT /flows/{id}
+func (h *FlowHandler) UpdateFlow(w http.ResponseWriter, r *http.Request) {
+	flow := h.flowFromPath(w, r)
+	if flow == nil {
+		return
+	}
+
+	var req FlowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.apply(flow)
+
+	updated, err := h.flows.Update(r.Context(), *flow)
+	if err != nil {
+		writeFlowError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteFlow handles DELETE /flows/{id}
+func (h *FlowHandler) DeleteFlow(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid flow ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.flows.Delete(r.Context(), id); err != nil {
+		writeFlowError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SimulateFlow handles POST /flows/{id}/simulate. The flow is started for a
+// simulated contact and fed the inputs; nothing is sent to the provider.
+func (h *FlowHandler) SimulateFlow(w http.ResponseWriter, r *http.Request) {
+	flow := h.flowFromPath(w, r)
+	if flow == nil {
+		return
+	}
+
+	var req SimulateFlowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	sim := flows.NewSimulator(*flow, h.config, h.templates)
+
+	turns := make([]SimulatedTurn, 0, len(req.Inputs)+1)
+	record := func(input *SimulatedInput, replies []string, err error) {
+		turn := SimulatedTurn{Input: input, Replies: replies}
+		if turn.Replies == nil {
+			turn.Replies = []string{}
+		}
+		if err != nil {
+			turn.Error = err.Error()
+		}
+		turns = append(turns, turn)
+	}
+
+	replies, err := sim.Start(ctx)
+	record(nil, replies, err)
+	for i := range req.Inputs {
+		input := &req.Inputs[i]
+		if input.Payload != "" {
+			replies, err = sim.Tap(ctx, input.Payload)
+		} else {
+			replies, err = sim.Say(ctx, input.Text)
+		}
+		record(input, replies, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Turns     []SimulatedTurn `json:"turns"`
+		Session   *flows.Session  `json:"session"`
+		HandedOff string          `json:"handed_off,omitempty"`
+	}{
+		Turns:     turns,
+		Session:   sim.Session(),
+		HandedOff: sim.HandedOff(),
+	})
+}
+
+func (h *FlowHandler) flowFromPath(w http.ResponseWriter, r *http.Request) *flows.Flow {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid flow ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	flow, err := h.flows.FindById(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch flow", "error", err, "id", id)
+		writeError(w, "Failed to fetch flow", http.StatusInternalServerError)
+		return nil
+	}
+	if flow == nil {
+		writeError(w, "Flow not found", http.StatusNotFound)
+		return nil
+	}
+	return flow
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"mbx/idempotency"
+	"net/http"
+)
+
+// maxIdempotentBody bounds the requests read in full to be fingerprinted
+const maxIdempotentBody = 1 << 20
+
+// Idempotency makes retries of requests with an Idempotency-Key header safe:
+// the first request runs, and repeats get its response back instead of
+// running again.
+type Idempotency struct {
+	keys *idempotency.Service
+}
+
+func NewIdempotency(keyService *idempotency.Service) *Idempotency {
+	return &Idempotency{
+		keys: keyService,
+	}
+}
+
+// Wrap runs next at most once per Idempotency-Key of the tenant. Requests
+// without the header always run. It goes inside the authenticator, which
+// scopes the request to its tenant.
+func (i *Idempotency) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			writeError(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		claim, record, err := i.keys.Begin(r.Context(), key, fingerprint(r, body))
+		switch {
+		case errors.Is(err, idempotency.ErrInvalidKey):
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, idempotency.ErrMismatch):
+			writeAPIError(w, http.StatusConflict, APIError{Code: CodeIdempotencyMismatch, Message: "Idempotency-Key was already used with a different request"})
+			return
+		case errors.Is(err, idempotency.ErrInProgress):
+			w.Header().Set("Retry-After", "1")
+			writeAPIError(w, http.StatusConflict, APIError{Code: CodeIdempotencyPending, Message: "A request with this Idempotency-Key is still in progress"})
+			return
+		case err != nil:
+			slog.Error("Failed to claim idempotency key", "error", err)
+			writeError(w, "Failed to process request", http.StatusInternalServerError)
+			return
+		case record != nil:
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.Status)
+			w.Write(record.Body)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		// the response is stored even when the caller went away, since that
+		// is when it retries
+		ctx := context.WithoutCancel(r.Context())
+		status := rec.statusCode()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			// failures worth retrying give the key up
+			if err := i.keys.Release(ctx, key, claim); err != nil {
+				slog.Error("Failed to release idempotency key", "error", err)
+			}
+			return
+		}
+		if err := i.keys.Complete(ctx, key, claim, status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+			slog.Error("Failed to store idempotent response", "error", err, "status", status)
+		}
+	}
+}
+
+// fingerprint hashes what makes two requests the same
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response written through it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mbx/idempotency"
+
+	"github.com/google/uuid"
+)
+
+// memoryKeys keeps idempotency records in memory
+type memoryKeys map[string]*idempotency.Record
+
+func (m memoryKeys) Claim(_ context.Context, record idempotency.Record, _ time.Time) (*idempotency.Record, error) {
+	if existing, ok := m[record.Key]; ok {
+		return existing, nil
+	}
+	m[record.Key] = &record
+	return nil, nil
+}
+
+func (m memoryKeys) Complete(_ context.Context, key string, claim uuid.UUID, status int, contentType string, body []byte) error {
+	if m[key] == nil || m[key].Claim != claim {
+		return idempotency.ErrClaimLost
+	}
+	m[key].Status, m[key].ContentType, m[key].Body = status, contentType, body
+	return nil
+}
+
+func (m memoryKeys) Release(_ context.Context, key string, claim uuid.UUID) error {
+	if m[key] != nil && m[key].Claim == claim {
+		delete(m, key)
+	}
+	return nil
+}
+
+func (m memoryKeys) DeleteExpired(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func TestIdempotency_Wrap(t *testing.T) {
+	keys := memoryKeys{}
+	runs := 0
+	status := http.StatusCreated
+	next := func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"sid":"SM1","echo":` + string(body) + `}`))
+	}
+	handler := NewIdempotency(idempotency.NewService(keys, idempotency.Config{})).Wrap(next)
+
+	send := func(key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/send-message", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	first := send("retry-1", `"hi"`)
+	replay := send("retry-1", `"hi"`)
+	if runs != 1 {
+		t.Errorf("Expected the request to run once, ran %d times", runs)
+	}
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Errorf("Expected the first response to be replayed, got %d: %s", replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" || replay.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected replay headers, got %v", replay.Header())
+	}
+
+	if rec := send("retry-1", `"bye"`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a different body, got %d", rec.Code)
+	}
+
+	keys["pending"] = &idempotency.Record{Key: "pending", Fingerprint: fingerprint(httptest.NewRequest(http.MethodPost, "/send-message", nil), []byte(`"hi"`))}
+	if rec := send("pending", `"hi"`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while the first request is in progress, got %d", rec.Code)
+	}
+
+	status = http.StatusBadGateway
+	send("retry-2", `"hi"`)
+	status = http.StatusCreated
+	if rec := send("retry-2", `"hi"`); rec.Code != http.StatusCreated || runs != 3 {
+		t.Errorf("Expected a failed request to run again, got %d after %d runs", rec.Code, runs)
+	}
+
+	send("", `"hi"`)
+	send("", `"hi"`)
+	if runs != 5 {
+		t.Errorf("Expected requests without a key to always run, ran %d times", runs)
+	}
+}
+
+func TestIdempotency_TakenOverKeyIsKept(t *testing.T) {
+	keys := memoryKeys{}
+	takeover := &idempotency.Record{Key: "slow", Claim: uuid.New()}
+	status := http.StatusCreated
+	next := func(w http.ResponseWriter, r *http.Request) {
+		// the request outlived the lock timeout and a retry claimed the key
+		keys["slow"] = takeover
+		w.WriteHeader(status)
+	}
+	handler := NewIdempotency(idempotency.NewService(keys, idempotency.Config{})).Wrap(next)
+
+	for _, status = range []int{http.StatusCreated, http.StatusBadGateway} {
+		delete(keys, "slow")
+		req := httptest.NewRequest(http.MethodPost, "/send-message", strings.NewReader(`"hi"`))
+		req.Header.Set("Idempotency-Key", "slow")
+		handler(httptest.NewRecorder(), req)
+
+		if keys["slow"] != takeover || takeover.Done() {
+			t.Errorf("Expected the retry to keep the key after a %d, got %+v", status, keys["slow"])
+		}
+	}
+}
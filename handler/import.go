@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mbx/campaigns"
+	"mbx/imports"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type ImportHandler struct {
+	imports *imports.Service
+}
+
+func NewImportHandler(importService *imports.Service) *ImportHandler {
+	return &ImportHandler{
+		imports: importService,
+	}
+}
+
+// writeImportError writes the response for an error from the imports
+// service
+func writeImportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, imports.ErrInvalidCSV):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, campaigns.ErrInvalidTransition):
+		writeError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, campaigns.ErrNotFound):
+		writeError(w, "Campaign not found", http.StatusNotFound)
+	default:
+		slog.Error("Import failed", "error", err)
+		writeError(w, "Failed to import file", http.StatusInternalServerError)
+	}
+}
+
+// CreateImport handles POST /imports. The multipart/form-data body has a
+// "file" part with the CSV, and optionally a "campaign_id" part naming the
+// draft campaign to add the rows to and a "variables" part with a JSON object
+// mapping template variables to columns. The file is streamed to disk and
+// imported in the background; the response is the pending job.
+func (h *ImportHandler) CreateImport(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, "Request must be multipart/form-data", http.StatusBadRequest)
+		return
+	}
+
+	var upload *imports.Upload
+	defer func() {
+		// the upload belongs to the job once it has started
+		if upload != nil {
+			upload.Discard()
+		}
+	}()
+	var campaignId *uuid.UUID
+	var variables map[string]string
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeError(w, "Invalid multipart body", http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "file":
+			if upload != nil {
+				writeError(w, "Only one file can be imported at a time", http.StatusBadRequest)
+				return
+			}
+			if upload, err = h.imports.Stage(part); err != nil {
+				writeImportError(w, err)
+				return
+			}
+		case "campaign_id":
+			value, err := io.ReadAll(io.LimitReader(part, 64))
+			if err != nil {
+				writeError(w, "Invalid multipart body", http.StatusBadRequest)
+				return
+			}
+			id, err := uuid.Parse(strings.TrimSpace(string(value)))
+			if err != nil {
+				writeError(w, "Invalid 'campaign_id' value", http.StatusBadRequest)
+				return
+			}
+			campaignId = &id
+		case "variables":
+			if err := json.NewDecoder(io.LimitReader(part, 64<<10)).Decode(&variables); err != nil {
+				writeError(w, "Invalid 'variables' value", http.StatusBadRequest)
+				return
+			}
+		}
+		part.Close()
+	}
+	if upload == nil {
+		writeError(w, "A 'file' part is required", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.imports.Start(r.Context(), upload, campaignId, variables)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+	upload = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/v1/imports/"+job.Id.String())
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetImport handles GET /imports/{id}
+func (h *ImportHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	job := h.importFromPath(w, r)
+	if job == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetImportErrors handles GET /imports/{id}/errors?limit=100&offset=0
+func (h *ImportHandler) GetImportErrors(w http.ResponseWriter, r *http.Request) {
+	job := h.importFromPath(w, r)
+	if job == nil {
+		return
+	}
+
+	limit, ok := limitFromQuery(w, r, 100)
+	if !ok {
+		return
+	}
+	offset := 0
+	if value := r.URL.Query().Get("offset"); value != "" {
+		var err error
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			writeError(w, "Invalid 'offset' value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rowErrors, err := h.imports.Errors(r.Context(), job.Id, limit, offset)
+	if err != nil {
+		slog.Error("Failed to list import errors", "error", err, "id", job.Id)
+		writeError(w, "Failed to list import errors", http.StatusInternalServerError)
+		return
+	}
+	if rowErrors == nil {
+		rowErrors = []imports.RowError{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rowErrors)
+}
+
+func (h *ImportHandler) importFromPath(w http.ResponseWriter, r *http.Request) *imports.Job {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid import ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	job, err := h.imports.FindById(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch import", "error", err, "id", id)
+		writeError(w, "Failed to fetch import", http.StatusInternalServerError)
+		return nil
+	}
+	if job == nil {
+		writeError(w, "Import not found", http.StatusNotFound)
+		return nil
+	}
+	return job
+}
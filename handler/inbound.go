@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"mbx/history"
+	"mbx/inbound"
+	"mbx/tenants"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+type InboundHandler struct {
+	inbound   *inbound.Service
+	signature twilioSignature
+}
+
+// NewInboundHandler creates the Twilio webhook handler. Messages belong to the
+// tenant of the number they were sent to. When publicURL is set, requests
+// must carry a valid X-Twilio-Signature of that tenant's account, computed
+// for that base URL.
+func NewInboundHandler(inboundService *inbound.Service, tenantService *tenants.Service, publicURL string) *InboundHandler {
+	return &InboundHandler{
+		inbound:   inboundService,
+		signature: newTwilioSignature(tenantService, publicURL),
+	}
+}
+
+// twilioSignature resolves the tenant of Twilio callbacks and validates their
+// X-Twilio-Signature. It accepts every request when no public URL is
+// configured.
+type twilioSignature struct {
+	tenants   *tenants.Service
+	publicURL string
+}
+
+func newTwilioSignature(tenantService *tenants.Service, publicURL string) twilioSignature {
+	return twilioSignature{tenants: tenantService, publicURL: strings.TrimSuffix(publicURL, "/")}
+}
+
+var errInvalidSignature = errors.New("invalid Twilio signature")
+
+// verify returns the request scoped to the tenant that owns number, failing
+// with errInvalidSignature when the signature is not that of the tenant's
+// account. It must be called after the form is parsed.
+func (s twilioSignature) verify(r *http.Request, number string) (*http.Request, error) {
+	tenant, err := s.tenants.FindByNumber(r.Context(), number)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.publicURL != "" {
+		params := make(map[string]string, len(r.PostForm))
+		for key := range r.PostForm {
+			params[key] = r.PostForm.Get(key)
+		}
+		// Twilio signs the URL it called, before the router stripped the
+		// version prefix off it
+		uri := r.RequestURI
+		if uri == "" {
+			uri = r.URL.RequestURI()
+		}
+		validator := client.NewRequestValidator(s.tenants.ConfigOf(tenant).TwilioAuthToken)
+		if !validator.Validate(s.publicURL+uri, params, r.Header.Get("X-Twilio-Signature")) {
+			return nil, errInvalidSignature
+		}
+	}
+	return r.WithContext(tenants.NewContext(r.Context(), tenant.Id)), nil
+}
+
+// parseInteraction reads the fields Twilio adds when the customer taps a quick
+// reply button or picks a list item, returning nil for typed messages
+func parseInteraction(form url.Values) *history.Interaction {
+	interaction := &history.Interaction{RepliedToSid: form.Get("OriginalRepliedMessageSid")}
+
+	switch {
+	case form.Get("ButtonPayload") != "":
+		interaction.Type = history.InteractionButton
+		interaction.Payload = form.Get("ButtonPayload")
+		interaction.Text = form.Get("ButtonText")
+	case form.Get("ListId") != "":
+		interaction.Type = history.InteractionList
+		interaction.Payload = form.Get("ListId")
+		interaction.Text = form.Get("ListTitle")
+	default:
+		return nil
+	}
+	return interaction
+}
+
+// ReceiveMessage handles POST /callbacks/twilio/inbound
+func (h *InboundHandler) ReceiveMessage(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "Invalid form payload", http.StatusBadRequest)
+		return
+	}
+	scoped, err := h.signature.verify(r, r.PostForm.Get("To"))
+	if errors.Is(err, errInvalidSignature) {
+		slog.Warn("Rejected inbound webhook with invalid signature", "remote_addr", r.RemoteAddr)
+		writeError(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to resolve the tenant of an inbound message", "error", err)
+		writeError(w, "Failed to process inbound message", http.StatusInternalServerError)
+		return
+	}
+	r = scoped
+
+	from := r.PostForm.Get("From")
+	if from == "" {
+		writeError(w, "Missing 'From' parameter", http.StatusBadRequest)
+		return
+	}
+
+	numMedia, _ := strconv.Atoi(r.PostForm.Get("NumMedia"))
+	msg := inbound.Message{
+		Sid:         r.PostForm.Get("MessageSid"),
+		From:        from,
+		To:          r.PostForm.Get("To"),
+		Body:        r.PostForm.Get("Body"),
+		ProfileName: r.PostForm.Get("ProfileName"),
+		NumMedia:    numMedia,
+		ReceivedAt:  time.Now(),
+
+		Interaction: parseInteraction(r.PostForm),
+	}
+
+	if err := h.inbound.Receive(r.Context(), msg); err != nil {
+		slog.Error("Failed to process inbound message", "error", err, "sid", msg.Sid)
+		writeError(w, "Failed to process inbound message", http.StatusInternalServerError)
+		return
+	}
+
+	// Replies are sent through the API, so Twilio gets an empty TwiML response
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Response></Response>`))
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"mbx/contacts"
 	"mbx/models"
 	"mbx/provider/twilio"
 	"mbx/sender"
@@ -12,17 +14,22 @@ import (
 	"time"
 )
 
+// defaultMessagePage is the number of messages listed without a limit
+const defaultMessagePage = 100
+
 type MessageHandler struct {
 	sender         sender.Whatsapp
 	templateSender sender.WhatsappTemplate
 	fetcher        twilio.WhatsappFetcher
+	contacts       contacts.Resolver
 }
 
-func NewMessageHandler(whatsapp sender.Whatsapp, templateSender sender.WhatsappTemplate, fetcher twilio.WhatsappFetcher) *MessageHandler {
+func NewMessageHandler(whatsapp sender.Whatsapp, templateSender sender.WhatsappTemplate, fetcher twilio.WhatsappFetcher, contacts contacts.Resolver) *MessageHandler {
 	return &MessageHandler{
 		sender:         whatsapp,
 		templateSender: templateSender,
 		fetcher:        fetcher,
+		contacts:       contacts,
 	}
 }
 
@@ -42,13 +49,13 @@ func parseScheduledTime(timeStr string) (*time.Time, error) {
 func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	afterStr := r.URL.Query().Get("after")
 	if afterStr == "" {
-		http.Error(w, "Missing 'after' query parameter", http.StatusBadRequest)
+		writeError(w, "Missing 'after' query parameter", http.StatusBadRequest)
 		return
 	}
 
 	afterTime, err := time.Parse("2006-01-02", afterStr)
 	if err != nil {
-		http.Error(w, "Invalid 'after' time format. Use YYYY-MM-DD format", http.StatusBadRequest)
+		writeError(w, "Invalid 'after' time format. Use YYYY-MM-DD format", http.StatusBadRequest)
 		return
 	}
 
@@ -56,22 +63,36 @@ func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 
 	validStatuses := []string{"", "sent", "read", "delivered", "failed", "scheduled", "queued", "sending"}
 	if status != "" && !slices.Contains(validStatuses, status) {
-		http.Error(w, "Invalid 'status' value", http.StatusBadRequest)
+		writeError(w, "Invalid 'status' value", http.StatusBadRequest)
+		return
+	}
+
+	// limit and cursor page through the messages, newest first. The cursor
+	// of the next page is returned in the Next-Cursor header.
+	limit, ok := limitFromQuery(w, r, defaultMessagePage)
+	if !ok {
 		return
 	}
 
-	messages, err := h.fetcher.GetMessages(r.Context(), afterTime)
+	page, err := h.fetcher.GetMessages(r.Context(), afterTime, limit, r.URL.Query().Get("cursor"))
+	if errors.Is(err, twilio.ErrInvalidCursor) {
+		writeError(w, "Invalid 'cursor' value", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		slog.Error("Failed to retrieve messages", "error", err)
-		http.Error(w, "Failed to retrieve messages", http.StatusInternalServerError)
+		writeProviderError(w, err, "Failed to retrieve messages")
 		return
 	}
+	messages := page.Messages
+	if page.NextCursor != "" {
+		w.Header().Set("Next-Cursor", page.NextCursor)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(messages)
 	if err != nil {
 		slog.Error("Failed to encode messages response", "error", err)
-		http.Error(w, "Failed to encode messages response", http.StatusInternalServerError)
+		writeError(w, "Failed to encode messages response", http.StatusInternalServerError)
 		return
 	}
 }
@@ -79,29 +100,35 @@ func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 func (h *MessageHandler) NormalMessage(w http.ResponseWriter, r *http.Request) {
 	var req models.WhatsappBodyDTO
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
 	// Validate required fields
 	if req.Body == "" {
-		http.Error(w, "Message body cannot be empty", http.StatusBadRequest)
+		writeError(w, "Message body cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if req.To == "" && req.ContactId == nil {
+		writeError(w, "Recipient number cannot be empty", http.StatusBadRequest)
 		return
 	}
-	if req.To == "" {
-		http.Error(w, "Recipient number cannot be empty", http.StatusBadRequest)
+
+	contact, err := resolveRecipient(r.Context(), h.contacts, req.To, req.ContactId)
+	if err != nil {
+		writeRecipientError(w, err)
 		return
 	}
 
 	whatsappMessage := models.WhatsappBody{
-		To:   fmt.Sprintf("whatsapp:%s", req.To),
+		To:   fmt.Sprintf("whatsapp:%s", contact.Phone),
 		Body: req.Body,
+		From: req.From,
 	}
 
 	msgResponse, err := h.sender.Send(r.Context(), whatsappMessage)
 	if err != nil {
-		slog.Error("Failed to send message", "error", err)
-		http.Error(w, "Failed to send message", http.StatusInternalServerError)
+		writeSendError(w, err, "Failed to send message")
 		return
 	}
 
@@ -110,7 +137,7 @@ func (h *MessageHandler) NormalMessage(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(msgResponse)
 	if err != nil {
 		slog.Error("Failed to encode message response", "error", err)
-		http.Error(w, "Failed to encode message response", http.StatusInternalServerError)
+		writeError(w, "Failed to encode message response", http.StatusInternalServerError)
 		return
 	}
 }
@@ -121,19 +148,19 @@ func (h *MessageHandler) CancelMessage(w http.ResponseWriter, r *http.Request) {
 	}{}
 	if err := json.NewDecoder(r.Body).Decode(&incReq); err != nil {
 		slog.Error("Failed to decode cancel message request", "error", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
 	if incReq.TwilioMessageId == "" {
-		http.Error(w, "Message ID cannot be empty", http.StatusBadRequest)
+		writeFieldError(w, "message_id", "Message ID cannot be empty")
 		return
 	}
 
 	err := h.sender.CancelMessage(r.Context(), incReq.TwilioMessageId)
 	if err != nil {
 		slog.Error("Failed to cancel message", "error", err, "message_id", incReq.TwilioMessageId)
-		http.Error(w, "Failed to cancel message: "+err.Error(), http.StatusInternalServerError)
+		writeProviderError(w, err, "Failed to cancel message")
 		return
 	}
 
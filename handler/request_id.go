@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIdHeader carries the ID of a request, which error responses repeat
+const RequestIdHeader = "X-Request-Id"
+
+// RequestId sets the X-Request-Id of every response, keeping the one the
+// caller sent when it is reasonable
+func RequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIdHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIdHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"mbx/contacts"
 	"mbx/models"
 	"mbx/schedules"
+	"mbx/senders"
+	"mbx/templates"
 	"net/http"
 	"time"
 
@@ -13,21 +17,30 @@ import (
 
 type ScheduledMessageHandler struct {
 	scheduleService *schedules.Service
+	contacts        contacts.Resolver
+	senders         *senders.Service
 }
 
-func NewScheduledMessageHandler(scheduleService *schedules.Service) *ScheduledMessageHandler {
+func NewScheduledMessageHandler(scheduleService *schedules.Service, contacts contacts.Resolver, senderService *senders.Service) *ScheduledMessageHandler {
 	return &ScheduledMessageHandler{
 		scheduleService: scheduleService,
+		contacts:        contacts,
+		senders:         senderService,
 	}
 }
 
 // CreateScheduledMessageRequest represents the request payload for scheduling a message
 type CreateScheduledMessageRequest struct {
 	To                 string                      `json:"to"`
+	ContactId          *uuid.UUID                  `json:"contact_id,omitempty"` // used instead of To
 	Content            string                      `json:"content"`
 	SendAt             time.Time                   `json:"send_at"`
 	ProviderTemplateId string                      `json:"provider_template_id,omitempty"`
+	TemplateName       string                      `json:"template_name,omitempty"` // template group, resolved at send time
+	Locale             string                      `json:"locale,omitempty"`
 	Type               models.ScheduledMessageType `json:"type"` // "template" or "freeform"
+	// the sender is only picked from the pool when the message is sent
+	models.From
 }
 
 // CreateScheduledMessage handles POST /scheduled-messages
@@ -35,47 +48,68 @@ func (h *ScheduledMessageHandler) CreateScheduledMessage(w http.ResponseWriter,
 	var req CreateScheduledMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		slog.Error("Failed to decode create scheduled message request", "error", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
 	// Validate required fields
-	if req.To == "" {
-		http.Error(w, "Recipient number cannot be empty", http.StatusBadRequest)
+	if req.To == "" && req.ContactId == nil {
+		writeError(w, "Recipient number cannot be empty", http.StatusBadRequest)
 		return
 	}
 	if req.Content == "" {
-		http.Error(w, "Message content cannot be empty", http.StatusBadRequest)
+		writeError(w, "Message content cannot be empty", http.StatusBadRequest)
 		return
 	}
 	if req.Type != models.ScheduleTypeTemplate && req.Type != models.ScheduleTypeFreeform {
-		http.Error(w, "Invalid message type. Must be 'template' or 'freeform'", http.StatusBadRequest)
+		writeError(w, "Invalid message type. Must be 'template' or 'freeform'", http.StatusBadRequest)
 		return
 	}
-	if req.Type == models.ScheduleTypeTemplate && req.ProviderTemplateId == "" {
-		http.Error(w, "Provider template ID required for template messages", http.StatusBadRequest)
+	if req.Type == models.ScheduleTypeTemplate && req.ProviderTemplateId == "" && req.TemplateName == "" {
+		writeError(w, "Provider template ID or template name required for template messages", http.StatusBadRequest)
 		return
 	}
 	if req.SendAt.Before(time.Now()) {
-		http.Error(w, "Send time cannot be in the past", http.StatusBadRequest)
+		writeError(w, "Send time cannot be in the past", http.StatusBadRequest)
 		return
 	}
+	if err := h.senders.Check(r.Context(), req.From); err != nil {
+		if errors.Is(err, senders.ErrInvalidSender) || errors.Is(err, senders.ErrUnknownSender) {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeProviderError(w, err, "Failed to retrieve messaging services")
+		return
+	}
+
+	contact, err := resolveRecipient(r.Context(), h.contacts, req.To, req.ContactId)
+	if err != nil {
+		writeRecipientError(w, err)
+		return
+	}
+	if req.Locale == "" {
+		req.Locale = contact.Locale
+	}
 
 	message := models.ScheduledMessage{
-		Id:         uuid.New(),
-		To:         req.To,
-		Content:    req.Content,
-		SendAt:     req.SendAt,
-		ProviderId: req.ProviderTemplateId,
-		Type:       req.Type,
-		Status:     models.StatusPending,
-		CreatedAt:  time.Now(),
+		Id:           uuid.New(),
+		ContactId:    &contact.Id,
+		To:           contact.Phone,
+		Content:      req.Content,
+		SendAt:       req.SendAt,
+		ProviderId:   req.ProviderTemplateId,
+		TemplateName: req.TemplateName,
+		Locale:       templates.NormalizeLocale(req.Locale),
+		Type:         req.Type,
+		Status:       models.StatusPending,
+		From:         req.From,
+		CreatedAt:    time.Now(),
 	}
 
-	err := h.scheduleService.Create(r.Context(), message)
+	err = h.scheduleService.Create(r.Context(), message)
 	if err != nil {
 		slog.Error("Failed to create scheduled message", "error", err)
-		http.Error(w, "Failed to create scheduled message", http.StatusInternalServerError)
+		writeError(w, "Failed to create scheduled message", http.StatusInternalServerError)
 		return
 	}
 
@@ -88,24 +122,24 @@ func (h *ScheduledMessageHandler) CreateScheduledMessage(w http.ResponseWriter,
 func (h *ScheduledMessageHandler) GetScheduledMessage(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	if idStr == "" {
-		http.Error(w, "Message ID is required", http.StatusBadRequest)
+		writeError(w, "Message ID is required", http.StatusBadRequest)
 		return
 	}
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "Invalid message ID format", http.StatusBadRequest)
+		writeError(w, "Invalid message ID format", http.StatusBadRequest)
 		return
 	}
 
 	message, err := h.scheduleService.FindById(r.Context(), id)
 	if err != nil {
 		slog.Error("Failed to fetch scheduled message", "error", err, "id", id)
-		http.Error(w, "Failed to fetch scheduled message", http.StatusInternalServerError)
+		writeError(w, "Failed to fetch scheduled message", http.StatusInternalServerError)
 		return
 	}
 	if message == nil {
-		http.Error(w, "Scheduled message not found", http.StatusNotFound)
+		writeError(w, "Scheduled message not found", http.StatusNotFound)
 		return
 	}
 
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,14 +10,46 @@ import (
 	"testing"
 	"time"
 
+	"mbx/contacts"
+	cmocks "mbx/contacts/mocks"
 	"mbx/models"
 	"mbx/schedules"
 	"mbx/schedules/mocks"
+	"mbx/senders"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 )
 
+// newContactResolver returns a resolver that links every number to a contact
+// with that same phone, leaving number normalization to the contacts tests.
+func newContactResolver(ctrl *gomock.Controller) *cmocks.MockResolver {
+	resolver := cmocks.NewMockResolver(ctrl)
+	resolver.EXPECT().
+		Resolve(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, phone string) (*contacts.Contact, error) {
+			return &contacts.Contact{Id: uuid.New(), Phone: phone}, nil
+		}).
+		AnyTimes()
+	return resolver
+}
+
+// accountServices are the Messaging Services of the tenant's account, with no
+// number of its own
+type accountServices []models.MessagingService
+
+func (a accountServices) FromNumber(context.Context) (string, error) { return "", nil }
+
+func (a accountServices) ListMessagingServices(context.Context) ([]models.MessagingService, error) {
+	return a, nil
+}
+
+// newSenders returns a sender service whose tenant has the MG123 Messaging
+// Service. Numbers are only checked at send time.
+func newSenders() *senders.Service {
+	return senders.NewService(nil, accountServices{{Sid: "MG123"}}, senders.Config{})
+}
+
 // Test: Create scheduled message successfully
 func TestCreateScheduledMessage_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -31,7 +64,7 @@ func TestCreateScheduledMessage_Success(t *testing.T) {
 		Times(1)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	futureTime := time.Now().Add(1 * time.Hour)
 	req := CreateScheduledMessageRequest{
@@ -73,7 +106,7 @@ func TestCreateScheduledMessage_Template(t *testing.T) {
 		Times(1)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	futureTime := time.Now().Add(2 * time.Hour)
 	req := CreateScheduledMessageRequest{
@@ -104,6 +137,85 @@ func TestCreateScheduledMessage_Template(t *testing.T) {
 	}
 }
 
+// Test: Create scheduled message referencing a template group
+func TestCreateScheduledMessage_TemplateName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockRepository(ctrl)
+	mockRepo.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	service := schedules.NewService(mockRepo)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
+
+	req := CreateScheduledMessageRequest{
+		To:           "1234567890",
+		Content:      `{"1": "Ana"}`,
+		SendAt:       time.Now().Add(2 * time.Hour),
+		Type:         models.ScheduleTypeTemplate,
+		TemplateName: "welcome",
+		Locale:       "pt-pt",
+	}
+
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest("POST", "/scheduled-messages", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateScheduledMessage(w, httpReq)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var response models.ScheduledMessage
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.TemplateName != "welcome" {
+		t.Errorf("Expected TemplateName welcome, got %s", response.TemplateName)
+	}
+	if response.Locale != "pt_PT" {
+		t.Errorf("Expected Locale pt_PT, got %s", response.Locale)
+	}
+}
+
+// Test: Create scheduled message for an unknown contact
+func TestCreateScheduledMessage_ContactNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockRepository(ctrl)
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	contactId := uuid.New()
+	resolver := cmocks.NewMockResolver(ctrl)
+	resolver.EXPECT().
+		FindById(gomock.Any(), contactId).
+		Return(nil, nil).
+		Times(1)
+
+	service := schedules.NewService(mockRepo)
+	handler := NewScheduledMessageHandler(service, resolver, newSenders())
+
+	req := CreateScheduledMessageRequest{
+		ContactId: &contactId,
+		Content:   "Test message",
+		SendAt:    time.Now().Add(1 * time.Hour),
+		Type:      models.ScheduleTypeFreeform,
+	}
+
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest("POST", "/scheduled-messages", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateScheduledMessage(w, httpReq)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
 // Test: Create scheduled message with missing recipient
 func TestCreateScheduledMessage_MissingTo(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -114,7 +226,7 @@ func TestCreateScheduledMessage_MissingTo(t *testing.T) {
 	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	req := CreateScheduledMessageRequest{
 		To:      "",
@@ -143,7 +255,7 @@ func TestCreateScheduledMessage_MissingContent(t *testing.T) {
 	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	req := CreateScheduledMessageRequest{
 		To:      "1234567890",
@@ -172,7 +284,7 @@ func TestCreateScheduledMessage_PastSendTime(t *testing.T) {
 	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	pastTime := time.Now().Add(-1 * time.Hour)
 	req := CreateScheduledMessageRequest{
@@ -202,7 +314,7 @@ func TestCreateScheduledMessage_TemplateMissingId(t *testing.T) {
 	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	req := CreateScheduledMessageRequest{
 		To:      "1234567890",
@@ -232,7 +344,7 @@ func TestCreateScheduledMessage_InvalidType(t *testing.T) {
 	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	body := []byte(`{
 		"to": "1234567890",
@@ -251,6 +363,35 @@ func TestCreateScheduledMessage_InvalidType(t *testing.T) {
 	}
 }
 
+// Test: Create scheduled message from a Messaging Service of another account
+func TestCreateScheduledMessage_UnknownMessagingService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockRepository(ctrl)
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	service := schedules.NewService(mockRepo)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
+
+	body := []byte(fmt.Sprintf(`{
+		"to": "1234567890",
+		"content": "Test message",
+		"send_at": %q,
+		"type": "freeform",
+		"messaging_service_sid": "MG999"
+	}`, time.Now().Add(time.Hour).Format(time.RFC3339)))
+
+	httpReq := httptest.NewRequest("POST", "/scheduled-messages", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateScheduledMessage(w, httpReq)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
 // Test: Create scheduled message with invalid JSON
 func TestCreateScheduledMessage_InvalidJSON(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -260,7 +401,7 @@ func TestCreateScheduledMessage_InvalidJSON(t *testing.T) {
 	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	body := []byte(`{invalid json}`)
 	httpReq := httptest.NewRequest("POST", "/scheduled-messages", bytes.NewReader(body))
@@ -285,7 +426,7 @@ func TestCreateScheduledMessage_RepositoryError(t *testing.T) {
 		Times(1)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	futureTime := time.Now().Add(1 * time.Hour)
 	req := CreateScheduledMessageRequest{
@@ -331,7 +472,7 @@ func TestGetScheduledMessage_Success(t *testing.T) {
 		Times(1)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	httpReq := httptest.NewRequest("GET", fmt.Sprintf("/scheduled-messages/%s", msgId), nil)
 	httpReq.SetPathValue("id", msgId.String())
@@ -367,7 +508,7 @@ func TestGetScheduledMessage_NotFound(t *testing.T) {
 		Times(1)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	httpReq := httptest.NewRequest("GET", fmt.Sprintf("/scheduled-messages/%s", fakeId), nil)
 	httpReq.SetPathValue("id", fakeId.String())
@@ -390,7 +531,7 @@ func TestGetScheduledMessage_InvalidId(t *testing.T) {
 	mockRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).Times(0)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	httpReq := httptest.NewRequest("GET", "/scheduled-messages/invalid-id", nil)
 	httpReq.SetPathValue("id", "invalid-id")
@@ -412,7 +553,7 @@ func TestGetScheduledMessage_EmptyId(t *testing.T) {
 	mockRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).Times(0)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	httpReq := httptest.NewRequest("GET", "/scheduled-messages/", nil)
 	httpReq.SetPathValue("id", "")
@@ -439,7 +580,7 @@ func TestGetScheduledMessage_RepositoryError(t *testing.T) {
 		Times(1)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	httpReq := httptest.NewRequest("GET", fmt.Sprintf("/scheduled-messages/%s", msgId), nil)
 	httpReq.SetPathValue("id", msgId.String())
@@ -477,7 +618,7 @@ func TestGetScheduledMessage_TemplateMessage(t *testing.T) {
 		Times(1)
 
 	service := schedules.NewService(mockRepo)
-	handler := NewScheduledMessageHandler(service)
+	handler := NewScheduledMessageHandler(service, newContactResolver(ctrl), newSenders())
 
 	httpReq := httptest.NewRequest("GET", fmt.Sprintf("/scheduled-messages/%s", msgId), nil)
 	httpReq.SetPathValue("id", msgId.String())
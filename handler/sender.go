@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/senders"
+	"net/http"
+)
+
+type SenderHandler struct {
+	senders *senders.Service
+}
+
+func NewSenderHandler(senderService *senders.Service) *SenderHandler {
+	return &SenderHandler{
+		senders: senderService,
+	}
+}
+
+// writeSenderError writes the response for an error from the senders service
+func writeSenderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, senders.ErrInvalidSender):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, senders.ErrDuplicate), errors.Is(err, senders.ErrTaken):
+		writeError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, senders.ErrNotFound):
+		writeError(w, "Sender number not found", http.StatusNotFound)
+	default:
+		slog.Error("Sender number operation failed", "error", err)
+		writeError(w, "Failed to save sender number", http.StatusInternalServerError)
+	}
+}
+
+// ListSenders handles GET /senders, listing the pool on top of the tenant's
+// own number
+func (h *SenderHandler) ListSenders(w http.ResponseWriter, r *http.Request) {
+	numbers, err := h.senders.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list sender numbers", "error", err)
+		writeError(w, "Failed to list sender numbers", http.StatusInternalServerError)
+		return
+	}
+	if numbers == nil {
+		numbers = []senders.Number{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(numbers)
+}
+
+// AddSender handles POST /senders
+func (h *SenderHandler) AddSender(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Number string `json:"number"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	number, err := h.senders.Add(r.Context(), req.Number)
+	if err != nil {
+		writeSenderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(number)
+}
+
+// RemoveSender handles DELETE /senders/{number}
+func (h *SenderHandler) RemoveSender(w http.ResponseWriter, r *http.Request) {
+	if err := h.senders.Remove(r.Context(), r.PathValue("number")); err != nil {
+		writeSenderError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"mbx/history"
+	"mbx/tenants"
+	"mbx/tracing"
+	"mbx/webhooks"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type StatusHandler struct {
+	history   history.Repository
+	webhooks  *webhooks.Service
+	signature twilioSignature
+}
+
+// NewStatusHandler creates the handler of Twilio status callbacks, validating
+// signatures like NewInboundHandler. Callbacks belong to the tenant of the
+// number the message was sent from.
+func NewStatusHandler(historyRepo history.Repository, webhookService *webhooks.Service, tenantService *tenants.Service, publicURL string) *StatusHandler {
+	return &StatusHandler{
+		history:   historyRepo,
+		webhooks:  webhookService,
+		signature: newTwilioSignature(tenantService, publicURL),
+	}
+}
+
+// ReceiveStatus handles POST /callbacks/twilio/status
+func (h *StatusHandler) ReceiveStatus(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "Invalid form payload", http.StatusBadRequest)
+		return
+	}
+	scoped, err := h.signature.verify(r, r.PostForm.Get("From"))
+	if errors.Is(err, errInvalidSignature) {
+		slog.Warn("Rejected status callback with invalid signature", "remote_addr", r.RemoteAddr)
+		writeError(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to resolve the tenant of a status callback", "error", err)
+		writeError(w, "Failed to process status callback", http.StatusInternalServerError)
+		return
+	}
+	r = scoped
+
+	sid := r.PostForm.Get("MessageSid")
+	status := r.PostForm.Get("MessageStatus")
+	if sid == "" || status == "" {
+		writeError(w, "Missing 'MessageSid' or 'MessageStatus' parameter", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.history.FindByProviderSid(r.Context(), sid)
+	if err != nil {
+		slog.Error("Failed to fetch message", "error", err, "sid", sid)
+		writeError(w, "Failed to process status callback", http.StatusInternalServerError)
+		return
+	}
+
+	change := webhooks.StatusChange{
+		Sid:       sid,
+		Phone:     r.PostForm.Get("To"),
+		Status:    status,
+		ErrorCode: r.PostForm.Get("ErrorCode"),
+		ChangedAt: time.Now(),
+	}
+	if message != nil {
+		if !history.StatusAdvances(message.Status, status) {
+			// a late callback for a status the message already went past
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err := h.history.UpdateStatus(r.Context(), sid, status); err != nil {
+			slog.Error("Failed to update message status", "error", err, "sid", sid)
+			writeError(w, "Failed to process status callback", http.StatusInternalServerError)
+			return
+		}
+		change.MessageId = &message.Id
+		change.ContactId = message.ContactId
+		change.Phone = message.Phone
+
+		// the callback has a trace of its own, linked to the send
+		if sc := tracing.SpanContext(message.TraceParent); sc.IsValid() {
+			trace.SpanFromContext(r.Context()).AddLink(trace.Link{SpanContext: sc})
+		}
+		if status == "failed" || status == "undelivered" {
+			slog.Warn("Message was not delivered", "sid", sid, "status", status, "error_code", change.ErrorCode, "trace_parent", message.TraceParent)
+		}
+	}
+
+	if eventType, ok := webhooks.StatusEvent(status); ok {
+		if err := h.webhooks.Publish(r.Context(), eventType, change); err != nil {
+			slog.Error("Failed to publish status event", "error", err, "sid", sid, "status", status)
+			writeError(w, "Failed to process status callback", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
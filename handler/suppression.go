@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/consent"
+	"mbx/contacts"
+	"mbx/optout"
+	"mbx/sender"
+	"mbx/senders"
+	"mbx/throttle"
+	"mbx/window"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type SuppressionHandler struct {
+	optout   *optout.Service
+	contacts *contacts.Service
+}
+
+func NewSuppressionHandler(optoutService *optout.Service, contactService *contacts.Service) *SuppressionHandler {
+	return &SuppressionHandler{
+		optout:   optoutService,
+		contacts: contactService,
+	}
+}
+
+// ListSuppressions handles GET /suppressions
+func (h *SuppressionHandler) ListSuppressions(w http.ResponseWriter, r *http.Request) {
+	suppressions, err := h.optout.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list suppressions", "error", err)
+		writeError(w, "Failed to list suppressions", http.StatusInternalServerError)
+		return
+	}
+	if suppressions == nil {
+		suppressions = []optout.Suppression{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suppressions)
+}
+
+// CreateSuppression handles POST /suppressions
+func (h *SuppressionHandler) CreateSuppression(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Phone string `json:"phone"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	contact, err := h.contacts.Resolve(r.Context(), req.Phone)
+	if err != nil {
+		writeRecipientError(w, err)
+		return
+	}
+
+	suppression := optout.Suppression{
+		Phone:     contact.Phone,
+		ContactId: &contact.Id,
+		Reason:    optout.ReasonManual,
+		CreatedAt: time.Now(),
+	}
+	if err := h.optout.Suppress(r.Context(), suppression); err != nil {
+		slog.Error("Failed to suppress number", "error", err, "phone", contact.Phone)
+		writeError(w, "Failed to suppress number", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(suppression)
+}
+
+// DeleteSuppression handles DELETE /suppressions/{phone}
+func (h *SuppressionHandler) DeleteSuppression(w http.ResponseWriter, r *http.Request) {
+	phone, err := h.contacts.Normalize(r.PathValue("phone"))
+	if err != nil {
+		writeError(w, "Invalid phone number", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.optout.Resubscribe(r.Context(), phone); err != nil {
+		slog.Error("Failed to remove suppression", "error", err, "phone", phone)
+		writeError(w, "Failed to remove suppression", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeSendError writes the response for an error returned by a send path
+func writeSendError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, optout.ErrSuppressed):
+		writeAPIError(w, http.StatusUnprocessableEntity, APIError{Code: CodeRecipientOptedOut, Message: "Recipient has opted out"})
+		return
+	case errors.Is(err, consent.ErrNoConsent):
+		writeAPIError(w, http.StatusUnprocessableEntity, APIError{Code: CodeConsentRequired, Message: "Recipient has not consented to marketing messages"})
+		return
+	case errors.Is(err, window.ErrWindowClosed):
+		writeAPIError(w, http.StatusUnprocessableEntity, APIError{Code: CodeOutsideWindow, Message: "Outside the 24-hour customer service window, send a template instead"})
+		return
+	case errors.Is(err, sender.ErrRejected):
+		writeAPIError(w, http.StatusUnprocessableEntity, APIError{Code: CodeMessageRejected, Message: "Message rejected"})
+		return
+	case errors.Is(err, throttle.ErrTierLimit):
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(throttle.NextDay(time.Now())).Seconds())+1))
+		writeAPIError(w, http.StatusTooManyRequests, APIError{Code: CodeTierLimit, Message: "Sender reached the daily recipient limit of its messaging tier"})
+		return
+	case errors.Is(err, senders.ErrInvalidSender), errors.Is(err, senders.ErrUnknownSender):
+		writeAPIError(w, http.StatusBadRequest, APIError{Code: CodeInvalidSender, Message: err.Error()})
+		return
+	case errors.Is(err, throttle.ErrThrottled):
+		w.Header().Set("Retry-After", "1")
+		writeAPIError(w, http.StatusTooManyRequests, APIError{Code: CodeThrottled, Message: "Too many messages queued for the sender, try again later"})
+		return
+	}
+
+	writeProviderError(w, err, message)
+}
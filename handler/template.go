@@ -2,7 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"mbx/contacts"
 	"mbx/provider/twilio"
 	"mbx/sender"
 	"mbx/templates"
@@ -11,22 +13,25 @@ import (
 )
 
 type TemplateHandler struct {
-	sender  sender.WhatsappTemplate
-	fetcher twilio.WhatsappFetcher
+	sender   sender.WhatsappTemplate
+	fetcher  twilio.WhatsappFetcher
+	resolver templates.GroupResolver
+	contacts contacts.Resolver
 }
 
-func NewTemplateHandler(whatsapp sender.WhatsappTemplate, fetcher twilio.WhatsappFetcher) *TemplateHandler {
+func NewTemplateHandler(whatsapp sender.WhatsappTemplate, fetcher twilio.WhatsappFetcher, resolver templates.GroupResolver, contacts contacts.Resolver) *TemplateHandler {
 	return &TemplateHandler{
-		sender:  whatsapp,
-		fetcher: fetcher,
+		sender:   whatsapp,
+		fetcher:  fetcher,
+		resolver: resolver,
+		contacts: contacts,
 	}
 }
 
 func (h *TemplateHandler) ListMessagingServices(w http.ResponseWriter, r *http.Request) {
 	services, err := h.fetcher.ListMessagingServices(r.Context())
 	if err != nil {
-		slog.Error("Failed to retrieve messaging services", "error", err)
-		http.Error(w, "Failed to retrieve messaging services", http.StatusInternalServerError)
+		writeProviderError(w, err, "Failed to retrieve messaging services")
 		return
 	}
 
@@ -34,7 +39,7 @@ func (h *TemplateHandler) ListMessagingServices(w http.ResponseWriter, r *http.R
 	err = json.NewEncoder(w).Encode(services)
 	if err != nil {
 		slog.Error("Failed to encode messaging services response", "error", err)
-		http.Error(w, "Failed to encode messaging services response", http.StatusInternalServerError)
+		writeError(w, "Failed to encode messaging services response", http.StatusInternalServerError)
 		return
 	}
 }
@@ -42,8 +47,7 @@ func (h *TemplateHandler) ListMessagingServices(w http.ResponseWriter, r *http.R
 func (h *TemplateHandler) GetScheduledMessages(w http.ResponseWriter, r *http.Request) {
 	messages, err := h.fetcher.GetScheduledMessages(r.Context(), time.Now())
 	if err != nil {
-		slog.Error("Failed to retrieve scheduled messages", "error", err)
-		http.Error(w, "Failed to retrieve scheduled messages", http.StatusInternalServerError)
+		writeProviderError(w, err, "Failed to retrieve scheduled messages")
 		return
 	}
 
@@ -51,7 +55,7 @@ func (h *TemplateHandler) GetScheduledMessages(w http.ResponseWriter, r *http.Re
 	err = json.NewEncoder(w).Encode(messages)
 	if err != nil {
 		slog.Error("Failed to encode scheduled messages response", "error", err)
-		http.Error(w, "Failed to encode scheduled messages response", http.StatusInternalServerError)
+		writeError(w, "Failed to encode scheduled messages response", http.StatusInternalServerError)
 		return
 	}
 }
@@ -59,28 +63,57 @@ func (h *TemplateHandler) GetScheduledMessages(w http.ResponseWriter, r *http.Re
 func (h *TemplateHandler) Send(w http.ResponseWriter, r *http.Request) {
 	var req templates.WhatsappTemplateDTO
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	slog.Info("Received template message request", "to", req.To, "template_id", req.TemplateId, "content", req.Content, "language", req.Language)
+	slog.Info("Received template message request", "to", req.To, "template_id", req.TemplateId, "template_name", req.TemplateName, "content", req.Content, "language", req.Language, "locale", req.Locale)
 
 	// Validate required fields
-	if req.TemplateId == "" {
-		http.Error(w, "Template ID cannot be empty", http.StatusBadRequest)
+	if req.TemplateId == "" && req.TemplateName == "" {
+		writeError(w, "Template ID or template name cannot be empty", http.StatusBadRequest)
 		return
 	}
-	if req.To == "" {
-		http.Error(w, "Recipient number cannot be empty", http.StatusBadRequest)
+	if req.To == "" && req.ContactId == nil {
+		writeError(w, "Recipient number cannot be empty", http.StatusBadRequest)
 		return
 	}
 
+	contact, err := resolveRecipient(r.Context(), h.contacts, req.To, req.ContactId)
+	if err != nil {
+		writeRecipientError(w, err)
+		return
+	}
+	req.To = contact.Phone
+	if req.Locale == "" {
+		req.Locale = contact.Locale
+	}
+
+	if req.TemplateName != "" {
+		resolved, err := h.resolver.Resolve(r.Context(), req.TemplateName, req.Locale)
+		if errors.Is(err, templates.ErrGroupNotFound) {
+			writeError(w, "Template group not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, templates.ErrNoVariant) {
+			writeError(w, "No template variant for locale", http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to resolve template group", "error", err, "template_name", req.TemplateName)
+			writeError(w, "Failed to resolve template group", http.StatusInternalServerError)
+			return
+		}
+		req.TemplateId = resolved.ContentSid
+		req.Language = resolved.Language
+	}
+
 	var contentStr string
 	if len(req.Content) > 0 {
 		contentJSON, err := json.Marshal(req.Content)
 		if err != nil {
 			slog.Error("Failed to marshal content variables", "error", err)
-			http.Error(w, "Invalid content variables", http.StatusBadRequest)
+			writeError(w, "Invalid content variables", http.StatusBadRequest)
 			return
 		}
 		contentStr = string(contentJSON)
@@ -93,12 +126,12 @@ func (h *TemplateHandler) Send(w http.ResponseWriter, r *http.Request) {
 		TemplateId: req.TemplateId,
 		Content:    contentStr,
 		Language:   req.Language,
+		From:       req.From,
 	}
 
 	msgResponse, err := h.sender.SendTemplate(r.Context(), whatsappTemplate)
 	if err != nil {
-		slog.Error("Failed to send template message", "error", err)
-		http.Error(w, "Failed to send template message", http.StatusInternalServerError)
+		writeSendError(w, err, "Failed to send template message")
 		return
 	}
 
@@ -107,7 +140,7 @@ func (h *TemplateHandler) Send(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(msgResponse)
 	if err != nil {
 		slog.Error("Failed to encode template message response", "error", err)
-		http.Error(w, "Failed to encode template message response", http.StatusInternalServerError)
+		writeError(w, "Failed to encode template message response", http.StatusInternalServerError)
 		return
 	}
 }
@@ -115,8 +148,7 @@ func (h *TemplateHandler) Send(w http.ResponseWriter, r *http.Request) {
 func (h *TemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
 	templates, err := h.fetcher.GetTemplates(r.Context())
 	if err != nil {
-		slog.Error("Failed to retrieve templates", "error", err)
-		http.Error(w, "Failed to retrieve templates", http.StatusInternalServerError)
+		writeProviderError(w, err, "Failed to retrieve templates")
 		return
 	}
 
@@ -124,7 +156,7 @@ func (h *TemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(templates)
 	if err != nil {
 		slog.Error("Failed to encode templates response", "error", err)
-		http.Error(w, "Failed to encode templates response", http.StatusInternalServerError)
+		writeError(w, "Failed to encode templates response", http.StatusInternalServerError)
 		return
 	}
 }
@@ -133,29 +165,28 @@ func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request)
 	var req templates.CreateTemplateDTO
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		slog.Error("Failed to decode create template request", "error", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
 	// Validate required fields
 	if req.FriendlyName == "" {
-		http.Error(w, "Friendly name cannot be empty", http.StatusBadRequest)
+		writeFieldError(w, "friendly_name", "Friendly name cannot be empty")
 		return
 	}
 	if req.Language == "" {
-		http.Error(w, "Language cannot be empty", http.StatusBadRequest)
+		writeFieldError(w, "language", "Language cannot be empty")
 		return
 	}
 	if req.Body == "" {
-		http.Error(w, "Body cannot be empty", http.StatusBadRequest)
+		writeFieldError(w, "body", "Body cannot be empty")
 		return
 	}
 
 	// Create the template
 	createdTemplate, err := h.sender.CreateTemplate(r.Context(), req)
 	if err != nil {
-		slog.Error("Failed to create template", "error", err)
-		http.Error(w, "Failed to create template: "+err.Error(), http.StatusInternalServerError)
+		writeProviderError(w, err, "Failed to create template")
 		return
 	}
 
@@ -163,7 +194,7 @@ func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(createdTemplate); err != nil {
 		slog.Error("Failed to encode template response", "error", err)
-		http.Error(w, "Failed to encode template response", http.StatusInternalServerError)
+		writeError(w, "Failed to encode template response", http.StatusInternalServerError)
 		return
 	}
 }
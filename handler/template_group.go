@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/templates"
+	"net/http"
+	"time"
+)
+
+type TemplateGroupHandler struct {
+	groups *templates.GroupService
+}
+
+func NewTemplateGroupHandler(groups *templates.GroupService) *TemplateGroupHandler {
+	return &TemplateGroupHandler{
+		groups: groups,
+	}
+}
+
+// CreateTemplateGroupRequest represents the request payload for creating a template group
+type CreateTemplateGroupRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Variants    []templates.Variant `json:"variants"`
+}
+
+// ListGroups handles GET /templates/groups
+func (h *TemplateGroupHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.groups.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to retrieve template groups", "error", err)
+		writeError(w, "Failed to retrieve template groups", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// CreateGroup handles POST /templates/groups
+func (h *TemplateGroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req CreateTemplateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Failed to decode create template group request", "error", err)
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		writeError(w, "Group name cannot be empty", http.StatusBadRequest)
+		return
+	}
+	for _, v := range req.Variants {
+		if v.Language == "" || v.ContentSid == "" {
+			writeError(w, "Variants require a language and a content SID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	group := templates.Group{
+		Name:        req.Name,
+		Description: req.Description,
+		Variants:    req.Variants,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := h.groups.Create(r.Context(), group); err != nil {
+		slog.Error("Failed to create template group", "error", err, "name", req.Name)
+		writeError(w, "Failed to create template group", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(group)
+}
+
+// GetGroup handles GET /templates/groups/{name}
+func (h *TemplateGroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	group, err := h.groups.FindByName(r.Context(), name)
+	if err != nil {
+		slog.Error("Failed to fetch template group", "error", err, "name", name)
+		writeError(w, "Failed to fetch template group", http.StatusInternalServerError)
+		return
+	}
+	if group == nil {
+		writeError(w, "Template group not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// PutVariant handles PUT /templates/groups/{name}/variants/{language}
+func (h *TemplateGroupHandler) PutVariant(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		ContentSid string `json:"content_sid"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.ContentSid == "" {
+		writeError(w, "Content SID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("name")
+	variant := templates.Variant{
+		Language:   r.PathValue("language"),
+		ContentSid: req.ContentSid,
+	}
+
+	err := h.groups.UpsertVariant(r.Context(), name, variant)
+	if errors.Is(err, templates.ErrGroupNotFound) {
+		writeError(w, "Template group not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to save template variant", "error", err, "name", name, "language", variant.Language)
+		writeError(w, "Failed to save template variant", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteVariant handles DELETE /templates/groups/{name}/variants/{language}
+func (h *TemplateGroupHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	name, language := r.PathValue("name"), r.PathValue("language")
+
+	err := h.groups.DeleteVariant(r.Context(), name, language)
+	if errors.Is(err, templates.ErrNoVariant) {
+		writeError(w, "Template variant not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to delete template variant", "error", err, "name", name, "language", language)
+		writeError(w, "Failed to delete template variant", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Resolve handles GET /templates/groups/{name}/resolve?locale=pt_PT
+func (h *TemplateGroupHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	locale := r.URL.Query().Get("locale")
+
+	resolved, err := h.groups.Resolve(r.Context(), name, locale)
+	if errors.Is(err, templates.ErrGroupNotFound) {
+		writeError(w, "Template group not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, templates.ErrNoVariant) {
+		writeError(w, "No template variant for locale", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to resolve template group", "error", err, "name", name, "locale", locale)
+		writeError(w, "Failed to resolve template group", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resolved)
+}
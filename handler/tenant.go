@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/apikeys"
+	"mbx/tenants"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// TenantHandler manages tenants. Only admin keys of the default tenant, which
+// operates the deployment, may use it.
+type TenantHandler struct {
+	tenants *tenants.Service
+	keys    *apikeys.Service
+}
+
+func NewTenantHandler(tenantService *tenants.Service, keyService *apikeys.Service) *TenantHandler {
+	return &TenantHandler{
+		tenants: tenantService,
+		keys:    keyService,
+	}
+}
+
+// TenantRequest represents the payload for creating or updating a tenant. On
+// update, only the fields that are present are changed. Every tenant but the
+// default one needs an account of its own.
+type TenantRequest struct {
+	Name       *string `json:"name,omitempty"`
+	AccountSid *string `json:"account_sid,omitempty"`
+	AuthToken  *string `json:"auth_token,omitempty"`
+	FromNumber *string `json:"from_number,omitempty"`
+}
+
+func (req TenantRequest) apply(tenant *tenants.Tenant) {
+	if req.Name != nil {
+		tenant.Name = *req.Name
+	}
+	if req.AccountSid != nil {
+		tenant.AccountSid = *req.AccountSid
+	}
+	if req.AuthToken != nil {
+		tenant.AuthToken = *req.AuthToken
+	}
+	if req.FromNumber != nil {
+		tenant.FromNumber = *req.FromNumber
+	}
+}
+
+// writeTenantError writes the response for an error from the tenants service
+func writeTenantError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, tenants.ErrInvalidTenant):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, tenants.ErrDuplicate):
+		writeError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, tenants.ErrNotFound):
+		writeError(w, "Tenant not found", http.StatusNotFound)
+	default:
+		slog.Error("Tenant operation failed", "error", err)
+		writeError(w, "Failed to save tenant", http.StatusInternalServerError)
+	}
+}
+
+// operator reports whether the request was made by the default tenant,
+// writing a 403 otherwise
+func operator(w http.ResponseWriter, r *http.Request) bool {
+	if tenants.FromContext(r.Context()) != tenants.DefaultId {
+		writeError(w, "Only the default tenant can manage tenants", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// ListTenants handles GET /tenants
+func (h *TenantHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	if !operator(w, r) {
+		return
+	}
+
+	list, err := h.tenants.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list tenants", "error", err)
+		writeError(w, "Failed to list tenants", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []tenants.Tenant{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateTenant handles POST /tenants
+func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	if !operator(w, r) {
+		return
+	}
+
+	var req TenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	var tenant tenants.Tenant
+	req.apply(&tenant)
+
+	created, err := h.tenants.Create(r.Context(), tenant)
+	if err != nil {
+		writeTenantError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetTenant handles GET /tenants/{id}
+func (h *TenantHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	tenant := h.tenantFromPath(w, r)
+	if tenant == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tenant)
+}
+
+// UpdateTenant handles PATCH /tenants/{id}
+func (h *TenantHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	tenant := h.tenantFromPath(w, r)
+	if tenant == nil {
+		return
+	}
+
+	var req TenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	// a new account with the old token, or the other way around, can't work
+	if (req.AccountSid != nil) != (req.AuthToken != nil) {
+		writeError(w, "account_sid and auth_token must change together", http.StatusBadRequest)
+		return
+	}
+	req.apply(tenant)
+
+	updated, err := h.tenants.Update(r.Context(), *tenant)
+	if err != nil {
+		writeTenantError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// CreateTenantAPIKey handles POST /tenants/{id}/api-keys, issuing the first
+// key of a tenant
+func (h *TenantHandler) CreateTenantAPIKey(w http.ResponseWriter, r *http.Request) {
+	tenant := h.tenantFromPath(w, r)
+	if tenant == nil {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	ctx := tenants.NewContext(r.Context(), tenant.Id)
+	key, token, err := h.keys.Create(ctx, req.Name, req.Scopes)
+	switch {
+	case errors.Is(err, apikeys.ErrInvalidName), errors.Is(err, apikeys.ErrInvalidScope):
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		slog.Error("Failed to create API key", "error", err, "tenant_id", tenant.Id)
+		writeError(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPIKeyResponse{Key: *key, Token: token})
+}
+
+// tenantFromPath loads the tenant named by the id path value, writing the
+// error response and returning nil when it can't
+func (h *TenantHandler) tenantFromPath(w http.ResponseWriter, r *http.Request) *tenants.Tenant {
+	if !operator(w, r) {
+		return nil
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid tenant ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	tenant, err := h.tenants.FindById(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch tenant", "error", err, "id", id)
+		writeError(w, "Failed to fetch tenant", http.StatusInternalServerError)
+		return nil
+	}
+	if tenant == nil {
+		writeError(w, "Tenant not found", http.StatusNotFound)
+		return nil
+	}
+	return tenant
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mbx/sender"
+	"mbx/tenants"
+	"mbx/tenants/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+func TestUpdateTenant_CredentialsChangeTogether(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	handler := NewTenantHandler(tenants.NewService(repo, sender.Config{}), nil)
+
+	brand := tenants.Tenant{Id: uuid.New(), Name: "Brand", AccountSid: "ACsub", AuthToken: "sub-token", FromNumber: "+15550001"}
+	repo.EXPECT().FindById(gomock.Any(), brand.Id).DoAndReturn(func(_, _ any) (*tenants.Tenant, error) {
+		tenant := brand
+		return &tenant, nil
+	}).AnyTimes()
+
+	update := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/tenants/"+brand.Id.String(), strings.NewReader(body))
+		req.SetPathValue("id", brand.Id.String())
+		rec := httptest.NewRecorder()
+		handler.UpdateTenant(rec, req)
+		return rec
+	}
+
+	for _, body := range []string{`{"account_sid":"ACother"}`, `{"auth_token":"rotated"}`} {
+		if rec := update(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	if rec := update(`{"account_sid":"ACother","auth_token":"other-token"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mbx/webhooks"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	webhooks *webhooks.Service
+}
+
+func NewWebhookHandler(webhookService *webhooks.Service) *WebhookHandler {
+	return &WebhookHandler{
+		webhooks: webhookService,
+	}
+}
+
+// WebhookRequest represents the payload for registering or updating an
+// endpoint. On update, only the fields that are present are changed. A secret
+// is generated when none is given on registration.
+type WebhookRequest struct {
+	URL    *string               `json:"url,omitempty"`
+	Secret *string               `json:"secret,omitempty"`
+	Events *[]webhooks.EventType `json:"events,omitempty"`
+	Active *bool                 `json:"active,omitempty"`
+}
+
+func (req WebhookRequest) apply(endpoint *webhooks.Endpoint) {
+	if req.URL != nil {
+		endpoint.URL = *req.URL
+	}
+	if req.Secret != nil {
+		endpoint.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		endpoint.Events = *req.Events
+	}
+	if req.Active != nil {
+		endpoint.Active = *req.Active
+	}
+}
+
+// ReplayRequest represents the payload for replaying an event. Without an
+// endpoint, the event goes to every endpoint subscribed to it.
+type ReplayRequest struct {
+	EndpointId *uuid.UUID `json:"endpoint_id,omitempty"`
+}
+
+// DeliveryLog is a delivery with the log of its attempts
+type DeliveryLog struct {
+	webhooks.Delivery
+	Log []webhooks.Attempt `json:"log"`
+}
+
+// writeWebhookError writes the response for an error from the webhooks
+// service
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrInvalidEndpoint):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, webhooks.ErrNotFound):
+		writeError(w, "Webhook endpoint not found", http.StatusNotFound)
+	case errors.Is(err, webhooks.ErrEventNotFound):
+		writeError(w, "Webhook event not found", http.StatusNotFound)
+	default:
+		slog.Error("Webhook operation failed", "error", err)
+		writeError(w, "Failed to save webhook", http.StatusInternalServerError)
+	}
+}
+
+// ListWebhooks handles GET /webhooks
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.webhooks.ListEndpoints(r.Context())
+	if err != nil {
+		slog.Error("Failed to list webhooks", "error", err)
+		writeError(w, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+	if endpoints == nil {
+		endpoints = []webhooks.Endpoint{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(endpoints)
+}
+
+// CreateWebhook handles POST /webhooks
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	endpoint := webhooks.Endpoint{Active: true}
+	req.apply(&endpoint)
+
+	created, err := h.webhooks.CreateEndpoint(r.Context(), endpoint)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetWebhook handles GET /webhooks/{id}
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint := h.endpointFromPath(w, r)
+	if endpoint == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(endpoint)
+}
+
+// UpdateWebhook handles PATCH /webhooks/{id}
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint := h.endpointFromPath(w, r)
+	if endpoint == nil {
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.apply(endpoint)
+
+	updated, err := h.webhooks.UpdateEndpoint(r.Context(), *endpoint)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteWebhook handles DELETE /webhooks/{id}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid webhook ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.webhooks.DeleteEndpoint(r.Context(), id); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries handles GET /webhooks/{id}/deliveries?limit=50
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	endpoint := h.endpointFromPath(w, r)
+	if endpoint == nil {
+		return
+	}
+	limit, ok := limitFromQuery(w, r, 50)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhooks.Deliveries(r.Context(), endpoint.Id, limit)
+	if err != nil {
+		slog.Error("Failed to list webhook deliveries", "error", err, "endpoint_id", endpoint.Id)
+		writeError(w, "Failed to list webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	logs := make([]DeliveryLog, 0, len(deliveries))
+	for _, d := range deliveries {
+		attempts, err := h.webhooks.Attempts(r.Context(), d.Id)
+		if err != nil {
+			slog.Error("Failed to list webhook attempts", "error", err, "delivery_id", d.Id)
+			writeError(w, "Failed to list webhook deliveries", http.StatusInternalServerError)
+			return
+		}
+		if attempts == nil {
+			attempts = []webhooks.Attempt{}
+		}
+		logs = append(logs, DeliveryLog{Delivery: d, Log: attempts})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logs)
+}
+
+// ListEvents handles GET /webhook-events?limit=100
+func (h *WebhookHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	limit, ok := limitFromQuery(w, r, 100)
+	if !ok {
+		return
+	}
+
+	events, err := h.webhooks.Events(r.Context(), limit)
+	if err != nil {
+		slog.Error("Failed to list webhook events", "error", err)
+		writeError(w, "Failed to list webhook events", http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []webhooks.Event{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// ReplayEvent handles POST /webhook-events/{id}/replay
+func (h *WebhookHandler) ReplayEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid event ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req ReplayRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	deliveries, err := h.webhooks.Replay(r.Context(), id, req.EndpointId)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func (h *WebhookHandler) endpointFromPath(w http.ResponseWriter, r *http.Request) *webhooks.Endpoint {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, "Invalid webhook ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	endpoint, err := h.webhooks.FindEndpoint(r.Context(), id)
+	if err != nil {
+		slog.Error("Failed to fetch webhook", "error", err, "id", id)
+		writeError(w, "Failed to fetch webhook", http.StatusInternalServerError)
+		return nil
+	}
+	if endpoint == nil {
+		writeError(w, "Webhook endpoint not found", http.StatusNotFound)
+		return nil
+	}
+	return endpoint
+}
+
+// limitFromQuery reads the limit query parameter, writing the error response
+// and returning false when it is invalid
+func limitFromQuery(w http.ResponseWriter, r *http.Request, fallback int) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return fallback, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > 500 {
+		writeError(w, "Invalid 'limit' value", http.StatusBadRequest)
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mbx/contacts"
+	"mbx/window"
+	"net/http"
+)
+
+type WindowHandler struct {
+	window   *window.Service
+	contacts *contacts.Service
+}
+
+func NewWindowHandler(windowService *window.Service, contactService *contacts.Service) *WindowHandler {
+	return &WindowHandler{
+		window:   windowService,
+		contacts: contactService,
+	}
+}
+
+// GetWindow handles GET /contacts/{id}/window
+func (h *WindowHandler) GetWindow(w http.ResponseWriter, r *http.Request) {
+	contact := contactFromPath(w, r, h.contacts)
+	if contact == nil {
+		return
+	}
+
+	state, err := h.window.State(r.Context(), contact.Id)
+	if err != nil {
+		slog.Error("Failed to compute customer service window", "error", err, "contact_id", contact.Id)
+		writeError(w, "Failed to compute customer service window", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Direction string
+
+const (
+	DirectionOutbound Direction = "outbound"
+	DirectionInbound  Direction = "inbound"
+)
+
+type InteractionType string
+
+const (
+	InteractionButton InteractionType = "button"
+	InteractionList   InteractionType = "list"
+)
+
+// Interaction is a tap on a quick reply button or a list picker item, which
+// WhatsApp delivers as a reply to the message that offered it
+type Interaction struct {
+	Type InteractionType `json:"type"`
+	// Payload is the ID of the button or list item
+	Payload string `json:"payload"`
+	// Text is the title the customer saw
+	Text string `json:"text,omitempty"`
+	// RepliedToSid is the provider SID of the message that was answered, and
+	// RepliedToId its ID in the history when it was sent by us
+	RepliedToSid string     `json:"replied_to_sid,omitempty"`
+	RepliedToId  *uuid.UUID `json:"replied_to_id,omitempty"`
+}
+
+// Message is a message exchanged with a contact, kept so the full history of
+// a customer can be read without going back to the provider.
+type Message struct {
+	Id          uuid.UUID  `json:"id"`
+	ContactId   *uuid.UUID `json:"contact_id,omitempty"`
+	Direction   Direction  `json:"direction"`
+	Phone       string     `json:"phone"`
+	Body        string     `json:"body,omitempty"`
+	TemplateId  string     `json:"template_id,omitempty"`
+	ProviderSid string     `json:"provider_sid,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	// Interaction is set on inbound button and list replies
+	Interaction *Interaction `json:"interaction,omitempty"`
+	// Sender is our number the message went out from or came in to, or the
+	// Messaging Service it went out through when Twilio had not picked a
+	// number yet
+	Sender    string    `json:"sender,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// TraceParent is the W3C traceparent of the send, so status callbacks
+	// about the message can be linked back to it
+	TraceParent string `json:"-"`
+}
+
+type Repository interface {
+	Record(context.Context, Message) error
+	ListByContact(ctx context.Context, contactId uuid.UUID, limit int) ([]Message, error)
+	// FindByProviderSid returns the message with the provider SID, or nil
+	FindByProviderSid(ctx context.Context, sid string) (*Message, error)
+	// UpdateStatus sets the status of the message with the provider SID
+	UpdateStatus(ctx context.Context, sid string, status string) error
+	// LastMessageAt returns when the latest message in the direction was
+	// exchanged with the contact, or nil if there is none
+	LastMessageAt(ctx context.Context, contactId uuid.UUID, direction Direction) (*time.Time, error)
+}
+
+// statusOrder ranks the statuses the provider reports for outbound messages
+var statusOrder = map[string]int{
+	"accepted":    1,
+	"scheduled":   1,
+	"queued":      2,
+	"sending":     3,
+	"sent":        4,
+	"delivered":   5,
+	"undelivered": 5,
+	"failed":      5,
+	"read":        6,
+	"canceled":    6,
+}
+
+// StatusAdvances reports whether a message in status from can move to status
+// to. Status callbacks may arrive out of order, and a late "sent" must not
+// overwrite "delivered".
+func StatusAdvances(from, to string) bool {
+	return statusOrder[to] > statusOrder[from] || statusOrder[from] == 0
+}
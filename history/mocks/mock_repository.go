@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: history/history.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	history "mbx/history"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindByProviderSid mocks base method.
+func (m *MockRepository) FindByProviderSid(ctx context.Context, sid string) (*history.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByProviderSid", ctx, sid)
+	ret0, _ := ret[0].(*history.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByProviderSid indicates an expected call of FindByProviderSid.
+func (mr *MockRepositoryMockRecorder) FindByProviderSid(ctx, sid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByProviderSid", reflect.TypeOf((*MockRepository)(nil).FindByProviderSid), ctx, sid)
+}
+
+// LastMessageAt mocks base method.
+func (m *MockRepository) LastMessageAt(ctx context.Context, contactId uuid.UUID, direction history.Direction) (*time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastMessageAt", ctx, contactId, direction)
+	ret0, _ := ret[0].(*time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastMessageAt indicates an expected call of LastMessageAt.
+func (mr *MockRepositoryMockRecorder) LastMessageAt(ctx, contactId, direction interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastMessageAt", reflect.TypeOf((*MockRepository)(nil).LastMessageAt), ctx, contactId, direction)
+}
+
+// ListByContact mocks base method.
+func (m *MockRepository) ListByContact(ctx context.Context, contactId uuid.UUID, limit int) ([]history.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByContact", ctx, contactId, limit)
+	ret0, _ := ret[0].([]history.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByContact indicates an expected call of ListByContact.
+func (mr *MockRepositoryMockRecorder) ListByContact(ctx, contactId, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByContact", reflect.TypeOf((*MockRepository)(nil).ListByContact), ctx, contactId, limit)
+}
+
+// Record mocks base method.
+func (m *MockRepository) Record(arg0 context.Context, arg1 history.Message) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockRepositoryMockRecorder) Record(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockRepository)(nil).Record), arg0, arg1)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockRepository) UpdateStatus(ctx context.Context, sid, status string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, sid, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockRepositoryMockRecorder) UpdateStatus(ctx, sid, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockRepository)(nil).UpdateStatus), ctx, sid, status)
+}
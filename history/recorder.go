@@ -0,0 +1,106 @@
+package history
+
+import (
+	"context"
+	"log/slog"
+	"mbx/contacts"
+	"mbx/models"
+	"mbx/sender"
+	"mbx/templates"
+	"mbx/tracing"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// RecordingSender wraps the provider senders and records every message that
+// was accepted by the provider, linked to the recipient's contact.
+type RecordingSender struct {
+	w        sender.Whatsapp
+	wt       sender.WhatsappTemplate
+	contacts contacts.Resolver
+	repo     Repository
+}
+
+var _ sender.Whatsapp = (*RecordingSender)(nil)
+var _ sender.WhatsappTemplate = (*RecordingSender)(nil)
+
+func NewRecordingSender(w sender.Whatsapp, wt sender.WhatsappTemplate, contacts contacts.Resolver, repo Repository) *RecordingSender {
+	return &RecordingSender{
+		w:        w,
+		wt:       wt,
+		contacts: contacts,
+		repo:     repo,
+	}
+}
+
+func (s *RecordingSender) Send(ctx context.Context, message models.WhatsappBody) (*api.ApiV2010Message, error) {
+	resp, err := s.w.Send(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, message.To, message.Body, "", resp)
+	return resp, nil
+}
+
+func (s *RecordingSender) SendTemplate(ctx context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	resp, err := s.wt.SendTemplate(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, template.To, template.Content, template.TemplateId, resp)
+	return resp, nil
+}
+
+func (s *RecordingSender) CreateTemplate(ctx context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return s.wt.CreateTemplate(ctx, dto)
+}
+
+func (s *RecordingSender) CancelMessage(ctx context.Context, twilioId string) error {
+	return s.w.CancelMessage(ctx, twilioId)
+}
+
+// record stores the sent message. The message already left, so failures are
+// only logged instead of being reported to the caller.
+func (s *RecordingSender) record(ctx context.Context, to string, body string, templateId string, resp *api.ApiV2010Message) {
+	message := Message{
+		Id:         uuid.New(),
+		Direction:  DirectionOutbound,
+		Phone:      strings.TrimPrefix(to, "whatsapp:"),
+		Body:       body,
+		TemplateId: templateId,
+		CreatedAt:  time.Now(),
+		// status callbacks for the message are linked back to this send
+		TraceParent: tracing.TraceParent(ctx),
+	}
+	if resp != nil {
+		if resp.Sid != nil {
+			message.ProviderSid = *resp.Sid
+		}
+		if resp.Status != nil {
+			message.Status = *resp.Status
+		}
+		switch {
+		case resp.From != nil && *resp.From != "":
+			message.Sender = strings.TrimPrefix(*resp.From, "whatsapp:")
+		case resp.MessagingServiceSid != nil:
+			message.Sender = *resp.MessagingServiceSid
+		}
+	}
+
+	contact, err := s.contacts.Resolve(ctx, message.Phone)
+	if err != nil {
+		slog.Warn("Failed to resolve contact for sent message", "error", err, "to", message.Phone)
+	} else {
+		message.Phone = contact.Phone
+		message.ContactId = &contact.Id
+	}
+
+	if err := s.repo.Record(ctx, message); err != nil {
+		slog.Error("Failed to record sent message", "error", err, "sid", message.ProviderSid)
+	}
+}
@@ -0,0 +1,58 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrMismatch is returned when a key is reused for a different request
+	ErrMismatch = errors.New("idempotency key was used for a different request")
+	// ErrInProgress is returned when the first request with a key has not
+	// finished yet
+	ErrInProgress = errors.New("a request with this idempotency key is in progress")
+	ErrInvalidKey = errors.New("invalid idempotency key")
+	// ErrClaimLost is returned when a request that held a key too long
+	// finishes after a retry took the key over
+	ErrClaimLost = errors.New("idempotency key was taken over by another request")
+)
+
+// Record is a request made with an idempotency key, and its response once it
+// finished. Keys are scoped to the tenant.
+type Record struct {
+	Key string
+	// Fingerprint is the hash of the method, path and body of the request
+	Fingerprint string
+	// Claim identifies the request holding the key, so only it can store
+	// its response or give the key up
+	Claim uuid.UUID
+	// Status is 0 while the request is in progress
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Done reports whether the response of the request was stored
+func (r *Record) Done() bool {
+	return r.Status != 0
+}
+
+type Repository interface {
+	// Claim stores record unless its key is already taken, returning the
+	// record holding the key instead. Expired records, and records whose
+	// request started before stale without finishing, give their key up.
+	Claim(ctx context.Context, record Record, stale time.Time) (*Record, error)
+	// Complete stores the response of the request holding key with claim,
+	// or returns ErrClaimLost when another request took the key over
+	Complete(ctx context.Context, key string, claim uuid.UUID, status int, contentType string, body []byte) error
+	// Release frees key so the request can be retried with it, unless
+	// another request took the key over
+	Release(ctx context.Context, key string, claim uuid.UUID) error
+	// DeleteExpired removes the records that expired by now
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
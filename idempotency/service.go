@@ -0,0 +1,99 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Config struct {
+	// TTL is how long a key is remembered, a day by default
+	TTL time.Duration
+	// LockTimeout is how long a request may hold its key without finishing
+	// before a retry takes over, in case the instance serving it died. Five
+	// minutes by default.
+	LockTimeout time.Duration
+	// PruningRate is how often expired keys are deleted, an hour by default
+	PruningRate time.Duration
+}
+
+type Service struct {
+	repo   Repository
+	config Config
+}
+
+func NewService(repo Repository, config Config) *Service {
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = 5 * time.Minute
+	}
+	if config.PruningRate <= 0 {
+		config.PruningRate = time.Hour
+	}
+	return &Service{repo: repo, config: config}
+}
+
+// Begin claims key for the request with fingerprint. It returns the claim to
+// finish the request with when it should go ahead, or the finished record
+// whose response should be replayed.
+func (s *Service) Begin(ctx context.Context, key string, fingerprint string) (uuid.UUID, *Record, error) {
+	if key == "" || len(key) > 255 {
+		return uuid.Nil, nil, fmt.Errorf("%w: keys are 1 to 255 characters long", ErrInvalidKey)
+	}
+
+	now := time.Now()
+	record := Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Claim:       uuid.New(),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.config.TTL),
+	}
+	existing, err := s.repo.Claim(ctx, record, now.Add(-s.config.LockTimeout))
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	switch {
+	case existing == nil:
+		return record.Claim, nil, nil
+	case existing.Fingerprint != fingerprint:
+		return uuid.Nil, nil, ErrMismatch
+	case !existing.Done():
+		return uuid.Nil, nil, ErrInProgress
+	}
+	return uuid.Nil, existing, nil
+}
+
+// Complete stores the response to replay for key
+func (s *Service) Complete(ctx context.Context, key string, claim uuid.UUID, status int, contentType string, body []byte) error {
+	return s.repo.Complete(ctx, key, claim, status, contentType, body)
+}
+
+// Release frees key after a failure that is worth retrying
+func (s *Service) Release(ctx context.Context, key string, claim uuid.UUID) error {
+	return s.repo.Release(ctx, key, claim)
+}
+
+// Run deletes expired keys until ctx is done
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PruningRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.repo.DeleteExpired(ctx, time.Now()); err != nil {
+				slog.Error("failed to delete expired idempotency keys", slog.Any("error", err))
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
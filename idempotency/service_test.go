@@ -0,0 +1,54 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"mbx/idempotency"
+	"mbx/idempotency/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+func TestService_Begin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := idempotency.NewService(repo, idempotency.Config{TTL: time.Hour, LockTimeout: time.Minute})
+	ctx := context.Background()
+
+	repo.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, record idempotency.Record, stale time.Time) (*idempotency.Record, error) {
+			if ttl := record.ExpiresAt.Sub(record.CreatedAt); ttl != time.Hour {
+				t.Errorf("Expected the key to expire after the TTL, got %v", ttl)
+			}
+			if lock := record.CreatedAt.Sub(stale); lock != time.Minute {
+				t.Errorf("Expected unfinished requests to go stale after the lock timeout, got %v", lock)
+			}
+			return nil, nil
+		})
+	if claim, record, err := service.Begin(ctx, "new", "abc"); claim == uuid.Nil || record != nil || err != nil {
+		t.Errorf("Expected a new key to go ahead with a claim, got %v, %+v, %v", claim, record, err)
+	}
+
+	done := &idempotency.Record{Key: "done", Fingerprint: "abc", Status: 201, Body: []byte(`{}`)}
+	repo.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(done, nil).Times(2)
+	if _, record, err := service.Begin(ctx, "done", "abc"); record != done || err != nil {
+		t.Errorf("Expected the finished record to replay, got %+v, %v", record, err)
+	}
+	if _, _, err := service.Begin(ctx, "done", "def"); !errors.Is(err, idempotency.ErrMismatch) {
+		t.Errorf("Expected ErrMismatch, got %v", err)
+	}
+
+	repo.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(&idempotency.Record{Key: "pending", Fingerprint: "abc"}, nil)
+	if _, _, err := service.Begin(ctx, "pending", "abc"); !errors.Is(err, idempotency.ErrInProgress) {
+		t.Errorf("Expected ErrInProgress, got %v", err)
+	}
+
+	if _, _, err := service.Begin(ctx, strings.Repeat("k", 256), "abc"); !errors.Is(err, idempotency.ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound   = errors.New("import not found")
+	ErrInvalidCSV = errors.New("invalid CSV")
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	// StatusFailed is set when the file could not be read to the end. Rows
+	// that fail validation are reported as row errors instead.
+	StatusFailed Status = "failed"
+)
+
+// Job imports the rows of a CSV file into contacts and, optionally, the
+// audience of a draft campaign
+type Job struct {
+	Id         uuid.UUID  `json:"id"`
+	CampaignId *uuid.UUID `json:"campaign_id,omitempty"`
+	// Variables maps template variables to the columns they are read from
+	Variables map[string]string `json:"variables"`
+	Status    Status            `json:"status"`
+	// Rows counts the data rows read so far, split into imported and failed
+	Rows        int        `json:"rows"`
+	Imported    int        `json:"imported"`
+	Failed      int        `json:"failed"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// RowError is why a row was not imported. Row counts from 1 for the first
+// data row, after the header.
+type RowError struct {
+	JobId uuid.UUID `json:"-"`
+	Row   int       `json:"row"`
+	Phone string    `json:"phone,omitempty"`
+	Error string    `json:"error"`
+}
+
+type Repository interface {
+	Create(context.Context, Job) error
+	// Update saves the status and counters of the job
+	Update(context.Context, Job) error
+	FindById(context.Context, uuid.UUID) (*Job, error)
+	AddErrors(context.Context, []RowError) error
+	ListErrors(ctx context.Context, jobId uuid.UUID, limit int, offset int) ([]RowError, error)
+}
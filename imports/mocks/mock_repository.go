@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: imports/job.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	imports "mbx/imports"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddErrors mocks base method.
+func (m *MockRepository) AddErrors(arg0 context.Context, arg1 []imports.RowError) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddErrors", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddErrors indicates an expected call of AddErrors.
+func (mr *MockRepositoryMockRecorder) AddErrors(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddErrors", reflect.TypeOf((*MockRepository)(nil).AddErrors), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockRepository) Create(arg0 context.Context, arg1 imports.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRepositoryMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), arg0, arg1)
+}
+
+// FindById mocks base method.
+func (m *MockRepository) FindById(arg0 context.Context, arg1 uuid.UUID) (*imports.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*imports.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockRepositoryMockRecorder) FindById(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockRepository)(nil).FindById), arg0, arg1)
+}
+
+// ListErrors mocks base method.
+func (m *MockRepository) ListErrors(ctx context.Context, jobId uuid.UUID, limit, offset int) ([]imports.RowError, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListErrors", ctx, jobId, limit, offset)
+	ret0, _ := ret[0].([]imports.RowError)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListErrors indicates an expected call of ListErrors.
+func (mr *MockRepositoryMockRecorder) ListErrors(ctx, jobId, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListErrors", reflect.TypeOf((*MockRepository)(nil).ListErrors), ctx, jobId, limit, offset)
+}
+
+// Update mocks base method.
+func (m *MockRepository) Update(arg0 context.Context, arg1 imports.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockRepositoryMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), arg0, arg1)
+}
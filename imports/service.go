@@ -0,0 +1,378 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mbx/campaigns"
+	"mbx/contacts"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const batchSize = 500
+
+// Contacts creates and updates the contacts of the imported rows
+type Contacts interface {
+	Resolve(ctx context.Context, phone string) (*contacts.Contact, error)
+	Update(ctx context.Context, contact contacts.Contact) (*contacts.Contact, error)
+}
+
+// Audience adds the imported rows to a draft campaign
+type Audience interface {
+	FindById(ctx context.Context, id uuid.UUID) (*campaigns.Campaign, error)
+	AddRecipients(ctx context.Context, id uuid.UUID, recipients []campaigns.Recipient) ([]bool, error)
+}
+
+type Service struct {
+	repo     Repository
+	contacts Contacts
+	audience Audience
+}
+
+func NewService(repo Repository, contacts Contacts, audience Audience) *Service {
+	return &Service{repo: repo, contacts: contacts, audience: audience}
+}
+
+// Upload is a CSV file staged on disk, so that it can be read once the
+// request that carried it is gone
+type Upload struct {
+	path   string
+	header []string
+}
+
+// Stage streams r to a temporary file and reads its header, which must have
+// a phone column. The upload must be given to Start or discarded.
+func (s *Service) Stage(r io.Reader) (*Upload, error) {
+	file, err := os.CreateTemp("", "mbx-import-*.csv")
+	if err != nil {
+		return nil, err
+	}
+	upload := &Upload{path: file.Name()}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		upload.header, err = readHeader(upload.path)
+	}
+	if err != nil {
+		upload.Discard()
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Discard removes the staged file
+func (u *Upload) Discard() {
+	os.Remove(u.path)
+}
+
+func readHeader(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header, err := newReader(file).Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidCSV)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCSV, err)
+	}
+	for i, name := range header {
+		header[i] = columnName(name)
+	}
+	if !slices.Contains(header, "phone") {
+		return nil, fmt.Errorf("%w: a phone column is required", ErrInvalidCSV)
+	}
+	return header, nil
+}
+
+func newReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+// Start creates the import job of upload and processes it in the background.
+// Rows are imported as contacts: phone, name and locale fill in the contact
+// and the other columns are kept as attributes. When campaignId is set, the
+// rows are also added to that draft campaign, with template variables read
+// from the columns named in variables, or from every other column when
+// variables is empty.
+func (s *Service) Start(ctx context.Context, upload *Upload, campaignId *uuid.UUID, variables map[string]string) (*Job, error) {
+	if campaignId == nil && len(variables) > 0 {
+		return nil, fmt.Errorf("%w: variables require a campaign", ErrInvalidCSV)
+	}
+	for variable, column := range variables {
+		if !slices.Contains(upload.header, columnName(column)) {
+			return nil, fmt.Errorf("%w: column %q of variable %q is missing", ErrInvalidCSV, column, variable)
+		}
+	}
+	if campaignId != nil {
+		campaign, err := s.audience.FindById(ctx, *campaignId)
+		if err != nil {
+			return nil, err
+		}
+		if campaign == nil {
+			return nil, campaigns.ErrNotFound
+		}
+		if campaign.Status != campaigns.StatusDraft {
+			return nil, fmt.Errorf("%w: recipients can only be added to draft campaigns", campaigns.ErrInvalidTransition)
+		}
+	}
+
+	now := time.Now()
+	job := Job{
+		Id:         uuid.New(),
+		CampaignId: campaignId,
+		Variables:  variables,
+		Status:     StatusPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if job.Variables == nil {
+		job.Variables = map[string]string{}
+	}
+	if err := s.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	created := job
+	go func() {
+		defer upload.Discard()
+		s.Process(context.WithoutCancel(ctx), &job, upload)
+	}()
+	return &created, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id uuid.UUID) (*Job, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s *Service) Errors(ctx context.Context, id uuid.UUID, limit int, offset int) ([]RowError, error) {
+	return s.repo.ListErrors(ctx, id, limit, offset)
+}
+
+// row is a parsed data row waiting to be added to the campaign
+type row struct {
+	number    int
+	recipient campaigns.Recipient
+}
+
+// Process reads the rows of upload in batches, saving the progress of job
+// after each one
+func (s *Service) Process(ctx context.Context, job *Job, upload *Upload) {
+	job.Status = StatusRunning
+	s.save(ctx, job)
+
+	err := s.process(ctx, job, upload)
+	now := time.Now()
+	job.Status = StatusCompleted
+	job.CompletedAt = &now
+	if err != nil {
+		slog.Error("Import failed", slog.Any("error", err), "import_id", job.Id)
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+	s.save(ctx, job)
+}
+
+func (s *Service) process(ctx context.Context, job *Job, upload *Upload) error {
+	file, err := os.Open(upload.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := newReader(file)
+	reader.FieldsPerRecord = len(upload.header)
+	reader.ReuseRecord = true
+	if _, err := reader.Read(); err != nil {
+		return err
+	}
+
+	columns := s.columns(job, upload.header)
+	var pending []row
+	var rowErrors []RowError
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		job.Rows++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) || !errors.Is(err, csv.ErrFieldCount) {
+				return fmt.Errorf("row %d: %w", job.Rows, err)
+			}
+			rowErrors = append(rowErrors, RowError{JobId: job.Id, Row: job.Rows, Error: fmt.Sprintf("expected %d columns, got %d", len(upload.header), len(record))})
+		} else if r, err := s.importRow(ctx, job, columns, record); err != nil {
+			rowErrors = append(rowErrors, RowError{JobId: job.Id, Row: job.Rows, Phone: columns.phone(record), Error: err.Error()})
+		} else if job.CampaignId != nil {
+			r.number = job.Rows
+			pending = append(pending, r)
+		}
+
+		if job.Rows%batchSize == 0 {
+			if rowErrors, err = s.flush(ctx, job, pending, rowErrors); err != nil {
+				return err
+			}
+			pending = pending[:0]
+		}
+	}
+	_, err = s.flush(ctx, job, pending, rowErrors)
+	return err
+}
+
+// flush adds the pending rows to the campaign and saves the row errors and
+// the progress of job
+func (s *Service) flush(ctx context.Context, job *Job, pending []row, rowErrors []RowError) ([]RowError, error) {
+	if len(pending) > 0 {
+		recipients := make([]campaigns.Recipient, len(pending))
+		for i, r := range pending {
+			recipients[i] = r.recipient
+		}
+		added, err := s.audience.AddRecipients(ctx, *job.CampaignId, recipients)
+		if err != nil {
+			return nil, err
+		}
+		for i, ok := range added {
+			if !ok {
+				rowErrors = append(rowErrors, RowError{JobId: job.Id, Row: pending[i].number, Phone: recipients[i].Phone, Error: "already in the campaign"})
+			}
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		if err := s.repo.AddErrors(ctx, rowErrors); err != nil {
+			return nil, err
+		}
+	}
+	job.Failed += len(rowErrors)
+	job.Imported = job.Rows - job.Failed
+	s.save(ctx, job)
+	return rowErrors[:0], nil
+}
+
+// save records the progress of job. Failing to do so does not stop the
+// import.
+func (s *Service) save(ctx context.Context, job *Job) {
+	job.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, *job); err != nil {
+		slog.Error("Failed to save import progress", slog.Any("error", err), "import_id", job.Id)
+	}
+}
+
+// importRow creates or updates the contact of a row and returns the
+// recipient it adds to the campaign
+func (s *Service) importRow(ctx context.Context, job *Job, columns columns, record []string) (row, error) {
+	phone := columns.phone(record)
+	if phone == "" {
+		return row{}, fmt.Errorf("phone is required")
+	}
+	contact, err := s.contacts.Resolve(ctx, phone)
+	if err != nil {
+		return row{}, err
+	}
+
+	changed := *contact
+	changed.Attributes = maps.Clone(contact.Attributes)
+	if changed.Attributes == nil {
+		changed.Attributes = map[string]string{}
+	}
+	if name := columns.value(record, "name"); name != "" {
+		changed.Name = name
+	}
+	if locale := columns.value(record, "locale"); locale != "" {
+		changed.Locale = locale
+	}
+	for _, column := range columns.attributes {
+		if value := strings.TrimSpace(record[column.index]); value != "" {
+			changed.Attributes[column.name] = value
+		}
+	}
+	if changed.Name != contact.Name || changed.Locale != contact.Locale || !maps.Equal(changed.Attributes, contact.Attributes) {
+		if contact, err = s.contacts.Update(ctx, changed); err != nil {
+			return row{}, err
+		}
+	}
+
+	recipient := campaigns.Recipient{
+		ContactId: &contact.Id,
+		Phone:     contact.Phone,
+		Locale:    contact.Locale,
+		Variables: make(map[string]string, len(columns.variables)),
+	}
+	for variable, index := range columns.variables {
+		recipient.Variables[variable] = strings.TrimSpace(record[index])
+	}
+	return row{recipient: recipient}, nil
+}
+
+type column struct {
+	name  string
+	index int
+}
+
+// columns locates the fields of a row by their header
+type columns struct {
+	index      map[string]int
+	attributes []column
+	variables  map[string]int
+}
+
+func (s *Service) columns(job *Job, header []string) columns {
+	c := columns{index: make(map[string]int, len(header)), variables: map[string]int{}}
+	for i, name := range header {
+		if _, ok := c.index[name]; ok || name == "" {
+			continue
+		}
+		c.index[name] = i
+		if name != "phone" && name != "name" && name != "locale" {
+			c.attributes = append(c.attributes, column{name: name, index: i})
+		}
+	}
+
+	if job.CampaignId == nil {
+		return c
+	}
+	if len(job.Variables) == 0 {
+		for _, column := range c.attributes {
+			c.variables[column.name] = column.index
+		}
+	}
+	for variable, name := range job.Variables {
+		c.variables[variable] = c.index[columnName(name)]
+	}
+	return c
+}
+
+func (c columns) value(record []string, name string) string {
+	i, ok := c.index[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (c columns) phone(record []string) string {
+	return c.value(record, "phone")
+}
+
+// columnName is the form header names are matched in
+func columnName(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
@@ -0,0 +1,197 @@
+package imports_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"mbx/campaigns"
+	"mbx/contacts"
+	"mbx/imports"
+	"mbx/imports/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+// stubContacts keeps contacts in memory, keyed by phone
+type stubContacts struct {
+	byPhone map[string]*contacts.Contact
+}
+
+func (s *stubContacts) Resolve(_ context.Context, phone string) (*contacts.Contact, error) {
+	normalized, err := contacts.NormalizePhone(phone, "BR")
+	if err != nil {
+		return nil, err
+	}
+	if contact, ok := s.byPhone[normalized]; ok {
+		copied := *contact
+		return &copied, nil
+	}
+	contact := &contacts.Contact{Id: uuid.New(), Phone: normalized, Attributes: map[string]string{}}
+	s.byPhone[normalized] = contact
+	copied := *contact
+	return &copied, nil
+}
+
+func (s *stubContacts) Update(_ context.Context, contact contacts.Contact) (*contacts.Contact, error) {
+	s.byPhone[contact.Phone] = &contact
+	return &contact, nil
+}
+
+// stubAudience is a draft campaign that rejects phones it already has
+type stubAudience struct {
+	campaign   campaigns.Campaign
+	recipients []campaigns.Recipient
+	phones     map[string]bool
+}
+
+func (s *stubAudience) FindById(_ context.Context, id uuid.UUID) (*campaigns.Campaign, error) {
+	if id != s.campaign.Id {
+		return nil, nil
+	}
+	return &s.campaign, nil
+}
+
+func (s *stubAudience) AddRecipients(_ context.Context, _ uuid.UUID, recipients []campaigns.Recipient) ([]bool, error) {
+	added := make([]bool, len(recipients))
+	for i, r := range recipients {
+		if !s.phones[r.Phone] {
+			s.phones[r.Phone] = true
+			s.recipients = append(s.recipients, r)
+			added[i] = true
+		}
+	}
+	return added, nil
+}
+
+func TestService_Stage(t *testing.T) {
+	service := imports.NewService(nil, nil, nil)
+
+	_, err := service.Stage(strings.NewReader("name,email\nAna,ana@example.com\n"))
+	if !errors.Is(err, imports.ErrInvalidCSV) {
+		t.Errorf("Expected ErrInvalidCSV without a phone column, got %v", err)
+	}
+	_, err = service.Stage(strings.NewReader(""))
+	if !errors.Is(err, imports.ErrInvalidCSV) {
+		t.Errorf("Expected ErrInvalidCSV for an empty file, got %v", err)
+	}
+
+	upload, err := service.Stage(strings.NewReader("\ufeff Phone ,Name\n11999990001,Ana\n"))
+	if err != nil {
+		t.Fatalf("Expected the header to be accepted, got %v", err)
+	}
+	upload.Discard()
+}
+
+func TestService_Start(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	audience := &stubAudience{campaign: campaigns.Campaign{Id: uuid.New(), Status: campaigns.StatusScheduled}}
+	service := imports.NewService(repo, &stubContacts{}, audience)
+
+	upload, err := service.Stage(strings.NewReader("phone,first_name\n11999990001,Ana\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upload.Discard()
+
+	_, err = service.Start(context.Background(), upload, nil, map[string]string{"1": "first_name"})
+	if !errors.Is(err, imports.ErrInvalidCSV) {
+		t.Errorf("Expected ErrInvalidCSV for variables without a campaign, got %v", err)
+	}
+	_, err = service.Start(context.Background(), upload, &audience.campaign.Id, map[string]string{"1": "last_name"})
+	if !errors.Is(err, imports.ErrInvalidCSV) {
+		t.Errorf("Expected ErrInvalidCSV for a missing column, got %v", err)
+	}
+	_, err = service.Start(context.Background(), upload, &audience.campaign.Id, nil)
+	if !errors.Is(err, campaigns.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition for a scheduled campaign, got %v", err)
+	}
+	missing := uuid.New()
+	_, err = service.Start(context.Background(), upload, &missing, nil)
+	if !errors.Is(err, campaigns.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing campaign, got %v", err)
+	}
+}
+
+func TestService_Process(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	existing := &contacts.Contact{Id: uuid.New(), Phone: "+5511999990002", Name: "Bruno", Attributes: map[string]string{"plan": "pro"}}
+	contactStore := &stubContacts{byPhone: map[string]*contacts.Contact{existing.Phone: existing}}
+	audience := &stubAudience{campaign: campaigns.Campaign{Id: uuid.New(), Status: campaigns.StatusDraft}, phones: map[string]bool{}}
+	service := imports.NewService(repo, contactStore, audience)
+
+	csv := strings.Join([]string{
+		"phone,name,locale,first_name,coupon",
+		"11999990001,Ana,pt_BR,Ana,A10",
+		"11999990002,,,Bruno,B20",
+		"12,Carla,,Carla,C30",
+		",Dani,,Dani,D40",
+		"11999990003,Eva",
+		"(11) 99999-0001,Ana,,Ana,A10",
+	}, "\n")
+	upload, err := service.Stage(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upload.Discard()
+
+	var rowErrors []imports.RowError
+	repo.EXPECT().AddErrors(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, errs []imports.RowError) error {
+			rowErrors = append(rowErrors, errs...)
+			return nil
+		})
+	var saved []imports.Job
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, job imports.Job) error {
+			saved = append(saved, job)
+			return nil
+		}).AnyTimes()
+
+	job := imports.Job{Id: uuid.New(), CampaignId: &audience.campaign.Id, Variables: map[string]string{"1": "First_Name"}}
+	service.Process(context.Background(), &job, upload)
+
+	if job.Status != imports.StatusCompleted || job.CompletedAt == nil {
+		t.Errorf("Expected completed job, got %+v", job)
+	}
+	if job.Rows != 6 || job.Imported != 2 || job.Failed != 4 {
+		t.Errorf("Expected 6 rows, 2 imported and 4 failed, got %d, %d and %d", job.Rows, job.Imported, job.Failed)
+	}
+	if len(saved) == 0 || saved[len(saved)-1].Status != imports.StatusCompleted {
+		t.Errorf("Expected the final progress to be saved, got %+v", saved)
+	}
+
+	rows := map[int]string{}
+	for _, e := range rowErrors {
+		rows[e.Row] = e.Error
+	}
+	for _, row := range []int{3, 4, 5, 6} {
+		if rows[row] == "" {
+			t.Errorf("Expected an error for row %d, got %v", row, rowErrors)
+		}
+	}
+	if rows[6] != "already in the campaign" {
+		t.Errorf("Expected row 6 to be a duplicate, got %q", rows[6])
+	}
+
+	ana := contactStore.byPhone["+5511999990001"]
+	if ana == nil || ana.Name != "Ana" || ana.Locale != "pt_BR" || ana.Attributes["coupon"] != "A10" {
+		t.Errorf("Expected the contact to be created with its columns, got %+v", ana)
+	}
+	bruno := contactStore.byPhone[existing.Phone]
+	if bruno.Name != "Bruno" || bruno.Attributes["plan"] != "pro" || bruno.Attributes["coupon"] != "B20" {
+		t.Errorf("Expected the existing contact to be merged, got %+v", bruno)
+	}
+
+	if len(audience.recipients) != 2 {
+		t.Fatalf("Expected 2 recipients, got %d", len(audience.recipients))
+	}
+	recipient := audience.recipients[0]
+	if recipient.Variables["1"] != "Ana" || len(recipient.Variables) != 1 || recipient.Locale != "pt_BR" {
+		t.Errorf("Expected the mapped variable and the contact locale, got %+v", recipient)
+	}
+}
@@ -0,0 +1,111 @@
+package inbound
+
+import (
+	"context"
+	"log/slog"
+	"mbx/contacts"
+	"mbx/history"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a message sent by a customer and delivered to our webhook
+type Message struct {
+	Sid         string
+	From        string // E.164, without the channel prefix
+	To          string
+	Body        string
+	ProfileName string
+	NumMedia    int
+	ReceivedAt  time.Time
+
+	// Interaction is set when the customer tapped a quick reply button or
+	// picked a list item instead of typing
+	Interaction *history.Interaction
+
+	// Contact is the sender of the message, resolved before listeners run
+	Contact *contacts.Contact
+
+	// Handled is set by a listener that fully dealt with the message, such as
+	// an opt-out keyword, so that later listeners do not react to it
+	Handled bool
+}
+
+// Payload returns the ID of the button or list item the customer picked, or
+// "" for typed messages
+func (m *Message) Payload() string {
+	if m.Interaction == nil {
+		return ""
+	}
+	return m.Interaction.Payload
+}
+
+// Listener reacts to inbound messages. Listeners run in registration order.
+type Listener interface {
+	HandleInbound(context.Context, *Message) error
+}
+
+type Service struct {
+	contacts  contacts.Resolver
+	history   history.Repository
+	listeners []Listener
+}
+
+func NewService(contacts contacts.Resolver, history history.Repository, listeners ...Listener) *Service {
+	return &Service{
+		contacts:  contacts,
+		history:   history,
+		listeners: listeners,
+	}
+}
+
+// Receive links the message to its contact, records it and hands it to the
+// listeners. Listener failures are logged so one broken listener does not
+// stop the others.
+func (s *Service) Receive(ctx context.Context, msg Message) error {
+	contact, err := s.contacts.Resolve(ctx, msg.From)
+	if err != nil {
+		return err
+	}
+	msg.Contact = contact
+	msg.From = contact.Phone
+
+	if msg.Interaction != nil && msg.Interaction.RepliedToSid != "" {
+		original, err := s.history.FindByProviderSid(ctx, msg.Interaction.RepliedToSid)
+		if err != nil {
+			return err
+		}
+		if original != nil {
+			msg.Interaction.RepliedToId = &original.Id
+		}
+	}
+
+	err = s.history.Record(ctx, history.Message{
+		Id:          uuid.New(),
+		ContactId:   &contact.Id,
+		Direction:   history.DirectionInbound,
+		Phone:       contact.Phone,
+		Body:        msg.Body,
+		ProviderSid: msg.Sid,
+		Status:      "received",
+		Interaction: msg.Interaction,
+		Sender:      strings.TrimPrefix(msg.To, "whatsapp:"),
+		CreatedAt:   msg.ReceivedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, l := range s.listeners {
+		if msg.Handled {
+			break
+		}
+		if err := l.HandleInbound(ctx, &msg); err != nil {
+			slog.Error("Inbound listener failed", "error", err, "sid", msg.Sid)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package inbound_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/contacts"
+	cmocks "mbx/contacts/mocks"
+	"mbx/history"
+	hmocks "mbx/history/mocks"
+	"mbx/inbound"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+type listenerFunc func(context.Context, *inbound.Message) error
+
+func (f listenerFunc) HandleInbound(ctx context.Context, msg *inbound.Message) error {
+	return f(ctx, msg)
+}
+
+func TestService_ReceiveCorrelatesInteraction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	contact := &contacts.Contact{Id: uuid.New(), Phone: "+5511999998888"}
+	original := &history.Message{Id: uuid.New(), ProviderSid: "SM-menu", Direction: history.DirectionOutbound}
+
+	resolver := cmocks.NewMockResolver(ctrl)
+	resolver.EXPECT().Resolve(gomock.Any(), "whatsapp:+5511999998888").Return(contact, nil)
+
+	historyRepo := hmocks.NewMockRepository(ctrl)
+	historyRepo.EXPECT().FindByProviderSid(gomock.Any(), "SM-menu").Return(original, nil)
+
+	var recorded history.Message
+	historyRepo.EXPECT().Record(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, m history.Message) error {
+		recorded = m
+		return nil
+	})
+
+	var payloads []string
+	first := listenerFunc(func(_ context.Context, msg *inbound.Message) error {
+		payloads = append(payloads, msg.Payload())
+		msg.Handled = true
+		return nil
+	})
+	second := listenerFunc(func(context.Context, *inbound.Message) error {
+		t.Error("Expected listeners after a handled message not to run")
+		return nil
+	})
+
+	service := inbound.NewService(resolver, historyRepo, first, second)
+	err := service.Receive(context.Background(), inbound.Message{
+		Sid:        "SM-reply",
+		From:       "whatsapp:+5511999998888",
+		Body:       "Track order",
+		ReceivedAt: time.Now(),
+		Interaction: &history.Interaction{
+			Type:         history.InteractionButton,
+			Payload:      "TRACK_ORDER",
+			Text:         "Track order",
+			RepliedToSid: "SM-menu",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to receive message: %v", err)
+	}
+
+	if recorded.Interaction == nil || recorded.Interaction.RepliedToId == nil || *recorded.Interaction.RepliedToId != original.Id {
+		t.Errorf("Expected reply to be linked to %s, got %+v", original.Id, recorded.Interaction)
+	}
+	if len(payloads) != 1 || payloads[0] != "TRACK_ORDER" {
+		t.Errorf("Expected listener to see the payload, got %q", payloads)
+	}
+}
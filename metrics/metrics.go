@@ -0,0 +1,116 @@
+// Package metrics holds the Prometheus metrics of the service and serves
+// them for scraping.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mbx"
+
+// ChannelWhatsapp is the channel label of WhatsApp messages
+const ChannelWhatsapp = "whatsapp"
+
+// Registry holds every metric of the service, along with the Go runtime and
+// process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// MessagesSent counts the messages handed to the provider by channel,
+	// type (freeform or template) and the status the provider returned, or
+	// failed when it refused them
+	MessagesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Messages handed to the provider by channel, type and status.",
+	}, []string{"channel", "type", "status"})
+
+	// ProviderDuration measures the Twilio REST calls by operation
+	ProviderDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Duration of the provider calls by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// ProviderErrors counts the failed Twilio REST calls by operation and
+	// Twilio error code
+	ProviderErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_errors_total",
+		Help:      "Failed provider calls by operation and Twilio error code.",
+	}, []string{"operation", "code"})
+
+	// SchedulerLag measures how late scheduled messages are dispatched
+	SchedulerLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scheduler_lag_seconds",
+		Help:      "Time between the send time of a scheduled message and its dispatch.",
+		Buckets:   []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 300, 900, 3600},
+	})
+
+	// ScheduledDispatched counts the scheduled messages the worker handled
+	// by type and the status they ended in, or held when the sender limits
+	// kept them pending
+	ScheduledDispatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduled_messages_dispatched_total",
+		Help:      "Scheduled messages handled by the worker by type and outcome.",
+	}, []string{"type", "status"})
+
+	// WebhooksReceived counts the provider callbacks by webhook (inbound or
+	// status) and outcome
+	WebhooksReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhooks_received_total",
+		Help:      "Provider callbacks processed by webhook and outcome.",
+	}, []string{"webhook", "outcome"})
+
+	// WebhookDeliveries counts the attempts to deliver events to the
+	// endpoints of tenants by result
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Attempts to deliver events to webhook endpoints by result.",
+	}, []string{"result"})
+
+	// HTTPRequests counts the API requests by route and status code
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "API requests by route and status code.",
+	}, []string{"route", "code"})
+
+	// HTTPDuration measures the API requests by route
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of the API requests by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		MessagesSent,
+		ProviderDuration,
+		ProviderErrors,
+		SchedulerLag,
+		ScheduledDispatched,
+		WebhooksReceived,
+		WebhookDeliveries,
+		HTTPRequests,
+		HTTPDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
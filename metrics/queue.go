@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// queueTimeout bounds the lookup of a queue's depth during a scrape
+const queueTimeout = 5 * time.Second
+
+// Queue is work waiting in the database
+type Queue interface {
+	// QueueDepth returns how many items are waiting to be processed, and
+	// how many were given up on
+	QueueDepth(ctx context.Context) (pending int, dead int, err error)
+}
+
+// queueCollector reads the depth of a queue on every scrape, so the gauge
+// is right whichever instance did the work
+type queueCollector struct {
+	name  string
+	queue Queue
+	desc  *prometheus.Desc
+}
+
+// RegisterQueue exposes the depth of queue as mbx_queue_depth{queue=name}
+func RegisterQueue(name string, queue Queue) error {
+	return Registry.Register(newQueueCollector(name, queue))
+}
+
+func newQueueCollector(name string, queue Queue) *queueCollector {
+	return &queueCollector{
+		name:  name,
+		queue: queue,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "queue_depth"),
+			"Items waiting in a queue by state, pending or dead.",
+			[]string{"state"},
+			prometheus.Labels{"queue": name},
+		),
+	}
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), queueTimeout)
+	defer cancel()
+
+	pending, dead, err := c.queue.QueueDepth(ctx)
+	if err != nil {
+		slog.Error("failed to read queue depth", slog.Any("error", err), slog.String("queue", c.name))
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(pending), "pending")
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(dead), "dead")
+}
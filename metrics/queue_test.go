@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type stubQueue struct {
+	pending, dead int
+	err           error
+}
+
+func (q stubQueue) QueueDepth(context.Context) (int, int, error) {
+	return q.pending, q.dead, q.err
+}
+
+func TestQueueCollector(t *testing.T) {
+	collector := newQueueCollector("scheduled_messages", stubQueue{pending: 12, dead: 3})
+
+	expected := `
+# HELP mbx_queue_depth Items waiting in a queue by state, pending or dead.
+# TYPE mbx_queue_depth gauge
+mbx_queue_depth{queue="scheduled_messages",state="dead"} 3
+mbx_queue_depth{queue="scheduled_messages",state="pending"} 12
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueueCollector_Error(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(newQueueCollector("webhook_deliveries", stubQueue{err: errors.New("connection refused")}))
+
+	if _, err := registry.Gather(); err == nil {
+		t.Error("Expected a failed lookup to fail the scrape")
+	}
+}
@@ -1,10 +1,38 @@
 package models
 
+import "github.com/google/uuid"
+
+// SenderStrategy picks one of the numbers of the sender pool
+type SenderStrategy string
+
+const (
+	// StrategySticky sends from the number the recipient last talked to, so
+	// conversations stay on one number
+	StrategySticky SenderStrategy = "sticky"
+	// StrategyRoundRobin takes turns through the pool
+	StrategyRoundRobin SenderStrategy = "round_robin"
+	// StrategyLeastLoaded sends from the number that sent the fewest
+	// messages lately
+	StrategyLeastLoaded SenderStrategy = "least_loaded"
+)
+
+// From selects the sender of a message: a number of the pool, a Twilio
+// Messaging Service, which picks one of its own numbers, or a pool strategy.
+// At most one is set; without any, the pool is sticky.
+type From struct {
+	Number              string         `json:"from,omitempty"`
+	MessagingServiceSid string         `json:"messaging_service_sid,omitempty"`
+	Strategy            SenderStrategy `json:"sender_strategy,omitempty"`
+}
+
 type WhatsappBodyDTO struct {
-	To   string `json:"to"`
-	Body string `json:"body"`
+	To        string     `json:"to"`
+	ContactId *uuid.UUID `json:"contact_id,omitempty"` // used instead of To
+	Body      string     `json:"body"`
+	From
 }
 type WhatsappBody struct {
 	To   string `json:"to"`
 	Body string `json:"body"`
+	From
 }
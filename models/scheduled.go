@@ -15,11 +15,22 @@ const (
 
 type ScheduledMessage struct {
 	Id         uuid.UUID
+	TenantId   uuid.UUID `json:"-"`
+	ContactId  *uuid.UUID
 	To         string
 	SendAt     time.Time
 	Content    string
 	ProviderId string
-	Type       ScheduledMessageType
-	Status     Status
-	CreatedAt  time.Time
+	// TemplateName and Locale are set instead of ProviderId when the template
+	// is resolved from a template group at send time
+	TemplateName string
+	Locale       string
+	Type         ScheduledMessageType
+	Status       Status
+	// From is resolved to a number when the message is sent
+	From      From
+	CreatedAt time.Time
+	// TraceParent is the W3C traceparent of the request that scheduled the
+	// message, so its send can be linked back to it
+	TraceParent string `json:"-"`
 }
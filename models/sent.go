@@ -11,3 +11,10 @@ type SentMessage struct {
 	Price        string `json:"price,omitempty"`
 	PriceUnit    string `json:"price_unit,omitempty"`
 }
+
+// MessagePage is a page of the messages listed by the provider. NextCursor
+// fetches the following page, and is empty on the last one.
+type MessagePage struct {
+	Messages   []SentMessage
+	NextCursor string
+}
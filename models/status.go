@@ -4,6 +4,12 @@ type Status string
 
 const (
 	StatusPending Status = "pending"
+	// StatusSending is set while a worker sends the message
+	StatusSending Status = "sending"
 	StatusSent    Status = "sent"
 	StatusFailed  Status = "failed"
+	// StatusSuppressed is set when the recipient opted out before the send
+	StatusSuppressed Status = "suppressed"
+	// StatusRejected is set when a send policy, such as consent, refused it
+	StatusRejected Status = "rejected"
 )
@@ -0,0 +1,31 @@
+package mbx
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec documents every route of SetupRouter. openapi_test.go checks it
+// against the routes and the responses of the handlers, so change it along
+// with them.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// docsPage renders the OpenAPI document with Swagger UI, loaded at an exact
+// version. API keys entered in it are not kept in the browser.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// serveOpenAPI handles GET /openapi.json
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// serveDocs handles GET /docs
+func serveDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
@@ -0,0 +1,545 @@
+package mbx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mbx/apikeys"
+	akmocks "mbx/apikeys/mocks"
+	"mbx/contacts"
+	cmocks "mbx/contacts/mocks"
+	"mbx/handler"
+	"mbx/idempotency"
+	imocks "mbx/idempotency/mocks"
+	"mbx/models"
+	"mbx/schedules"
+	smocks "mbx/schedules/mocks"
+	"mbx/sender"
+	"mbx/senders"
+	snmocks "mbx/senders/mocks"
+	"mbx/templates"
+	"mbx/tenants"
+	tmocks "mbx/tenants/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// route is a route registered in routes.go
+type route struct {
+	method, path string
+	// scope is the scope of the API key the route requires, empty for the
+	// routes without authentication
+	scope apikeys.Scope
+}
+
+var scopeNames = map[string]apikeys.Scope{
+	"ScopeSend":           apikeys.ScopeSend,
+	"ScopeSchedule":       apikeys.ScopeSchedule,
+	"ScopeTemplatesRead":  apikeys.ScopeTemplatesRead,
+	"ScopeTemplatesWrite": apikeys.ScopeTemplatesWrite,
+	"ScopeHistoryRead":    apikeys.ScopeHistoryRead,
+	"ScopeAdmin":          apikeys.ScopeAdmin,
+}
+
+// registeredRoutes reads the mux.HandleFunc calls of routes.go
+func registeredRoutes(t *testing.T) []route {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "routes.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var routes []route
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fun, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || fun.Sel.Name != "HandleFunc" || len(call.Args) != 2 {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok {
+			t.Fatalf("Expected a literal pattern, got %T", call.Args[0])
+		}
+		pattern, _ := strconv.Unquote(lit.Value)
+		method, path, _ := strings.Cut(pattern, " ")
+
+		r := route{method: strings.ToLower(method), path: path}
+		if auth, ok := call.Args[1].(*ast.CallExpr); ok {
+			if ident, ok := auth.Fun.(*ast.Ident); ok && ident.Name == "auth" {
+				scope := auth.Args[0].(*ast.SelectorExpr).Sel.Name
+				if r.scope, ok = scopeNames[scope]; !ok {
+					t.Fatalf("Unknown scope %s of %s", scope, pattern)
+				}
+			}
+		}
+		routes = append(routes, r)
+		return true
+	})
+	return routes
+}
+
+func loadSpec(t *testing.T) map[string]any {
+	t.Helper()
+	var spec map[string]any
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("Failed to parse openapi.json: %v", err)
+	}
+	return spec
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	spec := loadSpec(t)
+	paths := spec["paths"].(map[string]any)
+
+	documented := map[string]bool{}
+	for path, item := range paths {
+		for method := range item.(map[string]any) {
+			documented[method+" "+path] = true
+		}
+	}
+
+	for _, r := range registeredRoutes(t) {
+		pattern := r.method + " " + r.path
+		if !documented[pattern] {
+			t.Errorf("Route %s is missing from openapi.json", pattern)
+			continue
+		}
+		delete(documented, pattern)
+
+		op := paths[r.path].(map[string]any)[r.method].(map[string]any)
+		scope, _ := op["x-scope"].(string)
+		if scope != string(r.scope) {
+			t.Errorf("Expected %s to require scope %q, the document says %q", pattern, r.scope, scope)
+		}
+		if security, ok := op["security"].([]any); (r.scope == "") != (ok && len(security) == 0) {
+			t.Errorf("Expected %s to be documented as authenticated: %v", pattern, r.scope != "")
+		}
+		for _, name := range pathParams(r.path) {
+			if !hasParam(spec, op, name) {
+				t.Errorf("Path parameter %s of %s is not documented", name, pattern)
+			}
+		}
+	}
+	for pattern := range documented {
+		t.Errorf("openapi.json documents %s, which is not a route", pattern)
+	}
+}
+
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") {
+			names = append(names, strings.Trim(segment, "{}"))
+		}
+	}
+	return names
+}
+
+func hasParam(spec map[string]any, op map[string]any, name string) bool {
+	params, _ := op["parameters"].([]any)
+	for _, p := range params {
+		param := resolve(spec, p.(map[string]any))
+		if param["in"] == "path" && param["name"] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve follows the $ref of a document object
+func resolve(spec map[string]any, object map[string]any) map[string]any {
+	ref, ok := object["$ref"].(string)
+	if !ok {
+		return object
+	}
+	var target any = spec
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		target = target.(map[string]any)[part]
+	}
+	return resolve(spec, target.(map[string]any))
+}
+
+// conforms checks value against a schema of the document. Objects may only
+// have the properties their schema lists, so fields added to a handler
+// response must be documented too.
+func conforms(spec map[string]any, schema map[string]any, value any, at string) error {
+	schema = resolve(spec, schema)
+	if value == nil {
+		if schema["nullable"] == true || schema["type"] == nil {
+			return nil
+		}
+		return fmt.Errorf("%s: is null", at)
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %T", at, value)
+		}
+		for _, name := range asStrings(schema["required"]) {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", at, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		additional := schema["additionalProperties"]
+		if props == nil && additional == nil {
+			// a free-form object
+			return nil
+		}
+		for name, v := range object {
+			if prop, ok := props[name]; ok {
+				if err := conforms(spec, prop.(map[string]any), v, at+"."+name); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := additional.(type) {
+			case map[string]any:
+				if err := conforms(spec, additional, v, at+"."+name); err != nil {
+					return err
+				}
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: undocumented property %s", at, name)
+				}
+			default:
+				return fmt.Errorf("%s: undocumented property %s", at, name)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %T", at, value)
+		}
+		for i, item := range items {
+			if err := conforms(spec, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string, got %T", at, value)
+		}
+		if enum := asStrings(schema["enum"]); enum != nil && !slices.Contains(enum, s) {
+			return fmt.Errorf("%s: %q is not one of %v", at, s, enum)
+		}
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, s)
+			}
+		case "uuid":
+			if _, err := uuid.Parse(s); err != nil {
+				return fmt.Errorf("%s: %q is not a UUID", at, s)
+			}
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected an integer, got %v", at, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected a number, got %T", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %T", at, value)
+		}
+	}
+	return nil
+}
+
+func asStrings(value any) []string {
+	list, _ := value.([]any)
+	var strs []string
+	for _, v := range list {
+		strs = append(strs, v.(string))
+	}
+	return strs
+}
+
+// fakeProvider answers the send and fetch calls of the message and template
+// handlers
+type fakeProvider struct{}
+
+func (fakeProvider) Send(_ context.Context, body models.WhatsappBody) (*api.ApiV2010Message, error) {
+	sid, status := "SM123", "queued"
+	return &api.ApiV2010Message{Sid: &sid, To: &body.To, Body: &body.Body, Status: &status}, nil
+}
+
+func (fakeProvider) SendTemplate(_ context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	sid, status, service := "SM124", "accepted", "MG123"
+	return &api.ApiV2010Message{Sid: &sid, To: &template.To, Status: &status, MessagingServiceSid: &service}, nil
+}
+
+func (fakeProvider) CancelMessage(context.Context, string) error { return nil }
+
+func (fakeProvider) CreateTemplate(_ context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return &templates.SavedTemplate{ContentId: "HX123", FriendlyName: dto.FriendlyName, Language: dto.Language, Body: dto.Body, Types: dto.ToTwilioTypes()}, nil
+}
+
+func (fakeProvider) GetTemplates(context.Context) ([]templates.SavedTemplate, error) {
+	return []templates.SavedTemplate{{ContentId: "HX123", FriendlyName: "welcome", Language: "en", Body: "Hi {{1}}", Variables: map[string]any{"1": "name"}}}, nil
+}
+
+func (fakeProvider) GetMessages(context.Context, time.Time, int, string) (*models.MessagePage, error) {
+	return &models.MessagePage{
+		Messages:   []models.SentMessage{{ID: "SM123", To: "whatsapp:+31612345678", Body: "Hi", Status: "delivered", ErrorCode: 0}},
+		NextCursor: "next",
+	}, nil
+}
+
+func (fakeProvider) GetScheduledMessages(context.Context, time.Time) ([]models.SentMessage, error) {
+	return nil, nil
+}
+
+func (fakeProvider) ListMessagingServices(context.Context) ([]models.MessagingService, error) {
+	return []models.MessagingService{{Sid: "MG123", FriendlyName: "Default"}}, nil
+}
+
+var _ sender.WhatsappSender = fakeProvider{}
+
+type ownNumber string
+
+func (n ownNumber) FromNumber(context.Context) (string, error) { return string(n), nil }
+
+func (n ownNumber) ListMessagingServices(context.Context) ([]models.MessagingService, error) {
+	return nil, nil
+}
+
+// newTestRouter wires the handlers the response test calls to mocked
+// repositories. The other handlers are left nil.
+func newTestRouter(t *testing.T) (http.Handler, string) {
+	ctrl := gomock.NewController(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	keyRepo := akmocks.NewMockRepository(ctrl)
+	keyService := apikeys.NewService(keyRepo)
+	var keys []apikeys.Key
+	keyRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key apikeys.Key) error {
+		keys = append(keys, key)
+		return nil
+	}).AnyTimes()
+	keyRepo.EXPECT().FindByHash(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, hash string) (*apikeys.Key, error) {
+		for _, key := range keys {
+			if key.Hash == hash {
+				return &key, nil
+			}
+		}
+		return nil, nil
+	}).AnyTimes()
+	keyRepo.EXPECT().Touch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	keyRepo.EXPECT().List(gomock.Any()).DoAndReturn(func(context.Context) ([]apikeys.Key, error) {
+		return keys, nil
+	}).AnyTimes()
+	_, token, err := keyService.Create(t.Context(), "operator", []apikeys.Scope{
+		apikeys.ScopeSend, apikeys.ScopeSchedule, apikeys.ScopeTemplatesRead, apikeys.ScopeTemplatesWrite,
+		apikeys.ScopeHistoryRead, apikeys.ScopeAdmin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contact := contacts.Contact{Id: uuid.New(), Phone: "+31612345678", Name: "Ada", Locale: "nl", Tags: []string{"vip"}, CreatedAt: now, UpdatedAt: now}
+	contactRepo := cmocks.NewMockRepository(ctrl)
+	contactRepo.EXPECT().FindByPhone(gomock.Any(), gomock.Any()).Return(&contact, nil).AnyTimes()
+	contactRepo.EXPECT().FindById(gomock.Any(), contact.Id).Return(&contact, nil).AnyTimes()
+	contactRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	contactRepo.EXPECT().List(gomock.Any(), gomock.Any()).Return([]contacts.Contact{contact}, nil).AnyTimes()
+	contactService := contacts.NewService(contactRepo, contacts.Config{})
+
+	scheduleRepo := smocks.NewMockRepository(ctrl)
+	scheduled := map[uuid.UUID]models.ScheduledMessage{}
+	scheduleRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, message models.ScheduledMessage) error {
+		scheduled[message.Id] = message
+		return nil
+	}).AnyTimes()
+	scheduleRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id uuid.UUID) (*models.ScheduledMessage, error) {
+		if message, ok := scheduled[id]; ok {
+			return &message, nil
+		}
+		return nil, nil
+	}).AnyTimes()
+	scheduleRepo.EXPECT().ListByContact(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	scheduleService := schedules.NewService(scheduleRepo)
+
+	senderRepo := snmocks.NewMockRepository(ctrl)
+	senderRepo.EXPECT().List(gomock.Any()).Return([]senders.Number{{Number: "+31687654321", CreatedAt: now}}, nil).AnyTimes()
+	senderRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	senderService := senders.NewService(senderRepo, ownNumber("+31600000000"), senders.Config{})
+
+	tenantRepo := tmocks.NewMockRepository(ctrl)
+	tenant := tenants.Tenant{Id: tenants.DefaultId, Name: "Default", AccountSid: "AC123", AuthToken: "secret", CreatedAt: now, UpdatedAt: now}
+	tenantRepo.EXPECT().List(gomock.Any()).Return([]tenants.Tenant{tenant}, nil).AnyTimes()
+	tenantRepo.EXPECT().FindById(gomock.Any(), tenant.Id).Return(&tenant, nil).AnyTimes()
+	tenantService := tenants.NewService(tenantRepo, sender.Config{})
+
+	keyStore := imocks.NewMockRepository(ctrl)
+	keyStore.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	keyStore.EXPECT().Complete(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	idempotencyService := idempotency.NewService(keyStore, idempotency.Config{})
+
+	provider := fakeProvider{}
+	router := SetupRouter(
+		handler.NewMessageHandler(provider, provider, provider, contactService),
+		handler.NewTemplateHandler(provider, provider, nil, contactService),
+		nil,
+		handler.NewScheduledMessageHandler(scheduleService, contactService, senderService),
+		handler.NewContactHandler(contactService, nil, scheduleService),
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		handler.NewAPIKeyHandler(keyService),
+		handler.NewTenantHandler(tenantService, keyService),
+		handler.NewSenderHandler(senderService),
+		handler.NewAuthenticator(keyService),
+		handler.NewIdempotency(idempotencyService),
+	)
+	return router, token
+}
+
+func TestOpenAPI_Responses(t *testing.T) {
+	spec := loadSpec(t)
+	router, token := newTestRouter(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string // path of the operation in the document
+		url         string
+		body        any
+		noAuth      bool
+		headers     map[string]string
+		status      int
+		contentType string
+	}{
+		{name: "document", method: "get", path: "/openapi.json", url: "/openapi.json", noAuth: true, status: 200},
+		{name: "docs", method: "get", path: "/docs", url: "/docs", noAuth: true, status: 200, contentType: "text/html"},
+		{name: "missing key", method: "get", path: "/contacts", url: "/contacts", noAuth: true, status: 401},
+		{name: "send message", method: "post", path: "/send-message", url: "/send-message",
+			body: map[string]any{"to": "+31612345678", "body": "Hi", "sender_strategy": "round_robin"}, status: 201},
+		{name: "send message without body", method: "post", path: "/send-message", url: "/send-message",
+			body: map[string]any{"to": "+31612345678"}, status: 400},
+		{name: "send message with a key", method: "post", path: "/send-message", url: "/send-message",
+			body: map[string]any{"to": "+31612345678", "body": "Hi"}, headers: map[string]string{"Idempotency-Key": "abc"}, status: 201},
+		{name: "send template", method: "post", path: "/send-template", url: "/send-template",
+			body: map[string]any{"to": "+31612345678", "template": "HX123", "content": map[string]string{"1": "Ada"}, "messaging_service_sid": "MG123"}, status: 201},
+		{name: "cancel message", method: "post", path: "/messages/cancel", url: "/messages/cancel",
+			body: map[string]any{"message_id": "SM123"}, status: 204},
+		{name: "list messages", method: "get", path: "/messages", url: "/messages?after=2024-01-01", status: 200},
+		{name: "list messages without after", method: "get", path: "/messages", url: "/messages", status: 400},
+		{name: "list scheduled with the provider", method: "get", path: "/messages/templates", url: "/messages/templates", status: 200},
+		{name: "list templates", method: "get", path: "/templates", url: "/templates", status: 200},
+		{name: "create template", method: "post", path: "/templates", url: "/templates",
+			body: map[string]any{"friendly_name": "welcome", "language": "en", "body": "Hi {{1}}", "actions": []map[string]string{{"type": "URL", "title": "Open", "url": "https://example.com"}}}, status: 201},
+		{name: "create template without name", method: "post", path: "/templates", url: "/templates",
+			body: map[string]any{"language": "en", "body": "Hi"}, status: 400},
+		{name: "list messaging services", method: "get", path: "/templates/services", url: "/templates/services", status: 200},
+		{name: "schedule", method: "post", path: "/scheduled-messages", url: "/scheduled-messages",
+			body: map[string]any{"to": "+31612345678", "content": "Hi", "type": "freeform", "send_at": time.Now().Add(time.Hour).Format(time.RFC3339)}, status: 201},
+		{name: "scheduled message not found", method: "get", path: "/scheduled-messages/{id}", url: "/scheduled-messages/" + uuid.NewString(), status: 404},
+		{name: "list contacts", method: "get", path: "/contacts", url: "/contacts?tag=vip&limit=10", status: 200},
+		{name: "contact not found", method: "get", path: "/contacts/{id}", url: "/contacts/" + uuid.NewString(), status: 404},
+		{name: "invalid contact id", method: "get", path: "/contacts/{id}", url: "/contacts/nope", status: 400},
+		{name: "contact scheduled messages", method: "get", path: "/contacts/{id}/scheduled-messages", url: "/contacts/" + uuid.NewString() + "/scheduled-messages", status: 404},
+		{name: "list api keys", method: "get", path: "/api-keys", url: "/api-keys", status: 200},
+		{name: "create api key", method: "post", path: "/api-keys", url: "/api-keys",
+			body: map[string]any{"name": "ci", "scopes": []string{"send"}}, status: 201},
+		{name: "list senders", method: "get", path: "/senders", url: "/senders", status: 200},
+		{name: "add sender", method: "post", path: "/senders", url: "/senders", body: map[string]any{"number": "+31611111111"}, status: 201},
+		{name: "list tenants", method: "get", path: "/tenants", url: "/tenants", status: 200},
+		{name: "get tenant", method: "get", path: "/tenants/{id}", url: "/tenants/" + tenants.DefaultId.String(), status: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, ok := spec["paths"].(map[string]any)[tt.path].(map[string]any)[tt.method].(map[string]any)
+			if !ok {
+				t.Fatalf("%s %s is not documented", tt.method, tt.path)
+			}
+
+			var body bytes.Buffer
+			if tt.body != nil {
+				json.NewEncoder(&body).Encode(tt.body)
+			}
+			if tt.body != nil && tt.status < 300 {
+				// the valid requests of the test have to match the document
+				// too
+				var value any
+				json.Unmarshal(body.Bytes(), &value)
+				reqBody := resolve(spec, op["requestBody"].(map[string]any))
+				schema := reqBody["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+				if err := conforms(spec, schema, value, "request"); err != nil {
+					t.Fatalf("Request does not match the document: %v", err)
+				}
+			}
+
+			req := httptest.NewRequest(strings.ToUpper(tt.method), APIVersion+tt.url, &body)
+			if !tt.noAuth {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			documented, ok := op["responses"].(map[string]any)[strconv.Itoa(rec.Code)].(map[string]any)
+			if !ok {
+				t.Fatalf("Status %d of %s %s is not documented", rec.Code, tt.method, tt.path)
+			}
+			documented = resolve(spec, documented)
+
+			content, _ := documented["content"].(map[string]any)
+			if content == nil {
+				if rec.Body.Len() != 0 {
+					t.Fatalf("Expected no body, got %s", rec.Body.String())
+				}
+				return
+			}
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			if !strings.HasPrefix(rec.Header().Get("Content-Type"), contentType) {
+				t.Fatalf("Expected %s, got %s", contentType, rec.Header().Get("Content-Type"))
+			}
+			media, ok := content[contentType].(map[string]any)
+			if !ok {
+				t.Fatalf("Content type %s of %s %s is not documented", contentType, tt.method, tt.path)
+			}
+			if contentType != "application/json" {
+				return
+			}
+
+			var value any
+			if err := json.Unmarshal(rec.Body.Bytes(), &value); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if err := conforms(spec, media["schema"].(map[string]any), value, "response"); err != nil {
+				t.Errorf("Response does not match the document: %v\n%s", err, rec.Body.String())
+			}
+		})
+	}
+}
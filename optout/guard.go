@@ -0,0 +1,60 @@
+package optout
+
+import (
+	"context"
+	"mbx/models"
+	"mbx/sender"
+	"mbx/templates"
+	"strings"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// GuardedSender refuses to send to suppressed numbers. It wraps the senders
+// used by every send path so immediate, template and scheduled messages are
+// all checked the same way.
+type GuardedSender struct {
+	w       sender.Whatsapp
+	wt      sender.WhatsappTemplate
+	service *Service
+}
+
+var _ sender.Whatsapp = (*GuardedSender)(nil)
+var _ sender.WhatsappTemplate = (*GuardedSender)(nil)
+
+func NewGuardedSender(w sender.Whatsapp, wt sender.WhatsappTemplate, service *Service) *GuardedSender {
+	return &GuardedSender{w: w, wt: wt, service: service}
+}
+
+func (s *GuardedSender) check(ctx context.Context, to string) error {
+	suppressed, err := s.service.IsSuppressed(ctx, strings.TrimPrefix(to, "whatsapp:"))
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		return ErrSuppressed
+	}
+	return nil
+}
+
+func (s *GuardedSender) Send(ctx context.Context, message models.WhatsappBody) (*api.ApiV2010Message, error) {
+	if err := s.check(ctx, message.To); err != nil {
+		return nil, err
+	}
+	return s.w.Send(ctx, message)
+}
+
+func (s *GuardedSender) SendTemplate(ctx context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	if err := s.check(ctx, template.To); err != nil {
+		return nil, err
+	}
+	return s.wt.SendTemplate(ctx, template)
+}
+
+func (s *GuardedSender) CreateTemplate(ctx context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return s.wt.CreateTemplate(ctx, dto)
+}
+
+func (s *GuardedSender) CancelMessage(ctx context.Context, twilioId string) error {
+	return s.w.CancelMessage(ctx, twilioId)
+}
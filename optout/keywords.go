@@ -0,0 +1,73 @@
+package optout
+
+import (
+	"slices"
+	"strings"
+)
+
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionOptOut
+	ActionOptIn
+)
+
+// Keywords are the replies recognized for one language, along with the
+// optional confirmations sent back to the customer.
+type Keywords struct {
+	OptOut      []string
+	OptIn       []string
+	OptOutReply string
+	OptInReply  string
+}
+
+// DefaultKeywords covers the languages we message in, keyed by bare language
+func DefaultKeywords() map[string]Keywords {
+	return map[string]Keywords{
+		"en": {
+			OptOut:      []string{"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT"},
+			OptIn:       []string{"START", "UNSTOP", "SUBSCRIBE"},
+			OptOutReply: "You have been unsubscribed and will no longer receive messages. Reply START to subscribe again.",
+			OptInReply:  "You are subscribed again.",
+		},
+		"pt": {
+			OptOut:      []string{"PARAR", "SAIR", "CANCELAR"},
+			OptIn:       []string{"VOLTAR", "INICIAR"},
+			OptOutReply: "Você não receberá mais mensagens. Responda VOLTAR para voltar a receber.",
+			OptInReply:  "Você voltará a receber nossas mensagens.",
+		},
+		"es": {
+			OptOut:      []string{"BAJA", "DETENER"},
+			OptIn:       []string{"ALTA"},
+			OptOutReply: "Ya no recibirás mensajes. Responde ALTA para volver a recibirlos.",
+			OptInReply:  "Volverás a recibir nuestros mensajes.",
+		},
+	}
+}
+
+// normalizeKeyword turns " Stop! " into "STOP"
+func normalizeKeyword(body string) string {
+	return strings.ToUpper(strings.Trim(body, " \t\r\n.!?"))
+}
+
+// match finds the action for a message body. Keywords of every language are
+// accepted, since customers often reply in English whatever their locale.
+func match(keywords map[string]Keywords, body string) (Action, string) {
+	word := normalizeKeyword(body)
+	if word == "" {
+		return ActionNone, ""
+	}
+
+	for _, k := range keywords {
+		if slices.Contains(k.OptOut, word) {
+			return ActionOptOut, word
+		}
+	}
+	for _, k := range keywords {
+		if slices.Contains(k.OptIn, word) {
+			return ActionOptIn, word
+		}
+	}
+	return ActionNone, ""
+}
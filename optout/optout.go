@@ -0,0 +1,37 @@
+package optout
+
+import (
+	"context"
+	"fmt"
+	"mbx/sender"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrSuppressed = fmt.Errorf("%w: recipient has opted out", sender.ErrRejected)
+
+type Reason string
+
+const (
+	// ReasonKeyword is set when the customer replied with an opt-out keyword
+	ReasonKeyword Reason = "keyword"
+	// ReasonManual is set when the number was suppressed through the API
+	ReasonManual Reason = "manual"
+)
+
+// Suppression is a number that must not receive any message
+type Suppression struct {
+	Phone     string     `json:"phone"`
+	ContactId *uuid.UUID `json:"contact_id,omitempty"`
+	Reason    Reason     `json:"reason"`
+	Keyword   string     `json:"keyword,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type Repository interface {
+	Add(context.Context, Suppression) error
+	Remove(ctx context.Context, phone string) error
+	Find(ctx context.Context, phone string) (*Suppression, error)
+	List(context.Context) ([]Suppression, error)
+}
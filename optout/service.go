@@ -0,0 +1,116 @@
+package optout
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mbx/inbound"
+	"mbx/models"
+	"mbx/sender"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// Keywords per bare language, see DefaultKeywords
+	Keywords map[string]Keywords
+	// DefaultLanguage picks the confirmation text for contacts without a locale
+	DefaultLanguage string
+}
+
+type Service struct {
+	repo   Repository
+	config Config
+	// w sends the confirmations. It must not be guarded by this service,
+	// otherwise the opt-out confirmation itself would be suppressed.
+	w sender.Whatsapp
+}
+
+var _ inbound.Listener = (*Service)(nil)
+
+func NewService(repo Repository, config Config, w sender.Whatsapp) *Service {
+	if config.Keywords == nil {
+		config.Keywords = DefaultKeywords()
+	}
+	return &Service{repo: repo, config: config, w: w}
+}
+
+func (s *Service) IsSuppressed(ctx context.Context, phone string) (bool, error) {
+	suppression, err := s.repo.Find(ctx, phone)
+	if err != nil {
+		return false, err
+	}
+	return suppression != nil, nil
+}
+
+func (s *Service) Suppress(ctx context.Context, suppression Suppression) error {
+	if suppression.CreatedAt.IsZero() {
+		suppression.CreatedAt = time.Now()
+	}
+	return s.repo.Add(ctx, suppression)
+}
+
+func (s *Service) Resubscribe(ctx context.Context, phone string) error {
+	return s.repo.Remove(ctx, phone)
+}
+
+func (s *Service) List(ctx context.Context) ([]Suppression, error) {
+	return s.repo.List(ctx)
+}
+
+// HandleInbound suppresses or resubscribes the sender when the message is an
+// opt-out or opt-in keyword, and marks it handled so nothing else replies.
+func (s *Service) HandleInbound(ctx context.Context, msg *inbound.Message) error {
+	action, keyword := match(s.config.Keywords, msg.Body)
+	if action == ActionNone {
+		return nil
+	}
+	msg.Handled = true
+
+	phone := msg.Contact.Phone
+	language := s.language(msg.Contact.Locale)
+
+	switch action {
+	case ActionOptOut:
+		slog.Info("Recipient opted out", "phone", phone, "keyword", keyword)
+		err := s.Suppress(ctx, Suppression{
+			Phone:     phone,
+			ContactId: &msg.Contact.Id,
+			Reason:    ReasonKeyword,
+			Keyword:   keyword,
+			CreatedAt: msg.ReceivedAt,
+		})
+		if err != nil {
+			return err
+		}
+		return s.confirm(ctx, phone, s.config.Keywords[language].OptOutReply)
+
+	case ActionOptIn:
+		slog.Info("Recipient opted in", "phone", phone, "keyword", keyword)
+		if err := s.Resubscribe(ctx, phone); err != nil {
+			return err
+		}
+		return s.confirm(ctx, phone, s.config.Keywords[language].OptInReply)
+	}
+
+	return nil
+}
+
+func (s *Service) language(locale string) string {
+	lang, _, _ := strings.Cut(locale, "_")
+	if _, ok := s.config.Keywords[lang]; ok {
+		return lang
+	}
+	return s.config.DefaultLanguage
+}
+
+func (s *Service) confirm(ctx context.Context, phone string, text string) error {
+	if text == "" || s.w == nil {
+		return nil
+	}
+	_, err := s.w.Send(ctx, models.WhatsappBody{
+		To:   fmt.Sprintf("whatsapp:%s", phone),
+		Body: text,
+	})
+	return err
+}
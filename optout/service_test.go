@@ -0,0 +1,137 @@
+package optout_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mbx/contacts"
+	"mbx/inbound"
+	"mbx/models"
+	"mbx/optout"
+	"mbx/optout/mocks"
+	"mbx/templates"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// stubSender records the messages it was asked to send
+type stubSender struct {
+	sent []models.WhatsappBody
+}
+
+func (s *stubSender) Send(_ context.Context, msg models.WhatsappBody) (*api.ApiV2010Message, error) {
+	s.sent = append(s.sent, msg)
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CancelMessage(context.Context, string) error { return nil }
+
+func (s *stubSender) SendTemplate(context.Context, templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CreateTemplate(context.Context, templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return nil, nil
+}
+
+func newInbound(body string) *inbound.Message {
+	return &inbound.Message{
+		Body:       body,
+		ReceivedAt: time.Now(),
+		Contact:    &contacts.Contact{Id: uuid.New(), Phone: "+5511999998888", Locale: "pt_BR"},
+	}
+}
+
+func TestHandleInbound_OptOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().
+		Add(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, s optout.Suppression) error {
+			if s.Phone != "+5511999998888" || s.Keyword != "PARAR" || s.Reason != optout.ReasonKeyword {
+				t.Errorf("Unexpected suppression %+v", s)
+			}
+			return nil
+		}).
+		Times(1)
+
+	confirmations := &stubSender{}
+	service := optout.NewService(repo, optout.Config{DefaultLanguage: "en"}, confirmations)
+
+	msg := newInbound(" parar! ")
+	if err := service.HandleInbound(context.Background(), msg); err != nil {
+		t.Fatalf("HandleInbound returned error: %v", err)
+	}
+	if !msg.Handled {
+		t.Error("Expected opt-out message to be marked handled")
+	}
+	if len(confirmations.sent) != 1 || confirmations.sent[0].Body != optout.DefaultKeywords()["pt"].OptOutReply {
+		t.Errorf("Expected Portuguese confirmation, got %+v", confirmations.sent)
+	}
+}
+
+func TestHandleInbound_OptIn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().Remove(gomock.Any(), "+5511999998888").Return(nil).Times(1)
+
+	service := optout.NewService(repo, optout.Config{}, nil)
+
+	msg := newInbound("START")
+	if err := service.HandleInbound(context.Background(), msg); err != nil {
+		t.Fatalf("HandleInbound returned error: %v", err)
+	}
+	if !msg.Handled {
+		t.Error("Expected opt-in message to be marked handled")
+	}
+}
+
+func TestHandleInbound_RegularMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockRepository(ctrl)
+	service := optout.NewService(repo, optout.Config{}, nil)
+
+	msg := newInbound("please stop sending me the invoice twice")
+	if err := service.HandleInbound(context.Background(), msg); err != nil {
+		t.Fatalf("HandleInbound returned error: %v", err)
+	}
+	if msg.Handled {
+		t.Error("Expected regular message not to be handled")
+	}
+}
+
+func TestGuardedSender_Suppressed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().
+		Find(gomock.Any(), "+5511999998888").
+		Return(&optout.Suppression{Phone: "+5511999998888"}, nil).
+		Times(2)
+
+	next := &stubSender{}
+	guarded := optout.NewGuardedSender(next, next, optout.NewService(repo, optout.Config{}, nil))
+
+	_, err := guarded.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999998888", Body: "Hi"})
+	if !errors.Is(err, optout.ErrSuppressed) {
+		t.Errorf("Expected ErrSuppressed, got %v", err)
+	}
+	_, err = guarded.SendTemplate(context.Background(), templates.WhatsappTemplate{To: "+5511999998888", TemplateId: "HX1"})
+	if !errors.Is(err, optout.ErrSuppressed) {
+		t.Errorf("Expected ErrSuppressed, got %v", err)
+	}
+	if len(next.sent) != 0 {
+		t.Errorf("Expected nothing to be sent, got %+v", next.sent)
+	}
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/agents"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AgentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAgentRepository(db *pgxpool.Pool) *AgentRepository {
+	return &AgentRepository{db: db}
+}
+
+var _ agents.Repository = &AgentRepository{}
+
+const agentColumns = `id, name, email, active, auto_assign, last_assigned_at, created_at`
+
+func scanAgent(row pgx.Row) (*agents.Agent, error) {
+	var a agents.Agent
+	err := row.Scan(&a.Id, &a.Name, &a.Email, &a.Active, &a.AutoAssign, &a.LastAssignedAt, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AgentRepository) Create(ctx context.Context, agent agents.Agent) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO agents
+		(tenant_id, `+agentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`,
+		tenants.FromContext(ctx),
+		agent.Id,
+		agent.Name,
+		agent.Email,
+		agent.Active,
+		agent.AutoAssign,
+		agent.LastAssignedAt,
+		agent.CreatedAt,
+	)
+	if isUniqueViolation(err) {
+		return agents.ErrDuplicate
+	}
+	return err
+}
+
+func (r *AgentRepository) Update(ctx context.Context, agent agents.Agent) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE agents
+		SET name = $2, email = $3, active = $4, auto_assign = $5
+		WHERE id = $1 AND tenant_id = $6
+		`,
+		agent.Id,
+		agent.Name,
+		agent.Email,
+		agent.Active,
+		agent.AutoAssign,
+		tenants.FromContext(ctx),
+	)
+	if isUniqueViolation(err) {
+		return agents.ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return agents.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AgentRepository) FindById(ctx context.Context, id uuid.UUID) (*agents.Agent, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+agentColumns+` FROM agents WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	agent, err := scanAgent(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return agent, err
+}
+
+func (r *AgentRepository) List(ctx context.Context) ([]agents.Agent, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+agentColumns+` FROM agents WHERE tenant_id = $1 ORDER BY name, id`, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []agents.Agent
+	for rows.Next() {
+		agent, err := scanAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *agent)
+	}
+	return out, rows.Err()
+}
+
+func (r *AgentRepository) NextInPool(ctx context.Context) (*agents.Agent, error) {
+	// SKIP LOCKED lets concurrent inbound messages pick different agents
+	row := r.db.QueryRow(ctx, `
+		UPDATE agents
+		SET last_assigned_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM agents
+			WHERE tenant_id = $1 AND active AND auto_assign
+			ORDER BY last_assigned_at NULLS FIRST, created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+agentColumns, tenants.FromContext(ctx))
+	agent, err := scanAgent(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return agent, err
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/apikeys"
+	"mbx/tenants"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+var _ apikeys.Repository = &APIKeyRepository{}
+
+const apiKeyColumns = `id, tenant_id, name, prefix, hash, scopes, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (*apikeys.Key, error) {
+	var k apikeys.Key
+	var scopes []string
+	if err := row.Scan(&k.Id, &k.TenantId, &k.Name, &k.Prefix, &k.Hash, &scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	k.Scopes = make([]apikeys.Scope, 0, len(scopes))
+	for _, s := range scopes {
+		k.Scopes = append(k.Scopes, apikeys.Scope(s))
+	}
+	return &k, nil
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key apikeys.Key) error {
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, s := range key.Scopes {
+		scopes = append(scopes, string(s))
+	}
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO api_keys
+		(`+apiKeyColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`,
+		key.Id,
+		key.TenantId,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		scopes,
+		key.CreatedAt,
+		key.LastUsedAt,
+		key.RevokedAt,
+	)
+	return err
+}
+
+// FindByHash looks the key up among those of every tenant, as the tenant of
+// a request is only known once its key is
+func (r *APIKeyRepository) FindByHash(ctx context.Context, hash string) (*apikeys.Key, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE hash = $1`, hash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+func (r *APIKeyRepository) List(ctx context.Context) ([]apikeys.Key, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE tenant_id = $1 ORDER BY created_at, id`, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []apikeys.Key
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *key)
+	}
+	return out, rows.Err()
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE id = $1 AND tenant_id = $3
+		`, id, at, tenants.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return apikeys.ErrNotFound
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at)
+	return err
+}
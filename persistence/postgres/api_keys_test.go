@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"mbx/apikeys"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys_AuthenticateAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	service := apikeys.NewService(NewAPIKeyRepository(testDB))
+
+	key, token, err := service.Create(ctx, "billing", []apikeys.Scope{apikeys.ScopeSend, apikeys.ScopeHistoryRead})
+	require.NoError(t, err)
+
+	found, err := service.Authenticate(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, key.Id, found.Id)
+	require.Equal(t, key.Scopes, found.Scopes)
+
+	list, err := service.List(ctx)
+	require.NoError(t, err)
+	for _, k := range list {
+		if k.Id == key.Id {
+			require.NotNil(t, k.LastUsedAt)
+		}
+	}
+
+	require.NoError(t, service.Revoke(ctx, key.Id))
+	_, err = service.Authenticate(ctx, token)
+	require.ErrorIs(t, err, apikeys.ErrInvalidKey)
+
+	require.ErrorIs(t, service.Revoke(ctx, uuid.New()), apikeys.ErrNotFound)
+}
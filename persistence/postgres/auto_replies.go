@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/autoreply"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AutoReplyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAutoReplyRepository(db *pgxpool.Pool) *AutoReplyRepository {
+	return &AutoReplyRepository{db: db}
+}
+
+var _ autoreply.Repository = &AutoReplyRepository{}
+
+const autoReplyColumns = `id, name, enabled, priority, match, conditions, actions, created_at, updated_at`
+
+func scanAutoReply(row pgx.Row) (*autoreply.Rule, error) {
+	var r autoreply.Rule
+	err := row.Scan(&r.Id, &r.Name, &r.Enabled, &r.Priority, &r.Match, &r.Conditions, &r.Actions, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *AutoReplyRepository) Create(ctx context.Context, rule autoreply.Rule) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO auto_reply_rules
+		(tenant_id, `+autoReplyColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`,
+		tenants.FromContext(ctx),
+		rule.Id,
+		rule.Name,
+		rule.Enabled,
+		rule.Priority,
+		rule.Match,
+		rule.Conditions,
+		rule.Actions,
+		rule.CreatedAt,
+		rule.UpdatedAt,
+	)
+	return err
+}
+
+func (r *AutoReplyRepository) Update(ctx context.Context, rule autoreply.Rule) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE auto_reply_rules
+		SET name = $2, enabled = $3, priority = $4, match = $5, conditions = $6, actions = $7, updated_at = $8
+		WHERE id = $1 AND tenant_id = $9
+		`,
+		rule.Id,
+		rule.Name,
+		rule.Enabled,
+		rule.Priority,
+		rule.Match,
+		rule.Conditions,
+		rule.Actions,
+		rule.UpdatedAt,
+		tenants.FromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return autoreply.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AutoReplyRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM auto_reply_rules WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return autoreply.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AutoReplyRepository) FindById(ctx context.Context, id uuid.UUID) (*autoreply.Rule, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+autoReplyColumns+` FROM auto_reply_rules WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	rule, err := scanAutoReply(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return rule, err
+}
+
+func (r *AutoReplyRepository) List(ctx context.Context) ([]autoreply.Rule, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+autoReplyColumns+` FROM auto_reply_rules WHERE tenant_id = $1 ORDER BY priority, created_at, id`, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []autoreply.Rule
+	for rows.Next() {
+		rule, err := scanAutoReply(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rule)
+	}
+	return out, rows.Err()
+}
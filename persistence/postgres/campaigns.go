@@ -0,0 +1,367 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/campaigns"
+	"mbx/tenants"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CampaignRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCampaignRepository(db *pgxpool.Pool) *CampaignRepository {
+	return &CampaignRepository{db: db}
+}
+
+var _ campaigns.Repository = &CampaignRepository{}
+
+const campaignColumns = `id, tenant_id, name, template_id, template_name, locale, variables, start_at, rate_per_minute, status, created_at, updated_at`
+
+// recipientQuery reports sent recipients as delivered, read or failed from the
+// status callbacks recorded on their message
+const recipientQuery = `
+	SELECT r.id, r.campaign_id, r.contact_id, r.phone, r.locale, r.variables,
+		CASE
+			WHEN r.status = 'sent' AND m.status = 'read' THEN 'read'
+			WHEN r.status = 'sent' AND m.status = 'delivered' THEN 'delivered'
+			WHEN r.status = 'sent' AND m.status IN ('failed', 'undelivered') THEN 'failed'
+			ELSE r.status
+		END AS status,
+		r.provider_sid, r.error, r.sent_at, r.updated_at
+	FROM campaign_recipients r
+	LEFT JOIN messages m ON r.provider_sid <> '' AND m.provider_sid = r.provider_sid
+`
+
+func scanCampaign(row pgx.Row) (*campaigns.Campaign, error) {
+	var c campaigns.Campaign
+	err := row.Scan(&c.Id, &c.TenantId, &c.Name, &c.TemplateId, &c.TemplateName, &c.Locale, &c.Variables, &c.StartAt, &c.RatePerMinute, &c.Status, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func scanRecipient(row pgx.Row) (*campaigns.Recipient, error) {
+	var r campaigns.Recipient
+	err := row.Scan(&r.Id, &r.CampaignId, &r.ContactId, &r.Phone, &r.Locale, &r.Variables, &r.Status, &r.ProviderSid, &r.Error, &r.SentAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *CampaignRepository) Create(ctx context.Context, campaign campaigns.Campaign, recipients []campaigns.Recipient) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO campaigns
+		(`+campaignColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`,
+		campaign.Id,
+		tenants.FromContext(ctx),
+		campaign.Name,
+		campaign.TemplateId,
+		campaign.TemplateName,
+		campaign.Locale,
+		campaign.Variables,
+		campaign.StartAt,
+		campaign.RatePerMinute,
+		campaign.Status,
+		campaign.CreatedAt,
+		campaign.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	// lists can be large, so the recipients are copied rather than inserted
+	// one statement at a time
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"campaign_recipients"},
+		[]string{"id", "campaign_id", "contact_id", "phone", "locale", "variables", "status", "updated_at"},
+		pgx.CopyFromSlice(len(recipients), func(i int) ([]any, error) {
+			rc := recipients[i]
+			return []any{rc.Id, rc.CampaignId, rc.ContactId, rc.Phone, rc.Locale, rc.Variables, rc.Status, rc.UpdatedAt}, nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *CampaignRepository) AddRecipients(ctx context.Context, recipients []campaigns.Recipient) ([]bool, error) {
+	batch := &pgx.Batch{}
+	for _, rc := range recipients {
+		batch.Queue(`
+			INSERT INTO campaign_recipients (id, campaign_id, contact_id, phone, locale, variables, status, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (campaign_id, phone) DO NOTHING
+			`, rc.Id, rc.CampaignId, rc.ContactId, rc.Phone, rc.Locale, rc.Variables, rc.Status, rc.UpdatedAt)
+	}
+
+	results := r.db.SendBatch(ctx, batch)
+	defer results.Close()
+
+	added := make([]bool, len(recipients))
+	for i := range recipients {
+		tag, err := results.Exec()
+		if err != nil {
+			return nil, err
+		}
+		added[i] = tag.RowsAffected() > 0
+	}
+	return added, results.Close()
+}
+
+func (r *CampaignRepository) FindById(ctx context.Context, id uuid.UUID) (*campaigns.Campaign, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+campaignColumns+` FROM campaigns WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	campaign, err := scanCampaign(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return campaign, err
+}
+
+func (r *CampaignRepository) List(ctx context.Context) ([]campaigns.Campaign, error) {
+	return r.list(ctx, `SELECT `+campaignColumns+` FROM campaigns WHERE tenant_id = $1 ORDER BY created_at DESC, id`, tenants.FromContext(ctx))
+}
+
+// ListActive reads the active campaigns of every tenant, for the runner to
+// send each one scoped to its own
+func (r *CampaignRepository) ListActive(ctx context.Context, now time.Time) ([]campaigns.Campaign, error) {
+	return r.list(ctx, `
+		SELECT `+campaignColumns+`
+		FROM campaigns
+		WHERE status = 'running' OR (status = 'scheduled' AND start_at <= $1)
+		ORDER BY start_at, id
+		`, now)
+}
+
+func (r *CampaignRepository) list(ctx context.Context, query string, args ...any) ([]campaigns.Campaign, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []campaigns.Campaign
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *campaign)
+	}
+	return out, rows.Err()
+}
+
+func (r *CampaignRepository) Transition(ctx context.Context, id uuid.UUID, status campaigns.Status, from ...campaigns.Status) (bool, error) {
+	names := make([]string, 0, len(from))
+	for _, s := range from {
+		names = append(names, string(s))
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE campaigns
+		SET status = $2, updated_at = $3
+		WHERE id = $1 AND status = ANY($4::text[]) AND tenant_id = $5
+		`, id, status, time.Now(), names, tenants.FromContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *CampaignRepository) ClaimRecipients(ctx context.Context, campaignId uuid.UUID, limit int, staleBefore time.Time) ([]campaigns.Recipient, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE campaign_recipients
+		SET status = 'sending', claimed_at = $3, updated_at = $3
+		WHERE id IN (
+			SELECT id FROM campaign_recipients
+			WHERE campaign_id = $1
+			AND (status = 'queued' OR (status = 'sending' AND claimed_at < $5))
+			AND campaign_id IN (SELECT id FROM campaigns WHERE tenant_id = $4)
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, campaign_id, contact_id, phone, locale, variables, status, provider_sid, error, sent_at, updated_at
+		`, campaignId, limit, time.Now(), tenants.FromContext(ctx), staleBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []campaigns.Recipient
+	for rows.Next() {
+		recipient, err := scanRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *recipient)
+	}
+	return out, rows.Err()
+}
+
+// UpdateRecipient records the outcome of a send. A recipient canceled while
+// it was being sent keeps the outcome, but is not put back in the queue.
+func (r *CampaignRepository) UpdateRecipient(ctx context.Context, recipient campaigns.Recipient) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE campaign_recipients
+		SET status = $2, provider_sid = $3, error = $4, sent_at = $5, updated_at = $6
+		WHERE id = $1 AND campaign_id IN (SELECT id FROM campaigns WHERE tenant_id = $7)
+		AND NOT (status = 'canceled' AND $2 = 'queued')
+		`,
+		recipient.Id,
+		recipient.Status,
+		recipient.ProviderSid,
+		recipient.Error,
+		recipient.SentAt,
+		recipient.UpdatedAt,
+		tenants.FromContext(ctx),
+	)
+	return err
+}
+
+// Pace takes the sends of the campaign whose theoretical arrival time is at
+// most burst intervals ahead of now, and moves it past them. A campaign that
+// was not paced yet starts with one send. The row lock makes it atomic across
+// replicas.
+func (r *CampaignRepository) Pace(ctx context.Context, campaignId uuid.UUID, now time.Time, interval time.Duration, burst int) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var pacedUntil *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT paced_until FROM campaigns
+		WHERE id = $1 AND tenant_id = $2
+		FOR UPDATE
+		`, campaignId, tenants.FromContext(ctx)).Scan(&pacedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now = now.UTC()
+	tat := now.Add(time.Duration(burst-1) * interval)
+	if pacedUntil != nil {
+		tat = *pacedUntil
+	}
+	if tat.Before(now) {
+		tat = now
+	}
+	n := min(int(now.Add(time.Duration(burst)*interval).Sub(tat)/interval), burst)
+	if n <= 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE campaigns SET paced_until = $2 WHERE id = $1`, campaignId, tat.Add(time.Duration(n)*interval)); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit(ctx)
+}
+
+func (r *CampaignRepository) Refund(ctx context.Context, campaignId uuid.UUID, interval time.Duration, n int) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE campaigns
+		SET paced_until = paced_until - $2::bigint * interval '1 microsecond'
+		WHERE id = $1 AND paced_until IS NOT NULL AND tenant_id = $3
+		`, campaignId, (time.Duration(n) * interval).Microseconds(), tenants.FromContext(ctx))
+	return err
+}
+
+// CancelQueued cancels the queued recipients and the ones being sent. Those
+// would otherwise stay sending for good, as no runner claims the recipients
+// of a canceled campaign again; a runner still sending one records the
+// outcome over the cancel.
+func (r *CampaignRepository) CancelQueued(ctx context.Context, campaignId uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE campaign_recipients
+		SET status = 'canceled', updated_at = $2
+		WHERE campaign_id = $1 AND status IN ('queued', 'sending') AND campaign_id IN (SELECT id FROM campaigns WHERE tenant_id = $3)
+		`, campaignId, time.Now(), tenants.FromContext(ctx))
+	return err
+}
+
+func (r *CampaignRepository) ListRecipients(ctx context.Context, campaignId uuid.UUID, status campaigns.RecipientStatus, limit int, offset int) ([]campaigns.Recipient, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT * FROM (`+recipientQuery+`
+			WHERE r.campaign_id = $1 AND r.campaign_id IN (SELECT id FROM campaigns WHERE tenant_id = $5)
+		) recipients
+		WHERE ($2::text = '' OR status = $2::text)
+		ORDER BY phone
+		LIMIT $3 OFFSET $4
+		`, campaignId, status, limit, offset, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []campaigns.Recipient
+	for rows.Next() {
+		recipient, err := scanRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *recipient)
+	}
+	return out, rows.Err()
+}
+
+func (r *CampaignRepository) Progress(ctx context.Context, campaignId uuid.UUID) (*campaigns.Progress, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT status, COUNT(*) FROM (`+recipientQuery+`
+			WHERE r.campaign_id = $1 AND r.campaign_id IN (SELECT id FROM campaigns WHERE tenant_id = $2)
+		) recipients
+		GROUP BY status
+		`, campaignId, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var progress campaigns.Progress
+	for rows.Next() {
+		var status campaigns.RecipientStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+
+		progress.Total += count
+		switch status {
+		case campaigns.RecipientQueued, campaigns.RecipientSending:
+			progress.Queued += count
+		case campaigns.RecipientSent:
+			progress.Sent += count
+		case campaigns.RecipientDelivered:
+			progress.Delivered += count
+		case campaigns.RecipientRead:
+			progress.Read += count
+		case campaigns.RecipientFailed:
+			progress.Failed += count
+		case campaigns.RecipientCanceled:
+			progress.Canceled += count
+		}
+	}
+	return &progress, rows.Err()
+}
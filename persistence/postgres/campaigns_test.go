@@ -0,0 +1,238 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/campaigns"
+	"mbx/history"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaigns_ClaimAndProgress(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCampaignRepository(testDB)
+	historyRepo := NewHistoryRepository(testDB)
+
+	now := time.Now().Truncate(time.Millisecond)
+	campaign := campaigns.Campaign{
+		Id:            uuid.New(),
+		Name:          "Black Friday",
+		TemplateId:    "HX123",
+		Variables:     map[string]string{"1": "50%"},
+		StartAt:       now.Add(-time.Minute),
+		RatePerMinute: 60,
+		Status:        campaigns.StatusScheduled,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	var recipients []campaigns.Recipient
+	for _, phone := range []string{"+5511999990201", "+5511999990202", "+5511999990203"} {
+		recipients = append(recipients, campaigns.Recipient{
+			Id:         uuid.New(),
+			CampaignId: campaign.Id,
+			Phone:      phone,
+			Variables:  map[string]string{"2": phone},
+			Status:     campaigns.RecipientQueued,
+			UpdatedAt:  now,
+		})
+	}
+	require.NoError(t, repo.Create(ctx, campaign, recipients))
+
+	active, err := repo.ListActive(ctx, now)
+	require.NoError(t, err)
+	require.Contains(t, campaignIds(active), campaign.Id)
+
+	ok, err := repo.Transition(ctx, campaign.Id, campaigns.StatusRunning, campaigns.StatusScheduled)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = repo.Transition(ctx, campaign.Id, campaigns.StatusRunning, campaigns.StatusScheduled)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	claimed, err := repo.ClaimRecipients(ctx, campaign.Id, 2, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	require.Equal(t, campaigns.RecipientSending, claimed[0].Status)
+
+	sentAt := now
+	sent := claimed[0]
+	sent.Status = campaigns.RecipientSent
+	sent.ProviderSid = "SM" + uuid.NewString()
+	sent.SentAt = &sentAt
+	require.NoError(t, repo.UpdateRecipient(ctx, sent))
+	failed := claimed[1]
+	failed.Status = campaigns.RecipientFailed
+	failed.Error = "message rejected"
+	require.NoError(t, repo.UpdateRecipient(ctx, failed))
+
+	// the status callback of the sent message marks the recipient as read
+	require.NoError(t, historyRepo.Record(ctx, history.Message{
+		Id:          uuid.New(),
+		Direction:   history.DirectionOutbound,
+		Phone:       sent.Phone,
+		ProviderSid: sent.ProviderSid,
+		Status:      "read",
+		CreatedAt:   now,
+	}))
+
+	require.NoError(t, repo.CancelQueued(ctx, campaign.Id))
+
+	progress, err := repo.Progress(ctx, campaign.Id)
+	require.NoError(t, err)
+	require.Equal(t, campaigns.Progress{Total: 3, Read: 1, Failed: 1, Canceled: 1}, *progress)
+
+	read, err := repo.ListRecipients(ctx, campaign.Id, campaigns.RecipientRead, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, read, 1)
+	require.Equal(t, sent.Id, read[0].Id)
+	require.Equal(t, map[string]string{"2": sent.Phone}, read[0].Variables)
+}
+
+func TestCampaigns_ReclaimsStaleRecipients(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCampaignRepository(testDB)
+
+	now := time.Now().Truncate(time.Millisecond)
+	campaign := campaigns.Campaign{
+		Id:            uuid.New(),
+		Name:          "Restock",
+		TemplateId:    "HX123",
+		Variables:     map[string]string{},
+		StartAt:       now,
+		RatePerMinute: 60,
+		Status:        campaigns.StatusRunning,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	recipient := campaigns.Recipient{
+		Id:         uuid.New(),
+		CampaignId: campaign.Id,
+		Phone:      "+5511999990211",
+		Variables:  map[string]string{},
+		Status:     campaigns.RecipientQueued,
+		UpdatedAt:  now,
+	}
+	require.NoError(t, repo.Create(ctx, campaign, []campaigns.Recipient{recipient}))
+
+	claimed, err := repo.ClaimRecipients(ctx, campaign.Id, 1, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// a recipient being sent is only claimed again once the claim is stale
+	claimed, err = repo.ClaimRecipients(ctx, campaign.Id, 1, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+	claimed, err = repo.ClaimRecipients(ctx, campaign.Id, 1, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, recipient.Id, claimed[0].Id)
+}
+
+func TestCampaigns_CancelsRecipientsBeingSent(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCampaignRepository(testDB)
+
+	now := time.Now().Truncate(time.Millisecond)
+	campaign := campaigns.Campaign{
+		Id:            uuid.New(),
+		Name:          "Clearance",
+		TemplateId:    "HX123",
+		Variables:     map[string]string{},
+		StartAt:       now,
+		RatePerMinute: 60,
+		Status:        campaigns.StatusRunning,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	var recipients []campaigns.Recipient
+	for _, phone := range []string{"+5511999990221", "+5511999990222"} {
+		recipients = append(recipients, campaigns.Recipient{
+			Id:         uuid.New(),
+			CampaignId: campaign.Id,
+			Phone:      phone,
+			Variables:  map[string]string{},
+			Status:     campaigns.RecipientQueued,
+			UpdatedAt:  now,
+		})
+	}
+	require.NoError(t, repo.Create(ctx, campaign, recipients))
+
+	claimed, err := repo.ClaimRecipients(ctx, campaign.Id, 2, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	require.NoError(t, repo.CancelQueued(ctx, campaign.Id))
+
+	progress, err := repo.Progress(ctx, campaign.Id)
+	require.NoError(t, err)
+	require.Equal(t, campaigns.Progress{Total: 2, Canceled: 2}, *progress)
+
+	// the runner that was sending records what it sent, but does not put
+	// what it could not send back in the queue
+	sent := claimed[0]
+	sent.Status = campaigns.RecipientSent
+	sent.SentAt = &now
+	require.NoError(t, repo.UpdateRecipient(ctx, sent))
+	requeued := claimed[1]
+	requeued.Status = campaigns.RecipientQueued
+	require.NoError(t, repo.UpdateRecipient(ctx, requeued))
+
+	progress, err = repo.Progress(ctx, campaign.Id)
+	require.NoError(t, err)
+	require.Equal(t, campaigns.Progress{Total: 2, Sent: 1, Canceled: 1}, *progress)
+}
+
+func TestCampaigns_Pace(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCampaignRepository(testDB)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	campaign := campaigns.Campaign{
+		Id:            uuid.New(),
+		Name:          "Paced",
+		TemplateId:    "HX123",
+		StartAt:       now,
+		RatePerMinute: 120,
+		Status:        campaigns.StatusRunning,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	require.NoError(t, repo.Create(ctx, campaign, nil))
+	interval := 500 * time.Millisecond
+
+	// a new campaign starts with one send, and runners on other replicas
+	// share what is left
+	n, err := repo.Pace(ctx, campaign.Id, now, interval, 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	n, err = repo.Pace(ctx, campaign.Id, now, interval, 2)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// a second later the burst is full again, and sends given back are
+	// taken again right away
+	later := now.Add(time.Second)
+	n, err = repo.Pace(ctx, campaign.Id, later, interval, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.NoError(t, repo.Refund(ctx, campaign.Id, interval, 1))
+	n, err = repo.Pace(ctx, campaign.Id, later, interval, 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	// an idle campaign does not save up more than the burst
+	n, err = repo.Pace(ctx, campaign.Id, later.Add(time.Hour), interval, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func campaignIds(list []campaigns.Campaign) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(list))
+	for _, c := range list {
+		ids = append(ids, c.Id)
+	}
+	return ids
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/consent"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ConsentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewConsentRepository(db *pgxpool.Pool) *ConsentRepository {
+	return &ConsentRepository{db: db}
+}
+
+var _ consent.Repository = &ConsentRepository{}
+
+const consentColumns = `id, contact_id, channel, purpose, action, source, evidence, recorded_at, created_at`
+
+func scanConsent(row pgx.Row) (*consent.Record, error) {
+	var c consent.Record
+	err := row.Scan(&c.Id, &c.ContactId, &c.Channel, &c.Purpose, &c.Action, &c.Source, &c.Evidence, &c.RecordedAt, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *ConsentRepository) Record(ctx context.Context, record consent.Record) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO consent_records
+		(`+consentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`,
+		record.Id,
+		record.ContactId,
+		record.Channel,
+		record.Purpose,
+		record.Action,
+		record.Source,
+		record.Evidence,
+		record.RecordedAt,
+		record.CreatedAt,
+	)
+	return err
+}
+
+func (r *ConsentRepository) ListByContact(ctx context.Context, contactId uuid.UUID) ([]consent.Record, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+consentColumns+`
+		FROM consent_records
+		WHERE contact_id = $1 AND contact_id IN (SELECT id FROM contacts WHERE tenant_id = $2)
+		ORDER BY recorded_at DESC, created_at DESC
+		`, contactId, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []consent.Record
+	for rows.Next() {
+		record, err := scanConsent(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+func (r *ConsentRepository) Latest(ctx context.Context, contactId uuid.UUID, channel consent.Channel, purpose consent.Purpose) (*consent.Record, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT `+consentColumns+`
+		FROM consent_records
+		WHERE contact_id = $1 AND channel = $2 AND purpose = $3 AND contact_id IN (SELECT id FROM contacts WHERE tenant_id = $4)
+		ORDER BY recorded_at DESC, created_at DESC
+		LIMIT 1
+		`, contactId, channel, purpose, tenants.FromContext(ctx))
+	record, err := scanConsent(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return record, err
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/contacts"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pgUniqueViolation is the SQLSTATE returned when a unique constraint fails
+const pgUniqueViolation = "23505"
+
+type ContactRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewContactRepository(db *pgxpool.Pool) *ContactRepository {
+	return &ContactRepository{db: db}
+}
+
+var _ contacts.Repository = &ContactRepository{}
+
+const contactColumns = `id, phone, name, locale, timezone, tags, attributes, created_at, updated_at`
+
+func scanContact(row pgx.Row) (*contacts.Contact, error) {
+	var c contacts.Contact
+	err := row.Scan(&c.Id, &c.Phone, &c.Name, &c.Locale, &c.Timezone, &c.Tags, &c.Attributes, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation
+}
+
+func (r *ContactRepository) Create(ctx context.Context, contact contacts.Contact) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO contacts
+		(tenant_id, `+contactColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`,
+		tenants.FromContext(ctx),
+		contact.Id,
+		contact.Phone,
+		contact.Name,
+		contact.Locale,
+		contact.Timezone,
+		contact.Tags,
+		contact.Attributes,
+		contact.CreatedAt,
+		contact.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return contacts.ErrDuplicate
+	}
+	return err
+}
+
+func (r *ContactRepository) Update(ctx context.Context, contact contacts.Contact) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE contacts
+		SET phone = $2, name = $3, locale = $4, timezone = $5, tags = $6, attributes = $7, updated_at = $8
+		WHERE id = $1 AND tenant_id = $9
+		`,
+		contact.Id,
+		contact.Phone,
+		contact.Name,
+		contact.Locale,
+		contact.Timezone,
+		contact.Tags,
+		contact.Attributes,
+		contact.UpdatedAt,
+		tenants.FromContext(ctx),
+	)
+	if isUniqueViolation(err) {
+		return contacts.ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return contacts.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ContactRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM contacts WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return contacts.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ContactRepository) FindById(ctx context.Context, id uuid.UUID) (*contacts.Contact, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+contactColumns+` FROM contacts WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	contact, err := scanContact(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return contact, err
+}
+
+func (r *ContactRepository) FindByPhone(ctx context.Context, phone string) (*contacts.Contact, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+contactColumns+` FROM contacts WHERE phone = $1 AND tenant_id = $2`, phone, tenants.FromContext(ctx))
+	contact, err := scanContact(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return contact, err
+}
+
+func (r *ContactRepository) List(ctx context.Context, filter contacts.ListFilter) ([]contacts.Contact, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+contactColumns+`
+		FROM contacts
+		WHERE tenant_id = $5
+		AND ($1::text = '' OR $1::text = ANY(tags))
+		AND ($2::text = '' OR name ILIKE '%' || $2::text || '%' OR phone LIKE '%' || $2::text || '%')
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+		`, filter.Tag, filter.Query, filter.Limit, filter.Offset, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []contacts.Contact
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *contact)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/contacts"
+	"mbx/history"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContact(phone string) contacts.Contact {
+	return contacts.Contact{
+		Id:         uuid.New(),
+		Phone:      phone,
+		Name:       "Maria Silva",
+		Locale:     "pt_BR",
+		Timezone:   "America/Sao_Paulo",
+		Tags:       []string{"vip"},
+		Attributes: map[string]string{"plan": "gold"},
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+}
+
+func TestContacts_CreateAndFind(t *testing.T) {
+	ctx := context.Background()
+	repo := NewContactRepository(testDB)
+
+	contact := newTestContact("+5511999990001")
+	require.NoError(t, repo.Create(ctx, contact))
+
+	gotten, err := repo.FindByPhone(ctx, contact.Phone)
+	require.NoError(t, err)
+	require.NotNil(t, gotten)
+	require.Equal(t, contact.Id, gotten.Id)
+	require.Equal(t, []string{"vip"}, gotten.Tags)
+	require.Equal(t, "gold", gotten.Attributes["plan"])
+
+	duplicate := newTestContact(contact.Phone)
+	require.ErrorIs(t, repo.Create(ctx, duplicate), contacts.ErrDuplicate)
+}
+
+func TestContacts_ListByTag(t *testing.T) {
+	ctx := context.Background()
+	repo := NewContactRepository(testDB)
+
+	tagged := newTestContact("+5511999990002")
+	tagged.Tags = []string{"list-by-tag"}
+	require.NoError(t, repo.Create(ctx, tagged))
+	require.NoError(t, repo.Create(ctx, newTestContact("+5511999990003")))
+
+	list, err := repo.List(ctx, contacts.ListFilter{Tag: "list-by-tag", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, tagged.Id, list[0].Id)
+}
+
+func TestContacts_UpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	repo := NewContactRepository(testDB)
+
+	contact := newTestContact("+5511999990004")
+	require.NoError(t, repo.Create(ctx, contact))
+
+	contact.Name = "Maria Souza"
+	require.NoError(t, repo.Update(ctx, contact))
+	gotten, err := repo.FindById(ctx, contact.Id)
+	require.NoError(t, err)
+	require.Equal(t, "Maria Souza", gotten.Name)
+
+	require.NoError(t, repo.Delete(ctx, contact.Id))
+	require.ErrorIs(t, repo.Delete(ctx, contact.Id), contacts.ErrNotFound)
+}
+
+func TestHistory_ListByContact(t *testing.T) {
+	ctx := context.Background()
+	contactRepo := NewContactRepository(testDB)
+	historyRepo := NewHistoryRepository(testDB)
+
+	contact := newTestContact("+5511999990005")
+	require.NoError(t, contactRepo.Create(ctx, contact))
+
+	err := historyRepo.Record(ctx, history.Message{
+		Id:          uuid.New(),
+		ContactId:   &contact.Id,
+		Direction:   history.DirectionOutbound,
+		Phone:       contact.Phone,
+		Body:        "Hello",
+		ProviderSid: "SM123",
+		Status:      "queued",
+		CreatedAt:   time.Now(),
+	})
+	require.NoError(t, err)
+
+	messages, err := historyRepo.ListByContact(ctx, contact.Id, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, "SM123", messages[0].ProviderSid)
+}
+
+func TestHistory_InteractionReply(t *testing.T) {
+	ctx := context.Background()
+	contactRepo := NewContactRepository(testDB)
+	historyRepo := NewHistoryRepository(testDB)
+
+	contact := newTestContact("+5511999990006")
+	require.NoError(t, contactRepo.Create(ctx, contact))
+
+	original := history.Message{
+		Id:          uuid.New(),
+		ContactId:   &contact.Id,
+		Direction:   history.DirectionOutbound,
+		Phone:       contact.Phone,
+		ProviderSid: "SM-menu",
+		CreatedAt:   time.Now(),
+	}
+	require.NoError(t, historyRepo.Record(ctx, original))
+	require.NoError(t, historyRepo.Record(ctx, history.Message{
+		Id:        uuid.New(),
+		ContactId: &contact.Id,
+		Direction: history.DirectionInbound,
+		Phone:     contact.Phone,
+		Body:      "Track order",
+		Interaction: &history.Interaction{
+			Type:         history.InteractionButton,
+			Payload:      "TRACK_ORDER",
+			Text:         "Track order",
+			RepliedToSid: original.ProviderSid,
+			RepliedToId:  &original.Id,
+		},
+		CreatedAt: time.Now().Add(time.Second),
+	}))
+
+	found, err := historyRepo.FindByProviderSid(ctx, "SM-menu")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.Equal(t, original.Id, found.Id)
+	require.Nil(t, found.Interaction)
+
+	messages, err := historyRepo.ListByContact(ctx, contact.Id, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.NotNil(t, messages[0].Interaction)
+	require.Equal(t, "TRACK_ORDER", messages[0].Interaction.Payload)
+	require.Equal(t, &original.Id, messages[0].Interaction.RepliedToId)
+}
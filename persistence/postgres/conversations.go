@@ -0,0 +1,279 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/conversations"
+	"mbx/tenants"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ConversationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewConversationRepository(db *pgxpool.Pool) *ConversationRepository {
+	return &ConversationRepository{db: db}
+}
+
+var _ conversations.Repository = &ConversationRepository{}
+
+// conversationQuery builds one row per contact of the tenant in $1 that
+// exchanged messages. The unread count covers inbound messages after the last
+// read, and is at least one for threads an agent marked unread.
+const conversationQuery = `
+	SELECT * FROM (
+		SELECT
+			c.id, c.phone, c.name, c.locale, c.timezone, c.tags, c.attributes, c.created_at, c.updated_at,
+			last.created_at AS last_message_at, last.body AS last_message, last.direction AS last_direction,
+			GREATEST(
+				(SELECT COUNT(*) FROM messages m
+				 WHERE m.contact_id = c.id AND m.direction = 'inbound'
+				 AND (s.last_read_at IS NULL OR m.created_at > s.last_read_at)),
+				CASE WHEN COALESCE(s.marked_unread, FALSE) THEN 1 ELSE 0 END
+			) AS unread_count,
+			s.last_read_at,
+			COALESCE(s.status, 'open') AS status,
+			s.assignee_id,
+			COALESCE(s.tags, '{}') AS tags,
+			COALESCE(s.archived, FALSE) AS archived
+		FROM contacts c
+		JOIN LATERAL (
+			SELECT m.created_at, m.body, m.direction
+			FROM messages m
+			WHERE m.contact_id = c.id
+			ORDER BY m.created_at DESC
+			LIMIT 1
+		) last ON TRUE
+		LEFT JOIN conversations s ON s.contact_id = c.id
+		WHERE c.tenant_id = $1
+	) conversation
+`
+
+func scanConversation(row pgx.Row) (*conversations.Conversation, error) {
+	var c conversations.Conversation
+	err := row.Scan(
+		&c.Contact.Id, &c.Contact.Phone, &c.Contact.Name, &c.Contact.Locale, &c.Contact.Timezone,
+		&c.Contact.Tags, &c.Contact.Attributes, &c.Contact.CreatedAt, &c.Contact.UpdatedAt,
+		&c.LastMessageAt, &c.LastMessage, &c.LastDirection,
+		&c.UnreadCount, &c.LastReadAt,
+		&c.Status, &c.AssigneeId, &c.Tags, &c.Archived,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *ConversationRepository) List(ctx context.Context, filter conversations.ListFilter) ([]conversations.Conversation, error) {
+	rows, err := r.db.Query(ctx, conversationQuery+`
+		WHERE archived = $2
+		AND (NOT $3::bool OR unread_count > 0)
+		AND ($4::text = '' OR status = $4::text)
+		AND ($5::uuid IS NULL OR assignee_id = $5::uuid)
+		AND ($6::text = '' OR $6::text = ANY(tags))
+		ORDER BY last_message_at DESC, id
+		LIMIT $7 OFFSET $8
+		`, tenants.FromContext(ctx), filter.Archived, filter.UnreadOnly, filter.Status, filter.AssigneeId, filter.Tag, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []conversations.Conversation
+	for rows.Next() {
+		conversation, err := scanConversation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *conversation)
+	}
+	return out, rows.Err()
+}
+
+func (r *ConversationRepository) Find(ctx context.Context, contactId uuid.UUID) (*conversations.Conversation, error) {
+	row := r.db.QueryRow(ctx, conversationQuery+`WHERE id = $2`, tenants.FromContext(ctx), contactId)
+	conversation, err := scanConversation(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return conversation, err
+}
+
+func (r *ConversationRepository) State(ctx context.Context, contactId uuid.UUID) (*conversations.State, error) {
+	state := conversations.State{Status: conversations.StatusOpen, Tags: []string{}}
+	err := r.db.QueryRow(ctx, `
+		SELECT status, assignee_id, tags, archived
+		FROM conversations
+		WHERE contact_id = $1 AND contact_id IN (SELECT id FROM contacts WHERE tenant_id = $2)
+		`, contactId, tenants.FromContext(ctx)).Scan(&state.Status, &state.AssigneeId, &state.Tags, &state.Archived)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// conversationWritten reports a write that found no contact of the tenant,
+// since the conversation tables are only scoped through their contact
+func conversationWritten(tag pgconn.CommandTag, err error) error {
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return conversations.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ConversationRepository) MarkRead(ctx context.Context, contactId uuid.UUID, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO conversations (contact_id, last_read_at, marked_unread, updated_at)
+		SELECT $1, $2, FALSE, CURRENT_TIMESTAMP
+		WHERE EXISTS (SELECT 1 FROM contacts WHERE id = $1 AND tenant_id = $3)
+		ON CONFLICT (contact_id) DO UPDATE
+		SET last_read_at = EXCLUDED.last_read_at, marked_unread = FALSE, updated_at = EXCLUDED.updated_at
+		`, contactId, at, tenants.FromContext(ctx))
+	return conversationWritten(tag, err)
+}
+
+func (r *ConversationRepository) MarkUnread(ctx context.Context, contactId uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO conversations (contact_id, marked_unread, updated_at)
+		SELECT $1, TRUE, CURRENT_TIMESTAMP
+		WHERE EXISTS (SELECT 1 FROM contacts WHERE id = $1 AND tenant_id = $2)
+		ON CONFLICT (contact_id) DO UPDATE
+		SET marked_unread = TRUE, updated_at = EXCLUDED.updated_at
+		`, contactId, tenants.FromContext(ctx))
+	return conversationWritten(tag, err)
+}
+
+func (r *ConversationRepository) SetArchived(ctx context.Context, contactId uuid.UUID, archived bool) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO conversations (contact_id, archived, updated_at)
+		SELECT $1, $2, CURRENT_TIMESTAMP
+		WHERE EXISTS (SELECT 1 FROM contacts WHERE id = $1 AND tenant_id = $3)
+		ON CONFLICT (contact_id) DO UPDATE
+		SET archived = EXCLUDED.archived, updated_at = EXCLUDED.updated_at
+		`, contactId, archived, tenants.FromContext(ctx))
+	return conversationWritten(tag, err)
+}
+
+func (r *ConversationRepository) SetStatus(ctx context.Context, contactId uuid.UUID, status conversations.Status) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO conversations (contact_id, status, updated_at)
+		SELECT $1, $2, CURRENT_TIMESTAMP
+		WHERE EXISTS (SELECT 1 FROM contacts WHERE id = $1 AND tenant_id = $3)
+		ON CONFLICT (contact_id) DO UPDATE
+		SET status = EXCLUDED.status, updated_at = EXCLUDED.updated_at
+		`, contactId, status, tenants.FromContext(ctx))
+	return conversationWritten(tag, err)
+}
+
+func (r *ConversationRepository) SetAssignee(ctx context.Context, contactId uuid.UUID, agentId *uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO conversations (contact_id, assignee_id, updated_at)
+		SELECT $1, $2, CURRENT_TIMESTAMP
+		WHERE EXISTS (SELECT 1 FROM contacts WHERE id = $1 AND tenant_id = $3)
+		ON CONFLICT (contact_id) DO UPDATE
+		SET assignee_id = EXCLUDED.assignee_id, updated_at = EXCLUDED.updated_at
+		`, contactId, agentId, tenants.FromContext(ctx))
+	return conversationWritten(tag, err)
+}
+
+func (r *ConversationRepository) SetTags(ctx context.Context, contactId uuid.UUID, tags []string) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO conversations (contact_id, tags, updated_at)
+		SELECT $1, $2, CURRENT_TIMESTAMP
+		WHERE EXISTS (SELECT 1 FROM contacts WHERE id = $1 AND tenant_id = $3)
+		ON CONFLICT (contact_id) DO UPDATE
+		SET tags = EXCLUDED.tags, updated_at = EXCLUDED.updated_at
+		`, contactId, tags, tenants.FromContext(ctx))
+	return conversationWritten(tag, err)
+}
+
+func (r *ConversationRepository) AddNote(ctx context.Context, note conversations.Note) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO conversation_notes
+		(id, contact_id, agent_id, body, created_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE EXISTS (SELECT 1 FROM contacts WHERE id = $2 AND tenant_id = $6)
+		`,
+		note.Id,
+		note.ContactId,
+		note.AgentId,
+		note.Body,
+		note.CreatedAt,
+		tenants.FromContext(ctx),
+	)
+	return conversationWritten(tag, err)
+}
+
+func (r *ConversationRepository) ListNotes(ctx context.Context, contactId uuid.UUID) ([]conversations.Note, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, contact_id, agent_id, body, created_at
+		FROM conversation_notes
+		WHERE contact_id = $1 AND contact_id IN (SELECT id FROM contacts WHERE tenant_id = $2)
+		ORDER BY created_at, id
+		`, contactId, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []conversations.Note
+	for rows.Next() {
+		var n conversations.Note
+		if err := rows.Scan(&n.Id, &n.ContactId, &n.AgentId, &n.Body, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+func (r *ConversationRepository) RecordEvent(ctx context.Context, event conversations.Event) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO conversation_events
+		(id, contact_id, agent_id, type, from_value, to_value, created_at)
+		SELECT $1, $2, $3, $4, $5, $6, $7
+		WHERE EXISTS (SELECT 1 FROM contacts WHERE id = $2 AND tenant_id = $8)
+		`,
+		event.Id,
+		event.ContactId,
+		event.AgentId,
+		event.Type,
+		event.From,
+		event.To,
+		event.CreatedAt,
+		tenants.FromContext(ctx),
+	)
+	return conversationWritten(tag, err)
+}
+
+func (r *ConversationRepository) ListEvents(ctx context.Context, contactId uuid.UUID) ([]conversations.Event, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, contact_id, agent_id, type, from_value, to_value, created_at
+		FROM conversation_events
+		WHERE contact_id = $1 AND contact_id IN (SELECT id FROM contacts WHERE tenant_id = $2)
+		ORDER BY created_at, id
+		`, contactId, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []conversations.Event
+	for rows.Next() {
+		var e conversations.Event
+		if err := rows.Scan(&e.Id, &e.ContactId, &e.AgentId, &e.Type, &e.From, &e.To, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
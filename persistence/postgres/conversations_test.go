@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/conversations"
+	"mbx/history"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversations_UnreadAndArchive(t *testing.T) {
+	ctx := context.Background()
+	contactRepo := NewContactRepository(testDB)
+	historyRepo := NewHistoryRepository(testDB)
+	repo := NewConversationRepository(testDB)
+
+	contact := newTestContact("+5511999990101")
+	require.NoError(t, contactRepo.Create(ctx, contact))
+
+	now := time.Now().Truncate(time.Millisecond)
+	for i, direction := range []history.Direction{history.DirectionInbound, history.DirectionOutbound, history.DirectionInbound} {
+		require.NoError(t, historyRepo.Record(ctx, history.Message{
+			Id:        uuid.New(),
+			ContactId: &contact.Id,
+			Direction: direction,
+			Phone:     contact.Phone,
+			Body:      "message",
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	conversation, err := repo.Find(ctx, contact.Id)
+	require.NoError(t, err)
+	require.NotNil(t, conversation)
+	require.Equal(t, 2, conversation.UnreadCount)
+	require.Equal(t, history.DirectionInbound, conversation.LastDirection)
+
+	require.NoError(t, repo.MarkRead(ctx, contact.Id, now.Add(time.Minute)))
+	conversation, err = repo.Find(ctx, contact.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1, conversation.UnreadCount)
+
+	require.NoError(t, repo.SetArchived(ctx, contact.Id, true))
+	archived, err := repo.Find(ctx, contact.Id)
+	require.NoError(t, err)
+	require.True(t, archived.Archived)
+
+	require.NoError(t, repo.MarkRead(ctx, contact.Id, now.Add(time.Hour)))
+	require.NoError(t, repo.MarkUnread(ctx, contact.Id))
+	conversation, err = repo.Find(ctx, contact.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1, conversation.UnreadCount)
+	require.True(t, conversation.Archived)
+}
+
+func TestConversations_WritesStayInTenant(t *testing.T) {
+	ctx := context.Background()
+	contactRepo := NewContactRepository(testDB)
+	tenantRepo := NewTenantRepository(testDB)
+	repo := NewConversationRepository(testDB)
+
+	contact := newTestContact("+5511999990121")
+	require.NoError(t, contactRepo.Create(ctx, contact))
+
+	other := tenants.Tenant{Id: uuid.New(), Name: "Other", FromNumber: "+15550121", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, tenantRepo.Create(ctx, other))
+	otherCtx := tenants.NewContext(ctx, other.Id)
+
+	require.ErrorIs(t, repo.SetArchived(otherCtx, contact.Id, true), conversations.ErrNotFound)
+	require.ErrorIs(t, repo.MarkRead(otherCtx, contact.Id, time.Now()), conversations.ErrNotFound)
+	require.ErrorIs(t, repo.AddNote(otherCtx, conversations.Note{Id: uuid.New(), ContactId: contact.Id, Body: "note", CreatedAt: time.Now()}), conversations.ErrNotFound)
+
+	state, err := repo.State(ctx, contact.Id)
+	require.NoError(t, err)
+	require.False(t, state.Archived)
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"mbx/events"
+	"mbx/tenants"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEventRepository(db *pgxpool.Pool) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+var _ events.Repository = &EventRepository{}
+
+func (r *EventRepository) Append(ctx context.Context, event *events.Event) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO events (tenant_id, type, contact_id, data, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING seq
+		`,
+		tenants.FromContext(ctx),
+		event.Type,
+		event.ContactId,
+		event.Data,
+		event.CreatedAt,
+	).Scan(&event.Seq)
+}
+
+func (r *EventRepository) ListAfter(ctx context.Context, seq int64, filter events.Filter, limit int) ([]events.Event, error) {
+	types := make([]string, 0, len(filter.Types))
+	for _, t := range filter.Types {
+		types = append(types, string(t))
+	}
+
+	// seq is taken before the event commits, so events are listed by the
+	// transaction that wrote them instead, and only once that transaction is
+	// older than every one still running. Events committed later always
+	// come after the listed ones, and the cursor is the position of the event
+	// with seq.
+	rows, err := r.db.Query(ctx, `
+		SELECT seq, type, contact_id, data, created_at
+		FROM events
+		WHERE (xid, seq) > (COALESCE((SELECT xid FROM events WHERE seq = $1 AND tenant_id = $5), '0'::xid8), $1)
+		AND xid < pg_snapshot_xmin(pg_current_snapshot())
+		AND tenant_id = $5
+		AND (cardinality($2::text[]) = 0 OR type = ANY($2::text[]))
+		AND ($3::uuid IS NULL OR contact_id = $3::uuid)
+		ORDER BY xid, seq
+		LIMIT $4
+		`, seq, types, filter.ContactId, limit, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []events.Event
+	for rows.Next() {
+		var e events.Event
+		if err := rows.Scan(&e.Seq, &e.Type, &e.ContactId, &e.Data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *EventRepository) Resume(ctx context.Context, seq int64) (int64, error) {
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(
+			(SELECT seq FROM events WHERE seq = $1 AND tenant_id = $2),
+			(
+				SELECT seq FROM events
+				WHERE tenant_id = $2 AND xid < pg_snapshot_xmin(pg_current_snapshot())
+				ORDER BY xid DESC, seq DESC
+				LIMIT 1
+			),
+			0
+		)
+		`, seq, tenants.FromContext(ctx)).Scan(&seq)
+	return seq, err
+}
+
+func (r *EventRepository) LastSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE((
+			SELECT seq FROM events
+			WHERE tenant_id = $1 AND xid < pg_snapshot_xmin(pg_current_snapshot())
+			ORDER BY xid DESC, seq DESC
+			LIMIT 1
+		), 0)
+		`, tenants.FromContext(ctx)).Scan(&seq)
+	return seq, err
+}
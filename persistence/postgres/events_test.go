@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/events"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents_HoldsBackEventsCommittedOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	tenant := tenants.Tenant{Id: uuid.New(), Name: "Events", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, NewTenantRepository(testDB).Create(ctx, tenant))
+	ctx = tenants.NewContext(ctx, tenant.Id)
+	repo := NewEventRepository(testDB)
+
+	// the first event takes its seq in a transaction that commits last
+	tx, err := testDB.Begin(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback(ctx)
+	var first int64
+	require.NoError(t, tx.QueryRow(ctx, `
+		INSERT INTO events (tenant_id, type, data, created_at)
+		VALUES ($1, $2, '{}', $3)
+		RETURNING seq
+		`, tenant.Id, events.MessageCreated, time.Now()).Scan(&first))
+
+	second := events.Event{Type: events.StatusChanged, Data: []byte(`{}`), CreatedAt: time.Now()}
+	require.NoError(t, repo.Append(ctx, &second))
+	require.Greater(t, second.Seq, first)
+
+	// the committed event waits for the one still in flight
+	list, err := repo.ListAfter(ctx, 0, events.Filter{}, 10)
+	require.NoError(t, err)
+	require.Empty(t, list)
+	last, err := repo.LastSeq(ctx)
+	require.NoError(t, err)
+	require.Zero(t, last)
+
+	require.NoError(t, tx.Commit(ctx))
+	list, err = repo.ListAfter(ctx, 0, events.Filter{}, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, first, list[0].Seq)
+	require.Equal(t, second.Seq, list[1].Seq)
+
+	// resuming after the first event still gets the second one
+	resumed, err := repo.Resume(ctx, first)
+	require.NoError(t, err)
+	require.Equal(t, first, resumed)
+	list, err = repo.ListAfter(ctx, first, events.Filter{}, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, second.Seq, list[0].Seq)
+}
+
+func TestEvents_ResumesUnknownSeqFromNow(t *testing.T) {
+	ctx := context.Background()
+	tenantRepo := NewTenantRepository(testDB)
+	repo := NewEventRepository(testDB)
+
+	mine := tenants.Tenant{Id: uuid.New(), Name: "Mine", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	theirs := tenants.Tenant{Id: uuid.New(), Name: "Theirs", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, tenantRepo.Create(ctx, mine))
+	require.NoError(t, tenantRepo.Create(ctx, theirs))
+	mineCtx := tenants.NewContext(ctx, mine.Id)
+	theirsCtx := tenants.NewContext(ctx, theirs.Id)
+
+	own := events.Event{Type: events.MessageCreated, Data: []byte(`{}`), CreatedAt: time.Now()}
+	require.NoError(t, repo.Append(mineCtx, &own))
+	other := events.Event{Type: events.MessageCreated, Data: []byte(`{}`), CreatedAt: time.Now()}
+	require.NoError(t, repo.Append(theirsCtx, &other))
+
+	// neither a seq that does not exist nor one of another tenant replays
+	// the tenant's log
+	for _, seq := range []int64{other.Seq, other.Seq + 1000} {
+		resumed, err := repo.Resume(mineCtx, seq)
+		require.NoError(t, err)
+		require.Equal(t, own.Seq, resumed)
+		list, err := repo.ListAfter(mineCtx, resumed, events.Filter{}, 10)
+		require.NoError(t, err)
+		require.Empty(t, list)
+	}
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/flows"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FlowRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFlowRepository(db *pgxpool.Pool) *FlowRepository {
+	return &FlowRepository{db: db}
+}
+
+var _ flows.Repository = &FlowRepository{}
+
+const flowColumns = `id, name, enabled, trigger, start_node, nodes, timeout_minutes, created_at, updated_at`
+
+func scanFlow(row pgx.Row) (*flows.Flow, error) {
+	var f flows.Flow
+	err := row.Scan(&f.Id, &f.Name, &f.Enabled, &f.Trigger, &f.Start, &f.Nodes, &f.TimeoutMinutes, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *FlowRepository) Create(ctx context.Context, flow flows.Flow) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO flows
+		(tenant_id, `+flowColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`,
+		tenants.FromContext(ctx),
+		flow.Id,
+		flow.Name,
+		flow.Enabled,
+		flow.Trigger,
+		flow.Start,
+		flow.Nodes,
+		flow.TimeoutMinutes,
+		flow.CreatedAt,
+		flow.UpdatedAt,
+	)
+	return err
+}
+
+func (r *FlowRepository) Update(ctx context.Context, flow flows.Flow) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE flows
+		SET name = $2, enabled = $3, trigger = $4, start_node = $5, nodes = $6, timeout_minutes = $7, updated_at = $8
+		WHERE id = $1 AND tenant_id = $9
+		`,
+		flow.Id,
+		flow.Name,
+		flow.Enabled,
+		flow.Trigger,
+		flow.Start,
+		flow.Nodes,
+		flow.TimeoutMinutes,
+		flow.UpdatedAt,
+		tenants.FromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return flows.ErrNotFound
+	}
+	return nil
+}
+
+func (r *FlowRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM flows WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return flows.ErrNotFound
+	}
+	return nil
+}
+
+func (r *FlowRepository) FindById(ctx context.Context, id uuid.UUID) (*flows.Flow, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+flowColumns+` FROM flows WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	flow, err := scanFlow(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return flow, err
+}
+
+func (r *FlowRepository) List(ctx context.Context) ([]flows.Flow, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+flowColumns+` FROM flows WHERE tenant_id = $1 ORDER BY created_at, id`, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []flows.Flow
+	for rows.Next() {
+		flow, err := scanFlow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *flow)
+	}
+	return out, rows.Err()
+}
+
+type FlowSessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFlowSessionRepository(db *pgxpool.Pool) *FlowSessionRepository {
+	return &FlowSessionRepository{db: db}
+}
+
+var _ flows.SessionRepository = &FlowSessionRepository{}
+
+func (r *FlowSessionRepository) Find(ctx context.Context, contactId uuid.UUID) (*flows.Session, error) {
+	var s flows.Session
+	err := r.db.QueryRow(ctx, `
+		SELECT contact_id, flow_id, node, variables, attempts, expires_at, created_at, updated_at
+		FROM flow_sessions
+		WHERE contact_id = $1 AND contact_id IN (SELECT id FROM contacts WHERE tenant_id = $2)
+		`, contactId, tenants.FromContext(ctx)).Scan(&s.ContactId, &s.FlowId, &s.Node, &s.Variables, &s.Attempts, &s.ExpiresAt, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *FlowSessionRepository) Save(ctx context.Context, session flows.Session) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO flow_sessions
+		(contact_id, flow_id, node, variables, attempts, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (contact_id) DO UPDATE
+		SET flow_id = EXCLUDED.flow_id, node = EXCLUDED.node, variables = EXCLUDED.variables,
+			attempts = EXCLUDED.attempts, expires_at = EXCLUDED.expires_at, updated_at = EXCLUDED.updated_at
+		`,
+		session.ContactId,
+		session.FlowId,
+		session.Node,
+		session.Variables,
+		session.Attempts,
+		session.ExpiresAt,
+		session.CreatedAt,
+		session.UpdatedAt,
+	)
+	return err
+}
+
+func (r *FlowSessionRepository) Delete(ctx context.Context, contactId uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM flow_sessions WHERE contact_id = $1 AND contact_id IN (SELECT id FROM contacts WHERE tenant_id = $2)`, contactId, tenants.FromContext(ctx))
+	return err
+}
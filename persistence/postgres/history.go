@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/history"
+	"mbx/tenants"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HistoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewHistoryRepository(db *pgxpool.Pool) *HistoryRepository {
+	return &HistoryRepository{db: db}
+}
+
+var _ history.Repository = &HistoryRepository{}
+
+const historyColumns = `id, contact_id, direction, phone, body, template_id, provider_sid, status, interaction, sender, created_at, trace_parent`
+
+func scanMessage(row pgx.Row) (*history.Message, error) {
+	var m history.Message
+	err := row.Scan(&m.Id, &m.ContactId, &m.Direction, &m.Phone, &m.Body, &m.TemplateId, &m.ProviderSid, &m.Status, &m.Interaction, &m.Sender, &m.CreatedAt, &m.TraceParent)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *HistoryRepository) Record(ctx context.Context, message history.Message) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO messages
+		(tenant_id, `+historyColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		`,
+		tenants.FromContext(ctx),
+		message.Id,
+		message.ContactId,
+		message.Direction,
+		message.Phone,
+		message.Body,
+		message.TemplateId,
+		message.ProviderSid,
+		message.Status,
+		message.Interaction,
+		message.Sender,
+		message.CreatedAt,
+		message.TraceParent,
+	)
+	return err
+}
+
+func (r *HistoryRepository) ListByContact(ctx context.Context, contactId uuid.UUID, limit int) ([]history.Message, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+historyColumns+`
+		FROM messages
+		WHERE contact_id = $1 AND tenant_id = $3
+		ORDER BY created_at DESC
+		LIMIT $2
+		`, contactId, limit, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []history.Message
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *m)
+	}
+	return messages, rows.Err()
+}
+
+func (r *HistoryRepository) FindByProviderSid(ctx context.Context, sid string) (*history.Message, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+historyColumns+` FROM messages WHERE provider_sid = $1 AND tenant_id = $2 LIMIT 1`, sid, tenants.FromContext(ctx))
+	message, err := scanMessage(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return message, err
+}
+
+func (r *HistoryRepository) UpdateStatus(ctx context.Context, sid string, status string) error {
+	_, err := r.db.Exec(ctx, `UPDATE messages SET status = $2 WHERE provider_sid = $1 AND tenant_id = $3`, sid, status, tenants.FromContext(ctx))
+	return err
+}
+
+func (r *HistoryRepository) LastMessageAt(ctx context.Context, contactId uuid.UUID, direction history.Direction) (*time.Time, error) {
+	var last *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT MAX(created_at)
+		FROM messages
+		WHERE contact_id = $1 AND direction = $2 AND tenant_id = $3
+		`, contactId, direction, tenants.FromContext(ctx)).Scan(&last)
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/idempotency"
+	"mbx/tenants"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdempotencyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(db *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+var _ idempotency.Repository = &IdempotencyRepository{}
+
+func (r *IdempotencyRepository) Claim(ctx context.Context, record idempotency.Record, stale time.Time) (*idempotency.Record, error) {
+	tenantId := tenants.FromContext(ctx)
+	// a key released between the claim and the lookup is claimed again
+	for range 3 {
+		var claimed string
+		err := r.db.QueryRow(ctx, `
+			INSERT INTO idempotency_keys (tenant_id, key, fingerprint, claim, created_at, expires_at)
+			VALUES ($1, $2, $3, $7, $4, $5)
+			ON CONFLICT (tenant_id, key) DO UPDATE
+			SET fingerprint = EXCLUDED.fingerprint, claim = EXCLUDED.claim, status = 0, content_type = '', body = '',
+				created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+				OR (idempotency_keys.status = 0 AND idempotency_keys.created_at <= $6)
+			RETURNING key
+			`,
+			tenantId,
+			record.Key,
+			record.Fingerprint,
+			record.CreatedAt,
+			record.ExpiresAt,
+			stale,
+			record.Claim,
+		).Scan(&claimed)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+
+		var existing idempotency.Record
+		err = r.db.QueryRow(ctx, `
+			SELECT key, fingerprint, status, content_type, body, created_at, expires_at
+			FROM idempotency_keys
+			WHERE tenant_id = $1 AND key = $2
+			`, tenantId, record.Key).Scan(
+			&existing.Key,
+			&existing.Fingerprint,
+			&existing.Status,
+			&existing.ContentType,
+			&existing.Body,
+			&existing.CreatedAt,
+			&existing.ExpiresAt,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	return nil, idempotency.ErrInProgress
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, claim uuid.UUID, status int, contentType string, body []byte) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status = $3, content_type = $4, body = $5
+		WHERE tenant_id = $1 AND key = $2 AND claim = $6 AND status = 0
+		`, tenants.FromContext(ctx), key, status, contentType, body, claim)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return idempotency.ErrClaimLost
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, key string, claim uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE tenant_id = $1 AND key = $2 AND claim = $3 AND status = 0
+		`, tenants.FromContext(ctx), key, claim)
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/idempotency"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency_TakeoverKeepsTheNewClaim(t *testing.T) {
+	ctx := context.Background()
+	repo := NewIdempotencyRepository(testDB)
+
+	now := time.Now().Truncate(time.Millisecond)
+	first := idempotency.Record{Key: "order-" + uuid.NewString(), Fingerprint: "abc", Claim: uuid.New(), CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	existing, err := repo.Claim(ctx, first, now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	// the first request went stale, and a retry takes the key over
+	retry := first
+	retry.Claim = uuid.New()
+	retry.CreatedAt = now
+	existing, err = repo.Claim(ctx, retry, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	// the first request finishing late neither overwrites nor frees the key
+	require.ErrorIs(t, repo.Complete(ctx, first.Key, first.Claim, 201, "application/json", []byte(`{}`)), idempotency.ErrClaimLost)
+	require.NoError(t, repo.Release(ctx, first.Key, first.Claim))
+
+	require.NoError(t, repo.Complete(ctx, retry.Key, retry.Claim, 202, "application/json", []byte(`{"retry":true}`)))
+	existing, err = repo.Claim(ctx, retry, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	require.Equal(t, 202, existing.Status)
+}
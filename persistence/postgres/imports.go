@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/imports"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ImportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewImportRepository(db *pgxpool.Pool) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+var _ imports.Repository = &ImportRepository{}
+
+const importColumns = `id, campaign_id, variables, status, total_rows, imported, failed, error, created_at, updated_at, completed_at`
+
+func (r *ImportRepository) Create(ctx context.Context, job imports.Job) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO imports
+		(tenant_id, `+importColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`,
+		tenants.FromContext(ctx),
+		job.Id,
+		job.CampaignId,
+		job.Variables,
+		job.Status,
+		job.Rows,
+		job.Imported,
+		job.Failed,
+		job.Error,
+		job.CreatedAt,
+		job.UpdatedAt,
+		job.CompletedAt,
+	)
+	return err
+}
+
+func (r *ImportRepository) Update(ctx context.Context, job imports.Job) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE imports
+		SET status = $2, total_rows = $3, imported = $4, failed = $5, error = $6, updated_at = $7, completed_at = $8
+		WHERE id = $1 AND tenant_id = $9
+		`,
+		job.Id,
+		job.Status,
+		job.Rows,
+		job.Imported,
+		job.Failed,
+		job.Error,
+		job.UpdatedAt,
+		job.CompletedAt,
+		tenants.FromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return imports.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ImportRepository) FindById(ctx context.Context, id uuid.UUID) (*imports.Job, error) {
+	var job imports.Job
+	err := r.db.QueryRow(ctx, `SELECT `+importColumns+` FROM imports WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx)).Scan(
+		&job.Id, &job.CampaignId, &job.Variables, &job.Status, &job.Rows, &job.Imported, &job.Failed, &job.Error, &job.CreatedAt, &job.UpdatedAt, &job.CompletedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ImportRepository) AddErrors(ctx context.Context, rowErrors []imports.RowError) error {
+	_, err := r.db.CopyFrom(ctx,
+		pgx.Identifier{"import_errors"},
+		[]string{"import_id", "row_number", "phone", "error"},
+		pgx.CopyFromSlice(len(rowErrors), func(i int) ([]any, error) {
+			e := rowErrors[i]
+			return []any{e.JobId, e.Row, e.Phone, e.Error}, nil
+		}),
+	)
+	return err
+}
+
+func (r *ImportRepository) ListErrors(ctx context.Context, jobId uuid.UUID, limit int, offset int) ([]imports.RowError, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT import_id, row_number, phone, error
+		FROM import_errors
+		WHERE import_id = $1 AND import_id IN (SELECT id FROM imports WHERE tenant_id = $4)
+		ORDER BY row_number
+		LIMIT $2 OFFSET $3
+		`, jobId, limit, offset, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []imports.RowError
+	for rows.Next() {
+		var e imports.RowError
+		if err := rows.Scan(&e.JobId, &e.Row, &e.Phone, &e.Error); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/imports"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImports_ProgressAndErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := NewImportRepository(testDB)
+
+	now := time.Now().Truncate(time.Millisecond)
+	job := imports.Job{
+		Id:        uuid.New(),
+		Variables: map[string]string{},
+		Status:    imports.StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, repo.Create(ctx, job))
+
+	require.NoError(t, repo.AddErrors(ctx, []imports.RowError{
+		{JobId: job.Id, Row: 7, Phone: "12", Error: "invalid phone number"},
+		{JobId: job.Id, Row: 2, Error: "phone is required"},
+	}))
+	job.Status = imports.StatusCompleted
+	job.Rows, job.Imported, job.Failed = 10, 8, 2
+	job.CompletedAt = &now
+	require.NoError(t, repo.Update(ctx, job))
+
+	found, err := repo.FindById(ctx, job.Id)
+	require.NoError(t, err)
+	require.Equal(t, imports.StatusCompleted, found.Status)
+	require.Equal(t, 8, found.Imported)
+	require.NotNil(t, found.CompletedAt)
+
+	rowErrors, err := repo.ListErrors(ctx, job.Id, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, rowErrors, 2)
+	require.Equal(t, 2, rowErrors[0].Row)
+	require.Equal(t, "12", rowErrors[1].Phone)
+
+	missing, err := repo.FindById(ctx, uuid.New())
+	require.NoError(t, err)
+	require.Nil(t, missing)
+}
@@ -0,0 +1,16 @@
+CREATE TABLE template_groups (
+  name VARCHAR(255) PRIMARY KEY,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE template_group_variants (
+  group_name VARCHAR(255) NOT NULL REFERENCES template_groups(name) ON DELETE CASCADE,
+  language VARCHAR(16) NOT NULL,
+  content_sid VARCHAR(255) NOT NULL,
+  PRIMARY KEY (group_name, language)
+);
+
+ALTER TABLE scheduled_messages
+  ADD COLUMN template_name VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN locale VARCHAR(16) NOT NULL DEFAULT '';
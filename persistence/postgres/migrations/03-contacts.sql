@@ -0,0 +1,32 @@
+CREATE TABLE contacts (
+  id UUID PRIMARY KEY,
+  phone VARCHAR(32) NOT NULL UNIQUE,
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  locale VARCHAR(16) NOT NULL DEFAULT '',
+  timezone VARCHAR(64) NOT NULL DEFAULT '',
+  tags TEXT[] NOT NULL DEFAULT '{}',
+  attributes JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX contacts_tags_idx ON contacts USING GIN (tags);
+
+ALTER TABLE scheduled_messages
+  ADD COLUMN contact_id UUID REFERENCES contacts(id) ON DELETE SET NULL;
+
+CREATE INDEX scheduled_messages_contact_id_idx ON scheduled_messages (contact_id);
+
+CREATE TABLE messages (
+  id UUID PRIMARY KEY,
+  contact_id UUID REFERENCES contacts(id) ON DELETE SET NULL,
+  direction VARCHAR(16) NOT NULL,
+  phone VARCHAR(32) NOT NULL,
+  body TEXT NOT NULL DEFAULT '',
+  template_id VARCHAR(255) NOT NULL DEFAULT '',
+  provider_sid VARCHAR(64) NOT NULL DEFAULT '',
+  status VARCHAR(32) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX messages_contact_id_created_at_idx ON messages (contact_id, created_at);
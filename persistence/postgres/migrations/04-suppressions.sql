@@ -0,0 +1,9 @@
+ALTER TYPE message_status ADD VALUE 'suppressed';
+
+CREATE TABLE suppressions (
+  phone VARCHAR(32) PRIMARY KEY,
+  contact_id UUID REFERENCES contacts(id) ON DELETE SET NULL,
+  reason VARCHAR(32) NOT NULL,
+  keyword VARCHAR(64) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,15 @@
+ALTER TYPE message_status ADD VALUE 'rejected';
+
+CREATE TABLE consent_records (
+  id UUID PRIMARY KEY,
+  contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+  channel VARCHAR(16) NOT NULL,
+  purpose VARCHAR(32) NOT NULL,
+  action VARCHAR(16) NOT NULL,
+  source VARCHAR(32) NOT NULL,
+  evidence TEXT NOT NULL DEFAULT '',
+  recorded_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX consent_records_contact_idx ON consent_records (contact_id, channel, purpose, recorded_at DESC);
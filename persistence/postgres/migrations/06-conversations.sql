@@ -0,0 +1,9 @@
+CREATE TABLE conversations (
+  contact_id UUID PRIMARY KEY REFERENCES contacts(id) ON DELETE CASCADE,
+  last_read_at TIMESTAMP,
+  marked_unread BOOLEAN NOT NULL DEFAULT FALSE,
+  archived BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX messages_contact_id_direction_created_at_idx ON messages (contact_id, direction, created_at);
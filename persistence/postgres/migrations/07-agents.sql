@@ -0,0 +1,36 @@
+CREATE TABLE agents (
+  id UUID PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL UNIQUE,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  auto_assign BOOLEAN NOT NULL DEFAULT FALSE,
+  last_assigned_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE conversations
+  ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'open',
+  ADD COLUMN assignee_id UUID REFERENCES agents(id) ON DELETE SET NULL,
+  ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE conversation_notes (
+  id UUID PRIMARY KEY,
+  contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+  agent_id UUID REFERENCES agents(id) ON DELETE SET NULL,
+  body TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX conversation_notes_contact_id_idx ON conversation_notes (contact_id, created_at);
+
+CREATE TABLE conversation_events (
+  id UUID PRIMARY KEY,
+  contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+  agent_id UUID REFERENCES agents(id) ON DELETE SET NULL,
+  type VARCHAR(32) NOT NULL,
+  from_value TEXT NOT NULL DEFAULT '',
+  to_value TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX conversation_events_contact_id_idx ON conversation_events (contact_id, created_at);
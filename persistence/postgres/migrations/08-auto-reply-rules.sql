@@ -0,0 +1,11 @@
+CREATE TABLE auto_reply_rules (
+  id UUID PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  priority INTEGER NOT NULL DEFAULT 0,
+  match JSONB NOT NULL,
+  conditions JSONB NOT NULL DEFAULT '{}',
+  actions JSONB NOT NULL DEFAULT '[]',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
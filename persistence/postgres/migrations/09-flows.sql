@@ -0,0 +1,22 @@
+CREATE TABLE flows (
+  id UUID PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  trigger JSONB NOT NULL DEFAULT '{}',
+  start_node VARCHAR(255) NOT NULL,
+  nodes JSONB NOT NULL,
+  timeout_minutes INTEGER NOT NULL DEFAULT 30,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE flow_sessions (
+  contact_id UUID PRIMARY KEY REFERENCES contacts(id) ON DELETE CASCADE,
+  flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+  node VARCHAR(255) NOT NULL,
+  variables JSONB NOT NULL DEFAULT '{}',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
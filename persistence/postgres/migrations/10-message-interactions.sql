@@ -0,0 +1,4 @@
+ALTER TABLE messages
+  ADD COLUMN interaction JSONB;
+
+CREATE INDEX messages_provider_sid_idx ON messages (provider_sid) WHERE provider_sid <> '';
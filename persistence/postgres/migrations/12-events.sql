@@ -0,0 +1,9 @@
+CREATE TABLE events (
+  seq BIGSERIAL PRIMARY KEY,
+  type VARCHAR(50) NOT NULL,
+  contact_id UUID,
+  data JSONB NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX events_contact_idx ON events (contact_id, seq);
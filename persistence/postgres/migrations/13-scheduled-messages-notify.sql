@@ -0,0 +1,13 @@
+CREATE INDEX scheduled_messages_pending_idx ON scheduled_messages (send_at) WHERE status = 'pending';
+
+CREATE FUNCTION notify_scheduled_message() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('scheduled_messages', NEW.id::text);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER scheduled_messages_notify
+AFTER INSERT OR UPDATE OF send_at ON scheduled_messages
+FOR EACH ROW WHEN (NEW.status = 'pending')
+EXECUTE FUNCTION notify_scheduled_message();
@@ -0,0 +1,32 @@
+CREATE TABLE campaigns (
+  id UUID PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  template_id VARCHAR(255) NOT NULL DEFAULT '',
+  template_name VARCHAR(255) NOT NULL DEFAULT '',
+  locale VARCHAR(16) NOT NULL DEFAULT '',
+  variables JSONB NOT NULL DEFAULT '{}',
+  start_at TIMESTAMP NOT NULL,
+  rate_per_minute INTEGER NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX campaigns_active_idx ON campaigns (start_at) WHERE status IN ('scheduled', 'running');
+
+CREATE TABLE campaign_recipients (
+  id UUID PRIMARY KEY,
+  campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+  contact_id UUID REFERENCES contacts(id) ON DELETE SET NULL,
+  phone VARCHAR(32) NOT NULL,
+  locale VARCHAR(16) NOT NULL DEFAULT '',
+  variables JSONB NOT NULL DEFAULT '{}',
+  status VARCHAR(20) NOT NULL DEFAULT 'queued',
+  provider_sid VARCHAR(64) NOT NULL DEFAULT '',
+  error TEXT NOT NULL DEFAULT '',
+  sent_at TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (campaign_id, phone)
+);
+
+CREATE INDEX campaign_recipients_status_idx ON campaign_recipients (campaign_id, status);
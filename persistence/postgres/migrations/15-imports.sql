@@ -0,0 +1,21 @@
+CREATE TABLE imports (
+  id UUID PRIMARY KEY,
+  campaign_id UUID REFERENCES campaigns(id) ON DELETE SET NULL,
+  variables JSONB NOT NULL DEFAULT '{}',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  total_rows INTEGER NOT NULL DEFAULT 0,
+  imported INTEGER NOT NULL DEFAULT 0,
+  failed INTEGER NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  completed_at TIMESTAMP
+);
+
+CREATE TABLE import_errors (
+  import_id UUID NOT NULL REFERENCES imports(id) ON DELETE CASCADE,
+  row_number INTEGER NOT NULL,
+  phone VARCHAR(64) NOT NULL DEFAULT '',
+  error TEXT NOT NULL,
+  PRIMARY KEY (import_id, row_number)
+);
@@ -0,0 +1,20 @@
+-- tat is the theoretical arrival time of the next message of the sender, as
+-- in the generic cell rate algorithm
+CREATE TABLE sender_throughput (
+  sender VARCHAR(64) PRIMARY KEY,
+  tat TIMESTAMP NOT NULL
+);
+
+CREATE TABLE sender_recipients (
+  sender VARCHAR(64) NOT NULL,
+  day DATE NOT NULL,
+  phone VARCHAR(32) NOT NULL,
+  PRIMARY KEY (sender, day, phone)
+);
+
+CREATE TABLE sender_usage (
+  sender VARCHAR(64) NOT NULL,
+  day DATE NOT NULL,
+  recipients INTEGER NOT NULL,
+  PRIMARY KEY (sender, day)
+);
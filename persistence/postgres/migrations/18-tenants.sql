@@ -0,0 +1,76 @@
+CREATE TABLE tenants (
+  id UUID PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  account_sid VARCHAR(64) NOT NULL DEFAULT '',
+  auth_token VARCHAR(255) NOT NULL DEFAULT '',
+  from_number VARCHAR(32) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX tenants_from_number_idx ON tenants (from_number) WHERE from_number <> '';
+
+-- everything that exists so far belongs to the default tenant, which sends
+-- through the account configured in the environment
+INSERT INTO tenants (id, name) VALUES ('00000000-0000-0000-0000-000000000001', 'Default');
+
+ALTER TABLE contacts ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE scheduled_messages ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE messages ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE template_groups ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE template_group_variants ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE suppressions ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE agents ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE auto_reply_rules ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE flows ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE webhook_endpoints ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE webhook_events ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE events ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE campaigns ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE imports ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+ALTER TABLE api_keys ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(id);
+
+-- new rows always name their tenant
+ALTER TABLE contacts ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE scheduled_messages ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE messages ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE template_groups ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE template_group_variants ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE suppressions ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE agents ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE auto_reply_rules ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE flows ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE webhook_endpoints ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE webhook_events ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE events ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE campaigns ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE imports ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE api_keys ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- phones, emails and group names are only unique within a tenant
+ALTER TABLE contacts
+  DROP CONSTRAINT contacts_phone_key,
+  ADD CONSTRAINT contacts_tenant_id_phone_key UNIQUE (tenant_id, phone);
+
+ALTER TABLE agents
+  DROP CONSTRAINT agents_email_key,
+  ADD CONSTRAINT agents_tenant_id_email_key UNIQUE (tenant_id, email);
+
+ALTER TABLE suppressions
+  DROP CONSTRAINT suppressions_pkey,
+  ADD PRIMARY KEY (tenant_id, phone);
+
+ALTER TABLE template_group_variants
+  DROP CONSTRAINT template_group_variants_group_name_fkey,
+  DROP CONSTRAINT template_group_variants_pkey;
+ALTER TABLE template_groups
+  DROP CONSTRAINT template_groups_pkey,
+  ADD PRIMARY KEY (tenant_id, name);
+ALTER TABLE template_group_variants
+  ADD PRIMARY KEY (tenant_id, group_name, language),
+  ADD FOREIGN KEY (tenant_id, group_name) REFERENCES template_groups(tenant_id, name) ON DELETE CASCADE;
+
+CREATE INDEX scheduled_messages_tenant_id_idx ON scheduled_messages (tenant_id, send_at);
+CREATE INDEX messages_tenant_id_provider_sid_idx ON messages (tenant_id, provider_sid) WHERE provider_sid <> '';
+CREATE INDEX events_tenant_id_seq_idx ON events (tenant_id, seq);
+CREATE INDEX campaigns_tenant_id_idx ON campaigns (tenant_id, created_at);
@@ -0,0 +1,17 @@
+CREATE TABLE sender_numbers (
+  tenant_id UUID NOT NULL REFERENCES tenants(id),
+  number VARCHAR(32) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (tenant_id, number)
+);
+
+-- our number a message went out from or came in to, or the Messaging
+-- Service that sent it
+ALTER TABLE messages ADD COLUMN sender VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX messages_tenant_id_phone_idx ON messages (tenant_id, phone, created_at);
+CREATE INDEX messages_tenant_id_sender_idx ON messages (tenant_id, sender, created_at) WHERE direction = 'outbound';
+
+ALTER TABLE scheduled_messages
+  ADD COLUMN from_number VARCHAR(32) NOT NULL DEFAULT '',
+  ADD COLUMN messaging_service_sid VARCHAR(64) NOT NULL DEFAULT '',
+  ADD COLUMN sender_strategy VARCHAR(16) NOT NULL DEFAULT '';
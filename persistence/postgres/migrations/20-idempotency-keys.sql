@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+  tenant_id UUID NOT NULL REFERENCES tenants(id),
+  key VARCHAR(255) NOT NULL,
+  fingerprint CHAR(64) NOT NULL,
+  status INTEGER NOT NULL DEFAULT 0,
+  content_type VARCHAR(255) NOT NULL DEFAULT '',
+  body BYTEA NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (tenant_id, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
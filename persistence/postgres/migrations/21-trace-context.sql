@@ -0,0 +1,5 @@
+-- W3C traceparent of the request that created the row, so the work done
+-- for it later can be traced back to it
+ALTER TABLE scheduled_messages ADD COLUMN trace_parent VARCHAR(55) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN trace_parent VARCHAR(55) NOT NULL DEFAULT '';
+ALTER TABLE webhook_events ADD COLUMN trace_parent VARCHAR(55) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- a number belongs to a single tenant, which its callbacks are resolved to
+CREATE UNIQUE INDEX sender_numbers_number_idx ON sender_numbers (number);
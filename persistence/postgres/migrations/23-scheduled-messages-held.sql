@@ -0,0 +1,3 @@
+-- when a message held back by the sender limits is due again, without moving
+-- the send time it was scheduled for
+ALTER TABLE scheduled_messages ADD COLUMN held_until TIMESTAMP;
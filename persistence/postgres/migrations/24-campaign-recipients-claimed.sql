@@ -0,0 +1,3 @@
+-- when a runner claimed the recipient, so one that stopped mid-send can be
+-- claimed again
+ALTER TABLE campaign_recipients ADD COLUMN claimed_at TIMESTAMP;
@@ -0,0 +1,17 @@
+-- the transaction that wrote the event, so streams only move past events once
+-- every transaction that could still commit one before them is done
+ALTER TABLE events ADD COLUMN xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX events_tenant_xid_idx ON events (tenant_id, xid, seq);
+
+CREATE FUNCTION notify_event() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('events', NEW.tenant_id::text);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_notify
+AFTER INSERT ON events
+FOR EACH ROW
+EXECUTE FUNCTION notify_event();
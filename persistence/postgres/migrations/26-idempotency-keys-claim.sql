@@ -0,0 +1,3 @@
+-- the request holding the key, so one that outlived the lock timeout can't
+-- store its response over, or delete, the retry that took the key over
+ALTER TABLE idempotency_keys ADD COLUMN claim UUID;
@@ -0,0 +1,5 @@
+-- messages a worker claimed and is sending, so other replicas leave them
+-- alone; one whose worker stopped mid-send is claimed again once claimed_at
+-- is stale
+ALTER TYPE message_status ADD VALUE 'sending';
+ALTER TABLE scheduled_messages ADD COLUMN claimed_at TIMESTAMP;
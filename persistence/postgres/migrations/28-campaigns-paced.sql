@@ -0,0 +1,3 @@
+-- the theoretical arrival time of the campaign's next send, which paces it
+-- to its rate across every runner
+ALTER TABLE campaigns ADD COLUMN paced_until TIMESTAMP;
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"mbx/events"
+	"mbx/schedules"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// scheduleChannel is notified by a trigger on scheduled_messages whenever
+	// a pending message is inserted or its send time changes
+	scheduleChannel = "scheduled_messages"
+	// eventChannel is notified by a trigger on events when an event is
+	// committed, by any replica
+	eventChannel = "events"
+)
+
+// notifier listens for the notifications of a channel on a dedicated
+// connection, reconnecting when it is lost.
+type notifier struct {
+	db      *pgxpool.Pool
+	channel string
+	// retry is how long to wait before reconnecting
+	retry time.Duration
+}
+
+// ScheduleNotifier reports the changes of pending scheduled messages
+type ScheduleNotifier struct {
+	notifier
+}
+
+func NewScheduleNotifier(db *pgxpool.Pool) *ScheduleNotifier {
+	return &ScheduleNotifier{notifier{db: db, channel: scheduleChannel, retry: 5 * time.Second}}
+}
+
+var _ schedules.Notifier = &ScheduleNotifier{}
+
+// EventNotifier reports the events appended to the log
+type EventNotifier struct {
+	notifier
+}
+
+func NewEventNotifier(db *pgxpool.Pool) *EventNotifier {
+	return &EventNotifier{notifier{db: db, channel: eventChannel, retry: 5 * time.Second}}
+}
+
+var _ events.Notifier = &EventNotifier{}
+
+func (n *notifier) Listen(ctx context.Context) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changed)
+		for {
+			err := n.listen(ctx, changed)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("lost notifications, reconnecting", slog.String("channel", n.channel), slog.Any("error", err))
+
+			// notifications sent while disconnected are lost, so report a
+			// change to have the listener look again
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+
+			select {
+			case <-time.After(n.retry):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changed
+}
+
+func (n *notifier) listen(ctx context.Context, changed chan<- struct{}) error {
+	pooled, err := n.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the connection stays in LISTEN mode, so it is taken out of the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+n.channel); err != nil {
+		return err
+	}
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		// one pending wake-up covers any number of changes
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"mbx/models"
 	"mbx/schedules"
+	"mbx/tenants"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,20 +23,40 @@ func NewMessageRepository(db *pgxpool.Pool) *MessageRepository {
 
 var _ schedules.Repository = &MessageRepository{}
 
+const scheduledColumns = `id, tenant_id, contact_id, to_number, send_at, content, provider_template_id, template_name, locale, message_type, status, from_number, messaging_service_sid, sender_strategy, created_at, trace_parent`
+
+func scanScheduled(row pgx.Row) (*models.ScheduledMessage, error) {
+	var m models.ScheduledMessage
+	err := row.Scan(&m.Id, &m.TenantId, &m.ContactId, &m.To, &m.SendAt, &m.Content, &m.ProviderId, &m.TemplateName, &m.Locale, &m.Type, &m.Status,
+		&m.From.Number, &m.From.MessagingServiceSid, &m.From.Strategy, &m.CreatedAt, &m.TraceParent)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
 func (r *MessageRepository) Create(ctx context.Context, message models.ScheduledMessage) error {
 	_, err := r.db.Exec(ctx, `
 		INSERT INTO scheduled_messages
-		(id, to_number, send_at, content, provider_template_id, message_type, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		(`+scheduledColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		`,
 		message.Id,
+		tenants.FromContext(ctx),
+		message.ContactId,
 		message.To,
 		message.SendAt,
 		message.Content,
 		message.ProviderId,
+		message.TemplateName,
+		message.Locale,
 		message.Type,
 		message.Status,
+		message.From.Number,
+		message.From.MessagingServiceSid,
+		message.From.Strategy,
 		message.CreatedAt,
+		message.TraceParent,
 	)
 	if err != nil {
 		return err
@@ -46,27 +67,113 @@ func (r *MessageRepository) Create(ctx context.Context, message models.Scheduled
 
 func (r *MessageRepository) FindById(ctx context.Context, id uuid.UUID) (*models.ScheduledMessage, error) {
 	row := r.db.QueryRow(ctx, `
-		SELECT id, to_number, send_at, content, provider_template_id, message_type, status, created_at
+		SELECT `+scheduledColumns+`
 		FROM scheduled_messages
-		WHERE id = $1
-		`, id)
-	var message models.ScheduledMessage
-	err := row.Scan(&message.Id, &message.To, &message.SendAt, &message.Content, &message.ProviderId, &message.Type, &message.Status, &message.CreatedAt)
+		WHERE id = $1 AND tenant_id = $2
+		`, id, tenants.FromContext(ctx))
+	message, err := scanScheduled(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return message, err
+}
+
+func (r *MessageRepository) ListUpcoming(ctx context.Context, duration time.Duration) ([]models.ScheduledMessage, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+scheduledColumns+`
+		FROM scheduled_messages
+		WHERE status = 'pending' AND send_at >= NOW() AND send_at < NOW() + $1 AND tenant_id = $2
+		`, duration, tenants.FromContext(ctx))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.ScheduledMessage
+	for rows.Next() {
+		message, err := scanScheduled(rows)
+		if err != nil {
+			return nil, err
 		}
+		messages = append(messages, *message)
+	}
+	return messages, nil
+}
+
+// ClaimDue claims the due messages of every tenant, for the worker to send
+// each one scoped to its own. Rows another replica is claiming are skipped
+// rather than waited for.
+func (r *MessageRepository) ClaimDue(ctx context.Context, now time.Time, staleBefore time.Time, afterSendAt time.Time, afterId uuid.UUID, limit int) ([]models.ScheduledMessage, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH claimed AS (
+			UPDATE scheduled_messages
+			SET status = 'sending', claimed_at = $1
+			WHERE id IN (
+				SELECT id
+				FROM scheduled_messages
+				WHERE (
+					(status = 'pending' AND send_at <= $1 AND (held_until IS NULL OR held_until <= $1))
+					OR (status = 'sending' AND claimed_at < $2)
+				)
+				AND (send_at, id) > ($3, $4)
+				ORDER BY send_at, id
+				LIMIT $5
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING `+scheduledColumns+`
+		)
+		SELECT `+scheduledColumns+`
+		FROM claimed
+		ORDER BY send_at, id
+		`, now, staleBefore, afterSendAt, afterId, limit)
+	if err != nil {
 		return nil, err
 	}
-	return &message, nil
+	defer rows.Close()
+
+	var messages []models.ScheduledMessage
+	for rows.Next() {
+		message, err := scanScheduled(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *message)
+	}
+	return messages, rows.Err()
 }
 
-func (r *MessageRepository) ListUpcoming(ctx context.Context, duration time.Duration) ([]models.ScheduledMessage, error) {
+// QueueDepth counts the messages of every tenant, for the metrics. Messages
+// being sent are still pending.
+func (r *MessageRepository) QueueDepth(ctx context.Context) (int, int, error) {
+	var pending, failed int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE status IN ('pending', 'sending')), COUNT(*) FILTER (WHERE status = 'failed')
+		FROM scheduled_messages
+		WHERE status IN ('pending', 'sending', 'failed')
+		`).Scan(&pending, &failed)
+	return pending, failed, err
+}
+
+func (r *MessageRepository) NextSendAt(ctx context.Context) (*time.Time, error) {
+	var next *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT MIN(GREATEST(send_at, held_until)) -- GREATEST skips a NULL hold
+		FROM scheduled_messages
+		WHERE status = 'pending'
+		`).Scan(&next)
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+func (r *MessageRepository) ListByContact(ctx context.Context, contactId uuid.UUID) ([]models.ScheduledMessage, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, to_number, send_at, content, provider_template_id, message_type, status, created_at
+		SELECT `+scheduledColumns+`
 		FROM scheduled_messages
-		WHERE send_at >= NOW() AND send_at < NOW() + $1
-		`, duration)
+		WHERE contact_id = $1 AND tenant_id = $2
+		ORDER BY send_at
+		`, contactId, tenants.FromContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -74,14 +181,29 @@ func (r *MessageRepository) ListUpcoming(ctx context.Context, duration time.Dura
 
 	var messages []models.ScheduledMessage
 	for rows.Next() {
-		var message models.ScheduledMessage
-		if err := rows.Scan(&message.Id, &message.To, &message.SendAt, &message.Content, &message.ProviderId, &message.Type, &message.Status, &message.CreatedAt); err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, nil
-			}
+		message, err := scanScheduled(rows)
+		if err != nil {
 			return nil, err
 		}
-		messages = append(messages, message)
+		messages = append(messages, *message)
 	}
-	return messages, nil
+	return messages, rows.Err()
+}
+
+func (r *MessageRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE scheduled_messages
+		SET status = $2
+		WHERE id = $1 AND tenant_id = $3
+		`, id, status, tenants.FromContext(ctx))
+	return err
+}
+
+func (r *MessageRepository) Hold(ctx context.Context, id uuid.UUID, until time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE scheduled_messages
+		SET status = 'pending', held_until = $2, claimed_at = NULL
+		WHERE id = $1 AND tenant_id = $3
+		`, id, until, tenants.FromContext(ctx))
+	return err
 }
@@ -105,19 +105,374 @@ func TestMain(m *testing.M) {
 func runMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 	migrationSQL := `
 		DROP TYPE IF EXISTS message_status CASCADE;
-		CREATE TYPE message_status AS ENUM('pending', 'sent', 'failed');
-
+		CREATE TYPE message_status AS ENUM('pending', 'sent', 'failed', 'suppressed', 'rejected', 'sending');
+
+		DROP TABLE IF EXISTS idempotency_keys;
+		DROP TABLE IF EXISTS sender_numbers;
+		DROP TABLE IF EXISTS api_keys;
+		DROP TABLE IF EXISTS sender_usage;
+		DROP TABLE IF EXISTS sender_recipients;
+		DROP TABLE IF EXISTS sender_throughput;
+		DROP TABLE IF EXISTS import_errors;
+		DROP TABLE IF EXISTS imports;
+		DROP TABLE IF EXISTS campaign_recipients;
+		DROP TABLE IF EXISTS campaigns;
+		DROP TABLE IF EXISTS events;
+		DROP TABLE IF EXISTS webhook_attempts;
+		DROP TABLE IF EXISTS webhook_deliveries;
+		DROP TABLE IF EXISTS webhook_events;
+		DROP TABLE IF EXISTS webhook_endpoints;
+		DROP TABLE IF EXISTS messages;
+		DROP TABLE IF EXISTS suppressions;
+		DROP TABLE IF EXISTS consent_records;
+		DROP TABLE IF EXISTS flow_sessions;
+		DROP TABLE IF EXISTS flows;
+		DROP TABLE IF EXISTS auto_reply_rules;
+		DROP TABLE IF EXISTS conversation_events;
+		DROP TABLE IF EXISTS conversation_notes;
+		DROP TABLE IF EXISTS conversations;
+		DROP TABLE IF EXISTS agents;
 		DROP TABLE IF EXISTS scheduled_messages;
+		DROP TABLE IF EXISTS contacts;
+		DROP TABLE IF EXISTS tenants CASCADE;
+		CREATE TABLE tenants (
+			id UUID PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			account_sid VARCHAR(64) NOT NULL DEFAULT '',
+			auth_token VARCHAR(255) NOT NULL DEFAULT '',
+			from_number VARCHAR(32) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX tenants_from_number_idx ON tenants (from_number) WHERE from_number <> '';
+		INSERT INTO tenants (id, name) VALUES ('00000000-0000-0000-0000-000000000001', 'Default');
+
+		CREATE TABLE contacts (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			phone VARCHAR(32) NOT NULL,
+			name VARCHAR(255) NOT NULL DEFAULT '',
+			locale VARCHAR(16) NOT NULL DEFAULT '',
+			timezone VARCHAR(64) NOT NULL DEFAULT '',
+			tags TEXT[] NOT NULL DEFAULT '{}',
+			attributes JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (tenant_id, phone)
+		);
+
 		CREATE TABLE scheduled_messages (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
 			id UUID PRIMARY KEY,
+			contact_id UUID REFERENCES contacts(id) ON DELETE SET NULL,
 			to_number VARCHAR(255) NOT NULL,
 			send_at TIMESTAMP NOT NULL,
 			content TEXT NOT NULL,
 			provider_template_id VARCHAR(255) NOT NULL,
+			template_name VARCHAR(255) NOT NULL DEFAULT '',
+			locale VARCHAR(16) NOT NULL DEFAULT '',
 			message_type VARCHAR(255) NOT NULL,
 			status message_status NOT NULL DEFAULT 'pending',
+			from_number VARCHAR(32) NOT NULL DEFAULT '',
+			messaging_service_sid VARCHAR(64) NOT NULL DEFAULT '',
+			sender_strategy VARCHAR(16) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			trace_parent VARCHAR(55) NOT NULL DEFAULT '',
+			held_until TIMESTAMP,
+			claimed_at TIMESTAMP
+		);
+		CREATE OR REPLACE FUNCTION notify_scheduled_message() RETURNS trigger AS $$
+		BEGIN
+			PERFORM pg_notify('scheduled_messages', NEW.id::text);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+		CREATE TRIGGER scheduled_messages_notify
+		AFTER INSERT OR UPDATE OF send_at ON scheduled_messages
+		FOR EACH ROW WHEN (NEW.status = 'pending')
+		EXECUTE FUNCTION notify_scheduled_message();
+
+		DROP TABLE IF EXISTS template_group_variants;
+		DROP TABLE IF EXISTS template_groups;
+		CREATE TABLE template_groups (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			name VARCHAR(255) NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (tenant_id, name)
+		);
+		CREATE TABLE template_group_variants (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			group_name VARCHAR(255) NOT NULL,
+			language VARCHAR(16) NOT NULL,
+			content_sid VARCHAR(255) NOT NULL,
+			PRIMARY KEY (tenant_id, group_name, language),
+			FOREIGN KEY (tenant_id, group_name) REFERENCES template_groups(tenant_id, name) ON DELETE CASCADE
+		);
+
+		CREATE TABLE messages (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			contact_id UUID REFERENCES contacts(id) ON DELETE SET NULL,
+			direction VARCHAR(16) NOT NULL,
+			phone VARCHAR(32) NOT NULL,
+			body TEXT NOT NULL DEFAULT '',
+			template_id VARCHAR(255) NOT NULL DEFAULT '',
+			provider_sid VARCHAR(64) NOT NULL DEFAULT '',
+			status VARCHAR(32) NOT NULL DEFAULT '',
+			interaction JSONB,
+			sender VARCHAR(64) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			trace_parent VARCHAR(55) NOT NULL DEFAULT ''
+		);
+
+		CREATE TABLE suppressions (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			phone VARCHAR(32) NOT NULL,
+			contact_id UUID REFERENCES contacts(id) ON DELETE SET NULL,
+			reason VARCHAR(32) NOT NULL,
+			keyword VARCHAR(64) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (tenant_id, phone)
+		);
+
+		CREATE TABLE consent_records (
+			id UUID PRIMARY KEY,
+			contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+			channel VARCHAR(16) NOT NULL,
+			purpose VARCHAR(32) NOT NULL,
+			action VARCHAR(16) NOT NULL,
+			source VARCHAR(32) NOT NULL,
+			evidence TEXT NOT NULL DEFAULT '',
+			recorded_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE agents (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			auto_assign BOOLEAN NOT NULL DEFAULT FALSE,
+			last_assigned_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (tenant_id, email)
+		);
+		CREATE TABLE conversations (
+			contact_id UUID PRIMARY KEY REFERENCES contacts(id) ON DELETE CASCADE,
+			last_read_at TIMESTAMP,
+			marked_unread BOOLEAN NOT NULL DEFAULT FALSE,
+			archived BOOLEAN NOT NULL DEFAULT FALSE,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			status VARCHAR(16) NOT NULL DEFAULT 'open',
+			assignee_id UUID REFERENCES agents(id) ON DELETE SET NULL,
+			tags TEXT[] NOT NULL DEFAULT '{}'
+		);
+		CREATE TABLE conversation_notes (
+			id UUID PRIMARY KEY,
+			contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+			agent_id UUID REFERENCES agents(id) ON DELETE SET NULL,
+			body TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE conversation_events (
+			id UUID PRIMARY KEY,
+			contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+			agent_id UUID REFERENCES agents(id) ON DELETE SET NULL,
+			type VARCHAR(32) NOT NULL,
+			from_value TEXT NOT NULL DEFAULT '',
+			to_value TEXT NOT NULL DEFAULT '',
 			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 		);
+		CREATE TABLE auto_reply_rules (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			priority INTEGER NOT NULL DEFAULT 0,
+			match JSONB NOT NULL,
+			conditions JSONB NOT NULL DEFAULT '{}',
+			actions JSONB NOT NULL DEFAULT '[]',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE flows (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			trigger JSONB NOT NULL DEFAULT '{}',
+			start_node VARCHAR(255) NOT NULL,
+			nodes JSONB NOT NULL,
+			timeout_minutes INTEGER NOT NULL DEFAULT 30,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE flow_sessions (
+			contact_id UUID PRIMARY KEY REFERENCES contacts(id) ON DELETE CASCADE,
+			flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+			node VARCHAR(255) NOT NULL,
+			variables JSONB NOT NULL DEFAULT '{}',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE webhook_endpoints (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret VARCHAR(255) NOT NULL,
+			events TEXT[] NOT NULL DEFAULT '{}',
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE webhook_events (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			type VARCHAR(50) NOT NULL,
+			data JSONB NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			trace_parent VARCHAR(55) NOT NULL DEFAULT ''
+		);
+		CREATE TABLE webhook_deliveries (
+			id UUID PRIMARY KEY,
+			event_id UUID NOT NULL REFERENCES webhook_events(id) ON DELETE CASCADE,
+			endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE webhook_attempts (
+			id UUID PRIMARY KEY,
+			delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+			number INTEGER NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE events (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			seq BIGSERIAL PRIMARY KEY,
+			type VARCHAR(50) NOT NULL,
+			contact_id UUID,
+			data JSONB NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			xid xid8 NOT NULL DEFAULT pg_current_xact_id()
+		);
+		CREATE OR REPLACE FUNCTION notify_event() RETURNS trigger AS $$
+		BEGIN
+			PERFORM pg_notify('events', NEW.tenant_id::text);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+		CREATE TRIGGER events_notify
+		AFTER INSERT ON events
+		FOR EACH ROW
+		EXECUTE FUNCTION notify_event();
+		CREATE TABLE campaigns (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			template_id VARCHAR(255) NOT NULL DEFAULT '',
+			template_name VARCHAR(255) NOT NULL DEFAULT '',
+			locale VARCHAR(16) NOT NULL DEFAULT '',
+			variables JSONB NOT NULL DEFAULT '{}',
+			start_at TIMESTAMP NOT NULL,
+			rate_per_minute INTEGER NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			paced_until TIMESTAMP
+		);
+		CREATE TABLE campaign_recipients (
+			id UUID PRIMARY KEY,
+			campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+			contact_id UUID REFERENCES contacts(id) ON DELETE SET NULL,
+			phone VARCHAR(32) NOT NULL,
+			locale VARCHAR(16) NOT NULL DEFAULT '',
+			variables JSONB NOT NULL DEFAULT '{}',
+			status VARCHAR(20) NOT NULL DEFAULT 'queued',
+			provider_sid VARCHAR(64) NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			sent_at TIMESTAMP,
+			claimed_at TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (campaign_id, phone)
+		);
+		CREATE TABLE imports (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			campaign_id UUID REFERENCES campaigns(id) ON DELETE SET NULL,
+			variables JSONB NOT NULL DEFAULT '{}',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			total_rows INTEGER NOT NULL DEFAULT 0,
+			imported INTEGER NOT NULL DEFAULT 0,
+			failed INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed_at TIMESTAMP
+		);
+		CREATE TABLE import_errors (
+			import_id UUID NOT NULL REFERENCES imports(id) ON DELETE CASCADE,
+			row_number INTEGER NOT NULL,
+			phone VARCHAR(64) NOT NULL DEFAULT '',
+			error TEXT NOT NULL,
+			PRIMARY KEY (import_id, row_number)
+		);
+		CREATE TABLE sender_throughput (
+			sender VARCHAR(64) PRIMARY KEY,
+			tat TIMESTAMP NOT NULL
+		);
+		CREATE TABLE sender_recipients (
+			sender VARCHAR(64) NOT NULL,
+			day DATE NOT NULL,
+			phone VARCHAR(32) NOT NULL,
+			PRIMARY KEY (sender, day, phone)
+		);
+		CREATE TABLE sender_usage (
+			sender VARCHAR(64) NOT NULL,
+			day DATE NOT NULL,
+			recipients INTEGER NOT NULL,
+			PRIMARY KEY (sender, day)
+		);
+		CREATE TABLE api_keys (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			id UUID PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			prefix VARCHAR(16) NOT NULL,
+			hash CHAR(64) NOT NULL UNIQUE,
+			scopes TEXT[] NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_used_at TIMESTAMP,
+			revoked_at TIMESTAMP
+		);
+
+		CREATE TABLE sender_numbers (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			number VARCHAR(32) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (tenant_id, number)
+		);
+		CREATE UNIQUE INDEX sender_numbers_number_idx ON sender_numbers (number);
+
+		CREATE TABLE idempotency_keys (
+			tenant_id UUID NOT NULL REFERENCES tenants(id),
+			key VARCHAR(255) NOT NULL,
+			fingerprint CHAR(64) NOT NULL,
+			claim UUID,
+			status INTEGER NOT NULL DEFAULT 0,
+			content_type VARCHAR(255) NOT NULL DEFAULT '',
+			body BYTEA NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (tenant_id, key)
+		);
 	`
 
 	_, err := pool.Exec(ctx, migrationSQL)
@@ -250,4 +605,142 @@ func TestScheduledMessages_ListUpcoming(t *testing.T) {
 	require.Equal(t, scheduledMessage.To, msg.To)
 	require.Equal(t, scheduledMessage.Content, msg.Content)
 
+	// Messages that were already handled are not listed again
+	require.NoError(t, messageRepo.UpdateStatus(ctx, id, models.StatusSuppressed))
+	upcoming, err = messageRepo.ListUpcoming(ctx, 15*time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, upcoming)
+}
+
+func TestScheduledMessages_DueAndNotify(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	messageRepo := NewMessageRepository(testDB)
+
+	changed := NewScheduleNotifier(testDB).Listen(ctx)
+
+	due := models.ScheduledMessage{
+		Id:         uuid.New(),
+		To:         "1234567890",
+		SendAt:     time.Now().Add(-time.Minute),
+		Content:    "Due message",
+		ProviderId: "provider-123",
+		Type:       models.ScheduleTypeFreeform,
+		Status:     models.StatusPending,
+		CreatedAt:  time.Now(),
+	}
+	later := due
+	later.Id = uuid.New()
+	later.SendAt = time.Now().Add(time.Hour)
+
+	// wait for the LISTEN to be in place
+	time.Sleep(200 * time.Millisecond)
+	require.NoError(t, messageRepo.Create(ctx, due))
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Fatal("expected a notification for the new message")
+	}
+	require.NoError(t, messageRepo.Create(ctx, later))
+
+	list, err := messageRepo.ClaimDue(ctx, time.Now(), time.Now().Add(-time.Hour), time.Time{}, uuid.Nil, 100)
+	require.NoError(t, err)
+	ids := make(map[uuid.UUID]bool)
+	for _, m := range list {
+		ids[m.Id] = true
+	}
+	require.True(t, ids[due.Id])
+	require.False(t, ids[later.Id])
+
+	next, err := messageRepo.NextSendAt(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	require.False(t, next.After(due.SendAt))
+
+	require.NoError(t, messageRepo.UpdateStatus(ctx, due.Id, models.StatusSent))
+	list, err = messageRepo.ClaimDue(ctx, time.Now(), time.Now().Add(-time.Hour), time.Time{}, uuid.Nil, 100)
+	require.NoError(t, err)
+	for _, m := range list {
+		require.NotEqual(t, due.Id, m.Id)
+	}
+}
+
+func TestScheduledMessages_Hold(t *testing.T) {
+	ctx := context.Background()
+	messageRepo := NewMessageRepository(testDB)
+
+	held := models.ScheduledMessage{
+		Id:         uuid.New(),
+		To:         "1234567890",
+		SendAt:     time.Now().Add(-time.Minute),
+		Content:    "Held message",
+		ProviderId: "provider-123",
+		Type:       models.ScheduleTypeFreeform,
+		Status:     models.StatusPending,
+		CreatedAt:  time.Now(),
+	}
+	require.NoError(t, messageRepo.Create(ctx, held))
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	require.NoError(t, messageRepo.Hold(ctx, held.Id, until))
+
+	list, err := messageRepo.ClaimDue(ctx, time.Now(), time.Now().Add(-time.Hour), time.Time{}, uuid.Nil, 100)
+	require.NoError(t, err)
+	for _, m := range list {
+		require.NotEqual(t, held.Id, m.Id, "held messages are not due")
+	}
+	list, err = messageRepo.ClaimDue(ctx, until, until.Add(-time.Hour), time.Time{}, uuid.Nil, 100)
+	require.NoError(t, err)
+	ids := make(map[uuid.UUID]bool)
+	for _, m := range list {
+		ids[m.Id] = true
+	}
+	require.True(t, ids[held.Id], "held messages are due once the hold ends")
+
+	require.NoError(t, messageRepo.UpdateStatus(ctx, held.Id, models.StatusSent))
+}
+
+func TestScheduledMessages_ClaimDue(t *testing.T) {
+	ctx := context.Background()
+	messageRepo := NewMessageRepository(testDB)
+
+	due := models.ScheduledMessage{
+		Id:         uuid.New(),
+		To:         "1234567890",
+		SendAt:     time.Now().Add(-time.Minute),
+		Content:    "Claimed message",
+		ProviderId: "provider-123",
+		Type:       models.ScheduleTypeFreeform,
+		Status:     models.StatusPending,
+		CreatedAt:  time.Now(),
+	}
+	require.NoError(t, messageRepo.Create(ctx, due))
+	claimed := func(list []models.ScheduledMessage) bool {
+		for _, m := range list {
+			if m.Id == due.Id {
+				return true
+			}
+		}
+		return false
+	}
+
+	now := time.Now()
+	list, err := messageRepo.ClaimDue(ctx, now, now.Add(-time.Hour), time.Time{}, uuid.Nil, 100)
+	require.NoError(t, err)
+	require.True(t, claimed(list))
+	gotten, err := messageRepo.FindById(ctx, due.Id)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusSending, gotten.Status)
+
+	// another replica does not get it while the claim is fresh
+	list, err = messageRepo.ClaimDue(ctx, now, now.Add(-time.Hour), time.Time{}, uuid.Nil, 100)
+	require.NoError(t, err)
+	require.False(t, claimed(list))
+
+	// a worker that never recorded the outcome loses it once the claim is stale
+	later := now.Add(2 * time.Hour)
+	list, err = messageRepo.ClaimDue(ctx, later, later.Add(-time.Hour), time.Time{}, uuid.Nil, 100)
+	require.NoError(t, err)
+	require.True(t, claimed(list))
+
+	require.NoError(t, messageRepo.UpdateStatus(ctx, due.Id, models.StatusSent))
 }
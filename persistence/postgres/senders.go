@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/senders"
+	"mbx/tenants"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SenderRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSenderRepository(db *pgxpool.Pool) *SenderRepository {
+	return &SenderRepository{db: db}
+}
+
+var _ senders.Repository = &SenderRepository{}
+
+func (r *SenderRepository) Create(ctx context.Context, number senders.Number) error {
+	// a number belongs to one tenant, so callbacks for it reach that tenant
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO sender_numbers (tenant_id, number, created_at)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (SELECT 1 FROM tenants WHERE from_number = $2 AND id <> $1)
+		`,
+		tenants.FromContext(ctx),
+		number.Number,
+		number.CreatedAt,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		if pgErr.ConstraintName == "sender_numbers_number_idx" {
+			return senders.ErrTaken
+		}
+		return senders.ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return senders.ErrTaken
+	}
+	return nil
+}
+
+func (r *SenderRepository) Delete(ctx context.Context, number string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM sender_numbers WHERE number = $1 AND tenant_id = $2`, number, tenants.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return senders.ErrNotFound
+	}
+	return nil
+}
+
+func (r *SenderRepository) List(ctx context.Context) ([]senders.Number, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT number, created_at
+		FROM sender_numbers
+		WHERE tenant_id = $1
+		ORDER BY created_at, number
+		`, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var numbers []senders.Number
+	for rows.Next() {
+		var n senders.Number
+		if err := rows.Scan(&n.Number, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, rows.Err()
+}
+
+func (r *SenderRepository) LastSender(ctx context.Context, phone string, numbers []string) (string, error) {
+	var sender string
+	err := r.db.QueryRow(ctx, `
+		SELECT sender
+		FROM messages
+		WHERE tenant_id = $1 AND phone = $2 AND sender = ANY($3)
+		ORDER BY created_at DESC
+		LIMIT 1
+		`, tenants.FromContext(ctx), phone, numbers).Scan(&sender)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return sender, err
+}
+
+func (r *SenderRepository) Load(ctx context.Context, numbers []string, since time.Time) (map[string]int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT sender, COUNT(*)
+		FROM messages
+		WHERE tenant_id = $1 AND direction = 'outbound' AND sender = ANY($2) AND created_at >= $3
+		GROUP BY sender
+		`, tenants.FromContext(ctx), numbers, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	load := make(map[string]int, len(numbers))
+	for rows.Next() {
+		var sender string
+		var count int
+		if err := rows.Scan(&sender, &count); err != nil {
+			return nil, err
+		}
+		load[sender] = count
+	}
+	return load, rows.Err()
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/senders"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenders_NumberBelongsToOneTenant(t *testing.T) {
+	ctx := context.Background()
+	tenantRepo := NewTenantRepository(testDB)
+	senderRepo := NewSenderRepository(testDB)
+
+	brand := tenants.Tenant{Id: uuid.New(), Name: "Brand", FromNumber: "+15550100", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	other := tenants.Tenant{Id: uuid.New(), Name: "Other", FromNumber: "+15550200", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, tenantRepo.Create(ctx, brand))
+	require.NoError(t, tenantRepo.Create(ctx, other))
+
+	brandCtx := tenants.NewContext(ctx, brand.Id)
+	otherCtx := tenants.NewContext(ctx, other.Id)
+	require.NoError(t, senderRepo.Create(brandCtx, senders.Number{Number: "+15550101", CreatedAt: time.Now()}))
+
+	// callbacks for a pool number reach the tenant of the pool
+	found, err := tenantRepo.FindByNumber(ctx, "+15550101")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.Equal(t, brand.Id, found.Id)
+
+	require.ErrorIs(t, senderRepo.Create(brandCtx, senders.Number{Number: "+15550101", CreatedAt: time.Now()}), senders.ErrDuplicate)
+	require.ErrorIs(t, senderRepo.Create(otherCtx, senders.Number{Number: "+15550101", CreatedAt: time.Now()}), senders.ErrTaken)
+	require.ErrorIs(t, senderRepo.Create(otherCtx, senders.Number{Number: brand.FromNumber, CreatedAt: time.Now()}), senders.ErrTaken)
+
+	other.FromNumber = "+15550101"
+	require.ErrorIs(t, tenantRepo.Update(ctx, other), tenants.ErrDuplicate)
+}
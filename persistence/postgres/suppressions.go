@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/optout"
+	"mbx/tenants"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SuppressionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSuppressionRepository(db *pgxpool.Pool) *SuppressionRepository {
+	return &SuppressionRepository{db: db}
+}
+
+var _ optout.Repository = &SuppressionRepository{}
+
+func (r *SuppressionRepository) Add(ctx context.Context, s optout.Suppression) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO suppressions (phone, contact_id, reason, keyword, created_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id, phone) DO UPDATE
+		SET contact_id = EXCLUDED.contact_id, reason = EXCLUDED.reason, keyword = EXCLUDED.keyword, created_at = EXCLUDED.created_at
+		`,
+		s.Phone,
+		s.ContactId,
+		s.Reason,
+		s.Keyword,
+		s.CreatedAt,
+		tenants.FromContext(ctx),
+	)
+	return err
+}
+
+func (r *SuppressionRepository) Remove(ctx context.Context, phone string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM suppressions WHERE phone = $1 AND tenant_id = $2`, phone, tenants.FromContext(ctx))
+	return err
+}
+
+func (r *SuppressionRepository) Find(ctx context.Context, phone string) (*optout.Suppression, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT phone, contact_id, reason, keyword, created_at
+		FROM suppressions
+		WHERE phone = $1 AND tenant_id = $2
+		`, phone, tenants.FromContext(ctx))
+	var s optout.Suppression
+	err := row.Scan(&s.Phone, &s.ContactId, &s.Reason, &s.Keyword, &s.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SuppressionRepository) List(ctx context.Context) ([]optout.Suppression, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT phone, contact_id, reason, keyword, created_at
+		FROM suppressions
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+		`, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suppressions []optout.Suppression
+	for rows.Next() {
+		var s optout.Suppression
+		if err := rows.Scan(&s.Phone, &s.ContactId, &s.Reason, &s.Keyword, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, s)
+	}
+	return suppressions, rows.Err()
+}
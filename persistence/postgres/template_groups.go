@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/templates"
+	"mbx/tenants"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TemplateGroupRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTemplateGroupRepository(db *pgxpool.Pool) *TemplateGroupRepository {
+	return &TemplateGroupRepository{db: db}
+}
+
+var _ templates.GroupRepository = &TemplateGroupRepository{}
+
+func (r *TemplateGroupRepository) Create(ctx context.Context, group templates.Group) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tenantId := tenants.FromContext(ctx)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO template_groups (tenant_id, name, description, created_at)
+		VALUES ($1, $2, $3, $4)
+		`,
+		tenantId,
+		group.Name,
+		group.Description,
+		group.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range group.Variants {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO template_group_variants (tenant_id, group_name, language, content_sid)
+			VALUES ($1, $2, $3, $4)
+			`, tenantId, group.Name, v.Language, v.ContentSid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *TemplateGroupRepository) FindByName(ctx context.Context, name string) (*templates.Group, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT name, description, created_at
+		FROM template_groups
+		WHERE name = $1 AND tenant_id = $2
+		`, name, tenants.FromContext(ctx))
+	var group templates.Group
+	err := row.Scan(&group.Name, &group.Description, &group.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	variants, err := r.listVariants(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	group.Variants = variants[name]
+
+	return &group, nil
+}
+
+func (r *TemplateGroupRepository) List(ctx context.Context) ([]templates.Group, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT name, description, created_at
+		FROM template_groups
+		WHERE tenant_id = $1
+		ORDER BY name
+		`, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []templates.Group
+	for rows.Next() {
+		var group templates.Group
+		if err := rows.Scan(&group.Name, &group.Description, &group.CreatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	variants, err := r.listVariants(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].Variants = variants[groups[i].Name]
+	}
+
+	return groups, nil
+}
+
+// listVariants returns the variants keyed by group name, for a single group
+// when name is set or for every group otherwise.
+func (r *TemplateGroupRepository) listVariants(ctx context.Context, name string) (map[string][]templates.Variant, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT group_name, language, content_sid
+		FROM template_group_variants
+		WHERE tenant_id = $2 AND ($1 = '' OR group_name = $1)
+		ORDER BY group_name, language
+		`, name, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make(map[string][]templates.Variant)
+	for rows.Next() {
+		var groupName string
+		var v templates.Variant
+		if err := rows.Scan(&groupName, &v.Language, &v.ContentSid); err != nil {
+			return nil, err
+		}
+		variants[groupName] = append(variants[groupName], v)
+	}
+	return variants, rows.Err()
+}
+
+func (r *TemplateGroupRepository) UpsertVariant(ctx context.Context, name string, variant templates.Variant) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO template_group_variants (tenant_id, group_name, language, content_sid)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, group_name, language) DO UPDATE SET content_sid = EXCLUDED.content_sid
+		`, tenants.FromContext(ctx), name, variant.Language, variant.ContentSid)
+	return err
+}
+
+func (r *TemplateGroupRepository) DeleteVariant(ctx context.Context, name string, language string) error {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM template_group_variants
+		WHERE group_name = $1 AND language = $2 AND tenant_id = $3
+		`, name, language, tenants.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return templates.ErrNoVariant
+	}
+	return nil
+}
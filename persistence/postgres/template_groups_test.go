@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/templates"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateGroups_CreateAndFind(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTemplateGroupRepository(testDB)
+
+	group := templates.Group{
+		Name:        "welcome",
+		Description: "Onboarding message",
+		Variants: []templates.Variant{
+			{Language: "pt_BR", ContentSid: "HX-pt-br"},
+			{Language: "en", ContentSid: "HX-en"},
+		},
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, repo.Create(ctx, group))
+
+	gotten, err := repo.FindByName(ctx, "welcome")
+	require.NoError(t, err)
+	require.NotNil(t, gotten)
+	require.Equal(t, "Onboarding message", gotten.Description)
+	require.Len(t, gotten.Variants, 2)
+
+	require.NoError(t, repo.UpsertVariant(ctx, "welcome", templates.Variant{Language: "en", ContentSid: "HX-en-v2"}))
+	gotten, err = repo.FindByName(ctx, "welcome")
+	require.NoError(t, err)
+	require.Contains(t, gotten.Variants, templates.Variant{Language: "en", ContentSid: "HX-en-v2"})
+
+	require.NoError(t, repo.DeleteVariant(ctx, "welcome", "en"))
+	require.ErrorIs(t, repo.DeleteVariant(ctx, "welcome", "en"), templates.ErrNoVariant)
+
+	groups, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Variants, 1)
+}
+
+func TestTemplateGroups_FindByName_NotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTemplateGroupRepository(testDB)
+
+	gotten, err := repo.FindByName(ctx, "missing")
+	require.NoError(t, err)
+	require.Nil(t, gotten)
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TenantRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTenantRepository(db *pgxpool.Pool) *TenantRepository {
+	return &TenantRepository{db: db}
+}
+
+var _ tenants.Repository = &TenantRepository{}
+
+const tenantColumns = `id, name, account_sid, auth_token, from_number, created_at, updated_at`
+
+func scanTenant(row pgx.Row) (*tenants.Tenant, error) {
+	var t tenants.Tenant
+	err := row.Scan(&t.Id, &t.Name, &t.AccountSid, &t.AuthToken, &t.FromNumber, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TenantRepository) Create(ctx context.Context, tenant tenants.Tenant) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO tenants
+		(`+tenantColumns+`)
+		SELECT $1, $2, $3, $4, $5, $6, $7
+		WHERE NOT EXISTS (SELECT 1 FROM sender_numbers WHERE number = $5)
+		`,
+		tenant.Id,
+		tenant.Name,
+		tenant.AccountSid,
+		tenant.AuthToken,
+		tenant.FromNumber,
+		tenant.CreatedAt,
+		tenant.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return tenants.ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	// the number is in the sender pool of another tenant
+	if tag.RowsAffected() == 0 {
+		return tenants.ErrDuplicate
+	}
+	return nil
+}
+
+func (r *TenantRepository) Update(ctx context.Context, tenant tenants.Tenant) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE tenants
+		SET name = $2, account_sid = $3, auth_token = $4, from_number = $5, updated_at = $6
+		WHERE id = $1
+		AND NOT EXISTS (SELECT 1 FROM sender_numbers WHERE number = $5 AND tenant_id <> $1)
+		`,
+		tenant.Id,
+		tenant.Name,
+		tenant.AccountSid,
+		tenant.AuthToken,
+		tenant.FromNumber,
+		tenant.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return tenants.ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		existing, err := r.FindById(ctx, tenant.Id)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return tenants.ErrNotFound
+		}
+		// the number is in the sender pool of another tenant
+		return tenants.ErrDuplicate
+	}
+	return nil
+}
+
+func (r *TenantRepository) FindById(ctx context.Context, id uuid.UUID) (*tenants.Tenant, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE id = $1`, id)
+	tenant, err := scanTenant(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return tenant, err
+}
+
+func (r *TenantRepository) FindByNumber(ctx context.Context, number string) (*tenants.Tenant, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT `+tenantColumns+`
+		FROM tenants
+		WHERE (from_number = $1 AND from_number <> '')
+		OR id IN (SELECT tenant_id FROM sender_numbers WHERE number = $1)
+		`, number)
+	tenant, err := scanTenant(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return tenant, err
+}
+
+func (r *TenantRepository) List(ctx context.Context) ([]tenants.Tenant, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+tenantColumns+` FROM tenants ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []tenants.Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *tenant)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/throttle"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ThrottleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewThrottleRepository(db *pgxpool.Pool) *ThrottleRepository {
+	return &ThrottleRepository{db: db}
+}
+
+var _ throttle.Repository = &ThrottleRepository{}
+
+// Reserve moves the theoretical arrival time of the sender one interval
+// forward. A slot can be used once the arrival time is less than burst - 1
+// intervals ahead, which the row lock makes atomic across replicas.
+func (r *ThrottleRepository) Reserve(ctx context.Context, sender string, now time.Time, interval time.Duration, burst int, deadline time.Time) (time.Time, bool, error) {
+	tolerance := time.Duration(burst-1) * interval
+
+	var tat time.Time
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO sender_throughput (sender, tat)
+		VALUES ($1, $2::timestamp + $3::bigint * interval '1 microsecond')
+		ON CONFLICT (sender) DO UPDATE
+		SET tat = GREATEST(sender_throughput.tat, $2::timestamp) + $3::bigint * interval '1 microsecond'
+		WHERE sender_throughput.tat - $4::bigint * interval '1 microsecond' <= $5::timestamp
+		RETURNING tat
+		`, sender, now.UTC(), interval.Microseconds(), tolerance.Microseconds(), deadline.UTC()).Scan(&tat)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	at := tat.Add(-interval - tolerance)
+	if at.Before(now) {
+		at = now
+	}
+	return at, true, nil
+}
+
+func (r *ThrottleRepository) AddRecipient(ctx context.Context, sender string, day time.Time, phone string, limit int) (bool, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO sender_recipients (sender, day, phone)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+		`, sender, day, phone)
+	if err != nil {
+		return false, false, err
+	}
+	if tag.RowsAffected() == 0 {
+		// already counted today
+		return true, false, nil
+	}
+
+	var recipients int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO sender_usage (sender, day, recipients)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (sender, day) DO UPDATE
+		SET recipients = sender_usage.recipients + 1
+		WHERE sender_usage.recipients < $3
+		RETURNING recipients
+		`, sender, day, limit).Scan(&recipients)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	return true, true, tx.Commit(ctx)
+}
+
+func (r *ThrottleRepository) RemoveRecipient(ctx context.Context, sender string, day time.Time, phone string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM sender_recipients WHERE sender = $1 AND day = $2 AND phone = $3`, sender, day, phone)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE sender_usage
+		SET recipients = recipients - 1
+		WHERE sender = $1 AND day = $2 AND recipients > 0
+		`, sender, day)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *ThrottleRepository) Prune(ctx context.Context, day time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM sender_recipients WHERE day < $1`, day); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM sender_usage WHERE day < $1`, day); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
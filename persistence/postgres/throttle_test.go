@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/throttle"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle_Reserve(t *testing.T) {
+	ctx := context.Background()
+	repo := NewThrottleRepository(testDB)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	interval := 100 * time.Millisecond
+	deadline := now.Add(200 * time.Millisecond)
+
+	// a burst of 2 goes out right away, the rest is spaced by the interval
+	var slots []time.Time
+	for range 4 {
+		at, ok, err := repo.Reserve(ctx, "whatsapp:+15550001", now, interval, 2, deadline)
+		require.NoError(t, err)
+		require.True(t, ok)
+		slots = append(slots, at)
+	}
+	require.Equal(t, []time.Time{now, now, now.Add(interval), now.Add(2 * interval)}, slots)
+
+	_, ok, err := repo.Reserve(ctx, "whatsapp:+15550001", now, interval, 2, deadline)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	at, ok, err := repo.Reserve(ctx, "whatsapp:+15550002", now, interval, 2, deadline)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, now, at)
+}
+
+func TestThrottle_TierRecipients(t *testing.T) {
+	ctx := context.Background()
+	repo := NewThrottleRepository(testDB)
+	day := throttle.Day(time.Now())
+
+	for _, phone := range []string{"+5511999990301", "+5511999990302"} {
+		allowed, added, err := repo.AddRecipient(ctx, "whatsapp:+15550003", day, phone, 2)
+		require.NoError(t, err)
+		require.True(t, allowed)
+		require.True(t, added)
+	}
+	allowed, _, err := repo.AddRecipient(ctx, "whatsapp:+15550003", day, "+5511999990303", 2)
+	require.NoError(t, err)
+	require.False(t, allowed)
+	allowed, added, err := repo.AddRecipient(ctx, "whatsapp:+15550003", day, "+5511999990301", 2)
+	require.NoError(t, err)
+	require.True(t, allowed, "recipients already counted today are allowed")
+	require.False(t, added)
+
+	// a recipient whose message failed frees its place
+	require.NoError(t, repo.RemoveRecipient(ctx, "whatsapp:+15550003", day, "+5511999990302"))
+	allowed, _, err = repo.AddRecipient(ctx, "whatsapp:+15550003", day, "+5511999990303", 2)
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	require.NoError(t, repo.Prune(ctx, day.AddDate(0, 0, 1)))
+	allowed, _, err = repo.AddRecipient(ctx, "whatsapp:+15550003", day, "+5511999990304", 2)
+	require.NoError(t, err)
+	require.True(t, allowed)
+}
@@ -0,0 +1,373 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"mbx/tenants"
+	"mbx/webhooks"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+var _ webhooks.Repository = &WebhookRepository{}
+
+const (
+	webhookEndpointColumns = `id, url, secret, events, active, created_at`
+	webhookEventColumns    = `id, type, data, created_at, trace_parent`
+	webhookDeliveryColumns = `id, event_id, endpoint_id, status, attempts, next_attempt_at, last_error, created_at, updated_at`
+)
+
+func scanWebhookEndpoint(row pgx.Row) (*webhooks.Endpoint, error) {
+	var e webhooks.Endpoint
+	var events []string
+	if err := row.Scan(&e.Id, &e.URL, &e.Secret, &events, &e.Active, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	e.Events = make([]webhooks.EventType, 0, len(events))
+	for _, ev := range events {
+		e.Events = append(e.Events, webhooks.EventType(ev))
+	}
+	return &e, nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (*webhooks.Delivery, error) {
+	var d webhooks.Delivery
+	err := row.Scan(&d.Id, &d.EventId, &d.EndpointId, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func eventNames(events []webhooks.EventType) []string {
+	names := make([]string, 0, len(events))
+	for _, e := range events {
+		names = append(names, string(e))
+	}
+	return names
+}
+
+func (r *WebhookRepository) CreateEndpoint(ctx context.Context, endpoint webhooks.Endpoint) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO webhook_endpoints
+		(tenant_id, `+webhookEndpointColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`,
+		tenants.FromContext(ctx),
+		endpoint.Id,
+		endpoint.URL,
+		endpoint.Secret,
+		eventNames(endpoint.Events),
+		endpoint.Active,
+		endpoint.CreatedAt,
+	)
+	return err
+}
+
+func (r *WebhookRepository) UpdateEndpoint(ctx context.Context, endpoint webhooks.Endpoint) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE webhook_endpoints
+		SET url = $2, secret = $3, events = $4, active = $5
+		WHERE id = $1 AND tenant_id = $6
+		`,
+		endpoint.Id,
+		endpoint.URL,
+		endpoint.Secret,
+		eventNames(endpoint.Events),
+		endpoint.Active,
+		tenants.FromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return webhooks.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return webhooks.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) FindEndpoint(ctx context.Context, id uuid.UUID) (*webhooks.Endpoint, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx))
+	endpoint, err := scanWebhookEndpoint(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return endpoint, err
+}
+
+func (r *WebhookRepository) ListEndpoints(ctx context.Context) ([]webhooks.Endpoint, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE tenant_id = $1 ORDER BY created_at, id`, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []webhooks.Endpoint
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *endpoint)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) FindEvent(ctx context.Context, id uuid.UUID) (*webhooks.Event, error) {
+	var e webhooks.Event
+	err := r.db.QueryRow(ctx, `SELECT `+webhookEventColumns+` FROM webhook_events WHERE id = $1 AND tenant_id = $2`, id, tenants.FromContext(ctx)).
+		Scan(&e.Id, &e.Type, &e.Data, &e.CreatedAt, &e.TraceParent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *WebhookRepository) ListEvents(ctx context.Context, limit int) ([]webhooks.Event, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookEventColumns+`
+		FROM webhook_events
+		WHERE tenant_id = $2
+		ORDER BY created_at DESC, id
+		LIMIT $1
+		`, limit, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []webhooks.Event
+	for rows.Next() {
+		var e webhooks.Event
+		if err := rows.Scan(&e.Id, &e.Type, &e.Data, &e.CreatedAt, &e.TraceParent); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) Enqueue(ctx context.Context, event webhooks.Event, deliveries []webhooks.Delivery) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// replays enqueue an event that is already stored
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_events (tenant_id, `+webhookEventColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO NOTHING
+		`,
+		tenants.FromContext(ctx),
+		event.Id,
+		event.Type,
+		event.Data,
+		event.CreatedAt,
+		event.TraceParent,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			`,
+			d.Id,
+			d.EventId,
+			d.EndpointId,
+			d.Status,
+			d.Attempts,
+			d.NextAttemptAt,
+			d.LastError,
+			d.CreatedAt,
+			d.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ClaimDue claims the due deliveries of every tenant, for the dispatcher
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhooks.Job, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET next_attempt_at = $2
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= $1
+				ORDER BY next_attempt_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING `+webhookDeliveryColumns+`
+		)
+		SELECT c.id, c.event_id, c.endpoint_id, c.status, c.attempts, c.next_attempt_at, c.last_error, c.created_at, c.updated_at,
+			ev.id, ev.type, ev.data, ev.created_at, ev.trace_parent,
+			e.id, e.url, e.secret, e.events, e.active, e.created_at
+		FROM claimed c
+		JOIN webhook_events ev ON ev.id = c.event_id
+		JOIN webhook_endpoints e ON e.id = c.endpoint_id
+		ORDER BY c.created_at
+		`, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []webhooks.Job
+	for rows.Next() {
+		var job webhooks.Job
+		var events []string
+		d, ev, e := &job.Delivery, &job.Event, &job.Endpoint
+		err := rows.Scan(
+			&d.Id, &d.EventId, &d.EndpointId, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.UpdatedAt,
+			&ev.Id, &ev.Type, &ev.Data, &ev.CreatedAt, &ev.TraceParent,
+			&e.Id, &e.URL, &e.Secret, &events, &e.Active, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range events {
+			e.Events = append(e.Events, webhooks.EventType(name))
+		}
+		out = append(out, job)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, delivery webhooks.Delivery, attempt webhooks.Attempt) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_attempts (id, delivery_id, number, status_code, error, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`,
+		attempt.Id,
+		attempt.DeliveryId,
+		attempt.Number,
+		attempt.StatusCode,
+		attempt.Error,
+		attempt.DurationMs,
+		attempt.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, updated_at = $6
+		WHERE id = $1
+		`,
+		delivery.Id,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastError,
+		delivery.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, endpointId uuid.UUID, limit int) ([]webhooks.Delivery, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1 AND endpoint_id IN (SELECT id FROM webhook_endpoints WHERE tenant_id = $3)
+		ORDER BY created_at DESC, id
+		LIMIT $2
+		`, endpointId, limit, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []webhooks.Delivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *delivery)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepository) ListAttempts(ctx context.Context, deliveryId uuid.UUID) ([]webhooks.Attempt, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, delivery_id, number, status_code, error, duration_ms, created_at
+		FROM webhook_attempts
+		WHERE delivery_id = $1 AND delivery_id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			WHERE e.tenant_id = $2
+		)
+		ORDER BY number
+		`, deliveryId, tenants.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []webhooks.Attempt
+	for rows.Next() {
+		var a webhooks.Attempt
+		if err := rows.Scan(&a.Id, &a.DeliveryId, &a.Number, &a.StatusCode, &a.Error, &a.DurationMs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// QueueDepth counts the deliveries of every tenant, for the metrics
+func (r *WebhookRepository) QueueDepth(ctx context.Context) (int, int, error) {
+	var pending, failed int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE status = 'pending'), COUNT(*) FILTER (WHERE status = 'failed')
+		FROM webhook_deliveries
+		WHERE status IN ('pending', 'failed')
+		`).Scan(&pending, &failed)
+	return pending, failed, err
+}
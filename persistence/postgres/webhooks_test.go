@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"mbx/webhooks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks_ClaimAndRecordAttempt(t *testing.T) {
+	ctx := context.Background()
+	repo := NewWebhookRepository(testDB)
+
+	now := time.Now().Truncate(time.Millisecond)
+	endpoint := webhooks.Endpoint{
+		Id:        uuid.New(),
+		URL:       "https://example.com/hooks",
+		Secret:    "whsec_test",
+		Events:    []webhooks.EventType{webhooks.EventMessageDelivered},
+		Active:    true,
+		CreatedAt: now,
+	}
+	require.NoError(t, repo.CreateEndpoint(ctx, endpoint))
+
+	event := webhooks.Event{
+		Id:        uuid.New(),
+		Type:      webhooks.EventMessageDelivered,
+		Data:      json.RawMessage(`{"sid": "SM123"}`),
+		CreatedAt: now,
+	}
+	delivery := webhooks.Delivery{
+		Id:            uuid.New(),
+		EventId:       event.Id,
+		EndpointId:    endpoint.Id,
+		Status:        webhooks.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	require.NoError(t, repo.Enqueue(ctx, event, []webhooks.Delivery{delivery}))
+
+	jobs, err := repo.ClaimDue(ctx, now.Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, delivery.Id, jobs[0].Delivery.Id)
+	require.Equal(t, endpoint.URL, jobs[0].Endpoint.URL)
+	require.Equal(t, endpoint.Events, jobs[0].Endpoint.Events)
+	require.JSONEq(t, string(event.Data), string(jobs[0].Event.Data))
+
+	// leased, so a second dispatcher does not get it
+	jobs, err = repo.ClaimDue(ctx, now.Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+
+	delivery.Status = webhooks.DeliveryDelivered
+	delivery.Attempts = 1
+	require.NoError(t, repo.RecordAttempt(ctx, delivery, webhooks.Attempt{
+		Id:         uuid.New(),
+		DeliveryId: delivery.Id,
+		Number:     1,
+		StatusCode: 200,
+		DurationMs: 12,
+		CreatedAt:  now,
+	}))
+
+	deliveries, err := repo.ListDeliveries(ctx, endpoint.Id, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, webhooks.DeliveryDelivered, deliveries[0].Status)
+
+	attempts, err := repo.ListAttempts(ctx, delivery.Id)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	require.Equal(t, 200, attempts[0].StatusCode)
+
+	// replaying stores a new delivery for the same event
+	replay := delivery
+	replay.Id = uuid.New()
+	replay.Status = webhooks.DeliveryPending
+	replay.Attempts = 0
+	require.NoError(t, repo.Enqueue(ctx, event, []webhooks.Delivery{replay}))
+	deliveries, err = repo.ListDeliveries(ctx, endpoint.Id, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+}
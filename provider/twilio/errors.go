@@ -0,0 +1,39 @@
+package twilio
+
+import (
+	"errors"
+
+	"github.com/twilio/twilio-go/client"
+)
+
+// Twilio error codes we react to, see https://www.twilio.com/docs/api/errors
+const (
+	// CodeInvalidNumber is returned when the recipient is not a valid phone
+	// number
+	CodeInvalidNumber = 21211
+	// CodeInvalidContent is returned when the content variables don't fit
+	// the template
+	CodeInvalidContent = 21656
+	// CodeNotWhatsapp is returned when the recipient has no WhatsApp account
+	CodeNotWhatsapp = 63003
+	// CodeOutsideWindow is returned for freeform WhatsApp messages sent more
+	// than 24 hours after the customer's last message
+	CodeOutsideWindow = 63016
+	// CodeRateLimit is returned when the sender goes over the rate WhatsApp
+	// allows it
+	CodeRateLimit = 63018
+)
+
+// ErrInvalidCursor is returned for a page cursor that was not handed out by
+// a previous page
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// ErrorCode returns the Twilio error code wrapped in err, or 0 when err did
+// not come from the Twilio REST API.
+func ErrorCode(err error) int {
+	var restErr *client.TwilioRestError
+	if errors.As(err, &restErr) {
+		return restErr.Code
+	}
+	return 0
+}
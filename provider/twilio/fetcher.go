@@ -2,11 +2,13 @@ package twilio
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log/slog"
 	"mbx/models"
 	"mbx/sender"
 	"mbx/templates"
+	"net/url"
 	"time"
 
 	"github.com/twilio/twilio-go"
@@ -17,7 +19,9 @@ import (
 
 type WhatsappFetcher interface {
 	GetTemplates(context.Context) ([]templates.SavedTemplate, error)
-	GetMessages(ctx context.Context, after time.Time) ([]models.SentMessage, error)
+	// GetMessages returns a page of up to size messages sent since after,
+	// newest first. An empty cursor starts at the first page.
+	GetMessages(ctx context.Context, after time.Time, size int, cursor string) (*models.MessagePage, error)
 	GetScheduledMessages(ctx context.Context, after time.Time) ([]models.SentMessage, error)
 	ListMessagingServices(ctx context.Context) ([]models.MessagingService, error)
 }
@@ -53,7 +57,9 @@ func (s *TwilioFetcher) ListMessagingServices(ctx context.Context) ([]models.Mes
 	params.SetPageSize(1000)
 	params.SetLimit(1000)
 
+	call := startCall(ctx, "list_messaging_services")
 	services, err := s.client.MessagingV1.ListService(params)
+	call.end(err)
 	if err != nil {
 		slog.Error("Error fetching messaging services from Twilio", "error", err)
 		return nil, fmt.Errorf("error getting messaging services from twilio: %w", err)
@@ -72,15 +78,25 @@ func (s *TwilioFetcher) ListMessagingServices(ctx context.Context) ([]models.Mes
 }
 
 func (s *TwilioFetcher) GetScheduledMessages(ctx context.Context, after time.Time) ([]models.SentMessage, error) {
-	msgs, err := s.GetMessages(ctx, after)
+	params := &openapi.ListMessageParams{}
+	params.SetPageSize(1000)
+	params.SetLimit(1000)
+
+	params.SetDateSentAfter(after)
+
+	call := startCall(ctx, "list_messages")
+	messages, err := s.client.Api.ListMessage(params)
+	call.end(err)
 	if err != nil {
-		return nil, err
+		slog.Error("Error fetching messages from Twilio", "error", err, "after", after)
+		return nil, fmt.Errorf("error getting messages from twilio: %w", err)
 	}
+	slog.Info("Fetched messages from Twilio", "count", len(messages), "after", after)
 
 	var scheduledMessages []models.SentMessage
-	for _, msg := range msgs {
-		if msg.Status == "scheduled" {
-			scheduledMessages = append(scheduledMessages, msg)
+	for _, msg := range messages {
+		if sp(msg.Status) == "scheduled" {
+			scheduledMessages = append(scheduledMessages, sentMessage(msg))
 		}
 	}
 
@@ -88,36 +104,62 @@ func (s *TwilioFetcher) GetScheduledMessages(ctx context.Context, after time.Tim
 	return scheduledMessages, nil
 }
 
-func (s *TwilioFetcher) GetMessages(ctx context.Context, after time.Time) ([]models.SentMessage, error) {
-	params := &openapi.ListMessageParams{}
-	params.SetPageSize(1000)
-	params.SetLimit(1000)
+// GetMessages fetches a single page from Twilio. The cursor carries the page
+// number and token of Twilio's next page URI.
+func (s *TwilioFetcher) GetMessages(ctx context.Context, after time.Time, size int, cursor string) (*models.MessagePage, error) {
+	var pageNumber, pageToken string
+	if cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values, err := url.ParseQuery(string(decoded))
+		if err != nil || values.Get("Page") == "" || values.Get("PageToken") == "" {
+			return nil, ErrInvalidCursor
+		}
+		pageNumber, pageToken = values.Get("Page"), values.Get("PageToken")
+	}
 
+	params := &openapi.ListMessageParams{}
+	params.SetPageSize(size)
 	params.SetDateSentAfter(after)
 
-	messages, err := s.client.Api.ListMessage(params)
+	call := startCall(ctx, "list_messages")
+	response, err := s.client.Api.PageMessage(params, pageToken, pageNumber)
+	call.end(err)
 	if err != nil {
 		slog.Error("Error fetching messages from Twilio", "error", err, "after", after)
 		return nil, fmt.Errorf("error getting messages from twilio: %w", err)
 	}
-	slog.Info("Fetched messages from Twilio", "count", len(messages), "after", after)
 
-	sentMessages := make([]models.SentMessage, len(messages))
-	for i, msg := range messages {
-		sentMessages[i] = models.SentMessage{
-			ID:           sp(msg.Sid),
-			To:           sp(msg.To),
-			Body:         sp(msg.Body),
-			DateSent:     sp(msg.DateSent),
-			ErrorCode:    sp(msg.ErrorCode),
-			ErrorMessage: sp(msg.ErrorMessage),
-			Status:       sp(msg.Status),
-			Price:        sp(msg.Price),
-			PriceUnit:    sp(msg.PriceUnit),
+	page := &models.MessagePage{Messages: make([]models.SentMessage, len(response.Messages))}
+	for i, msg := range response.Messages {
+		page.Messages[i] = sentMessage(msg)
+	}
+	if next := sp(response.NextPageUri); next != "" {
+		uri, err := url.Parse(next)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the next page of messages from twilio: %w", err)
 		}
+		query := uri.Query()
+		cursor := url.Values{"Page": {query.Get("Page")}, "PageToken": {query.Get("PageToken")}}
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(cursor.Encode()))
 	}
+	return page, nil
+}
 
-	return sentMessages, nil
+func sentMessage(msg openapi.ApiV2010Message) models.SentMessage {
+	return models.SentMessage{
+		ID:           sp(msg.Sid),
+		To:           sp(msg.To),
+		Body:         sp(msg.Body),
+		DateSent:     sp(msg.DateSent),
+		ErrorCode:    sp(msg.ErrorCode),
+		ErrorMessage: sp(msg.ErrorMessage),
+		Status:       sp(msg.Status),
+		Price:        sp(msg.Price),
+		PriceUnit:    sp(msg.PriceUnit),
+	}
 }
 
 func (s *TwilioFetcher) GetTemplates(ctx context.Context) ([]templates.SavedTemplate, error) {
@@ -128,7 +170,9 @@ func (s *TwilioFetcher) GetTemplates(ctx context.Context) ([]templates.SavedTemp
 	contentParams.SetPageSize(1000)
 
 	slog.Info("Fetching WhatsApp templates")
+	call := startCall(ctx, "list_templates")
 	contents, err := contentService.ListContent(contentParams)
+	call.end(err)
 	if err != nil {
 		return nil, err
 	}
@@ -203,3 +247,23 @@ func (s *TwilioFetcher) GetTemplates(ctx context.Context) ([]templates.SavedTemp
 
 	return templatesOut, nil
 }
+
+// GetTemplateCategory returns the WhatsApp category Meta assigned to the
+// template when approving it, e.g. MARKETING or UTILITY. Templates that were
+// never submitted for approval have no category.
+func (s *TwilioFetcher) GetTemplateCategory(ctx context.Context, contentSid string) (string, error) {
+	contentService := content.NewApiServiceWithClient(s.client.Client)
+
+	call := startCall(ctx, "fetch_template_approval")
+	approval, err := contentService.FetchApprovalFetch(contentSid)
+	call.end(err)
+	if err != nil {
+		return "", fmt.Errorf("error getting template approval from twilio: %w", err)
+	}
+
+	if approval.Whatsapp == nil {
+		return "", nil
+	}
+	category, _ := (*approval.Whatsapp)["category"].(string)
+	return category, nil
+}
@@ -0,0 +1,64 @@
+package twilio
+
+import (
+	"context"
+	"mbx/metrics"
+	"mbx/models"
+	"mbx/tracing"
+	"strconv"
+	"time"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// call is a Twilio REST call being traced and measured
+type call struct {
+	operation string
+	start     time.Time
+	span      trace.Span
+}
+
+// startCall starts the span and the timer of a provider call. The REST
+// client takes no context, so the span is all that ties the call to the
+// request that made it.
+func startCall(ctx context.Context, operation string) *call {
+	_, span := tracing.Tracer().Start(ctx, "twilio."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("rpc.system", "twilio"), attribute.String("rpc.method", operation)),
+	)
+	return &call{operation: operation, start: time.Now(), span: span}
+}
+
+// end records the duration and outcome of the call
+func (c *call) end(err error) {
+	defer c.span.End()
+	metrics.ProviderDuration.WithLabelValues(c.operation).Observe(time.Since(c.start).Seconds())
+	if err == nil {
+		return
+	}
+
+	code := "none"
+	if errorCode := ErrorCode(err); errorCode != 0 {
+		code = strconv.Itoa(errorCode)
+		c.span.SetAttributes(attribute.Int("twilio.error_code", errorCode))
+	}
+	metrics.ProviderErrors.WithLabelValues(c.operation, code).Inc()
+	c.span.RecordError(err)
+	c.span.SetStatus(codes.Error, "provider call failed")
+}
+
+// countSent records a message handed to the provider, with the status it was
+// accepted in or failed
+func countSent(messageType models.ScheduledMessageType, resp *api.ApiV2010Message, err error) {
+	status := "failed"
+	if err == nil {
+		status = "unknown"
+		if resp != nil && resp.Status != nil {
+			status = *resp.Status
+		}
+	}
+	metrics.MessagesSent.WithLabelValues(metrics.ChannelWhatsapp, string(messageType), status).Inc()
+}
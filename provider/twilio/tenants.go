@@ -0,0 +1,163 @@
+package twilio
+
+import (
+	"context"
+	"mbx/models"
+	"mbx/sender"
+	"mbx/templates"
+	"mbx/tenants"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// Configs resolves the provider configuration of the tenant a context is
+// scoped to
+type Configs interface {
+	Config(ctx context.Context) (*sender.Config, error)
+}
+
+// TenantClients sends and fetches through the Twilio account of the tenant
+// in the context. Clients are created on first use and kept, looking the
+// configuration up again after the TTL so that new credentials are picked up.
+type TenantClients struct {
+	configs Configs
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	clients map[uuid.UUID]*tenantClient
+}
+
+type tenantClient struct {
+	cfg       sender.Config
+	sender    *TwilioSender
+	fetcher   *TwilioFetcher
+	checkedAt time.Time
+}
+
+var _ sender.Whatsapp = (*TenantClients)(nil)
+var _ sender.WhatsappTemplate = (*TenantClients)(nil)
+var _ WhatsappFetcher = (*TenantClients)(nil)
+
+func NewTenantClients(configs Configs, ttl time.Duration) *TenantClients {
+	return &TenantClients{
+		configs: configs,
+		ttl:     ttl,
+		clients: make(map[uuid.UUID]*tenantClient),
+	}
+}
+
+func (c *TenantClients) client(ctx context.Context) (*tenantClient, error) {
+	id := tenants.FromContext(ctx)
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.clients[id]
+	if ok && now.Sub(cached.checkedAt) < c.ttl {
+		c.mu.Unlock()
+		return cached, nil
+	}
+	c.mu.Unlock()
+
+	cfg, err := c.configs.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// a concurrent lookup may have refreshed it already
+	if cached, ok := c.clients[id]; ok && cached.cfg == *cfg {
+		cached.checkedAt = now
+		return cached, nil
+	}
+	tc := &tenantClient{cfg: *cfg, checkedAt: now}
+	client := NewTwilioClient(&tc.cfg)
+	tc.sender = NewSender(client, &tc.cfg)
+	tc.fetcher = NewTwilioFetcher(client, &tc.cfg)
+	c.clients[id] = tc
+	return tc, nil
+}
+
+// FromNumber returns the number the tenant in ctx sends from
+func (c *TenantClients) FromNumber(ctx context.Context) (string, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	return tc.cfg.TwilioFromNumber, nil
+}
+
+func (c *TenantClients) Send(ctx context.Context, message models.WhatsappBody) (*api.ApiV2010Message, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tc.sender.Send(ctx, message)
+}
+
+func (c *TenantClients) CancelMessage(ctx context.Context, twilioId string) error {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return err
+	}
+	return tc.sender.CancelMessage(ctx, twilioId)
+}
+
+func (c *TenantClients) SendTemplate(ctx context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tc.sender.SendTemplate(ctx, template)
+}
+
+func (c *TenantClients) CreateTemplate(ctx context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tc.sender.CreateTemplate(ctx, dto)
+}
+
+func (c *TenantClients) GetTemplates(ctx context.Context) ([]templates.SavedTemplate, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tc.fetcher.GetTemplates(ctx)
+}
+
+func (c *TenantClients) GetTemplateCategory(ctx context.Context, contentSid string) (string, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	return tc.fetcher.GetTemplateCategory(ctx, contentSid)
+}
+
+func (c *TenantClients) GetMessages(ctx context.Context, after time.Time, size int, cursor string) (*models.MessagePage, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tc.fetcher.GetMessages(ctx, after, size, cursor)
+}
+
+func (c *TenantClients) GetScheduledMessages(ctx context.Context, after time.Time) ([]models.SentMessage, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tc.fetcher.GetScheduledMessages(ctx, after)
+}
+
+func (c *TenantClients) ListMessagingServices(ctx context.Context) ([]models.MessagingService, error) {
+	tc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tc.fetcher.ListMessagingServices(ctx)
+}
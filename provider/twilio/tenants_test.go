@@ -0,0 +1,77 @@
+package twilio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/sender"
+	"mbx/tenants"
+
+	"github.com/google/uuid"
+)
+
+// stubConfigs counts the lookups of each tenant configuration
+type stubConfigs struct {
+	configs map[uuid.UUID]sender.Config
+	lookups int
+}
+
+func (s *stubConfigs) Config(ctx context.Context) (*sender.Config, error) {
+	s.lookups++
+	cfg := s.configs[tenants.FromContext(ctx)]
+	return &cfg, nil
+}
+
+func TestTenantClients_CachesPerTenant(t *testing.T) {
+	brand := uuid.New()
+	configs := &stubConfigs{configs: map[uuid.UUID]sender.Config{
+		tenants.DefaultId: {TwilioAccountSID: "AC1", TwilioAuthToken: "one", TwilioFromNumber: "whatsapp:+15550001"},
+		brand:             {TwilioAccountSID: "AC2", TwilioAuthToken: "two", TwilioFromNumber: "whatsapp:+15550002"},
+	}}
+	clients := NewTenantClients(configs, time.Hour)
+
+	ctx := context.Background()
+	brandCtx := tenants.NewContext(ctx, brand)
+	first, err := clients.client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := clients.client(ctx); again != first {
+		t.Errorf("Expected the client to be cached")
+	}
+	other, _ := clients.client(brandCtx)
+	if other == first || other.cfg.TwilioAccountSID != "AC2" {
+		t.Errorf("Expected a client of the brand account, got %+v", other.cfg)
+	}
+	if configs.lookups != 2 {
+		t.Errorf("Expected one lookup per tenant, got %d", configs.lookups)
+	}
+
+	number, _ := clients.FromNumber(brandCtx)
+	if number != "whatsapp:+15550002" {
+		t.Errorf("Expected the brand number, got %q", number)
+	}
+}
+
+func TestTenantClients_RebuildsOnNewCredentials(t *testing.T) {
+	configs := &stubConfigs{configs: map[uuid.UUID]sender.Config{
+		tenants.DefaultId: {TwilioAccountSID: "AC1", TwilioAuthToken: "one"},
+	}}
+	clients := NewTenantClients(configs, 0)
+
+	ctx := context.Background()
+	first, _ := clients.client(ctx)
+	if again, _ := clients.client(ctx); again != first {
+		t.Errorf("Expected the client to be kept while its configuration is the same")
+	}
+
+	configs.configs[tenants.DefaultId] = sender.Config{TwilioAccountSID: "AC1", TwilioAuthToken: "rotated"}
+	rebuilt, _ := clients.client(ctx)
+	if rebuilt == first || rebuilt.cfg.TwilioAuthToken != "rotated" {
+		t.Errorf("Expected a client with the rotated token, got %+v", rebuilt.cfg)
+	}
+	if configs.lookups != 3 {
+		t.Errorf("Expected a lookup once the TTL passed, got %d", configs.lookups)
+	}
+}
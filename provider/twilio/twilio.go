@@ -39,16 +39,35 @@ func NewSender(client *twilio.RestClient, cfg *sender.Config) *TwilioSender {
 	}
 }
 
+// setFrom sends from the selected number or Messaging Service, or from the
+// configured number when none was selected
+func (s *TwilioSender) setFrom(params *api.CreateMessageParams, from models.From) {
+	switch {
+	case from.MessagingServiceSid != "":
+		params.SetMessagingServiceSid(from.MessagingServiceSid)
+	case from.Number != "":
+		params.SetFrom("whatsapp:" + from.Number)
+	default:
+		params.SetFrom(s.cfg.TwilioFromNumber)
+	}
+}
+
 func (s *TwilioSender) Send(ctx context.Context, message models.WhatsappBody) (*api.ApiV2010Message, error) {
 	messageParams := &api.CreateMessageParams{
 		To:   &message.To,
-		From: &s.cfg.TwilioFromNumber,
 		Body: &message.Body,
 	}
+	s.setFrom(messageParams, message.From)
+	if s.cfg.StatusCallbackURL != "" {
+		messageParams.SetStatusCallback(s.cfg.StatusCallbackURL)
+	}
 
+	call := startCall(ctx, "send_message")
 	resp, err := s.client.Api.CreateMessage(messageParams)
+	call.end(err)
+	countSent(models.ScheduleTypeFreeform, resp, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send message: %v", err)
+		return nil, fmt.Errorf("failed to send message: %w", err)
 	}
 
 	return resp, nil
@@ -58,9 +77,11 @@ func (s *TwilioSender) SendTemplate(ctx context.Context, template templates.What
 	messageParams := &api.CreateMessageParams{}
 
 	messageParams.SetTo(fmt.Sprintf("whatsapp:%s", template.To))
-	messageParams.SetFrom(fmt.Sprintf("whatsapp:%s", s.cfg.TwilioFromNumber))
+	s.setFrom(messageParams, template.From)
 
-	messageParams.SetStatusCallback("https://mbx-sender-callbacks.fly.dev/api/v1/callbacks/twilio")
+	if s.cfg.StatusCallbackURL != "" {
+		messageParams.SetStatusCallback(s.cfg.StatusCallbackURL)
+	}
 	messageParams.SetContentSid(template.TemplateId)
 
 	if template.Content != "" && template.Content != "{}" && template.Content != "null" {
@@ -71,9 +92,12 @@ func (s *TwilioSender) SendTemplate(ctx context.Context, template templates.What
 		"template_id", template.TemplateId,
 		"content_variables", template.Content)
 
+	call := startCall(ctx, "send_template")
 	resp, err := s.client.Api.CreateMessage(messageParams)
+	call.end(err)
+	countSent(models.ScheduleTypeTemplate, resp, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send message: %v", err)
+		return nil, fmt.Errorf("failed to send message: %w", err)
 	}
 	slog.Info("Sent template message", "sid", *resp.Sid)
 
@@ -96,9 +120,11 @@ func (s *TwilioSender) CreateTemplate(ctx context.Context, dto templates.CreateT
 	}
 
 	slog.Info("Creating WhatsApp template", "friendly_name", dto.FriendlyName, "language", dto.Language)
+	call := startCall(ctx, "create_template")
 	createdContent, err := contentService.CreateContent(createParams)
+	call.end(err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create template: %v", err)
+		return nil, fmt.Errorf("failed to create template: %w", err)
 	}
 
 	slog.Info("Created WhatsApp template", "sid", *createdContent.Sid)
@@ -168,9 +194,11 @@ func (s *TwilioSender) CancelMessage(ctx context.Context, twilioId string) error
 	}
 
 	slog.Info("Canceling WhatsApp template message", "sid", twilioId)
+	call := startCall(ctx, "cancel_message")
 	msg, err := s.client.Api.UpdateMessage(twilioId, updateMessageParams)
+	call.end(err)
 	if err != nil {
-		return fmt.Errorf("failed to cancel template message: %v", err)
+		return fmt.Errorf("failed to cancel template message: %w", err)
 	}
 
 	if msg.Status == nil || *msg.Status != canceled {
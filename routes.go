@@ -1,16 +1,144 @@
 package mbx
 
 import (
+	"mbx/apikeys"
 	"mbx/handler"
+	"mbx/metrics"
+	"mbx/tracing"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// APIVersion is the path prefix of the current version of the API
+const APIVersion = "/v1"
+
+// DeprecatedMiddleware marks the responses of the unversioned paths, which
+// predate APIVersion and are kept as aliases, as deprecated
+func DeprecatedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+APIVersion+r.URL.Path+">; rel=\"successor-version\"")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusWriter remembers the status of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps the event stream working through the writer
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// MetricsMiddleware counts and times the requests to next by the route that
+// served them. next has to be the mux for the route to be known.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		// the mux sets the pattern of the route it matched on r
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.WithLabelValues(route, strconv.Itoa(sw.statusCode())).Inc()
+		metrics.HTTPDuration.WithLabelValues(route).Observe(time.Since(started).Seconds())
+	})
+}
+
+// TracingMiddleware starts a span for every request to next, continuing the
+// trace of the caller when it sent one. Like MetricsMiddleware it has to wrap
+// the mux to name the span after the route.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(sw, r)
+
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			_, route, _ := strings.Cut(r.Pattern, " ")
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		status := sw.statusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// countWebhook counts the provider callbacks next handled by outcome
+func countWebhook(webhook string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		next(sw, r)
+
+		outcome := "processed"
+		switch status := sw.statusCode(); {
+		case status >= http.StatusInternalServerError:
+			outcome = "failed"
+		case status >= http.StatusBadRequest:
+			outcome = "rejected"
+		}
+		metrics.WebhooksReceived.WithLabelValues(webhook, outcome).Inc()
+	}
+}
+
 // CORSMiddleware adds CORS headers to all responses
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*") // Change "*" to specific domain in production
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Agent-Id, Last-Event-ID, Idempotency-Key, X-Request-Id, traceparent, tracestate")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-Id, Retry-After, Idempotent-Replayed, Deprecation, Link, Location")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
 		// Handle preflight OPTIONS request
@@ -24,21 +152,153 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func SetupRouter(messageHandler *handler.MessageHandler, templateHandler *handler.TemplateHandler) http.Handler {
+func SetupRouter(
+	messageHandler *handler.MessageHandler,
+	templateHandler *handler.TemplateHandler,
+	templateGroupHandler *handler.TemplateGroupHandler,
+	scheduleHandler *handler.ScheduledMessageHandler,
+	contactHandler *handler.ContactHandler,
+	inboundHandler *handler.InboundHandler,
+	suppressionHandler *handler.SuppressionHandler,
+	consentHandler *handler.ConsentHandler,
+	windowHandler *handler.WindowHandler,
+	conversationHandler *handler.ConversationHandler,
+	agentHandler *handler.AgentHandler,
+	autoReplyHandler *handler.AutoReplyHandler,
+	flowHandler *handler.FlowHandler,
+	statusHandler *handler.StatusHandler,
+	webhookHandler *handler.WebhookHandler,
+	eventStreamHandler *handler.EventStreamHandler,
+	campaignHandler *handler.CampaignHandler,
+	importHandler *handler.ImportHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	tenantHandler *handler.TenantHandler,
+	senderHandler *handler.SenderHandler,
+	authenticator *handler.Authenticator,
+	idempotency *handler.Idempotency,
+) http.Handler {
+	// the routes are served under APIVersion, and under their old paths
+	// until clients have moved over
 	mux := http.NewServeMux()
+	// every route needs an API key with its scope, except the Twilio
+	// callbacks, which are signed instead, and the documentation
+	auth := authenticator.Require
+	// sends and schedules are retried on timeouts, so they accept an
+	// Idempotency-Key
+	once := idempotency.Wrap
+
+	mux.HandleFunc("GET /messages", auth(apikeys.ScopeHistoryRead, messageHandler.GetMessages))
+	mux.HandleFunc("GET /messages/templates", auth(apikeys.ScopeHistoryRead, templateHandler.GetScheduledMessages))
+	mux.HandleFunc("POST /messages/cancel", auth(apikeys.ScopeSchedule, messageHandler.CancelMessage))
+
+	mux.HandleFunc("GET /templates", auth(apikeys.ScopeTemplatesRead, templateHandler.GetTemplates))
+	mux.HandleFunc("POST /templates", auth(apikeys.ScopeTemplatesWrite, templateHandler.CreateTemplate))
+	mux.HandleFunc("GET /templates/services", auth(apikeys.ScopeTemplatesRead, templateHandler.ListMessagingServices))
+
+	mux.HandleFunc("GET /templates/groups", auth(apikeys.ScopeTemplatesRead, templateGroupHandler.ListGroups))
+	mux.HandleFunc("POST /templates/groups", auth(apikeys.ScopeTemplatesWrite, templateGroupHandler.CreateGroup))
+	mux.HandleFunc("GET /templates/groups/{name}", auth(apikeys.ScopeTemplatesRead, templateGroupHandler.GetGroup))
+	mux.HandleFunc("GET /templates/groups/{name}/resolve", auth(apikeys.ScopeTemplatesRead, templateGroupHandler.Resolve))
+	mux.HandleFunc("PUT /templates/groups/{name}/variants/{language}", auth(apikeys.ScopeTemplatesWrite, templateGroupHandler.PutVariant))
+	mux.HandleFunc("DELETE /templates/groups/{name}/variants/{language}", auth(apikeys.ScopeTemplatesWrite, templateGroupHandler.DeleteVariant))
+
+	mux.HandleFunc("POST /scheduled-messages", auth(apikeys.ScopeSchedule, once(scheduleHandler.CreateScheduledMessage)))
+	mux.HandleFunc("GET /scheduled-messages/{id}", auth(apikeys.ScopeSchedule, scheduleHandler.GetScheduledMessage))
+
+	mux.HandleFunc("GET /contacts", auth(apikeys.ScopeHistoryRead, contactHandler.ListContacts))
+	mux.HandleFunc("POST /contacts", auth(apikeys.ScopeAdmin, contactHandler.CreateContact))
+	mux.HandleFunc("GET /contacts/{id}", auth(apikeys.ScopeHistoryRead, contactHandler.GetContact))
+	mux.HandleFunc("PATCH /contacts/{id}", auth(apikeys.ScopeAdmin, contactHandler.UpdateContact))
+	mux.HandleFunc("DELETE /contacts/{id}", auth(apikeys.ScopeAdmin, contactHandler.DeleteContact))
+	mux.HandleFunc("GET /contacts/{id}/messages", auth(apikeys.ScopeHistoryRead, contactHandler.GetContactMessages))
+	mux.HandleFunc("GET /contacts/{id}/scheduled-messages", auth(apikeys.ScopeHistoryRead, contactHandler.GetContactScheduledMessages))
+	mux.HandleFunc("GET /contacts/{id}/consents", auth(apikeys.ScopeHistoryRead, consentHandler.GetConsents))
+	mux.HandleFunc("POST /contacts/{id}/consents", auth(apikeys.ScopeAdmin, consentHandler.RecordConsent))
+	mux.HandleFunc("GET /contacts/{id}/window", auth(apikeys.ScopeHistoryRead, windowHandler.GetWindow))
+
+	mux.HandleFunc("GET /conversations", auth(apikeys.ScopeHistoryRead, conversationHandler.ListConversations))
+	mux.HandleFunc("PATCH /conversations/{id}", auth(apikeys.ScopeAdmin, conversationHandler.UpdateConversation))
+	mux.HandleFunc("GET /conversations/{id}/messages", auth(apikeys.ScopeHistoryRead, conversationHandler.GetConversationMessages))
+	mux.HandleFunc("POST /conversations/{id}/messages", auth(apikeys.ScopeSend, once(conversationHandler.Reply)))
+	mux.HandleFunc("PUT /conversations/{id}/assignee", auth(apikeys.ScopeAdmin, conversationHandler.AssignConversation))
+	mux.HandleFunc("GET /conversations/{id}/notes", auth(apikeys.ScopeHistoryRead, conversationHandler.GetNotes))
+	mux.HandleFunc("POST /conversations/{id}/notes", auth(apikeys.ScopeAdmin, conversationHandler.AddNote))
+	mux.HandleFunc("GET /conversations/{id}/events", auth(apikeys.ScopeHistoryRead, conversationHandler.GetEvents))
+
+	mux.HandleFunc("GET /agents", auth(apikeys.ScopeAdmin, agentHandler.ListAgents))
+	mux.HandleFunc("POST /agents", auth(apikeys.ScopeAdmin, agentHandler.CreateAgent))
+	mux.HandleFunc("PATCH /agents/{id}", auth(apikeys.ScopeAdmin, agentHandler.UpdateAgent))
+
+	mux.HandleFunc("GET /auto-replies", auth(apikeys.ScopeAdmin, autoReplyHandler.ListRules))
+	mux.HandleFunc("POST /auto-replies", auth(apikeys.ScopeAdmin, autoReplyHandler.CreateRule))
+	mux.HandleFunc("GET /auto-replies/{id}", auth(apikeys.ScopeAdmin, autoReplyHandler.GetRule))
+	mux.HandleFunc("PUT /auto-replies/{id}", auth(apikeys.ScopeAdmin, autoReplyHandler.UpdateRule))
+	mux.HandleFunc("DELETE /auto-replies/{id}", auth(apikeys.ScopeAdmin, autoReplyHandler.DeleteRule))
+
+	mux.HandleFunc("GET /flows", auth(apikeys.ScopeAdmin, flowHandler.ListFlows))
+	mux.HandleFunc("POST /flows", auth(apikeys.ScopeAdmin, flowHandler.CreateFlow))
+	mux.HandleFunc("GET /flows/{id}", auth(apikeys.ScopeAdmin, flowHandler.GetFlow))
+	mux.HandleFunc("PUT /flows/{id}", auth(apikeys.ScopeAdmin, flowHandler.UpdateFlow))
+	mux.HandleFunc("DELETE /flows/{id}", auth(apikeys.ScopeAdmin, flowHandler.DeleteFlow))
+	mux.HandleFunc("POST /flows/{id}/simulate", auth(apikeys.ScopeAdmin, flowHandler.SimulateFlow))
+
+	mux.HandleFunc("GET /webhooks", auth(apikeys.ScopeAdmin, webhookHandler.ListWebhooks))
+	mux.HandleFunc("POST /webhooks", auth(apikeys.ScopeAdmin, webhookHandler.CreateWebhook))
+	mux.HandleFunc("GET /webhooks/{id}", auth(apikeys.ScopeAdmin, webhookHandler.GetWebhook))
+	mux.HandleFunc("PATCH /webhooks/{id}", auth(apikeys.ScopeAdmin, webhookHandler.UpdateWebhook))
+	mux.HandleFunc("DELETE /webhooks/{id}", auth(apikeys.ScopeAdmin, webhookHandler.DeleteWebhook))
+	mux.HandleFunc("GET /webhooks/{id}/deliveries", auth(apikeys.ScopeAdmin, webhookHandler.GetDeliveries))
+	mux.HandleFunc("GET /webhook-events", auth(apikeys.ScopeAdmin, webhookHandler.ListEvents))
+	mux.HandleFunc("POST /webhook-events/{id}/replay", auth(apikeys.ScopeAdmin, webhookHandler.ReplayEvent))
+
+	mux.HandleFunc("GET /campaigns", auth(apikeys.ScopeHistoryRead, campaignHandler.ListCampaigns))
+	mux.HandleFunc("POST /campaigns", auth(apikeys.ScopeSchedule, campaignHandler.CreateCampaign))
+	mux.HandleFunc("GET /campaigns/{id}", auth(apikeys.ScopeHistoryRead, campaignHandler.GetCampaign))
+	mux.HandleFunc("GET /campaigns/{id}/recipients", auth(apikeys.ScopeHistoryRead, campaignHandler.GetRecipients))
+	mux.HandleFunc("POST /campaigns/{id}/start", auth(apikeys.ScopeSchedule, campaignHandler.StartCampaign))
+	mux.HandleFunc("POST /campaigns/{id}/pause", auth(apikeys.ScopeSchedule, campaignHandler.PauseCampaign))
+	mux.HandleFunc("POST /campaigns/{id}/resume", auth(apikeys.ScopeSchedule, campaignHandler.ResumeCampaign))
+	mux.HandleFunc("POST /campaigns/{id}/cancel", auth(apikeys.ScopeSchedule, campaignHandler.CancelCampaign))
+
+	mux.HandleFunc("POST /imports", auth(apikeys.ScopeSchedule, importHandler.CreateImport))
+	mux.HandleFunc("GET /imports/{id}", auth(apikeys.ScopeHistoryRead, importHandler.GetImport))
+	mux.HandleFunc("GET /imports/{id}/errors", auth(apikeys.ScopeHistoryRead, importHandler.GetImportErrors))
+
+	mux.HandleFunc("GET /events/stream", auth(apikeys.ScopeHistoryRead, eventStreamHandler.Stream))
+
+	mux.HandleFunc("GET /suppressions", auth(apikeys.ScopeAdmin, suppressionHandler.ListSuppressions))
+	mux.HandleFunc("POST /suppressions", auth(apikeys.ScopeAdmin, suppressionHandler.CreateSuppression))
+	mux.HandleFunc("DELETE /suppressions/{phone}", auth(apikeys.ScopeAdmin, suppressionHandler.DeleteSuppression))
+
+	mux.HandleFunc("GET /api-keys", auth(apikeys.ScopeAdmin, apiKeyHandler.ListAPIKeys))
+	mux.HandleFunc("POST /api-keys", auth(apikeys.ScopeAdmin, apiKeyHandler.CreateAPIKey))
+	mux.HandleFunc("DELETE /api-keys/{id}", auth(apikeys.ScopeAdmin, apiKeyHandler.RevokeAPIKey))
+
+	mux.HandleFunc("GET /senders", auth(apikeys.ScopeAdmin, senderHandler.ListSenders))
+	mux.HandleFunc("POST /senders", auth(apikeys.ScopeAdmin, senderHandler.AddSender))
+	mux.HandleFunc("DELETE /senders/{number}", auth(apikeys.ScopeAdmin, senderHandler.RemoveSender))
+
+	mux.HandleFunc("GET /tenants", auth(apikeys.ScopeAdmin, tenantHandler.ListTenants))
+	mux.HandleFunc("POST /tenants", auth(apikeys.ScopeAdmin, tenantHandler.CreateTenant))
+	mux.HandleFunc("GET /tenants/{id}", auth(apikeys.ScopeAdmin, tenantHandler.GetTenant))
+	mux.HandleFunc("PATCH /tenants/{id}", auth(apikeys.ScopeAdmin, tenantHandler.UpdateTenant))
+	mux.HandleFunc("POST /tenants/{id}/api-keys", auth(apikeys.ScopeAdmin, tenantHandler.CreateTenantAPIKey))
 
-	mux.HandleFunc("GET /messages", messageHandler.GetMessages)
-	mux.HandleFunc("GET /messages/templates", templateHandler.GetScheduledMessages)
-	mux.HandleFunc("POST /messages/cancel", messageHandler.CancelMessage)
+	mux.HandleFunc("POST /send-message", auth(apikeys.ScopeSend, once(messageHandler.NormalMessage)))
+	mux.HandleFunc("POST /send-template", auth(apikeys.ScopeSend, once(templateHandler.Send)))
 
-	mux.HandleFunc("GET /templates", templateHandler.GetTemplates)
-	mux.HandleFunc("POST /templates", templateHandler.CreateTemplate)
-	mux.HandleFunc("GET /templates/services", templateHandler.ListMessagingServices)
+	mux.HandleFunc("POST /callbacks/twilio/inbound", countWebhook("inbound", inboundHandler.ReceiveMessage))
+	mux.HandleFunc("POST /callbacks/twilio/status", countWebhook("status", statusHandler.ReceiveStatus))
 
-	mux.HandleFunc("POST /send-message", messageHandler.NormalMessage)
-	mux.HandleFunc("POST /send-template", templateHandler.Send)
+	mux.HandleFunc("GET /openapi.json", serveOpenAPI)
+	mux.HandleFunc("GET /docs", serveDocs)
 
-	// mux.HandleFunc("POST /callbacks/twilio", messageHandler.GetMessages)
+	instrumented := TracingMiddleware(MetricsMiddleware(mux))
+	root := http.NewServeMux()
+	root.Handle(APIVersion+"/", http.StripPrefix(APIVersion, instrumented))
+	root.Handle("/", DeprecatedMiddleware(instrumented))
+	// the metrics are for the scraper, outside of the versioned API
+	root.Handle("GET /metrics", metrics.Handler())
 
-	return CORSMiddleware(mux)
+	return CORSMiddleware(handler.RequestId(root))
 }
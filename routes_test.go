@@ -0,0 +1,104 @@
+package mbx
+
+import (
+	"mbx/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupRouter_Versions(t *testing.T) {
+	router, token := newTestRouter(t)
+
+	tests := []struct {
+		path       string
+		deprecated bool
+	}{
+		{"/v1/senders", false},
+		{"/senders", true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected %s to be served, got %d", tt.path, rec.Code)
+		}
+		if deprecated := rec.Header().Get("Deprecation") == "true"; deprecated != tt.deprecated {
+			t.Errorf("Expected %s to be deprecated: %v", tt.path, tt.deprecated)
+		}
+		if tt.deprecated && rec.Header().Get("Link") != `</v1/senders>; rel="successor-version"` {
+			t.Errorf("Expected a link to the versioned path, got %q", rec.Header().Get("Link"))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/senders", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown versions to be 404, got %d", rec.Code)
+	}
+}
+
+func TestSetupRouter_Metrics(t *testing.T) {
+	router, token := newTestRouter(t)
+	requests := metrics.HTTPRequests.WithLabelValues("GET /senders", "200")
+	before := testutil.ToFloat64(requests)
+
+	for _, path := range []string{"/v1/senders", "/senders"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if got := testutil.ToFloat64(requests) - before; got != 2 {
+		t.Errorf("Expected both paths to count for the route, got %v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the metrics to be served without a key, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `mbx_http_requests_total{code="200",route="GET /senders"}`) {
+		t.Errorf("Expected the request count in the metrics, got %s", body)
+	}
+}
+
+func TestSetupRouter_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	router, token := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/senders", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var server sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "GET /senders" {
+			server = span
+		}
+	}
+	if server == nil {
+		t.Fatal("Expected a span named after the route")
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the request to continue the caller's trace, got %s", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Expected the caller's span to be the parent, got %s", got)
+	}
+}
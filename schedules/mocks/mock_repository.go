@@ -37,6 +37,21 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ClaimDue mocks base method.
+func (m *MockRepository) ClaimDue(ctx context.Context, now, staleBefore, afterSendAt time.Time, afterId uuid.UUID, limit int) ([]models.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDue", ctx, now, staleBefore, afterSendAt, afterId, limit)
+	ret0, _ := ret[0].([]models.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDue indicates an expected call of ClaimDue.
+func (mr *MockRepositoryMockRecorder) ClaimDue(ctx, now, staleBefore, afterSendAt, afterId, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDue", reflect.TypeOf((*MockRepository)(nil).ClaimDue), ctx, now, staleBefore, afterSendAt, afterId, limit)
+}
+
 // Create mocks base method.
 func (m *MockRepository) Create(arg0 context.Context, arg1 models.ScheduledMessage) error {
 	m.ctrl.T.Helper()
@@ -66,6 +81,35 @@ func (mr *MockRepositoryMockRecorder) FindById(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockRepository)(nil).FindById), arg0, arg1)
 }
 
+// Hold mocks base method.
+func (m *MockRepository) Hold(ctx context.Context, id uuid.UUID, until time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Hold", ctx, id, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Hold indicates an expected call of Hold.
+func (mr *MockRepositoryMockRecorder) Hold(ctx, id, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Hold", reflect.TypeOf((*MockRepository)(nil).Hold), ctx, id, until)
+}
+
+// ListByContact mocks base method.
+func (m *MockRepository) ListByContact(arg0 context.Context, arg1 uuid.UUID) ([]models.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByContact", arg0, arg1)
+	ret0, _ := ret[0].([]models.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByContact indicates an expected call of ListByContact.
+func (mr *MockRepositoryMockRecorder) ListByContact(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByContact", reflect.TypeOf((*MockRepository)(nil).ListByContact), arg0, arg1)
+}
+
 // ListUpcoming mocks base method.
 func (m *MockRepository) ListUpcoming(arg0 context.Context, arg1 time.Duration) ([]models.ScheduledMessage, error) {
 	m.ctrl.T.Helper()
@@ -80,3 +124,85 @@ func (mr *MockRepositoryMockRecorder) ListUpcoming(arg0, arg1 interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUpcoming", reflect.TypeOf((*MockRepository)(nil).ListUpcoming), arg0, arg1)
 }
+
+// NextSendAt mocks base method.
+func (m *MockRepository) NextSendAt(arg0 context.Context) (*time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NextSendAt", arg0)
+	ret0, _ := ret[0].(*time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NextSendAt indicates an expected call of NextSendAt.
+func (mr *MockRepositoryMockRecorder) NextSendAt(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextSendAt", reflect.TypeOf((*MockRepository)(nil).NextSendAt), arg0)
+}
+
+// QueueDepth mocks base method.
+func (m *MockRepository) QueueDepth(arg0 context.Context) (int, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueueDepth", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// QueueDepth indicates an expected call of QueueDepth.
+func (mr *MockRepositoryMockRecorder) QueueDepth(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueueDepth", reflect.TypeOf((*MockRepository)(nil).QueueDepth), arg0)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockRepository) UpdateStatus(arg0 context.Context, arg1 uuid.UUID, arg2 models.Status) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockRepositoryMockRecorder) UpdateStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockRepository)(nil).UpdateStatus), arg0, arg1, arg2)
+}
+
+// MockNotifier is a mock of Notifier interface.
+type MockNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotifierMockRecorder
+}
+
+// MockNotifierMockRecorder is the mock recorder for MockNotifier.
+type MockNotifierMockRecorder struct {
+	mock *MockNotifier
+}
+
+// NewMockNotifier creates a new mock instance.
+func NewMockNotifier(ctrl *gomock.Controller) *MockNotifier {
+	mock := &MockNotifier{ctrl: ctrl}
+	mock.recorder = &MockNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotifier) EXPECT() *MockNotifierMockRecorder {
+	return m.recorder
+}
+
+// Listen mocks base method.
+func (m *MockNotifier) Listen(ctx context.Context) <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Listen", ctx)
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Listen indicates an expected call of Listen.
+func (mr *MockNotifierMockRecorder) Listen(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Listen", reflect.TypeOf((*MockNotifier)(nil).Listen), ctx)
+}
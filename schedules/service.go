@@ -3,6 +3,7 @@ package schedules
 import (
 	"context"
 	"mbx/models"
+	"mbx/tracing"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +13,30 @@ type Repository interface {
 	FindById(context.Context, uuid.UUID) (*models.ScheduledMessage, error)
 	ListUpcoming(context.Context, time.Duration) ([]models.ScheduledMessage, error)
 	Create(context.Context, models.ScheduledMessage) error
+	ListByContact(context.Context, uuid.UUID) ([]models.ScheduledMessage, error)
+	UpdateStatus(context.Context, uuid.UUID, models.Status) error
+	// Hold puts a claimed message held back by the sender limits back to
+	// pending, not due again before until
+	Hold(ctx context.Context, id uuid.UUID, until time.Time) error
+	// ClaimDue marks pending messages whose send time is not after now as
+	// sending and returns them, earliest first, starting after the message
+	// sent at afterSendAt with afterId. Held messages are due once their hold
+	// ends, and messages claimed before staleBefore are claimed again. Messages
+	// claimed by someone else are skipped.
+	ClaimDue(ctx context.Context, now time.Time, staleBefore time.Time, afterSendAt time.Time, afterId uuid.UUID, limit int) ([]models.ScheduledMessage, error)
+	// NextSendAt returns when the earliest pending message is due, or nil
+	// when there is none
+	NextSendAt(context.Context) (*time.Time, error)
+	// QueueDepth counts the pending and failed messages of every tenant
+	QueueDepth(context.Context) (pending int, failed int, err error)
+}
+
+// Notifier reports when pending messages are created or rescheduled, so the
+// worker does not sleep past a send time it did not know about
+type Notifier interface {
+	// Listen returns a channel that receives a value after each change. It is
+	// closed when ctx is done.
+	Listen(ctx context.Context) <-chan struct{}
 }
 
 type Service struct {
@@ -27,9 +52,21 @@ func (s *Service) FindById(ctx context.Context, id uuid.UUID) (*models.Scheduled
 }
 
 func (s *Service) Create(ctx context.Context, message models.ScheduledMessage) error {
+	if message.TraceParent == "" {
+		message.TraceParent = tracing.TraceParent(ctx)
+	}
 	return s.repo.Create(ctx, message)
 }
 
 func (s *Service) ListUpcoming(ctx context.Context, duration time.Duration) ([]models.ScheduledMessage, error) {
 	return s.repo.ListUpcoming(ctx, duration)
 }
+
+func (s *Service) ListByContact(ctx context.Context, contactId uuid.UUID) ([]models.ScheduledMessage, error) {
+	return s.repo.ListByContact(ctx, contactId)
+}
+
+// QueueDepth returns how many messages wait to be sent, and how many failed
+func (s *Service) QueueDepth(ctx context.Context) (int, int, error) {
+	return s.repo.QueueDepth(ctx)
+}
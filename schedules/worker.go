@@ -2,77 +2,258 @@ package schedules
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"mbx/metrics"
 	"mbx/models"
+	"mbx/optout"
 	"mbx/sender"
 	"mbx/templates"
+	"mbx/tenants"
+	"mbx/throttle"
+	"mbx/tracing"
 	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Config struct {
+	// PoolingRate is the longest the worker sleeps between checks for due
+	// messages, a safety net for missed notifications
 	PoolingRate time.Duration
+	// DefaultLocale is used for template messages scheduled without a locale
+	DefaultLocale string
+	// ClaimTimeout is how long a message stays claimed by a worker before
+	// another one may claim it again, when the first stopped mid-send or
+	// could not record the outcome
+	ClaimTimeout time.Duration
 }
 
+// dueBatch is how many due messages are claimed at a time
+const dueBatch = 100
+
+const defaultClaimTimeout = 10 * time.Minute
+
 type Worker struct {
-	config Config
-	w      sender.Whatsapp
-	wt     sender.WhatsappTemplate
-	repo   Repository
+	config   Config
+	w        sender.Whatsapp
+	wt       sender.WhatsappTemplate
+	repo     Repository
+	resolver templates.GroupResolver
+	notifier Notifier
 }
 
-func NewWorker(config Config, w sender.Whatsapp, wt sender.WhatsappTemplate, repo Repository) *Worker {
+// NewWorker creates the scheduler. The notifier is optional; without it the
+// worker only wakes for the messages it knew about and every PoolingRate.
+func NewWorker(config Config, w sender.Whatsapp, wt sender.WhatsappTemplate, repo Repository, resolver templates.GroupResolver, notifier Notifier) *Worker {
+	if config.ClaimTimeout <= 0 {
+		config.ClaimTimeout = defaultClaimTimeout
+	}
 	return &Worker{
-		config: config,
-		w:      w,
-		wt:     wt,
-		repo:   repo,
+		config:   config,
+		w:        w,
+		wt:       wt,
+		repo:     repo,
+		resolver: resolver,
+		notifier: notifier,
 	}
 }
 
+// Run sends the due messages, then sleeps until the next send time, at most
+// PoolingRate, or until the notifier reports a change.
 func (w *Worker) Run(ctx context.Context) {
-	ticker := time.NewTicker(w.config.PoolingRate)
+	var changed <-chan struct{}
+	if w.notifier != nil {
+		changed = w.notifier.Listen(ctx)
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			upcoming, err := w.repo.ListUpcoming(ctx, 5*time.Minute)
-			if err != nil {
-				slog.Error("failed to list upcoming messages", slog.Any("error", err))
+		case <-timer.C:
+		case _, open := <-changed:
+			if !open {
+				// keep polling if the notifier gives up
+				changed = nil
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		started := time.Now()
+		w.SendDue(ctx)
+		timer.Reset(w.sleep(ctx, started))
+	}
+}
+
+// SendDue claims and sends every message that is due, each one for its own
+// tenant. Claiming keeps the other replicas from sending the same messages.
+func (w *Worker) SendDue(ctx context.Context) {
+	ctx, span := tracing.Tracer().Start(ctx, "schedules.tick")
+	defer span.End()
+
+	now := time.Now()
+	// when the tenants held back by the sender limits can send again
+	held := make(map[uuid.UUID]time.Time)
+	// the pages go on from the last message read, so messages that stay
+	// pending do not come back ahead of the others
+	var afterSendAt time.Time
+	var afterId uuid.UUID
+	for {
+		due, err := w.repo.ClaimDue(ctx, now, now.Add(-w.config.ClaimTimeout), afterSendAt, afterId, dueBatch)
+		if err != nil {
+			slog.Error("failed to claim due messages", slog.Any("error", err))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to claim due messages")
+			return
+		}
+		for _, msg := range due {
+			afterSendAt, afterId = msg.SendAt, msg.Id
+			scoped := tenants.NewContext(ctx, msg.TenantId)
+			if until, ok := held[msg.TenantId]; ok {
+				w.hold(scoped, msg, until)
 				continue
 			}
-			for _, msg := range upcoming {
-				w.Send(ctx, msg)
+			if err := w.Send(scoped, msg); err != nil {
+				// the rest of the tenant would be held back as well
+				held[msg.TenantId] = throttle.RetryAt(err, time.Now())
 			}
-
-		case <-ctx.Done():
+		}
+		if len(due) < dueBatch {
 			return
 		}
 	}
 }
 
-func (w *Worker) Send(ctx context.Context, msg models.ScheduledMessage) {
+// sleep returns how long to wait for the next pending message, after a pass
+// over the messages due at started
+func (w *Worker) sleep(ctx context.Context, started time.Time) time.Duration {
+	next, err := w.repo.NextSendAt(ctx)
+	if err != nil {
+		slog.Error("failed to find next scheduled message", slog.Any("error", err))
+		return w.config.PoolingRate
+	}
+	// a message that was due and is still pending is being claimed by another
+	// replica, and waking up right away would spin on it
+	if next == nil || !next.After(started) {
+		return w.config.PoolingRate
+	}
+	return min(max(time.Until(*next), 0), w.config.PoolingRate)
+}
+
+// Send delivers a claimed message and records the outcome on it. Messages
+// held back by the sender limits go back to pending, held until the limits
+// are expected to let them go, and their error is returned. A message whose
+// outcome could not be recorded stays claimed until ClaimTimeout passes.
+func (w *Worker) Send(ctx context.Context, msg models.ScheduledMessage) error {
+	if _, err := tenants.Require(ctx); err != nil {
+		return err
+	}
+	ctx, span := w.startSend(ctx, msg)
+	defer span.End()
+
+	status := models.StatusSent
+	dispatchedAt := time.Now()
+	err := w.deliver(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+	}
+	switch {
+	case errors.Is(err, throttle.ErrLimited):
+		slog.Info("scheduled message held back by the sender limits", slog.Any("reason", err), slog.String("id", msg.Id.String()))
+		metrics.ScheduledDispatched.WithLabelValues(string(msg.Type), "held").Inc()
+		w.hold(ctx, msg, throttle.RetryAt(err, dispatchedAt))
+		return err
+	case errors.Is(err, optout.ErrSuppressed):
+		slog.Info("skipping scheduled message to opted-out recipient", slog.String("id", msg.Id.String()))
+		status = models.StatusSuppressed
+	case errors.Is(err, sender.ErrRejected):
+		slog.Info("scheduled message rejected", slog.Any("reason", err), slog.String("id", msg.Id.String()))
+		status = models.StatusRejected
+	case err != nil:
+		slog.Error("failed to send scheduled message", slog.Any("error", err), slog.String("id", msg.Id.String()), slog.String("type", string(msg.Type)))
+		status = models.StatusFailed
+	}
+	span.SetAttributes(attribute.String("mbx.message.status", string(status)))
+	if status == models.StatusFailed {
+		span.SetStatus(codes.Error, "failed to send scheduled message")
+	}
+	// held messages are measured when they finally leave
+	metrics.SchedulerLag.Observe(dispatchedAt.Sub(msg.SendAt).Seconds())
+	metrics.ScheduledDispatched.WithLabelValues(string(msg.Type), string(status)).Inc()
+
+	if err := w.repo.UpdateStatus(ctx, msg.Id, status); err != nil {
+		slog.Error("failed to update scheduled message status", slog.Any("error", err), slog.String("id", msg.Id.String()))
+	}
+	return nil
+}
+
+// hold returns a message held back by the sender limits to the pending ones,
+// out of the due ones until the limits let it go, so the worker does not wake
+// up for it before
+func (w *Worker) hold(ctx context.Context, msg models.ScheduledMessage, until time.Time) {
+	if err := w.repo.Hold(ctx, msg.Id, until); err != nil {
+		slog.Error("failed to hold scheduled message", slog.Any("error", err), slog.String("id", msg.Id.String()))
+	}
+}
+
+// startSend starts the span of a send. It continues the trace of the request
+// that scheduled the message, and is linked to the tick that picked it up.
+func (w *Worker) startSend(ctx context.Context, msg models.ScheduledMessage) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithAttributes(
+			attribute.String("mbx.message.id", msg.Id.String()),
+			attribute.String("mbx.message.type", string(msg.Type)),
+			attribute.String("mbx.tenant.id", msg.TenantId.String()),
+		),
+	}
+	if sc := tracing.SpanContext(msg.TraceParent); sc.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.LinkFromContext(ctx)))
+		ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return tracing.Tracer().Start(ctx, "schedules.send", opts...)
+}
+
+func (w *Worker) deliver(ctx context.Context, msg models.ScheduledMessage) error {
 	switch msg.Type {
 	case models.ScheduleTypeTemplate:
+		templateId, language := msg.ProviderId, msg.Locale
+		if language == "" {
+			language = w.config.DefaultLocale
+		}
+
+		if msg.TemplateName != "" {
+			resolved, err := w.resolver.Resolve(ctx, msg.TemplateName, language)
+			if err != nil {
+				return fmt.Errorf("failed to resolve template group %q for locale %q: %w", msg.TemplateName, language, err)
+			}
+			templateId, language = resolved.ContentSid, resolved.Language
+		}
+
 		_, err := w.wt.SendTemplate(ctx,
 			templates.WhatsappTemplate{
 				To:         msg.To,
-				TemplateId: msg.ProviderId,
+				TemplateId: templateId,
 				Content:    msg.Content,
-				Language:   "pt_BR",
+				Language:   language,
+				From:       msg.From,
 			})
-		if err != nil {
-			slog.Error("failed to send template message", slog.Any("error", err))
-		}
+		return err
 
 	case models.ScheduleTypeFreeform:
 		_, err := w.w.Send(ctx, models.WhatsappBody{
-			To:   msg.To,
+			To:   fmt.Sprintf("whatsapp:%s", msg.To),
 			Body: msg.Content,
+			From: msg.From,
 		})
-
-		if err != nil {
-			slog.Error("failed to send freeform message", slog.Any("error", err))
-		}
+		return err
 	}
 
+	return fmt.Errorf("unknown scheduled message type %q", msg.Type)
 }
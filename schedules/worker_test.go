@@ -0,0 +1,269 @@
+package schedules_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mbx/metrics"
+	"mbx/models"
+	"mbx/schedules"
+	"mbx/schedules/mocks"
+	"mbx/templates"
+	"mbx/tenants"
+	"mbx/throttle"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type stubSender struct {
+	sent chan models.WhatsappBody
+	err  error
+}
+
+func (s *stubSender) Send(_ context.Context, msg models.WhatsappBody) (*api.ApiV2010Message, error) {
+	s.sent <- msg
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CancelMessage(context.Context, string) error { return nil }
+
+func (s *stubSender) SendTemplate(context.Context, templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CreateTemplate(context.Context, templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return nil, nil
+}
+
+// limitedSender holds back the messages of a tenant
+type limitedSender struct {
+	stubSender
+	tenant uuid.UUID
+}
+
+func (s *limitedSender) Send(ctx context.Context, msg models.WhatsappBody) (*api.ApiV2010Message, error) {
+	s.sent <- msg
+	if tenants.FromContext(ctx) == s.tenant {
+		return nil, throttle.ErrThrottled
+	}
+	return &api.ApiV2010Message{}, nil
+}
+
+type stubNotifier chan struct{}
+
+func (n stubNotifier) Listen(context.Context) <-chan struct{} { return n }
+
+func freeform(sendAt time.Time) models.ScheduledMessage {
+	return models.ScheduledMessage{
+		Id:      uuid.New(),
+		To:      "+5511999990000",
+		SendAt:  sendAt,
+		Content: "Your order shipped",
+		Type:    models.ScheduleTypeFreeform,
+		Status:  models.StatusPending,
+	}
+}
+
+func expectSent(t *testing.T, s *stubSender) models.WhatsappBody {
+	t.Helper()
+	select {
+	case msg := <-s.sent:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the message to be sent")
+		return models.WhatsappBody{}
+	}
+}
+
+func TestWorker_WakesOnNotification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	msg := freeform(time.Now())
+
+	gomock.InOrder(
+		repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil),
+		repo.EXPECT().NextSendAt(gomock.Any()).Return(nil, nil),
+		repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.ScheduledMessage{msg}, nil),
+		repo.EXPECT().UpdateStatus(gomock.Any(), msg.Id, models.StatusSent).Return(nil),
+	)
+	repo.EXPECT().NextSendAt(gomock.Any()).Return(nil, nil).AnyTimes()
+
+	s := &stubSender{sent: make(chan models.WhatsappBody, 1)}
+	notifier := make(stubNotifier, 1)
+	worker := schedules.NewWorker(schedules.Config{PoolingRate: time.Hour}, s, s, repo, nil, notifier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Run(ctx)
+
+	// the worker sleeps for the whole pooling rate unless notified
+	time.Sleep(50 * time.Millisecond)
+	notifier <- struct{}{}
+
+	sent := expectSent(t, s)
+	if sent.To != "whatsapp:"+msg.To {
+		t.Errorf("Expected message to %s, got %s", msg.To, sent.To)
+	}
+}
+
+func TestWorker_SleepsUntilNextSendAt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	next := time.Now().Add(100 * time.Millisecond)
+	msg := freeform(next)
+
+	gomock.InOrder(
+		repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil),
+		repo.EXPECT().NextSendAt(gomock.Any()).Return(&next, nil),
+		repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, now, _, _ time.Time, _ uuid.UUID, _ int) ([]models.ScheduledMessage, error) {
+				if now.Before(next) {
+					t.Errorf("Woke up %s before the send time", next.Sub(now))
+				}
+				return []models.ScheduledMessage{msg}, nil
+			}),
+		repo.EXPECT().UpdateStatus(gomock.Any(), msg.Id, models.StatusSent).Return(nil),
+	)
+	repo.EXPECT().NextSendAt(gomock.Any()).Return(nil, nil).AnyTimes()
+
+	s := &stubSender{sent: make(chan models.WhatsappBody, 1)}
+	worker := schedules.NewWorker(schedules.Config{PoolingRate: time.Hour}, s, s, repo, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Run(ctx)
+
+	expectSent(t, s)
+}
+
+func TestWorker_StuckMessageWaitsForPoolingRate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	msg := freeform(time.Now().Add(-time.Minute))
+
+	// the status update fails, so the message stays claimed, and is only
+	// claimed again once the claim is stale
+	repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, now, staleBefore, _ time.Time, _ uuid.UUID, _ int) ([]models.ScheduledMessage, error) {
+			if want := now.Add(-10 * time.Minute); !staleBefore.Equal(want) {
+				t.Errorf("Expected claims to go stale after the default timeout, got %v", now.Sub(staleBefore))
+			}
+			return []models.ScheduledMessage{msg}, nil
+		}).Times(1)
+	repo.EXPECT().UpdateStatus(gomock.Any(), msg.Id, models.StatusSent).Return(context.DeadlineExceeded).Times(1)
+	repo.EXPECT().NextSendAt(gomock.Any()).Return(&msg.SendAt, nil).Times(1)
+
+	s := &stubSender{sent: make(chan models.WhatsappBody, 2)}
+	worker := schedules.NewWorker(schedules.Config{PoolingRate: time.Hour}, s, s, repo, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go worker.Run(ctx)
+	expectSent(t, s)
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	if len(s.sent) != 0 {
+		t.Errorf("Expected the message to be sent once, got %d more", len(s.sent))
+	}
+}
+
+func TestWorker_HeldBackMessagesStayPending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	due := []models.ScheduledMessage{freeform(time.Now()), freeform(time.Now())}
+
+	// no status update, and the second message is not tried; both are held
+	// until the sender has room again
+	repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(due, nil)
+	started := time.Now()
+	for _, msg := range due {
+		repo.EXPECT().Hold(gomock.Any(), msg.Id, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ uuid.UUID, until time.Time) error {
+				if until.Before(started) {
+					t.Errorf("Expected the message to be held until later, got %v", until)
+				}
+				return nil
+			})
+	}
+
+	s := &stubSender{sent: make(chan models.WhatsappBody, 2), err: throttle.ErrThrottled}
+	worker := schedules.NewWorker(schedules.Config{PoolingRate: time.Hour}, s, s, repo, nil, nil)
+	worker.SendDue(context.Background())
+
+	if len(s.sent) != 1 {
+		t.Errorf("Expected one send attempt, got %d", len(s.sent))
+	}
+}
+
+func TestWorker_PagesPastHeldTenant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+
+	// a full page of a tenant that is held back, then another tenant's message
+	held := uuid.New()
+	var page []models.ScheduledMessage
+	for i := range 100 {
+		msg := freeform(time.Now().Add(-time.Hour + time.Duration(i)*time.Second))
+		msg.TenantId = held
+		page = append(page, msg)
+	}
+	other := freeform(time.Now())
+	other.TenantId = uuid.New()
+	last := page[len(page)-1]
+
+	gomock.InOrder(
+		repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), time.Time{}, uuid.Nil, 100).Return(page, nil),
+		repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), last.SendAt, last.Id, 100).Return([]models.ScheduledMessage{other}, nil),
+	)
+	repo.EXPECT().Hold(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(100)
+	repo.EXPECT().UpdateStatus(gomock.Any(), other.Id, models.StatusSent).Return(nil)
+
+	s := &limitedSender{stubSender: stubSender{sent: make(chan models.WhatsappBody, 2)}, tenant: held}
+	worker := schedules.NewWorker(schedules.Config{PoolingRate: time.Hour}, s, s, repo, nil, nil)
+	worker.SendDue(context.Background())
+
+	// only the first message of the held tenant is tried
+	if len(s.sent) != 2 {
+		t.Errorf("Expected the other tenant's message to be sent, got %d send attempts", len(s.sent))
+	}
+}
+
+func TestWorker_MeasuresDispatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().UpdateStatus(gomock.Any(), gomock.Any(), models.StatusSent).Return(nil)
+
+	sent := metrics.ScheduledDispatched.WithLabelValues("freeform", "sent")
+	before, lagBefore := testutil.ToFloat64(sent), lag(t)
+
+	s := &stubSender{sent: make(chan models.WhatsappBody, 1)}
+	worker := schedules.NewWorker(schedules.Config{PoolingRate: time.Hour}, s, s, repo, nil, nil)
+	if err := worker.Send(context.Background(), freeform(time.Now().Add(-time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testutil.ToFloat64(sent) - before; got != 1 {
+		t.Errorf("Expected the sent message to be counted once, got %v", got)
+	}
+	lagAfter := lag(t)
+	if lagAfter.GetSampleCount() != lagBefore.GetSampleCount()+1 || lagAfter.GetSampleSum()-lagBefore.GetSampleSum() < 60 {
+		t.Errorf("Expected a lag of a minute to be observed, got %v", lagAfter.GetSampleSum()-lagBefore.GetSampleSum())
+	}
+}
+
+func lag(t *testing.T) *dto.Histogram {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.SchedulerLag.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram()
+}
@@ -4,4 +4,6 @@ type Config struct {
 	TwilioAccountSID string
 	TwilioAuthToken  string
 	TwilioFromNumber string
+	// StatusCallbackURL receives the delivery status updates of sent messages
+	StatusCallbackURL string
 }
@@ -2,6 +2,7 @@ package sender
 
 import (
 	"context"
+	"errors"
 	"mbx/models"
 	"mbx/templates"
 
@@ -17,3 +18,14 @@ type WhatsappTemplate interface {
 	SendTemplate(context.Context, templates.WhatsappTemplate) (*api.ApiV2010Message, error)
 	CreateTemplate(context.Context, templates.CreateTemplateDTO) (*templates.SavedTemplate, error)
 }
+
+// WhatsappSender sends both freeform and template messages. The send guards
+// implement it so they can wrap one another.
+type WhatsappSender interface {
+	Whatsapp
+	WhatsappTemplate
+}
+
+// ErrRejected is wrapped by the errors of send guards that refuse to deliver
+// a message because of a policy, such as an opt-out or missing consent.
+var ErrRejected = errors.New("message rejected")
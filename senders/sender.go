@@ -0,0 +1,55 @@
+package senders
+
+import (
+	"context"
+	"mbx/models"
+	"mbx/sender"
+	"mbx/templates"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// SelectingSender resolves the sender of every message before passing it on,
+// so the senders below it always get a number or a Messaging Service.
+type SelectingSender struct {
+	w       sender.Whatsapp
+	wt      sender.WhatsappTemplate
+	service *Service
+}
+
+var _ sender.Whatsapp = (*SelectingSender)(nil)
+var _ sender.WhatsappTemplate = (*SelectingSender)(nil)
+
+func NewSelectingSender(w sender.Whatsapp, wt sender.WhatsappTemplate, service *Service) *SelectingSender {
+	return &SelectingSender{
+		w:       w,
+		wt:      wt,
+		service: service,
+	}
+}
+
+func (s *SelectingSender) Send(ctx context.Context, message models.WhatsappBody) (*api.ApiV2010Message, error) {
+	from, err := s.service.Select(ctx, message.To, message.From)
+	if err != nil {
+		return nil, err
+	}
+	message.From = from
+	return s.w.Send(ctx, message)
+}
+
+func (s *SelectingSender) SendTemplate(ctx context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	from, err := s.service.Select(ctx, template.To, template.From)
+	if err != nil {
+		return nil, err
+	}
+	template.From = from
+	return s.wt.SendTemplate(ctx, template)
+}
+
+func (s *SelectingSender) CreateTemplate(ctx context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return s.wt.CreateTemplate(ctx, dto)
+}
+
+func (s *SelectingSender) CancelMessage(ctx context.Context, twilioId string) error {
+	return s.w.CancelMessage(ctx, twilioId)
+}
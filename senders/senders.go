@@ -0,0 +1,76 @@
+package senders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mbx/models"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("sender number not found")
+	// ErrInvalidSender is returned for a malformed sender selection or number
+	ErrInvalidSender = errors.New("invalid sender")
+	// ErrUnknownSender is returned when a message asks for a number that is
+	// not in the pool of its tenant, or a Messaging Service of another account
+	ErrUnknownSender = errors.New("sender is not one of the tenant's")
+	ErrDuplicate     = errors.New("sender number already in the pool")
+	// ErrTaken is returned when another tenant already sends from the number
+	ErrTaken = errors.New("sender number belongs to another tenant")
+)
+
+// Number is a WhatsApp sender of the tenant's pool, on top of the tenant's own
+// number
+type Number struct {
+	Number    string    `json:"number"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Repository interface {
+	Create(context.Context, Number) error
+	Delete(ctx context.Context, number string) error
+	List(context.Context) ([]Number, error)
+	// LastSender returns which of numbers the latest message exchanged with
+	// phone went through, or "" when none did
+	LastSender(ctx context.Context, phone string, numbers []string) (string, error)
+	// Load counts the outbound messages each of numbers sent since
+	Load(ctx context.Context, numbers []string, since time.Time) (map[string]int, error)
+}
+
+// Validate checks the format of a sender selection, without looking at the
+// pool, which may change before a scheduled message goes out. Service.Check
+// also makes sure a Messaging Service belongs to the tenant.
+func Validate(from models.From) error {
+	set := 0
+	for _, value := range []string{from.Number, from.MessagingServiceSid, string(from.Strategy)} {
+		if value != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("%w: from, messaging_service_sid and sender_strategy are exclusive", ErrInvalidSender)
+	}
+
+	if from.Number != "" && !validNumber(normalize(from.Number)) {
+		return fmt.Errorf("%w: from must be in E.164 format", ErrInvalidSender)
+	}
+	if from.MessagingServiceSid != "" && !strings.HasPrefix(from.MessagingServiceSid, "MG") {
+		return fmt.Errorf("%w: messaging_service_sid must be a Twilio Messaging Service SID", ErrInvalidSender)
+	}
+	switch from.Strategy {
+	case "", models.StrategySticky, models.StrategyRoundRobin, models.StrategyLeastLoaded:
+	default:
+		return fmt.Errorf("%w: unknown sender_strategy %q", ErrInvalidSender, from.Strategy)
+	}
+	return nil
+}
+
+func normalize(number string) string {
+	return strings.TrimPrefix(strings.TrimSpace(number), "whatsapp:")
+}
+
+func validNumber(number string) bool {
+	return len(number) > 1 && strings.HasPrefix(number, "+") && strings.Trim(number[1:], "0123456789") == ""
+}
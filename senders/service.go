@@ -0,0 +1,220 @@
+package senders
+
+import (
+	"context"
+	"fmt"
+	"mbx/models"
+	"mbx/tenants"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Numbers resolves the number and the Messaging Services of the account of
+// the tenant a context is scoped to
+type Numbers interface {
+	FromNumber(ctx context.Context) (string, error)
+	ListMessagingServices(ctx context.Context) ([]models.MessagingService, error)
+}
+
+type Config struct {
+	// LoadWindow is how far back the least loaded strategy counts messages,
+	// an hour by default
+	LoadWindow time.Duration
+	// ServicesTTL is how long the Messaging Services of a tenant are trusted
+	// before they are listed again, five minutes by default
+	ServicesTTL time.Duration
+}
+
+type Service struct {
+	repo    Repository
+	numbers Numbers
+	config  Config
+
+	mu    sync.Mutex
+	turns map[uuid.UUID]int
+	// services are the Messaging Service SIDs each tenant's account was last
+	// seen with
+	services map[uuid.UUID]knownServices
+}
+
+type knownServices struct {
+	sids     []string
+	listedAt time.Time
+}
+
+func NewService(repo Repository, numbers Numbers, config Config) *Service {
+	if config.LoadWindow <= 0 {
+		config.LoadWindow = time.Hour
+	}
+	if config.ServicesTTL <= 0 {
+		config.ServicesTTL = 5 * time.Minute
+	}
+	return &Service{
+		repo:     repo,
+		numbers:  numbers,
+		config:   config,
+		turns:    make(map[uuid.UUID]int),
+		services: make(map[uuid.UUID]knownServices),
+	}
+}
+
+// Add puts number in the pool of the tenant. The number must already be a
+// WhatsApp sender of the tenant's Twilio account.
+func (s *Service) Add(ctx context.Context, number string) (*Number, error) {
+	number = normalize(number)
+	if !validNumber(number) {
+		return nil, fmt.Errorf("%w: number must be in E.164 format", ErrInvalidSender)
+	}
+
+	created := Number{Number: number, CreatedAt: time.Now()}
+	if err := s.repo.Create(ctx, created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *Service) Remove(ctx context.Context, number string) error {
+	return s.repo.Delete(ctx, normalize(number))
+}
+
+func (s *Service) List(ctx context.Context) ([]Number, error) {
+	return s.repo.List(ctx)
+}
+
+// Pool returns the numbers the tenant can send from, its own number first
+func (s *Service) Pool(ctx context.Context) ([]string, error) {
+	own, err := s.numbers.FromNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pool []string
+	if own = normalize(own); own != "" {
+		pool = append(pool, own)
+	}
+	for _, n := range numbers {
+		if !slices.Contains(pool, n.Number) {
+			pool = append(pool, n.Number)
+		}
+	}
+	return pool, nil
+}
+
+// Check validates a sender selection, and makes sure a Messaging Service it
+// names belongs to the account of the tenant. Numbers are checked against the
+// pool by Select, as the pool may change before a scheduled message goes out.
+func (s *Service) Check(ctx context.Context, from models.From) error {
+	if err := Validate(from); err != nil {
+		return err
+	}
+	if from.MessagingServiceSid == "" {
+		return nil
+	}
+
+	id := tenants.FromContext(ctx)
+	s.mu.Lock()
+	known := s.services[id]
+	s.mu.Unlock()
+	if slices.Contains(known.sids, from.MessagingServiceSid) && time.Since(known.listedAt) < s.config.ServicesTTL {
+		return nil
+	}
+
+	// a service the tenant created since it was last listed is found again
+	services, err := s.numbers.ListMessagingServices(ctx)
+	if err != nil {
+		return err
+	}
+	known = knownServices{listedAt: time.Now()}
+	for _, service := range services {
+		known.sids = append(known.sids, service.Sid)
+	}
+	s.mu.Lock()
+	s.services[id] = known
+	s.mu.Unlock()
+
+	if !slices.Contains(known.sids, from.MessagingServiceSid) {
+		return fmt.Errorf("%w: %s", ErrUnknownSender, from.MessagingServiceSid)
+	}
+	return nil
+}
+
+// Select resolves from to the number or Messaging Service a message to to
+// goes out from
+func (s *Service) Select(ctx context.Context, to string, from models.From) (models.From, error) {
+	if err := s.Check(ctx, from); err != nil {
+		return models.From{}, err
+	}
+	if from.MessagingServiceSid != "" {
+		return from, nil
+	}
+
+	pool, err := s.Pool(ctx)
+	if err != nil {
+		return models.From{}, err
+	}
+	if from.Number != "" {
+		number := normalize(from.Number)
+		if !slices.Contains(pool, number) {
+			return models.From{}, fmt.Errorf("%w: %s", ErrUnknownSender, number)
+		}
+		return models.From{Number: number}, nil
+	}
+
+	var number string
+	switch {
+	case len(pool) == 0:
+		// nothing to choose from, the provider sends from its default
+		return models.From{}, nil
+	case len(pool) == 1:
+		number = pool[0]
+	case from.Strategy == models.StrategyRoundRobin:
+		number = s.next(ctx, pool)
+	case from.Strategy == models.StrategyLeastLoaded:
+		number, err = s.leastLoaded(ctx, pool)
+	default:
+		number, err = s.repo.LastSender(ctx, normalize(to), pool)
+		if err == nil && number == "" {
+			// a new conversation goes to the number with the most room
+			number, err = s.leastLoaded(ctx, pool)
+		}
+	}
+	if err != nil {
+		return models.From{}, err
+	}
+	return models.From{Number: number}, nil
+}
+
+// next takes the next turn of the tenant's round-robin
+func (s *Service) next(ctx context.Context, pool []string) string {
+	id := tenants.FromContext(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turn := s.turns[id]
+	s.turns[id] = turn + 1
+	return pool[turn%len(pool)]
+}
+
+// leastLoaded returns the number of the pool that sent the fewest messages in
+// the load window, the earliest in the pool on ties
+func (s *Service) leastLoaded(ctx context.Context, pool []string) (string, error) {
+	load, err := s.repo.Load(ctx, pool, time.Now().Add(-s.config.LoadWindow))
+	if err != nil {
+		return "", err
+	}
+
+	best := pool[0]
+	for _, number := range pool[1:] {
+		if load[number] < load[best] {
+			best = number
+		}
+	}
+	return best, nil
+}
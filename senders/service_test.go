@@ -0,0 +1,102 @@
+package senders_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mbx/models"
+	"mbx/senders"
+	"mbx/senders/mocks"
+
+	"github.com/golang/mock/gomock"
+)
+
+// number is the tenant's own number, on an account with the MG123 Messaging
+// Service
+type number string
+
+func (n number) FromNumber(context.Context) (string, error) { return string(n), nil }
+
+func (n number) ListMessagingServices(context.Context) ([]models.MessagingService, error) {
+	return []models.MessagingService{{Sid: "MG123", FriendlyName: "Marketing"}}, nil
+}
+
+var pool = []senders.Number{{Number: "+15550002"}, {Number: "+15550003"}}
+
+func TestService_SelectStrategies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := senders.NewService(repo, number("whatsapp:+15550001"), senders.Config{})
+	ctx := context.Background()
+	repo.EXPECT().List(gomock.Any()).Return(pool, nil).AnyTimes()
+	all := []string{"+15550001", "+15550002", "+15550003"}
+
+	var turns []string
+	for range 4 {
+		from, err := service.Select(ctx, "+5511999990000", models.From{Strategy: models.StrategyRoundRobin})
+		if err != nil {
+			t.Fatal(err)
+		}
+		turns = append(turns, from.Number)
+	}
+	if turns[0] != all[0] || turns[1] != all[1] || turns[2] != all[2] || turns[3] != all[0] {
+		t.Errorf("Expected round-robin through the pool, got %v", turns)
+	}
+
+	repo.EXPECT().Load(gomock.Any(), all, gomock.Any()).Return(map[string]int{"+15550001": 9, "+15550002": 4}, nil)
+	from, err := service.Select(ctx, "+5511999990000", models.From{Strategy: models.StrategyLeastLoaded})
+	if err != nil || from.Number != "+15550003" {
+		t.Errorf("Expected the idle number, got %+v, %v", from, err)
+	}
+
+	repo.EXPECT().LastSender(gomock.Any(), "+5511999990000", all).Return("+15550002", nil)
+	from, err = service.Select(ctx, "whatsapp:+5511999990000", models.From{})
+	if err != nil || from.Number != "+15550002" {
+		t.Errorf("Expected the conversation to stay on its number, got %+v, %v", from, err)
+	}
+
+	repo.EXPECT().LastSender(gomock.Any(), "+5511999990001", all).Return("", nil)
+	repo.EXPECT().Load(gomock.Any(), all, gomock.Any()).Return(map[string]int{"+15550001": 2, "+15550003": 1}, nil)
+	from, err = service.Select(ctx, "+5511999990001", models.From{Strategy: models.StrategySticky})
+	if err != nil || from.Number != "+15550002" {
+		t.Errorf("Expected a new conversation on the least loaded number, got %+v, %v", from, err)
+	}
+}
+
+func TestService_SelectExplicit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := senders.NewService(repo, number("whatsapp:+15550001"), senders.Config{})
+	ctx := context.Background()
+
+	from, err := service.Select(ctx, "+5511999990000", models.From{MessagingServiceSid: "MG123"})
+	if err != nil || from.MessagingServiceSid != "MG123" {
+		t.Errorf("Expected the Messaging Service, got %+v, %v", from, err)
+	}
+	// a Messaging Service of another account is not the tenant's to send from
+	if _, err := service.Select(ctx, "+5511999990000", models.From{MessagingServiceSid: "MG999"}); !errors.Is(err, senders.ErrUnknownSender) {
+		t.Errorf("Expected ErrUnknownSender, got %v", err)
+	}
+
+	repo.EXPECT().List(gomock.Any()).Return(pool, nil).Times(2)
+	from, err = service.Select(ctx, "+5511999990000", models.From{Number: "whatsapp:+15550003"})
+	if err != nil || from.Number != "+15550003" {
+		t.Errorf("Expected the pool number, got %+v, %v", from, err)
+	}
+	if _, err := service.Select(ctx, "+5511999990000", models.From{Number: "+15559999"}); !errors.Is(err, senders.ErrUnknownSender) {
+		t.Errorf("Expected ErrUnknownSender, got %v", err)
+	}
+
+	invalid := []models.From{
+		{Number: "15550002"},
+		{MessagingServiceSid: "PN123"},
+		{Strategy: "random"},
+		{Number: "+15550002", Strategy: models.StrategyRoundRobin},
+	}
+	for _, from := range invalid {
+		if _, err := service.Select(ctx, "+5511999990000", from); !errors.Is(err, senders.ErrInvalidSender) {
+			t.Errorf("Expected ErrInvalidSender for %+v, got %v", from, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package templates
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrGroupNotFound = errors.New("template group not found")
+	ErrNoVariant     = errors.New("no template variant for locale")
+)
+
+// Group is a logical template name that maps each language to the Twilio
+// content SID approved for it, so callers never have to know the SIDs.
+type Group struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Variants    []Variant `json:"variants"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Variant is the content SID of a group in a single language
+type Variant struct {
+	Language   string `json:"language"`
+	ContentSid string `json:"content_sid"`
+}
+
+// ResolvedTemplate is the variant picked for a recipient locale
+type ResolvedTemplate struct {
+	Name       string `json:"name"`
+	Language   string `json:"language"`
+	ContentSid string `json:"content_sid"`
+}
+
+// NormalizeLocale turns "pt-br", "PT_br" or "pt" into "pt_BR" / "pt"
+func NormalizeLocale(locale string) string {
+	locale = strings.TrimSpace(strings.ReplaceAll(locale, "-", "_"))
+	if locale == "" {
+		return ""
+	}
+
+	lang, region, found := strings.Cut(locale, "_")
+	if !found {
+		return strings.ToLower(lang)
+	}
+	return strings.ToLower(lang) + "_" + strings.ToUpper(region)
+}
+
+// FallbackChain returns the locales to try, in order, for a recipient locale.
+// The exact locale comes first, then the preferred region for its language
+// (e.g. pt -> pt_BR), then the bare language, then the default languages.
+func FallbackChain(locale string, preferredRegions map[string]string, defaults ...string) []string {
+	var chain []string
+	add := func(l string) {
+		l = NormalizeLocale(l)
+		if l == "" {
+			return
+		}
+		for _, existing := range chain {
+			if existing == l {
+				return
+			}
+		}
+		chain = append(chain, l)
+	}
+
+	locale = NormalizeLocale(locale)
+	add(locale)
+
+	base, _, _ := strings.Cut(locale, "_")
+	if preferred, ok := preferredRegions[base]; ok {
+		add(preferred)
+	}
+	add(base)
+
+	for _, d := range defaults {
+		add(d)
+	}
+
+	return chain
+}
+
+// Resolve picks the variant of the group that best matches the locale. Each
+// locale of the chain is tried in order; for a bare language any regional
+// variant of it is accepted, picking the first one alphabetically.
+func (g *Group) Resolve(chain []string) (*ResolvedTemplate, error) {
+	byLanguage := make(map[string]Variant, len(g.Variants))
+	for _, v := range g.Variants {
+		byLanguage[NormalizeLocale(v.Language)] = v
+	}
+
+	for _, locale := range chain {
+		if v, ok := byLanguage[locale]; ok {
+			return &ResolvedTemplate{Name: g.Name, Language: v.Language, ContentSid: v.ContentSid}, nil
+		}
+
+		if strings.Contains(locale, "_") {
+			continue
+		}
+
+		var regional []string
+		for lang := range byLanguage {
+			if strings.HasPrefix(lang, locale+"_") {
+				regional = append(regional, lang)
+			}
+		}
+		if len(regional) > 0 {
+			sort.Strings(regional)
+			v := byLanguage[regional[0]]
+			return &ResolvedTemplate{Name: g.Name, Language: v.Language, ContentSid: v.ContentSid}, nil
+		}
+	}
+
+	return nil, ErrNoVariant
+}
@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	cases := map[string]string{
+		"pt-br":   "pt_BR",
+		"PT_br":   "pt_BR",
+		"en":      "en",
+		" es-MX ": "es_MX",
+		"":        "",
+	}
+	for in, want := range cases {
+		if got := NormalizeLocale(in); got != want {
+			t.Errorf("NormalizeLocale(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	chain := FallbackChain("pt-PT", map[string]string{"pt": "pt_BR"}, "en")
+	want := []string{"pt_PT", "pt_BR", "pt", "en"}
+	if !slices.Equal(chain, want) {
+		t.Errorf("Expected chain %v, got %v", want, chain)
+	}
+
+	chain = FallbackChain("", nil, "en")
+	if !slices.Equal(chain, []string{"en"}) {
+		t.Errorf("Expected chain [en], got %v", chain)
+	}
+}
+
+func TestGroupResolve(t *testing.T) {
+	group := Group{
+		Name: "welcome",
+		Variants: []Variant{
+			{Language: "pt_BR", ContentSid: "HX-pt-br"},
+			{Language: "es_AR", ContentSid: "HX-es-ar"},
+			{Language: "en", ContentSid: "HX-en"},
+		},
+	}
+	regions := map[string]string{"pt": "pt_BR"}
+
+	cases := []struct {
+		locale string
+		want   string
+	}{
+		{"pt_BR", "HX-pt-br"},
+		{"pt_PT", "HX-pt-br"},
+		{"es_MX", "HX-es-ar"},
+		{"fr_FR", "HX-en"},
+		{"", "HX-en"},
+	}
+	for _, c := range cases {
+		resolved, err := group.Resolve(FallbackChain(c.locale, regions, "en"))
+		if err != nil {
+			t.Fatalf("Resolve(%q) returned error: %v", c.locale, err)
+		}
+		if resolved.ContentSid != c.want {
+			t.Errorf("Resolve(%q) = %s, want %s", c.locale, resolved.ContentSid, c.want)
+		}
+	}
+
+	_, err := group.Resolve(FallbackChain("fr", nil))
+	if !errors.Is(err, ErrNoVariant) {
+		t.Errorf("Expected ErrNoVariant, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: templates/service.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	templates "mbx/templates"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockITemplateService is a mock of ITemplateService interface.
+type MockITemplateService struct {
+	ctrl     *gomock.Controller
+	recorder *MockITemplateServiceMockRecorder
+}
+
+// MockITemplateServiceMockRecorder is the mock recorder for MockITemplateService.
+type MockITemplateServiceMockRecorder struct {
+	mock *MockITemplateService
+}
+
+// NewMockITemplateService creates a new mock instance.
+func NewMockITemplateService(ctrl *gomock.Controller) *MockITemplateService {
+	mock := &MockITemplateService{ctrl: ctrl}
+	mock.recorder = &MockITemplateServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockITemplateService) EXPECT() *MockITemplateServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockITemplateService) Create(arg0 context.Context, arg1 templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(*templates.SavedTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockITemplateServiceMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockITemplateService)(nil).Create), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockITemplateService) List(arg0 context.Context) ([]templates.SavedTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]templates.SavedTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockITemplateServiceMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockITemplateService)(nil).List), arg0)
+}
+
+// MockGroupResolver is a mock of GroupResolver interface.
+type MockGroupResolver struct {
+	ctrl     *gomock.Controller
+	recorder *MockGroupResolverMockRecorder
+}
+
+// MockGroupResolverMockRecorder is the mock recorder for MockGroupResolver.
+type MockGroupResolverMockRecorder struct {
+	mock *MockGroupResolver
+}
+
+// NewMockGroupResolver creates a new mock instance.
+func NewMockGroupResolver(ctrl *gomock.Controller) *MockGroupResolver {
+	mock := &MockGroupResolver{ctrl: ctrl}
+	mock.recorder = &MockGroupResolverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGroupResolver) EXPECT() *MockGroupResolverMockRecorder {
+	return m.recorder
+}
+
+// Resolve mocks base method.
+func (m *MockGroupResolver) Resolve(ctx context.Context, name, locale string) (*templates.ResolvedTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resolve", ctx, name, locale)
+	ret0, _ := ret[0].(*templates.ResolvedTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resolve indicates an expected call of Resolve.
+func (mr *MockGroupResolverMockRecorder) Resolve(ctx, name, locale interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockGroupResolver)(nil).Resolve), ctx, name, locale)
+}
@@ -6,3 +6,11 @@ type TemplateRepository interface {
 	List(context.Context) ([]SavedTemplate, error)
 	Create(context.Context, CreateTemplateDTO) (*SavedTemplate, error)
 }
+
+type GroupRepository interface {
+	List(context.Context) ([]Group, error)
+	FindByName(ctx context.Context, name string) (*Group, error)
+	Create(context.Context, Group) error
+	UpsertVariant(ctx context.Context, name string, variant Variant) error
+	DeleteVariant(ctx context.Context, name string, language string) error
+}
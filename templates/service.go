@@ -20,3 +20,75 @@ func (s *Service) List(ctx context.Context) ([]SavedTemplate, error) {
 func (s *Service) Create(ctx context.Context, dto CreateTemplateDTO) (*SavedTemplate, error) {
 	return s.repo.Create(ctx, dto)
 }
+
+// GroupResolver resolves a logical template name and recipient locale into
+// the content SID that should be sent.
+type GroupResolver interface {
+	Resolve(ctx context.Context, name string, locale string) (*ResolvedTemplate, error)
+}
+
+type GroupConfig struct {
+	// DefaultLanguages are tried, in order, after the recipient's own language
+	DefaultLanguages []string
+	// PreferredRegions maps a bare language to the region tried first for it,
+	// e.g. {"pt": "pt_BR"} makes pt_PT fall back to pt_BR before en
+	PreferredRegions map[string]string
+}
+
+type GroupService struct {
+	repo   GroupRepository
+	config GroupConfig
+}
+
+var _ GroupResolver = (*GroupService)(nil)
+
+func NewGroupService(repo GroupRepository, config GroupConfig) *GroupService {
+	return &GroupService{repo: repo, config: config}
+}
+
+func (s *GroupService) List(ctx context.Context) ([]Group, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *GroupService) FindByName(ctx context.Context, name string) (*Group, error) {
+	return s.repo.FindByName(ctx, name)
+}
+
+func (s *GroupService) Create(ctx context.Context, group Group) error {
+	for i, v := range group.Variants {
+		group.Variants[i].Language = NormalizeLocale(v.Language)
+	}
+	return s.repo.Create(ctx, group)
+}
+
+func (s *GroupService) UpsertVariant(ctx context.Context, name string, variant Variant) error {
+	group, err := s.repo.FindByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return ErrGroupNotFound
+	}
+
+	variant.Language = NormalizeLocale(variant.Language)
+	return s.repo.UpsertVariant(ctx, name, variant)
+}
+
+func (s *GroupService) DeleteVariant(ctx context.Context, name string, language string) error {
+	return s.repo.DeleteVariant(ctx, name, NormalizeLocale(language))
+}
+
+// Resolve finds the content SID of the group for the locale, walking the
+// fallback chain built from the configured preferred regions and defaults.
+func (s *GroupService) Resolve(ctx context.Context, name string, locale string) (*ResolvedTemplate, error) {
+	group, err := s.repo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+
+	chain := FallbackChain(locale, s.config.PreferredRegions, s.config.DefaultLanguages...)
+	return group.Resolve(chain)
+}
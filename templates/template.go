@@ -1,6 +1,9 @@
 package templates
 
 import (
+	"mbx/models"
+
+	"github.com/google/uuid"
 	content "github.com/twilio/twilio-go/rest/content/v1"
 )
 
@@ -129,6 +132,12 @@ type WhatsappTemplateDTO struct {
 	TemplateId string            `json:"template"`
 	Content    map[string]string `json:"content"`
 	Language   string            `json:"language"`
+	// TemplateName and Locale select a template group variant instead of TemplateId
+	TemplateName string `json:"template_name,omitempty"`
+	Locale       string `json:"locale,omitempty"`
+	// ContactId addresses a contact instead of To
+	ContactId *uuid.UUID `json:"contact_id,omitempty"`
+	models.From
 }
 
 type WhatsappTemplate struct {
@@ -136,4 +145,5 @@ type WhatsappTemplate struct {
 	TemplateId string `json:"template"`
 	Content    string `json:"content"`
 	Language   string `json:"language"`
+	models.From
 }
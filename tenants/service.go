@@ -0,0 +1,137 @@
+package tenants
+
+import (
+	"context"
+	"fmt"
+	"mbx/sender"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo     Repository
+	defaults sender.Config
+}
+
+// NewService creates the tenant service. The default tenant sends with the
+// credentials and number of defaults; every other tenant needs a subaccount
+// of its own, so that nothing it reads from the provider belongs to another.
+func NewService(repo Repository, defaults sender.Config) *Service {
+	return &Service{repo: repo, defaults: defaults}
+}
+
+func (s *Service) Create(ctx context.Context, tenant Tenant) (*Tenant, error) {
+	tenant.Id = uuid.New()
+	if err := validate(&tenant); err != nil {
+		return nil, err
+	}
+
+	tenant.CreatedAt = time.Now()
+	tenant.UpdatedAt = tenant.CreatedAt
+	if err := s.repo.Create(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func (s *Service) Update(ctx context.Context, tenant Tenant) (*Tenant, error) {
+	if err := validate(&tenant); err != nil {
+		return nil, err
+	}
+
+	tenant.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func validate(tenant *Tenant) error {
+	tenant.Name = strings.TrimSpace(tenant.Name)
+	tenant.AccountSid = strings.TrimSpace(tenant.AccountSid)
+	tenant.AuthToken = strings.TrimSpace(tenant.AuthToken)
+	tenant.FromNumber = strings.TrimPrefix(strings.TrimSpace(tenant.FromNumber), "whatsapp:")
+
+	if tenant.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTenant)
+	}
+	if (tenant.AccountSid == "") != (tenant.AuthToken == "") {
+		return fmt.Errorf("%w: account_sid and auth_token go together", ErrInvalidTenant)
+	}
+	if tenant.AccountSid == "" && tenant.Id != DefaultId {
+		return fmt.Errorf("%w: account_sid and auth_token are required", ErrInvalidTenant)
+	}
+	if tenant.AccountSid != "" && !strings.HasPrefix(tenant.AccountSid, "AC") {
+		return fmt.Errorf("%w: account_sid must be a Twilio account SID", ErrInvalidTenant)
+	}
+	if tenant.FromNumber == "" {
+		if tenant.Id != DefaultId {
+			return fmt.Errorf("%w: from_number is required", ErrInvalidTenant)
+		}
+		return nil
+	}
+	if !strings.HasPrefix(tenant.FromNumber, "+") || strings.Trim(tenant.FromNumber[1:], "0123456789") != "" {
+		return fmt.Errorf("%w: from_number must be in E.164 format", ErrInvalidTenant)
+	}
+	return nil
+}
+
+func (s *Service) FindById(ctx context.Context, id uuid.UUID) (*Tenant, error) {
+	return s.repo.FindById(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]Tenant, error) {
+	return s.repo.List(ctx)
+}
+
+// FindByNumber returns the tenant sending from number, as its own number or
+// one of its sender pool. number may carry the whatsapp: prefix. Numbers no
+// tenant claims belong to the default tenant.
+func (s *Service) FindByNumber(ctx context.Context, number string) (*Tenant, error) {
+	tenant, err := s.repo.FindByNumber(ctx, strings.TrimPrefix(number, "whatsapp:"))
+	if err != nil || tenant != nil {
+		return tenant, err
+	}
+	return s.find(ctx, DefaultId)
+}
+
+// Config returns the provider configuration of the tenant ctx is scoped to.
+// Tenants other than the default one never get the main account: messages,
+// templates and services listed through it would be those of every tenant.
+func (s *Service) Config(ctx context.Context) (*sender.Config, error) {
+	tenant, err := s.find(ctx, FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if tenant.Id != DefaultId && tenant.AccountSid == "" {
+		return nil, fmt.Errorf("%w: tenant %s has no account of its own", ErrInvalidTenant, tenant.Id)
+	}
+	cfg := s.ConfigOf(tenant)
+	return &cfg, nil
+}
+
+// ConfigOf returns the provider configuration of tenant
+func (s *Service) ConfigOf(tenant *Tenant) sender.Config {
+	cfg := s.defaults
+	if tenant.AccountSid != "" {
+		cfg.TwilioAccountSID = tenant.AccountSid
+		cfg.TwilioAuthToken = tenant.AuthToken
+	}
+	if tenant.FromNumber != "" {
+		cfg.TwilioFromNumber = "whatsapp:" + tenant.FromNumber
+	}
+	return cfg
+}
+
+func (s *Service) find(ctx context.Context, id uuid.UUID) (*Tenant, error) {
+	tenant, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return tenant, nil
+}
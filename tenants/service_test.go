@@ -0,0 +1,131 @@
+package tenants_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mbx/sender"
+	"mbx/tenants"
+	"mbx/tenants/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+var defaults = sender.Config{
+	TwilioAccountSID:  "ACmain",
+	TwilioAuthToken:   "main-token",
+	TwilioFromNumber:  "whatsapp:+15550000",
+	StatusCallbackURL: "https://mbx.example.com/callbacks/twilio/status",
+}
+
+func TestService_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := tenants.NewService(repo, defaults)
+
+	invalid := []tenants.Tenant{
+		{FromNumber: "+15550001"},
+		{Name: "Brand"},
+		{Name: "Brand", FromNumber: "15550001"},
+		{Name: "Brand", FromNumber: "+15550001", AccountSid: "ACsub"},
+		{Name: "Brand", FromNumber: "+15550001", AccountSid: "SKsub", AuthToken: "token"},
+		{Name: "Brand", FromNumber: "+15550001"},
+	}
+	for _, tenant := range invalid {
+		if _, err := service.Create(context.Background(), tenant); !errors.Is(err, tenants.ErrInvalidTenant) {
+			t.Errorf("Expected ErrInvalidTenant for %+v, got %v", tenant, err)
+		}
+	}
+
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	created, err := service.Create(context.Background(), tenants.Tenant{Name: " Brand ", AccountSid: "ACsub", AuthToken: "token", FromNumber: "whatsapp:+15550001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != "Brand" || created.FromNumber != "+15550001" || created.Id == uuid.Nil {
+		t.Errorf("Expected a normalized tenant, got %+v", created)
+	}
+}
+
+func TestService_Config(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := tenants.NewService(repo, defaults)
+
+	brand := tenants.Tenant{Id: uuid.New(), Name: "Brand", AccountSid: "ACsub", AuthToken: "sub-token", FromNumber: "+15550001"}
+	repo.EXPECT().FindById(gomock.Any(), brand.Id).Return(&brand, nil)
+	cfg, err := service.Config(tenants.NewContext(context.Background(), brand.Id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TwilioAccountSID != "ACsub" || cfg.TwilioAuthToken != "sub-token" || cfg.TwilioFromNumber != "whatsapp:+15550001" {
+		t.Errorf("Expected the brand account and number, got %+v", cfg)
+	}
+	if cfg.StatusCallbackURL != defaults.StatusCallbackURL {
+		t.Errorf("Expected the shared status callback, got %q", cfg.StatusCallbackURL)
+	}
+
+	repo.EXPECT().FindById(gomock.Any(), tenants.DefaultId).Return(&tenants.Tenant{Id: tenants.DefaultId, Name: "Default"}, nil)
+	cfg, err = service.Config(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cfg != defaults {
+		t.Errorf("Expected the default tenant to use the environment, got %+v", cfg)
+	}
+
+	// a tenant left without an account must not fall back to the main one
+	legacy := tenants.Tenant{Id: uuid.New(), Name: "Legacy", FromNumber: "+15550002"}
+	repo.EXPECT().FindById(gomock.Any(), legacy.Id).Return(&legacy, nil)
+	if _, err := service.Config(tenants.NewContext(context.Background(), legacy.Id)); !errors.Is(err, tenants.ErrInvalidTenant) {
+		t.Errorf("Expected ErrInvalidTenant, got %v", err)
+	}
+
+	missing := uuid.New()
+	repo.EXPECT().FindById(gomock.Any(), missing).Return(nil, nil)
+	if _, err := service.Config(tenants.NewContext(context.Background(), missing)); !errors.Is(err, tenants.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestService_FindByNumber(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := tenants.NewService(repo, defaults)
+
+	brand := tenants.Tenant{Id: uuid.New(), Name: "Brand", FromNumber: "+15550001"}
+	repo.EXPECT().FindByNumber(gomock.Any(), "+15550001").Return(&brand, nil)
+	tenant, err := service.FindByNumber(context.Background(), "whatsapp:+15550001")
+	if err != nil || tenant.Id != brand.Id {
+		t.Errorf("Expected the brand, got %+v, %v", tenant, err)
+	}
+
+	repo.EXPECT().FindByNumber(gomock.Any(), "+15559999").Return(nil, nil)
+	repo.EXPECT().FindById(gomock.Any(), tenants.DefaultId).Return(&tenants.Tenant{Id: tenants.DefaultId}, nil)
+	tenant, err = service.FindByNumber(context.Background(), "whatsapp:+15559999")
+	if err != nil || tenant.Id != tenants.DefaultId {
+		t.Errorf("Expected unclaimed numbers to fall back to the default tenant, got %+v, %v", tenant, err)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	id, err := tenants.Require(context.Background())
+	if err != nil || id != tenants.DefaultId {
+		t.Errorf("Expected requests without a tenant to use the default one, got %s, %v", id, err)
+	}
+
+	background := tenants.Background(context.Background())
+	if _, err := tenants.Require(background); !errors.Is(err, tenants.ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant on a background path, got %v", err)
+	}
+	if id := tenants.FromContext(background); id != uuid.Nil {
+		t.Errorf("Expected no tenant on a background path, got %s", id)
+	}
+
+	brand := uuid.New()
+	if id, err := tenants.Require(tenants.NewContext(background, brand)); err != nil || id != brand {
+		t.Errorf("Expected the scoped tenant, got %s, %v", id, err)
+	}
+}
@@ -0,0 +1,87 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound      = errors.New("tenant not found")
+	ErrInvalidTenant = errors.New("invalid tenant")
+	ErrDuplicate     = errors.New("another tenant already sends from this number")
+	// ErrNoTenant is returned for background work that was not scoped to a
+	// tenant
+	ErrNoTenant = errors.New("background work is not scoped to a tenant")
+)
+
+// DefaultId is the tenant of everything created before tenants existed. It
+// sends through the provider account configured in the environment.
+var DefaultId = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// Tenant is a brand with its own provider account, sender number and data.
+// Every repository only sees the rows of the tenant in the request context.
+type Tenant struct {
+	Id   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// AccountSid and AuthToken are the Twilio account or subaccount the tenant
+	// sends through. When empty, the account of the environment is used.
+	AccountSid string `json:"account_sid,omitempty"`
+	AuthToken  string `json:"-"`
+	// FromNumber is the WhatsApp number the tenant sends from, in E.164
+	// format. Only the default tenant may leave it to the environment.
+	FromNumber string    `json:"from_number,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type Repository interface {
+	Create(context.Context, Tenant) error
+	Update(context.Context, Tenant) error
+	FindById(ctx context.Context, id uuid.UUID) (*Tenant, error)
+	// FindByNumber returns the tenant that sends from number, its own or
+	// one of its sender pool
+	FindByNumber(ctx context.Context, number string) (*Tenant, error)
+	List(context.Context) ([]Tenant, error)
+}
+
+type contextKey struct{}
+
+type backgroundKey struct{}
+
+// NewContext returns a copy of ctx scoped to the tenant
+func NewContext(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Background returns a copy of ctx for work done outside of requests, such as
+// the workers. It has no default tenant: each piece of work has to be scoped
+// to its own with NewContext.
+func Background(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundKey{}, true)
+}
+
+// FromContext returns the tenant ctx is scoped to, or the default tenant. On
+// background paths without a tenant it returns uuid.Nil, which matches no
+// rows and no tenant a row can be written for.
+func FromContext(ctx context.Context) uuid.UUID {
+	id, err := Require(ctx)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+// Require returns the tenant ctx is scoped to, like FromContext, but fails
+// with ErrNoTenant on background paths without a tenant
+func Require(ctx context.Context) (uuid.UUID, error) {
+	if id, ok := ctx.Value(contextKey{}).(uuid.UUID); ok {
+		return id, nil
+	}
+	if background, _ := ctx.Value(backgroundKey{}).(bool); background {
+		return uuid.Nil, ErrNoTenant
+	}
+	return DefaultId, nil
+}
@@ -0,0 +1,96 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: throttle/throttle.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddRecipient mocks base method.
+func (m *MockRepository) AddRecipient(ctx context.Context, sender string, day time.Time, phone string, limit int) (bool, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRecipient", ctx, sender, day, phone, limit)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AddRecipient indicates an expected call of AddRecipient.
+func (mr *MockRepositoryMockRecorder) AddRecipient(ctx, sender, day, phone, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRecipient", reflect.TypeOf((*MockRepository)(nil).AddRecipient), ctx, sender, day, phone, limit)
+}
+
+// Prune mocks base method.
+func (m *MockRepository) Prune(ctx context.Context, day time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Prune", ctx, day)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Prune indicates an expected call of Prune.
+func (mr *MockRepositoryMockRecorder) Prune(ctx, day interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Prune", reflect.TypeOf((*MockRepository)(nil).Prune), ctx, day)
+}
+
+// RemoveRecipient mocks base method.
+func (m *MockRepository) RemoveRecipient(ctx context.Context, sender string, day time.Time, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRecipient", ctx, sender, day, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveRecipient indicates an expected call of RemoveRecipient.
+func (mr *MockRepositoryMockRecorder) RemoveRecipient(ctx, sender, day, phone interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRecipient", reflect.TypeOf((*MockRepository)(nil).RemoveRecipient), ctx, sender, day, phone)
+}
+
+// Reserve mocks base method.
+func (m *MockRepository) Reserve(ctx context.Context, sender string, now time.Time, interval time.Duration, burst int, deadline time.Time) (time.Time, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, sender, now, interval, burst, deadline)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockRepositoryMockRecorder) Reserve(ctx, sender, now, interval, burst, deadline interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockRepository)(nil).Reserve), ctx, sender, now, interval, burst, deadline)
+}
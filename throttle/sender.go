@@ -0,0 +1,178 @@
+package throttle
+
+import (
+	"context"
+	"log/slog"
+	"mbx/models"
+	"mbx/sender"
+	"mbx/templates"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type Config struct {
+	// Rate is how many messages a second the sender can send, 80 by default
+	Rate float64
+	// Burst is how many messages can go out at once after the sender was
+	// idle, one second of Rate by default
+	Burst int
+	// MaxWait is how long a send queues for its slot before it fails with
+	// ErrThrottled, a minute by default
+	MaxWait time.Duration
+	// Tier limits the unique recipients of a day
+	Tier Tier
+}
+
+// Numbers resolves the number messages without a sender of their own are sent
+// from, which depends on the tenant of the context
+type Numbers interface {
+	FromNumber(ctx context.Context) (string, error)
+}
+
+// Sender paces the messages of each sender number. Sends wait for their slot
+// in the bucket of their number instead of failing when a burst goes over the
+// rate.
+type Sender struct {
+	w       sender.Whatsapp
+	wt      sender.WhatsappTemplate
+	repo    Repository
+	config  Config
+	numbers Numbers
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+var _ sender.Whatsapp = (*Sender)(nil)
+var _ sender.WhatsappTemplate = (*Sender)(nil)
+
+func NewSender(w sender.Whatsapp, wt sender.WhatsappTemplate, repo Repository, config Config, numbers Numbers) *Sender {
+	if config.Rate <= 0 {
+		config.Rate = 80
+	}
+	if config.Burst <= 0 {
+		config.Burst = max(int(config.Rate), 1)
+	}
+	if config.MaxWait <= 0 {
+		config.MaxWait = time.Minute
+	}
+	return &Sender{w: w, wt: wt, repo: repo, config: config, numbers: numbers}
+}
+
+// bucket returns the number, or Messaging Service, whose limits a message from
+// from uses up
+func (s *Sender) bucket(ctx context.Context, from models.From) (string, error) {
+	switch {
+	case from.MessagingServiceSid != "":
+		return from.MessagingServiceSid, nil
+	case from.Number != "":
+		return "whatsapp:" + from.Number, nil
+	}
+	return s.numbers.FromNumber(ctx)
+}
+
+// admit returns once a message from from to to can be sent. The slot is
+// reserved before the recipient is counted, and release gives the count back
+// when the message does not go out after all.
+func (s *Sender) admit(ctx context.Context, from models.From, to string) (release func(), err error) {
+	bucket, err := s.bucket(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	interval := time.Duration(float64(time.Second) / s.config.Rate)
+	at, ok, err := s.repo.Reserve(ctx, bucket, now, interval, s.config.Burst, now.Add(s.config.MaxWait))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrThrottled
+	}
+
+	release = func() {}
+	if s.config.Tier != TierUnlimited {
+		day := Day(now)
+		phone := strings.TrimPrefix(to, "whatsapp:")
+		s.prune(ctx, day)
+		allowed, added, err := s.repo.AddRecipient(ctx, bucket, day, phone, int(s.config.Tier))
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, ErrTierLimit
+		}
+		if added {
+			release = func() {
+				// the send may have failed because ctx is done
+				if err := s.repo.RemoveRecipient(context.WithoutCancel(ctx), bucket, day, phone); err != nil {
+					slog.Error("Failed to give back a tier recipient", "error", err, "sender", bucket)
+				}
+			}
+		}
+	}
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return release, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+// prune forgets the recipients of past days, once a day
+func (s *Sender) prune(ctx context.Context, day time.Time) {
+	s.mu.Lock()
+	if !day.After(s.pruned) {
+		s.mu.Unlock()
+		return
+	}
+	s.pruned = day
+	s.mu.Unlock()
+
+	if err := s.repo.Prune(ctx, day); err != nil {
+		slog.Error("Failed to prune tier recipients", "error", err)
+	}
+}
+
+func (s *Sender) Send(ctx context.Context, message models.WhatsappBody) (*api.ApiV2010Message, error) {
+	release, err := s.admit(ctx, message.From, message.To)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.w.Send(ctx, message)
+	if err != nil {
+		release()
+	}
+	return resp, err
+}
+
+func (s *Sender) SendTemplate(ctx context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	release, err := s.admit(ctx, template.From, template.To)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.wt.SendTemplate(ctx, template)
+	if err != nil {
+		release()
+	}
+	return resp, err
+}
+
+func (s *Sender) CreateTemplate(ctx context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return s.wt.CreateTemplate(ctx, dto)
+}
+
+func (s *Sender) CancelMessage(ctx context.Context, twilioId string) error {
+	return s.w.CancelMessage(ctx, twilioId)
+}
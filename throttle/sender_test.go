@@ -0,0 +1,182 @@
+package throttle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mbx/models"
+	"mbx/templates"
+	"mbx/throttle"
+	"mbx/throttle/mocks"
+
+	"github.com/golang/mock/gomock"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type stubSender struct {
+	sent int
+}
+
+func (s *stubSender) Send(context.Context, models.WhatsappBody) (*api.ApiV2010Message, error) {
+	s.sent++
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CancelMessage(context.Context, string) error { return nil }
+
+func (s *stubSender) SendTemplate(context.Context, templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	s.sent++
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CreateTemplate(context.Context, templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return nil, nil
+}
+
+// number is a fixed sender number
+type number string
+
+func (n number) FromNumber(context.Context) (string, error) {
+	return string(n), nil
+}
+
+func TestSender_WaitsForSlot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	stub := &stubSender{}
+	s := throttle.NewSender(stub, stub, repo, throttle.Config{Rate: 10}, number("whatsapp:+15550001"))
+
+	repo.EXPECT().Reserve(gomock.Any(), "whatsapp:+15550001", gomock.Any(), 100*time.Millisecond, 10, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, now time.Time, _ time.Duration, _ int, deadline time.Time) (time.Time, bool, error) {
+			if deadline.Sub(now) != time.Minute {
+				t.Errorf("Expected to queue for a minute at most, got %v", deadline.Sub(now))
+			}
+			return now.Add(50 * time.Millisecond), true, nil
+		})
+
+	started := time.Now()
+	if _, err := s.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999990001", Body: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the send to wait for its slot, took %v", elapsed)
+	}
+	if stub.sent != 1 {
+		t.Errorf("Expected the message to be sent, got %d sends", stub.sent)
+	}
+}
+
+func TestSender_Throttled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	stub := &stubSender{}
+	s := throttle.NewSender(stub, stub, repo, throttle.Config{}, number("whatsapp:+15550001"))
+
+	repo.EXPECT().Reserve(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(time.Time{}, false, nil)
+
+	_, err := s.SendTemplate(context.Background(), templates.WhatsappTemplate{To: "+5511999990001", TemplateId: "HX123"})
+	if !errors.Is(err, throttle.ErrThrottled) || !errors.Is(err, throttle.ErrLimited) {
+		t.Errorf("Expected ErrThrottled, got %v", err)
+	}
+	if stub.sent != 0 {
+		t.Errorf("Expected nothing to be sent, got %d sends", stub.sent)
+	}
+}
+
+func TestSender_TierLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	stub := &stubSender{}
+	s := throttle.NewSender(stub, stub, repo, throttle.Config{Tier: throttle.Tier1K}, number("whatsapp:+15550001"))
+
+	today := throttle.Day(time.Now())
+	repo.EXPECT().Prune(gomock.Any(), today).Return(nil)
+	repo.EXPECT().AddRecipient(gomock.Any(), "whatsapp:+15550001", today, "+5511999990001", 1000).Return(true, true, nil)
+	repo.EXPECT().AddRecipient(gomock.Any(), "whatsapp:+15550001", today, "+5511999990002", 1000).Return(false, false, nil)
+	repo.EXPECT().Reserve(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, now time.Time, _ time.Duration, _ int, _ time.Time) (time.Time, bool, error) {
+			return now, true, nil
+		}).Times(2)
+
+	if _, err := s.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999990001"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := s.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999990002"})
+	if !errors.Is(err, throttle.ErrTierLimit) {
+		t.Errorf("Expected ErrTierLimit, got %v", err)
+	}
+	if stub.sent != 1 {
+		t.Errorf("Expected only the first message to be sent, got %d sends", stub.sent)
+	}
+}
+
+// failingSender rejects every message
+type failingSender struct {
+	stubSender
+}
+
+func (s *failingSender) Send(context.Context, models.WhatsappBody) (*api.ApiV2010Message, error) {
+	return nil, errors.New("provider unavailable")
+}
+
+func TestSender_GivesBackRecipientOnFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	stub := &failingSender{}
+	s := throttle.NewSender(stub, stub, repo, throttle.Config{Tier: throttle.Tier1K}, number("whatsapp:+15550001"))
+
+	today := throttle.Day(time.Now())
+	gomock.InOrder(
+		repo.EXPECT().Reserve(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, now time.Time, _ time.Duration, _ int, _ time.Time) (time.Time, bool, error) {
+				return now, true, nil
+			}),
+		repo.EXPECT().Prune(gomock.Any(), today).Return(nil),
+		repo.EXPECT().AddRecipient(gomock.Any(), "whatsapp:+15550001", today, "+5511999990001", 1000).Return(true, true, nil),
+		repo.EXPECT().RemoveRecipient(gomock.Any(), "whatsapp:+15550001", today, "+5511999990001").Return(nil),
+	)
+
+	if _, err := s.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999990001"}); err == nil {
+		t.Fatal("Expected the provider error")
+	}
+}
+
+func TestSender_ThrottledBeforeCounting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	stub := &stubSender{}
+	s := throttle.NewSender(stub, stub, repo, throttle.Config{Tier: throttle.Tier1K}, number("whatsapp:+15550001"))
+
+	// no AddRecipient: a throttled send does not use up the day's quota
+	repo.EXPECT().Reserve(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(time.Time{}, false, nil)
+
+	if _, err := s.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999990001"}); !errors.Is(err, throttle.ErrThrottled) {
+		t.Errorf("Expected ErrThrottled, got %v", err)
+	}
+}
+
+func TestSender_PacesEachSender(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	stub := &stubSender{}
+	s := throttle.NewSender(stub, stub, repo, throttle.Config{}, number("whatsapp:+15550001"))
+
+	reserve := func(_ context.Context, _ string, now time.Time, _ time.Duration, _ int, _ time.Time) (time.Time, bool, error) {
+		return now, true, nil
+	}
+	repo.EXPECT().Reserve(gomock.Any(), "whatsapp:+15550002", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(reserve)
+	repo.EXPECT().Reserve(gomock.Any(), "MG123", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(reserve)
+
+	message := models.WhatsappBody{To: "whatsapp:+5511999990001", From: models.From{Number: "+15550002"}}
+	if _, err := s.Send(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	template := templates.WhatsappTemplate{To: "+5511999990001", From: models.From{MessagingServiceSid: "MG123"}}
+	if _, err := s.SendTemplate(context.Background(), template); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,69 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLimited is wrapped by the errors of sends held back by a sender limit.
+// Nothing was sent, so the message can be retried later.
+var ErrLimited = errors.New("sender limit reached")
+
+var (
+	ErrThrottled = fmt.Errorf("%w: too many messages queued for the sender", ErrLimited)
+	ErrTierLimit = fmt.Errorf("%w: daily recipients of the messaging tier used up", ErrLimited)
+)
+
+// Tier is the number of unique recipients a sender can start conversations
+// with in a day
+type Tier int
+
+const (
+	TierUnlimited Tier = 0
+	Tier1K        Tier = 1_000
+	Tier10K       Tier = 10_000
+	Tier100K      Tier = 100_000
+)
+
+// Repository keeps the limits of every sender in one place, so that every
+// replica draws from the same bucket
+type Repository interface {
+	// Reserve takes the next send slot of sender. Slots are freed one every
+	// interval, and up to burst of them can be taken at once. It returns when
+	// the slot can be used, or false when that is after deadline, in which
+	// case nothing is taken.
+	Reserve(ctx context.Context, sender string, now time.Time, interval time.Duration, burst int, deadline time.Time) (time.Time, bool, error)
+	// AddRecipient counts phone as a recipient of sender on day, unless it
+	// would be recipient number limit + 1. Phones already counted on the day
+	// are always allowed. added reports whether phone was counted by this
+	// call, and so can be given back with RemoveRecipient.
+	AddRecipient(ctx context.Context, sender string, day time.Time, phone string, limit int) (allowed bool, added bool, err error)
+	// RemoveRecipient gives back the count of phone on day, for a message
+	// that was not sent after all
+	RemoveRecipient(ctx context.Context, sender string, day time.Time, phone string) error
+	// Prune forgets the recipients counted before day
+	Prune(ctx context.Context, day time.Time) error
+}
+
+// Day returns the UTC day of t, which tier limits are counted in
+func Day(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// NextDay returns when the tier limits reached at t reset
+func NextDay(t time.Time) time.Time {
+	return Day(t).AddDate(0, 0, 1)
+}
+
+// RetryAt returns when a send held back at now by err, one of the limit
+// errors, is worth trying again
+func RetryAt(err error, now time.Time) time.Time {
+	if errors.Is(err, ErrTierLimit) {
+		return NextDay(now)
+	}
+	// the queue of the sender was full, and drains at its rate
+	return now.Add(time.Second)
+}
@@ -0,0 +1,115 @@
+// Package tracing sets up OpenTelemetry tracing, and carries trace context
+// over the places where work is handed over: scheduled messages, the message
+// history and webhook deliveries keep the traceparent of the request that
+// created them.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+type Config struct {
+	// Exporter is where spans go: ExporterOTLP, ExporterStdout for local
+	// use, or ExporterNone, the default. The OTLP exporter is configured by
+	// the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter string
+	// ServiceName names the service in the traces, mbx by default.
+	// OTEL_SERVICE_NAME takes precedence.
+	ServiceName string
+}
+
+// Setup installs the tracer provider and the W3C propagators. The returned
+// function flushes the spans that were not exported yet.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	if config.ServiceName == "" {
+		config.ServiceName = "mbx"
+	}
+	// context is propagated even when nothing is exported, so the callers
+	// and webhook receivers keep their traces
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracegrpc.New(ctx)
+	// console is what the OpenTelemetry specification calls it
+	case ExporterStdout, "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", config.Exporter, err)
+	}
+
+	// the detectors that come later win, so the environment overrides the
+	// service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(config.ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer starts the spans of the service
+func Tracer() trace.Tracer {
+	return otel.Tracer("mbx")
+}
+
+// TraceParent returns the W3C traceparent of the span in ctx, to be stored
+// with the work it started, or "" when ctx is not traced
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// SpanContext returns the span a stored traceparent refers to. It is invalid
+// when traceParent is empty or malformed.
+func SpanContext(traceParent string) trace.SpanContext {
+	if traceParent == "" {
+		return trace.SpanContext{}
+	}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": traceParent})
+	return trace.SpanContextFromContext(ctx)
+}
+
+// ContinueFrom returns ctx with the span of traceParent as the remote parent
+// of the next span, so work done later joins the trace that started it.
+// ctx is returned as is when traceParent is not valid.
+func ContinueFrom(ctx context.Context, traceParent string) context.Context {
+	sc := SpanContext(traceParent)
+	if !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
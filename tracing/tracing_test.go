@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceParent(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	traceParent := TraceParent(ctx)
+	if traceParent == "" {
+		t.Fatal("Expected the traceparent of the span")
+	}
+	if sc := SpanContext(traceParent); !sc.Equal(span.SpanContext().WithRemote(true)) {
+		t.Errorf("Expected %s to refer to the span, got %v", traceParent, sc)
+	}
+
+	if got := TraceParent(context.Background()); got != "" {
+		t.Errorf("Expected no traceparent without a span, got %q", got)
+	}
+}
+
+func TestContinueFrom(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	ctx, request := provider.Tracer("test").Start(context.Background(), "request")
+	request.End()
+	traceParent := TraceParent(ctx)
+
+	_, later := provider.Tracer("test").Start(ContinueFrom(context.Background(), traceParent), "later")
+	defer later.End()
+	if later.SpanContext().TraceID() != request.SpanContext().TraceID() {
+		t.Error("Expected the later span to join the trace of the request")
+	}
+	if parent := later.(sdktrace.ReadOnlySpan).Parent(); parent.SpanID() != request.SpanContext().SpanID() {
+		t.Errorf("Expected the request to be the parent, got %s", parent.SpanID())
+	}
+
+	for _, invalid := range []string{"", "00-garbage"} {
+		if ctx := ContinueFrom(context.Background(), invalid); trace.SpanContextFromContext(ctx).IsValid() {
+			t.Errorf("Expected %q to be ignored", invalid)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Exporter: "jaeger"}); err == nil {
+		t.Error("Expected an unknown exporter to fail")
+	}
+
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
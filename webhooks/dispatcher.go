@@ -0,0 +1,200 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mbx/egress"
+	"mbx/metrics"
+	"mbx/tracing"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type DispatcherConfig struct {
+	PoolingRate time.Duration
+	// Timeout bounds each request to an endpoint
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it fails
+	MaxAttempts int
+	// Backoff is the delay after the first failed attempt, doubled after
+	// each following one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	BatchSize  int
+	// AllowPrivateNetworks lets endpoints resolve to private and local
+	// addresses, for development
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher sends the queued deliveries. Several dispatchers can share the
+// queue; each delivery is claimed by one of them at a time.
+type Dispatcher struct {
+	config DispatcherConfig
+	repo   Repository
+	client *http.Client
+}
+
+func NewDispatcher(config DispatcherConfig, repo Repository) *Dispatcher {
+	if config.PoolingRate == 0 {
+		config.PoolingRate = 5 * time.Second
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 10
+	}
+	if config.Backoff == 0 {
+		config.Backoff = 30 * time.Second
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 6 * time.Hour
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50
+	}
+	return &Dispatcher{
+		config: config,
+		repo:   repo,
+		client: egress.NewClient(config.Timeout, config.AllowPrivateNetworks),
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PoolingRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := d.Dispatch(ctx); err != nil {
+				slog.Error("failed to dispatch webhooks", slog.Any("error", err))
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Dispatch sends one batch of due deliveries and returns how many were tried
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	// the lease outlives the requests of the batch, so a crashed dispatcher
+	// only delays its deliveries instead of losing them
+	lease := d.config.Timeout*time.Duration(d.config.BatchSize) + time.Minute
+	jobs, err := d.repo.ClaimDue(ctx, time.Now(), lease, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		d.deliver(ctx, job)
+	}
+	return len(jobs), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, job Job) {
+	// the attempt continues the trace that published the event, and the
+	// endpoint receives it in the traceparent header
+	ctx, span := tracing.Tracer().Start(tracing.ContinueFrom(ctx, job.Event.TraceParent), "webhooks.deliver",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("webhook.event", string(job.Event.Type)),
+			attribute.String("webhook.event_id", job.Event.Id.String()),
+			attribute.String("webhook.delivery_id", job.Delivery.Id.String()),
+			attribute.Int("webhook.attempt", job.Delivery.Attempts+1),
+		),
+	)
+	defer span.End()
+
+	delivery := job.Delivery
+	delivery.Attempts++
+	attempt := Attempt{
+		Id:         uuid.New(),
+		DeliveryId: delivery.Id,
+		Number:     delivery.Attempts,
+		CreatedAt:  time.Now(),
+	}
+
+	attempt.StatusCode, attempt.Error = d.post(ctx, job)
+	attempt.DurationMs = time.Since(attempt.CreatedAt).Milliseconds()
+	if attempt.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", attempt.StatusCode))
+	}
+	if attempt.Error != "" {
+		span.SetStatus(codes.Error, attempt.Error)
+	}
+
+	delivery.UpdatedAt = time.Now()
+	delivery.LastError = attempt.Error
+	result := "retry"
+	switch {
+	case attempt.Error == "":
+		delivery.Status = DeliveryDelivered
+		result = string(DeliveryDelivered)
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = DeliveryFailed
+		result = string(DeliveryFailed)
+		slog.Warn("webhook delivery failed", slog.String("delivery", delivery.Id.String()), slog.String("url", job.Endpoint.URL), slog.String("error", attempt.Error))
+	default:
+		delivery.NextAttemptAt = delivery.UpdatedAt.Add(d.backoff(delivery.Attempts))
+	}
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+
+	if err := d.repo.RecordAttempt(ctx, delivery, attempt); err != nil {
+		slog.Error("failed to record webhook attempt", slog.Any("error", err), slog.String("delivery", delivery.Id.String()))
+	}
+}
+
+// post sends the event and returns the response status and, when the
+// delivery did not succeed, why
+func (d *Dispatcher) post(ctx context.Context, job Job) (int, string) {
+	body, err := json.Marshal(job.Event)
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(job.Event.Type))
+	req.Header.Set(HeaderDelivery, job.Delivery.Id.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(job.Endpoint.Secret, now, body))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Sprintf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, ""
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.Backoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.MaxBackoff)
+}
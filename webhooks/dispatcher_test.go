@@ -0,0 +1,236 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"mbx/webhooks"
+	"mbx/webhooks/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const testSecret = "whsec_test"
+
+// receiver is a local endpoint that checks the signature of what it gets and
+// answers with status
+func receiver(t *testing.T, status int, received *[]webhooks.Event) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		unix, err := strconv.ParseInt(r.Header.Get(webhooks.HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		assert.True(t, webhooks.Verify(testSecret, time.Unix(unix, 0), body, r.Header.Get(webhooks.HeaderSignature)), "invalid signature")
+		assert.NotEmpty(t, r.Header.Get(webhooks.HeaderDelivery))
+
+		var event webhooks.Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, string(event.Type), r.Header.Get(webhooks.HeaderEvent))
+		*received = append(*received, event)
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newJob(url string, attempts int) webhooks.Job {
+	event := webhooks.Event{
+		Id:        uuid.New(),
+		Type:      webhooks.EventMessageDelivered,
+		Data:      json.RawMessage(`{"sid":"SM123","status":"delivered"}`),
+		CreatedAt: time.Now(),
+	}
+	endpoint := webhooks.Endpoint{Id: uuid.New(), URL: url, Secret: testSecret, Active: true}
+	return webhooks.Job{
+		Delivery: webhooks.Delivery{
+			Id:         uuid.New(),
+			EventId:    event.Id,
+			EndpointId: endpoint.Id,
+			Status:     webhooks.DeliveryPending,
+			Attempts:   attempts,
+		},
+		Event:    event,
+		Endpoint: endpoint,
+	}
+}
+
+func TestDispatcher_Delivered(t *testing.T) {
+	var received []webhooks.Event
+	server := receiver(t, http.StatusOK, &received)
+	job := newJob(server.URL, 0)
+
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), 10).Return([]webhooks.Job{job}, nil)
+	repo.EXPECT().RecordAttempt(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, d webhooks.Delivery, a webhooks.Attempt) error {
+			assert.Equal(t, webhooks.DeliveryDelivered, d.Status)
+			assert.Equal(t, 1, d.Attempts)
+			assert.Empty(t, d.LastError)
+			assert.Equal(t, job.Delivery.Id, a.DeliveryId)
+			assert.Equal(t, 1, a.Number)
+			assert.Equal(t, http.StatusOK, a.StatusCode)
+			return nil
+		})
+
+	dispatcher := webhooks.NewDispatcher(webhooks.DispatcherConfig{BatchSize: 10, AllowPrivateNetworks: true}, repo)
+	n, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, received, 1)
+	assert.Equal(t, job.Event.Id, received[0].Id)
+	assert.JSONEq(t, string(job.Event.Data), string(received[0].Data))
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	var received []webhooks.Event
+	server := receiver(t, http.StatusServiceUnavailable, &received)
+	job := newJob(server.URL, 2)
+
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]webhooks.Job{job}, nil)
+	repo.EXPECT().RecordAttempt(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, d webhooks.Delivery, a webhooks.Attempt) error {
+			assert.Equal(t, webhooks.DeliveryPending, d.Status)
+			assert.Equal(t, 3, d.Attempts)
+			assert.Contains(t, d.LastError, "503")
+			// third attempt: the backoff doubled twice
+			assert.WithinDuration(t, time.Now().Add(4*time.Minute), d.NextAttemptAt, 5*time.Second)
+			assert.Equal(t, 3, a.Number)
+			assert.Equal(t, http.StatusServiceUnavailable, a.StatusCode)
+			return nil
+		})
+
+	dispatcher := webhooks.NewDispatcher(webhooks.DispatcherConfig{Backoff: time.Minute, AllowPrivateNetworks: true}, repo)
+	_, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, received, 1)
+}
+
+func TestDispatcher_RefusesLocalEndpoints(t *testing.T) {
+	var received []webhooks.Event
+	server := receiver(t, http.StatusOK, &received)
+	job := newJob(server.URL, 0)
+
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]webhooks.Job{job}, nil)
+	repo.EXPECT().RecordAttempt(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, d webhooks.Delivery, a webhooks.Attempt) error {
+			assert.Equal(t, webhooks.DeliveryPending, d.Status)
+			assert.Contains(t, a.Error, "not publicly routable")
+			return nil
+		})
+
+	dispatcher := webhooks.NewDispatcher(webhooks.DispatcherConfig{}, repo)
+	_, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, received)
+}
+
+func TestDispatcher_PropagatesTrace(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	var traceParent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	job := newJob(server.URL, 0)
+	job.Event.TraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]webhooks.Job{job}, nil)
+	repo.EXPECT().RecordAttempt(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	dispatcher := webhooks.NewDispatcher(webhooks.DispatcherConfig{AllowPrivateNetworks: true}, repo)
+	_, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+
+	// the endpoint gets the delivery span, in the trace that published the event
+	require.Len(t, traceParent, 55)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-", traceParent[:36])
+	assert.NotEqual(t, job.Event.TraceParent, traceParent)
+}
+
+func TestDispatcher_FailsAfterMaxAttempts(t *testing.T) {
+	var received []webhooks.Event
+	server := receiver(t, http.StatusInternalServerError, &received)
+	job := newJob(server.URL, 4)
+
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]webhooks.Job{job}, nil)
+	repo.EXPECT().RecordAttempt(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, d webhooks.Delivery, a webhooks.Attempt) error {
+			assert.Equal(t, webhooks.DeliveryFailed, d.Status)
+			assert.Equal(t, 5, d.Attempts)
+			return nil
+		})
+
+	dispatcher := webhooks.NewDispatcher(webhooks.DispatcherConfig{MaxAttempts: 5, AllowPrivateNetworks: true}, repo)
+	_, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+}
+
+func TestService_PublishToSubscribers(t *testing.T) {
+	all := webhooks.Endpoint{Id: uuid.New(), URL: "https://a.example.com", Active: true}
+	readOnly := webhooks.Endpoint{Id: uuid.New(), URL: "https://b.example.com", Active: true, Events: []webhooks.EventType{webhooks.EventMessageRead}}
+	inactive := webhooks.Endpoint{Id: uuid.New(), URL: "https://c.example.com"}
+
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	repo.EXPECT().ListEndpoints(gomock.Any()).Return([]webhooks.Endpoint{all, readOnly, inactive}, nil)
+	repo.EXPECT().Enqueue(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, e webhooks.Event, deliveries []webhooks.Delivery) error {
+			assert.Equal(t, webhooks.EventMessageFailed, e.Type)
+			assert.JSONEq(t, `{"sid":"SM1"}`, string(e.Data))
+			require.Len(t, deliveries, 1)
+			assert.Equal(t, all.Id, deliveries[0].EndpointId)
+			assert.Equal(t, e.Id, deliveries[0].EventId)
+			assert.Equal(t, webhooks.DeliveryPending, deliveries[0].Status)
+			return nil
+		})
+
+	service := webhooks.NewService(repo)
+	err := service.Publish(context.Background(), webhooks.EventMessageFailed, map[string]string{"sid": "SM1"})
+	require.NoError(t, err)
+}
+
+func TestService_CreateEndpointValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+	service := webhooks.NewService(repo)
+
+	_, err := service.CreateEndpoint(context.Background(), webhooks.Endpoint{URL: "ftp://example.com"})
+	assert.ErrorIs(t, err, webhooks.ErrInvalidEndpoint)
+
+	_, err = service.CreateEndpoint(context.Background(), webhooks.Endpoint{URL: "https://example.com", Events: []webhooks.EventType{"message.bounced"}})
+	assert.ErrorIs(t, err, webhooks.ErrInvalidEndpoint)
+
+	repo.EXPECT().CreateEndpoint(gomock.Any(), gomock.Any()).Return(nil)
+	created, err := service.CreateEndpoint(context.Background(), webhooks.Endpoint{URL: "https://example.com/hooks", Active: true})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, created.Id)
+	assert.NotEmpty(t, created.Secret)
+}
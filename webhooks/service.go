@@ -0,0 +1,265 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mbx/history"
+	"mbx/inbound"
+	"mbx/tenants"
+	"mbx/tracing"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo Repository
+}
+
+var _ inbound.Listener = (*Service)(nil)
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) CreateEndpoint(ctx context.Context, endpoint Endpoint) (*Endpoint, error) {
+	if err := validateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	if endpoint.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		endpoint.Secret = secret
+	}
+	if endpoint.Events == nil {
+		endpoint.Events = []EventType{}
+	}
+
+	endpoint.Id = uuid.New()
+	endpoint.CreatedAt = time.Now()
+	if err := s.repo.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (s *Service) UpdateEndpoint(ctx context.Context, endpoint Endpoint) (*Endpoint, error) {
+	if err := validateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	if endpoint.Events == nil {
+		endpoint.Events = []EventType{}
+	}
+	if err := s.repo.UpdateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (s *Service) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteEndpoint(ctx, id)
+}
+
+func (s *Service) FindEndpoint(ctx context.Context, id uuid.UUID) (*Endpoint, error) {
+	return s.repo.FindEndpoint(ctx, id)
+}
+
+func (s *Service) ListEndpoints(ctx context.Context) ([]Endpoint, error) {
+	return s.repo.ListEndpoints(ctx)
+}
+
+// Publish records an event and queues its delivery to every subscribed
+// endpoint. Events are kept even without subscribers so they can be replayed.
+func (s *Service) Publish(ctx context.Context, eventType EventType, data any) error {
+	if _, err := tenants.Require(ctx); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	event := Event{
+		Id:        uuid.New(),
+		Type:      eventType,
+		Data:      payload,
+		CreatedAt: time.Now(),
+		// deliveries join the trace of what happened, e.g. the API call
+		// that sent the message
+		TraceParent: tracing.TraceParent(ctx),
+	}
+
+	endpoints, err := s.repo.ListEndpoints(ctx)
+	if err != nil {
+		return err
+	}
+	var subscribed []Endpoint
+	for _, e := range endpoints {
+		if e.Subscribed(eventType) {
+			subscribed = append(subscribed, e)
+		}
+	}
+	return s.repo.Enqueue(ctx, event, newDeliveries(event, subscribed))
+}
+
+// Replay queues the event again, to one endpoint or, when endpointId is nil,
+// to every endpoint currently subscribed to it
+func (s *Service) Replay(ctx context.Context, eventId uuid.UUID, endpointId *uuid.UUID) ([]Delivery, error) {
+	event, err := s.repo.FindEvent(ctx, eventId)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, ErrEventNotFound
+	}
+
+	var endpoints []Endpoint
+	if endpointId != nil {
+		endpoint, err := s.repo.FindEndpoint(ctx, *endpointId)
+		if err != nil {
+			return nil, err
+		}
+		if endpoint == nil {
+			return nil, ErrNotFound
+		}
+		endpoints = append(endpoints, *endpoint)
+	} else {
+		all, err := s.repo.ListEndpoints(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range all {
+			if e.Subscribed(event.Type) {
+				endpoints = append(endpoints, e)
+			}
+		}
+	}
+
+	deliveries := newDeliveries(*event, endpoints)
+	if err := s.repo.Enqueue(ctx, *event, deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s *Service) Events(ctx context.Context, limit int) ([]Event, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.repo.ListEvents(ctx, limit)
+}
+
+func (s *Service) Deliveries(ctx context.Context, endpointId uuid.UUID, limit int) ([]Delivery, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.repo.ListDeliveries(ctx, endpointId, limit)
+}
+
+func (s *Service) Attempts(ctx context.Context, deliveryId uuid.UUID) ([]Attempt, error) {
+	return s.repo.ListAttempts(ctx, deliveryId)
+}
+
+// QueueDepth returns how many deliveries wait to be sent, and how many ran
+// out of attempts
+func (s *Service) QueueDepth(ctx context.Context) (int, int, error) {
+	return s.repo.QueueDepth(ctx)
+}
+
+// ReceivedMessage is the data of message.received events
+type ReceivedMessage struct {
+	Sid         string               `json:"sid"`
+	ContactId   uuid.UUID            `json:"contact_id"`
+	From        string               `json:"from"`
+	To          string               `json:"to"`
+	Body        string               `json:"body"`
+	ProfileName string               `json:"profile_name,omitempty"`
+	NumMedia    int                  `json:"num_media,omitempty"`
+	Interaction *history.Interaction `json:"interaction,omitempty"`
+	ReceivedAt  time.Time            `json:"received_at"`
+}
+
+// HandleInbound publishes message.received for every inbound message
+func (s *Service) HandleInbound(ctx context.Context, msg *inbound.Message) error {
+	return s.Publish(ctx, EventMessageReceived, ReceivedMessage{
+		Sid:         msg.Sid,
+		ContactId:   msg.Contact.Id,
+		From:        msg.From,
+		To:          msg.To,
+		Body:        msg.Body,
+		ProfileName: msg.ProfileName,
+		NumMedia:    msg.NumMedia,
+		Interaction: msg.Interaction,
+		ReceivedAt:  msg.ReceivedAt,
+	})
+}
+
+// StatusChange is the data of the message status events
+type StatusChange struct {
+	Sid       string     `json:"sid"`
+	MessageId *uuid.UUID `json:"message_id,omitempty"`
+	ContactId *uuid.UUID `json:"contact_id,omitempty"`
+	Phone     string     `json:"phone,omitempty"`
+	Status    string     `json:"status"`
+	ErrorCode string     `json:"error_code,omitempty"`
+	ChangedAt time.Time  `json:"changed_at"`
+}
+
+// StatusEvent returns the event published when the provider reports a
+// message in status, if any
+func StatusEvent(status string) (EventType, bool) {
+	switch status {
+	case "sent":
+		return EventMessageSent, true
+	case "delivered":
+		return EventMessageDelivered, true
+	case "read":
+		return EventMessageRead, true
+	case "failed", "undelivered":
+		return EventMessageFailed, true
+	}
+	return "", false
+}
+
+func newDeliveries(event Event, endpoints []Endpoint) []Delivery {
+	deliveries := make([]Delivery, 0, len(endpoints))
+	for _, e := range endpoints {
+		deliveries = append(deliveries, Delivery{
+			Id:            uuid.New(),
+			EventId:       event.Id,
+			EndpointId:    e.Id,
+			Status:        DeliveryPending,
+			NextAttemptAt: event.CreatedAt,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		})
+	}
+	return deliveries
+}
+
+func validateEndpoint(endpoint Endpoint) error {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidEndpoint)
+	}
+	for _, e := range endpoint.Events {
+		if !slices.Contains(EventTypes, e) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidEndpoint, e)
+		}
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,32 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Mbx-Event"
+	HeaderDelivery  = "X-Mbx-Delivery"
+	HeaderTimestamp = "X-Mbx-Timestamp"
+	HeaderSignature = "X-Mbx-Signature"
+)
+
+// Sign returns the signature of a payload sent at timestamp, the hex encoded
+// HMAC-SHA256 of "<unix timestamp>.<body>" prefixed with "sha256=".
+// Receivers should recompute it and reject stale timestamps.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign
+func Verify(secret string, timestamp time.Time, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,128 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound        = errors.New("webhook endpoint not found")
+	ErrEventNotFound   = errors.New("webhook event not found")
+	ErrInvalidEndpoint = errors.New("invalid webhook endpoint")
+)
+
+type EventType string
+
+const (
+	EventMessageSent      EventType = "message.sent"
+	EventMessageDelivered EventType = "message.delivered"
+	EventMessageRead      EventType = "message.read"
+	EventMessageFailed    EventType = "message.failed"
+	EventMessageReceived  EventType = "message.received"
+)
+
+// EventTypes lists every event endpoints can subscribe to
+var EventTypes = []EventType{
+	EventMessageSent,
+	EventMessageDelivered,
+	EventMessageRead,
+	EventMessageFailed,
+	EventMessageReceived,
+}
+
+// Endpoint is a URL of one of our services that receives events
+type Endpoint struct {
+	Id  uuid.UUID `json:"id"`
+	URL string    `json:"url"`
+	// Secret signs the payloads, see Sign
+	Secret string `json:"secret"`
+	// Events the endpoint subscribes to, all of them when empty
+	Events    []EventType `json:"events"`
+	Active    bool        `json:"active"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func (e Endpoint) Subscribed(eventType EventType) bool {
+	return e.Active && (len(e.Events) == 0 || slices.Contains(e.Events, eventType))
+}
+
+// Event is something that happened, as delivered to the endpoints
+type Event struct {
+	Id        uuid.UUID       `json:"id"`
+	Type      EventType       `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+	// TraceParent is the W3C traceparent of the work that published the
+	// event. Its deliveries continue that trace and pass it on to the
+	// endpoints.
+	TraceParent string `json:"-"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is the queued sending of an event to an endpoint. Pending
+// deliveries are retried with backoff until they succeed or run out of
+// attempts.
+type Delivery struct {
+	Id            uuid.UUID      `json:"id"`
+	EventId       uuid.UUID      `json:"event_id"`
+	EndpointId    uuid.UUID      `json:"endpoint_id"`
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	LastError     string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Attempt is one try of a delivery, kept as a log
+type Attempt struct {
+	Id         uuid.UUID `json:"id"`
+	DeliveryId uuid.UUID `json:"delivery_id"`
+	Number     int       `json:"number"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Job is a claimed delivery with what is needed to send it
+type Job struct {
+	Delivery Delivery
+	Event    Event
+	Endpoint Endpoint
+}
+
+type Repository interface {
+	CreateEndpoint(context.Context, Endpoint) error
+	UpdateEndpoint(context.Context, Endpoint) error
+	DeleteEndpoint(context.Context, uuid.UUID) error
+	FindEndpoint(context.Context, uuid.UUID) (*Endpoint, error)
+	ListEndpoints(context.Context) ([]Endpoint, error)
+
+	FindEvent(context.Context, uuid.UUID) (*Event, error)
+	ListEvents(ctx context.Context, limit int) ([]Event, error)
+	// Enqueue stores the event with its deliveries in one transaction
+	Enqueue(context.Context, Event, []Delivery) error
+
+	// ClaimDue returns pending deliveries due at now and postpones them by
+	// lease, so that other dispatchers skip them while they are sent
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Job, error)
+	// RecordAttempt logs the attempt and saves the new state of its delivery
+	RecordAttempt(context.Context, Delivery, Attempt) error
+	ListDeliveries(ctx context.Context, endpointId uuid.UUID, limit int) ([]Delivery, error)
+	ListAttempts(ctx context.Context, deliveryId uuid.UUID) ([]Attempt, error)
+	// QueueDepth counts the pending and failed deliveries of every tenant
+	QueueDepth(context.Context) (pending int, failed int, err error)
+}
@@ -0,0 +1,106 @@
+package window
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mbx/contacts"
+	"mbx/models"
+	"mbx/provider/twilio"
+	"mbx/sender"
+	"mbx/templates"
+	"strings"
+
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type Config struct {
+	// FallbackTemplate is the template group sent instead of a freeform
+	// message outside the window, with the message body as variable {{1}}.
+	// When empty such messages are rejected.
+	FallbackTemplate string
+}
+
+// GuardedSender checks the customer service window before freeform sends and
+// either rejects them or converts them to the fallback template.
+type GuardedSender struct {
+	w         sender.Whatsapp
+	wt        sender.WhatsappTemplate
+	service   *Service
+	contacts  contacts.Resolver
+	templates templates.GroupResolver
+	config    Config
+}
+
+var _ sender.Whatsapp = (*GuardedSender)(nil)
+var _ sender.WhatsappTemplate = (*GuardedSender)(nil)
+
+func NewGuardedSender(w sender.Whatsapp, wt sender.WhatsappTemplate, service *Service, contacts contacts.Resolver, templates templates.GroupResolver, config Config) *GuardedSender {
+	return &GuardedSender{
+		w:         w,
+		wt:        wt,
+		service:   service,
+		contacts:  contacts,
+		templates: templates,
+		config:    config,
+	}
+}
+
+func (s *GuardedSender) Send(ctx context.Context, message models.WhatsappBody) (*api.ApiV2010Message, error) {
+	contact, err := s.contacts.Resolve(ctx, strings.TrimPrefix(message.To, "whatsapp:"))
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.service.State(ctx, contact.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !state.Open {
+		return s.fallback(ctx, contact, message)
+	}
+
+	resp, err := s.w.Send(ctx, message)
+	if twilio.ErrorCode(err) == twilio.CodeOutsideWindow {
+		// our view of the window disagrees with WhatsApp's, trust WhatsApp
+		return nil, fmt.Errorf("%w: %w", ErrWindowClosed, err)
+	}
+	return resp, err
+}
+
+func (s *GuardedSender) fallback(ctx context.Context, contact *contacts.Contact, message models.WhatsappBody) (*api.ApiV2010Message, error) {
+	if s.config.FallbackTemplate == "" {
+		return nil, ErrWindowClosed
+	}
+
+	resolved, err := s.templates.Resolve(ctx, s.config.FallbackTemplate, contact.Locale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve fallback template: %w", err)
+	}
+	variables, err := json.Marshal(map[string]string{"1": message.Body})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Converting freeform message outside the window to a template", "to", contact.Phone, "template", resolved.ContentSid)
+	return s.wt.SendTemplate(ctx, templates.WhatsappTemplate{
+		To:         contact.Phone,
+		TemplateId: resolved.ContentSid,
+		Content:    string(variables),
+		Language:   resolved.Language,
+		From:       message.From,
+	})
+}
+
+func (s *GuardedSender) SendTemplate(ctx context.Context, template templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	return s.wt.SendTemplate(ctx, template)
+}
+
+func (s *GuardedSender) CreateTemplate(ctx context.Context, dto templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return s.wt.CreateTemplate(ctx, dto)
+}
+
+func (s *GuardedSender) CancelMessage(ctx context.Context, twilioId string) error {
+	return s.w.CancelMessage(ctx, twilioId)
+}
@@ -0,0 +1,122 @@
+package window_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mbx/contacts"
+	cmocks "mbx/contacts/mocks"
+	"mbx/history"
+	hmocks "mbx/history/mocks"
+	"mbx/models"
+	"mbx/templates"
+	tmocks "mbx/templates/mocks"
+	"mbx/window"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/twilio/twilio-go/client"
+	api "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type stubSender struct {
+	sendErr   error
+	sent      []models.WhatsappBody
+	templates []templates.WhatsappTemplate
+}
+
+func (s *stubSender) Send(_ context.Context, msg models.WhatsappBody) (*api.ApiV2010Message, error) {
+	if s.sendErr != nil {
+		return nil, s.sendErr
+	}
+	s.sent = append(s.sent, msg)
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CancelMessage(context.Context, string) error { return nil }
+
+func (s *stubSender) SendTemplate(_ context.Context, t templates.WhatsappTemplate) (*api.ApiV2010Message, error) {
+	s.templates = append(s.templates, t)
+	return &api.ApiV2010Message{}, nil
+}
+
+func (s *stubSender) CreateTemplate(context.Context, templates.CreateTemplateDTO) (*templates.SavedTemplate, error) {
+	return nil, nil
+}
+
+func newGuard(t *testing.T, lastInbound *time.Time, config window.Config) (*window.GuardedSender, *stubSender) {
+	ctrl := gomock.NewController(t)
+
+	contact := &contacts.Contact{Id: uuid.New(), Phone: "+5511999998888", Locale: "pt_BR"}
+	resolver := cmocks.NewMockResolver(ctrl)
+	resolver.EXPECT().Resolve(gomock.Any(), contact.Phone).Return(contact, nil).AnyTimes()
+
+	historyRepo := hmocks.NewMockRepository(ctrl)
+	historyRepo.EXPECT().
+		LastMessageAt(gomock.Any(), contact.Id, history.DirectionInbound).
+		Return(lastInbound, nil).
+		AnyTimes()
+
+	groups := tmocks.NewMockGroupResolver(ctrl)
+	groups.EXPECT().
+		Resolve(gomock.Any(), "follow_up", "pt_BR").
+		Return(&templates.ResolvedTemplate{Name: "follow_up", Language: "pt_BR", ContentSid: "HX-follow-up"}, nil).
+		AnyTimes()
+
+	next := &stubSender{}
+	return window.NewGuardedSender(next, next, window.NewService(historyRepo), resolver, groups, config), next
+}
+
+func TestGuardedSender_OpenWindow(t *testing.T) {
+	last := time.Now().Add(-2 * time.Hour)
+	guard, next := newGuard(t, &last, window.Config{})
+
+	_, err := guard.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999998888", Body: "Hi"})
+	if err != nil {
+		t.Fatalf("Expected message to be sent, got %v", err)
+	}
+	if len(next.sent) != 1 {
+		t.Errorf("Expected one message to be sent, got %d", len(next.sent))
+	}
+}
+
+func TestGuardedSender_ClosedWindowRejected(t *testing.T) {
+	last := time.Now().Add(-25 * time.Hour)
+	guard, next := newGuard(t, &last, window.Config{})
+
+	_, err := guard.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999998888", Body: "Hi"})
+	if !errors.Is(err, window.ErrWindowClosed) {
+		t.Errorf("Expected ErrWindowClosed, got %v", err)
+	}
+	if len(next.sent) != 0 {
+		t.Errorf("Expected nothing to be sent, got %+v", next.sent)
+	}
+}
+
+func TestGuardedSender_ClosedWindowFallback(t *testing.T) {
+	guard, next := newGuard(t, nil, window.Config{FallbackTemplate: "follow_up"})
+
+	_, err := guard.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999998888", Body: "Your order shipped"})
+	if err != nil {
+		t.Fatalf("Expected fallback template to be sent, got %v", err)
+	}
+	if len(next.sent) != 0 || len(next.templates) != 1 {
+		t.Fatalf("Expected only the fallback template to be sent, got %+v and %+v", next.sent, next.templates)
+	}
+	if next.templates[0].TemplateId != "HX-follow-up" || next.templates[0].Content != `{"1":"Your order shipped"}` {
+		t.Errorf("Unexpected fallback template %+v", next.templates[0])
+	}
+}
+
+func TestGuardedSender_ProviderOutsideWindow(t *testing.T) {
+	last := time.Now().Add(-time.Hour)
+	guard, next := newGuard(t, &last, window.Config{})
+	next.sendErr = &client.TwilioRestError{Code: 63016, Message: "Outside the allowed window"}
+
+	_, err := guard.Send(context.Background(), models.WhatsappBody{To: "whatsapp:+5511999998888", Body: "Hi"})
+	if !errors.Is(err, window.ErrWindowClosed) {
+		t.Errorf("Expected ErrWindowClosed, got %v", err)
+	}
+}
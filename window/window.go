@@ -0,0 +1,50 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"mbx/history"
+	"mbx/sender"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrWindowClosed = fmt.Errorf("%w: outside the 24-hour customer service window", sender.ErrRejected)
+
+// Duration is how long after the customer's last message WhatsApp accepts
+// freeform messages
+const Duration = 24 * time.Hour
+
+// State is the customer service window of a contact
+type State struct {
+	Open          bool       `json:"open"`
+	LastInboundAt *time.Time `json:"last_inbound_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+type Service struct {
+	history history.Repository
+}
+
+func NewService(history history.Repository) *Service {
+	return &Service{history: history}
+}
+
+// State computes the window from the last inbound message of the contact
+func (s *Service) State(ctx context.Context, contactId uuid.UUID) (*State, error) {
+	last, err := s.history.LastMessageAt(ctx, contactId, history.DirectionInbound)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return &State{}, nil
+	}
+
+	expiresAt := last.Add(Duration)
+	return &State{
+		Open:          time.Now().Before(expiresAt),
+		LastInboundAt: last,
+		ExpiresAt:     &expiresAt,
+	}, nil
+}